@@ -0,0 +1,93 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// crawlHistoryLen is the number of crawls kept in the crawl history of a
+// package.
+const crawlHistoryLen = 20
+
+// CrawlRecord describes a crawl of a package.
+type CrawlRecord struct {
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	// Size of the encoded documentation in bytes, before truncation.
+	Size int `json:"size"`
+}
+
+// decodeCrawlRecord decodes an element of the crawl history list of a
+// package: the start time in Unix seconds, the duration in milliseconds and
+// the size, separated by spaces.
+func decodeCrawlRecord(s string) (CrawlRecord, error) {
+	var t, ms int64
+	var r CrawlRecord
+	if _, err := fmt.Sscanf(s, "%d %d %d", &t, &ms, &r.Size); err != nil {
+		return CrawlRecord{}, fmt.Errorf("bad crawl record %q: %v", s, err)
+	}
+	r.Time = time.Unix(t, 0).UTC()
+	r.Duration = time.Duration(ms) * time.Millisecond
+	return r, nil
+}
+
+var addCrawlRecordScript = redis.NewScript(0, `
+    local path = ARGV[1]
+    local t = ARGV[2]
+    local ms = ARGV[3]
+    local n = tonumber(ARGV[4])
+
+    local id = redis.call('HGET', 'ids', path)
+    if not id then
+        return false
+    end
+    local size = redis.call('HGET', 'pkg:' .. id, 'size') or '0'
+    redis.call('LPUSH', 'crawls:' .. id, t .. ' ' .. ms .. ' ' .. size)
+    return redis.call('LTRIM', 'crawls:' .. id, 0, n - 1)
+`)
+
+// AddCrawlRecord adds a crawl of the package with the given import path,
+// which started at t and took d, to the crawl history of the package. The
+// size of the crawl is the size of the documentation stored by the last
+// call to Put. The history of a package not in the database is not changed.
+func (db *Database) AddCrawlRecord(path string, t time.Time, d time.Duration) error {
+	c := db.Pool.Get()
+	defer c.Close()
+	_, err := addCrawlRecordScript.Do(c, path, t.Unix(), int64(d/time.Millisecond), crawlHistoryLen)
+	return err
+}
+
+// CrawlHistory returns the recent crawls of the package with the given
+// import path, most recent first.
+func (db *Database) CrawlHistory(path string) ([]CrawlRecord, error) {
+	c := db.readConn()
+	defer c.Close()
+
+	id, err := redis.String(c.Do("HGET", "ids", path))
+	if err == redis.ErrNil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	values, err := redis.Strings(c.Do("LRANGE", "crawls:"+id, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+	records := make([]CrawlRecord, 0, len(values))
+	for _, v := range values {
+		r, err := decodeCrawlRecord(v)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
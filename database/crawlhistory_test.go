@@ -0,0 +1,28 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDecodeCrawlRecord(t *testing.T) {
+	got, err := decodeCrawlRecord("1622505600 1500 48213")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := CrawlRecord{Time: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), Duration: 1500 * time.Millisecond, Size: 48213}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("decodeCrawlRecord() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := decodeCrawlRecord("1622505600 x"); err == nil {
+		t.Error("decodeCrawlRecord() of bad record returned no error")
+	}
+}
@@ -12,19 +12,39 @@
 //      terms: space separated search terms
 //      path: import path
 //      synopsis: synopsis
-//      gob: snappy compressed gob encoded doc.Package
+//      gob: snappy or gzip compressed gob encoded doc.Package
 //      score: document search score
 //      etag:
 //      kind: p=package, c=command, d=directory with no go files
+//      requires: newline separated "<path>\t<version>\t<replace>" module
+//      requirements of the go.mod file in the package directory
+//      updated: Unix time of the crawl which stored the document
+//      views: number of views of the package page, if view counting is
+//      enabled
 // index:<term> set: package ids for given search term
 // index:import:<path> set: packages with import path
 // index:project:<root> set: packages in project with root
+// index:require:<path> set: packages with go.mod file requiring module path
+// tree:<root> string: newline separated directories of the repository of the
+//      project with root, see doc.Package.Tree
+// index:suggest zset: "<lowercase path or last path element>\x00<path>" with
+//      score 0, for prefix lookups of import paths and package names
+// implementations hash maps "<import path>.<interface name>" to space
+//      separated "[*]<import path>.<type name>" types implementing the
+//      interface, rebuilt by UpdateImplementations
 // block set: packages to block
+// hidden set: ids of packages hidden from search and listings by SetHidden
+// gone hash maps path of permanently removed packages to the reason of removal
 // popular zset: package id, score
 // popular:0 string: scaled base time for popular scores
 // nextCrawl zset: package id, Unix time for next crawl
 // newCrawl set: new paths to crawl
 // badCrawl set: paths that returned error when crawling.
+// notFound:<path> string: set with a TTL when crawling path returned not found.
+// redirect:<path> string: canonical import path of path, set with a TTL when
+//      crawling path returned a redirect.
+// packageCount string: number of packages in ids
+// packageCount:host hash maps host to number of packages with that host
 
 // Package database manages storage for GoPkgDoc.
 package database
@@ -39,7 +59,6 @@ import (
 	"math"
 	"net/url"
 	"os"
-	"path"
 	"sort"
 	"strconv"
 	"strings"
@@ -48,7 +67,6 @@ import (
 
 	"cloud.google.com/go/trace"
 	"github.com/garyburd/redigo/redis"
-	"github.com/golang/snappy"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/appengine"
 	"google.golang.org/appengine/remote_api"
@@ -63,7 +81,25 @@ type Database struct {
 		Get() redis.Conn
 	}
 
+	// Replicas of the database at Pool. If set, read only queries for
+	// serving pages are sent to the replicas and fall back to Pool when a
+	// replica fails. Writes are always sent to Pool.
+	Replicas []interface {
+		Get() redis.Conn
+	}
+
 	RemoteClient *remote_api.Client
+
+	// Gzip compresses stored documents with gzip instead of snappy. Gzip
+	// takes more CPU time and less memory. Documents are readable with either
+	// setting and are stored with the current one on the next crawl.
+	Gzip bool
+
+	// Ranking are the weights of the search ranking of Query. If nil,
+	// DefaultRankingWeights are used.
+	Ranking *RankingWeights
+
+	next uint32 // next replica to read from
 }
 
 // Package represents the content of a package both for the search index and
@@ -77,6 +113,10 @@ type Package struct {
 	Fork        bool    `json:"fork,omitempty"`
 	Stars       int     `json:"stars,omitempty"`
 	Score       float64 `json:"score,omitempty"`
+
+	// Time of the last commit of the package in the App Engine search
+	// index, used to filter results by recency. Zero if unknown.
+	Updated time.Time `json:"-"`
 }
 
 type byPath []Package
@@ -134,15 +174,20 @@ func newRemoteClient(host string) (*remote_api.Client, error) {
 	return remote_api.NewClient(host, client)
 }
 
-// New creates a gddo database. serverURI, idleTimeout, and logConn configure
-// the use of redis. gaeEndpoint is the target of the App Engine remoteapi
-// endpoint.
-func New(serverURI string, idleTimeout time.Duration, logConn bool, gaeEndpoint string) (*Database, error) {
-	pool := &redis.Pool{
+// NewPool returns a pool of connections to the redis server at serverURI.
+func NewPool(serverURI string, idleTimeout time.Duration, logConn bool) *redis.Pool {
+	return &redis.Pool{
 		Dial:        newDBDialer(serverURI, logConn),
 		MaxIdle:     10,
 		IdleTimeout: idleTimeout,
 	}
+}
+
+// New creates a gddo database. serverURI, idleTimeout, and logConn configure
+// the use of redis. gaeEndpoint is the target of the App Engine remoteapi
+// endpoint.
+func New(serverURI string, idleTimeout time.Duration, logConn bool, gaeEndpoint string) (*Database, error) {
+	pool := NewPool(serverURI, idleTimeout, logConn)
 
 	var rc *remote_api.Client
 	if gaeEndpoint != "" {
@@ -169,7 +214,7 @@ func (db *Database) CheckHealth() error {
 
 // Exists returns true if package with import path exists in the database.
 func (db *Database) Exists(path string) (bool, error) {
-	c := db.Pool.Get()
+	c := db.readConn()
 	defer c.Close()
 	return redis.Bool(c.Do("HEXISTS", "ids", path))
 }
@@ -183,13 +228,22 @@ var putScript = redis.NewScript(0, `
     local etag = ARGV[6]
     local kind = ARGV[7]
     local nextCrawl = ARGV[8]
+    local requires = ARGV[9]
+    local updated = ARGV[10]
+    local committed = ARGV[11]
+    local size = ARGV[12]
 
     local id = redis.call('HGET', 'ids', path)
     if not id then
         id = redis.call('INCR', 'maxPackageId')
         redis.call('HSET', 'ids', path, id)
+        redis.call('INCR', 'packageCount')
+        redis.call('HINCRBY', 'packageCount:host', string.match(path, '^[^/]*'), 1)
     end
 
+    local base = string.match(path, '[^/]*$')
+    redis.call('ZADD', 'index:suggest', 0, string.lower(path) .. '\0' .. path, 0, string.lower(base) .. '\0' .. path)
+
     if etag ~= '' and etag == redis.call('HGET', 'pkg:' .. id, 'clone') then
         terms = ''
         score = 0
@@ -214,13 +268,14 @@ var putScript = redis.NewScript(0, `
 
     redis.call('SREM', 'badCrawl', path)
     redis.call('SREM', 'newCrawl', path)
+    redis.call('DEL', 'notFound:' .. path)
 
     if nextCrawl ~= '0' then
         redis.call('ZADD', 'nextCrawl', nextCrawl, id)
         redis.call('HSET', 'pkg:' .. id, 'crawl', nextCrawl)
     end
 
-    return redis.call('HMSET', 'pkg:' .. id, 'path', path, 'synopsis', synopsis, 'score', score, 'gob', gob, 'terms', terms, 'etag', etag, 'kind', kind)
+    return redis.call('HMSET', 'pkg:' .. id, 'path', path, 'synopsis', synopsis, 'score', score, 'gob', gob, 'terms', terms, 'etag', etag, 'kind', kind, 'requires', requires, 'updated', updated, 'committed', committed, 'size', size)
 `)
 
 var addCrawlScript = redis.NewScript(0, `
@@ -247,18 +302,37 @@ func (db *Database) Put(ctx context.Context, pdoc *doc.Package, nextCrawl time.T
 	c := db.Pool.Get()
 	defer c.Close()
 
+	hidden, err := redis.Bool(isHiddenScript.Do(c, pdoc.ImportPath))
+	if err != nil {
+		return err
+	}
+
 	score := 0.0
-	if !hide {
+	if !hide && !hidden {
 		score = documentScore(pdoc)
 	}
 	terms := documentTerms(pdoc, score)
 
-	var gobBuf bytes.Buffer
-	if err := gob.NewEncoder(&gobBuf).Encode(pdoc); err != nil {
+	// The directory tree is the same for all packages of the repository.
+	if len(pdoc.Tree) > 0 && pdoc.ProjectRoot != "" {
+		if _, err := c.Do("SET", "tree:"+normalizeProjectRoot(pdoc.ProjectRoot), strings.Join(pdoc.Tree, "\n")); err != nil {
+			return err
+		}
+	}
+	if pdoc.Tree != nil {
+		pdocNew := *pdoc
+		pdoc = &pdocNew
+		pdoc.Tree = nil
+	}
+
+	gobBytes, err := encodeDoc(pdoc, db.Gzip)
+	if err != nil {
 		return err
 	}
 
-	gobBytes := snappy.Encode(nil, gobBuf.Bytes())
+	// The size of the documentation before truncation is recorded in the
+	// crawl history to spot packages that grow too large.
+	size := len(gobBytes)
 
 	// Truncate large documents.
 	if len(gobBytes) > 1200000 {
@@ -270,11 +344,10 @@ func (db *Database) Put(ctx context.Context, pdoc *doc.Package, nextCrawl time.T
 		pdoc.Types = nil
 		pdoc.Consts = nil
 		pdoc.Examples = nil
-		gobBuf.Reset()
-		if err := gob.NewEncoder(&gobBuf).Encode(pdoc); err != nil {
+		gobBytes, err = encodeDoc(pdoc, db.Gzip)
+		if err != nil {
 			return err
 		}
-		gobBytes = snappy.Encode(nil, gobBuf.Bytes())
 	}
 
 	kind := "p"
@@ -290,6 +363,21 @@ func (db *Database) Put(ctx context.Context, pdoc *doc.Package, nextCrawl time.T
 		t = nextCrawl.Unix()
 	}
 
+	updated := int64(0)
+	if !pdoc.Updated.IsZero() {
+		updated = pdoc.Updated.Unix()
+	}
+
+	committed := int64(0)
+	if !pdoc.Committed.IsZero() {
+		committed = pdoc.Committed.Unix()
+	}
+
+	var requires []string
+	for _, r := range pdoc.Requires {
+		requires = append(requires, r.Path+"\t"+r.Version+"\t"+r.Replace)
+	}
+
 	// Get old version of the package to extract its imports.
 	// If the package does not exist, both oldDoc and err will be nil.
 	old, _, err := db.getDoc(ctx, c, pdoc.ImportPath)
@@ -297,11 +385,15 @@ func (db *Database) Put(ctx context.Context, pdoc *doc.Package, nextCrawl time.T
 		return err
 	}
 
-	_, err = putScript.Do(c, pdoc.ImportPath, pdoc.Synopsis, score, gobBytes, strings.Join(terms, " "), pdoc.Etag, kind, t)
+	_, err = putScript.Do(c, pdoc.ImportPath, pdoc.Synopsis, score, gobBytes, strings.Join(terms, " "), pdoc.Etag, kind, t, strings.Join(requires, "\n"), updated, committed, size)
 	if err != nil {
 		return err
 	}
 
+	if err := deleteImportGraphs(c, pdoc.ImportPath); err != nil {
+		return err
+	}
+
 	id, n, err := pkgIDAndImportCount(c, pdoc.ImportPath)
 	if err != nil {
 		return err
@@ -331,17 +423,17 @@ func (db *Database) Put(ctx context.Context, pdoc *doc.Package, nextCrawl time.T
 	paths := make(map[string]bool)
 	for _, p := range pdoc.Imports {
 		if gosrc.IsValidRemotePath(p) {
-			paths[p] = true
+			paths[gosrc.CanonicalPath(p)] = true
 		}
 	}
 	for _, p := range pdoc.TestImports {
 		if gosrc.IsValidRemotePath(p) {
-			paths[p] = true
+			paths[gosrc.CanonicalPath(p)] = true
 		}
 	}
 	for _, p := range pdoc.XTestImports {
 		if gosrc.IsValidRemotePath(p) {
-			paths[p] = true
+			paths[gosrc.CanonicalPath(p)] = true
 		}
 	}
 	if pdoc.ImportPath != pdoc.ProjectRoot && pdoc.ProjectRoot != "" {
@@ -508,22 +600,17 @@ func (db *Database) getDoc(ctx context.Context, c redis.Conn, path string) (*doc
 		return nil, time.Time{}, err
 	}
 
-	p, err = snappy.Decode(nil, p)
+	pdoc, err := decodeDoc(p)
 	if err != nil {
 		return nil, time.Time{}, err
 	}
 
-	var pdoc doc.Package
-	if err := gob.NewDecoder(bytes.NewReader(p)).Decode(&pdoc); err != nil {
-		return nil, time.Time{}, err
-	}
-
 	nextCrawl := pdoc.Updated
 	if t != 0 {
 		nextCrawl = time.Unix(t, 0).UTC()
 	}
 
-	return &pdoc, nextCrawl, err
+	return pdoc, nextCrawl, err
 }
 
 var getSubdirsScript = redis.NewScript(0, `
@@ -575,7 +662,7 @@ func (db *Database) getSubdirs(c redis.Conn, path string, pdoc *doc.Package) ([]
 		if err != nil {
 			return nil, err
 		}
-		if (kind == "p" || kind == "c") && strings.HasPrefix(pkg.Path, prefix) {
+		if (kind == "p" || kind == "c") && strings.HasPrefix(pkg.Path, prefix) && !gosrc.IsExcludedPath(pkg.Path) {
 			subdirs = append(subdirs, pkg)
 		}
 	}
@@ -586,7 +673,7 @@ func (db *Database) getSubdirs(c redis.Conn, path string, pdoc *doc.Package) ([]
 // Get gets the package documentation and sub-directories for the the given
 // import path.
 func (db *Database) Get(ctx context.Context, path string) (*doc.Package, []Package, time.Time, error) {
-	c := db.Pool.Get()
+	c := db.readConn()
 	defer c.Close()
 
 	pdoc, nextCrawl, err := db.getDoc(ctx, c, path)
@@ -607,7 +694,7 @@ func (db *Database) Get(ctx context.Context, path string) (*doc.Package, []Packa
 }
 
 func (db *Database) GetDoc(ctx context.Context, path string) (*doc.Package, time.Time, error) {
-	c := db.Pool.Get()
+	c := db.readConn()
 	defer c.Close()
 	return db.getDoc(ctx, c, path)
 }
@@ -622,12 +709,23 @@ var deleteScript = redis.NewScript(0, `
 
     for term in string.gmatch(redis.call('HGET', 'pkg:' .. id, 'terms') or '', '([^ ]+)') do
         redis.call('SREM', 'index:' .. term, id)
+        if string.sub(term, 1, 8) == 'project:' and redis.call('SCARD', 'index:' .. term) == 0 then
+            redis.call('DEL', 'tree:' .. string.sub(term, 9))
+        end
     end
 
+    local base = string.match(path, '[^/]*$')
+    redis.call('ZREM', 'index:suggest', string.lower(path) .. '\0' .. path, string.lower(base) .. '\0' .. path)
     redis.call('ZREM', 'nextCrawl', id)
     redis.call('SREM', 'newCrawl', path)
     redis.call('ZREM', 'popular', id)
     redis.call('DEL', 'pkg:' .. id)
+    redis.call('DEL', 'crawls:' .. id)
+    redis.call('DECR', 'packageCount')
+    local host = string.match(path, '^[^/]*')
+    if redis.call('HINCRBY', 'packageCount:host', host, -1) <= 0 then
+        redis.call('HDEL', 'packageCount:host', host)
+    end
     return redis.call('HDEL', 'ids', path)
 `)
 
@@ -647,10 +745,60 @@ func (db *Database) Delete(ctx context.Context, path string) error {
 		return err
 	}
 
+	if err := deleteImportGraphs(c, path); err != nil {
+		return err
+	}
 	_, err = deleteScript.Do(c, path)
 	return err
 }
 
+// PackageCount returns the number of packages in the database and the number
+// of packages for each host.
+func (db *Database) PackageCount() (int, map[string]int, error) {
+	c := db.readConn()
+	defer c.Close()
+	n, err := redis.Int(c.Do("GET", "packageCount"))
+	if err != nil && err != redis.ErrNil {
+		return 0, nil, err
+	}
+	hosts, err := redis.IntMap(c.Do("HGETALL", "packageCount:host"))
+	if err != nil {
+		return 0, nil, err
+	}
+	for host, count := range hosts {
+		if count <= 0 {
+			delete(hosts, host)
+		}
+	}
+	return n, hosts, nil
+}
+
+var repairPackageCountScript = redis.NewScript(0, `
+    local paths = redis.call('HKEYS', 'ids')
+    local hosts = {}
+    for i=1,#paths do
+        local host = string.match(paths[i], '^[^/]*')
+        hosts[host] = (hosts[host] or 0) + 1
+    end
+    redis.call('DEL', 'packageCount:host')
+    for host, n in pairs(hosts) do
+        redis.call('HSET', 'packageCount:host', host, n)
+    end
+    redis.call('SET', 'packageCount', #paths)
+    return #paths
+`)
+
+// RepairPackageCount recomputes the counts returned by PackageCount from the
+// set of packages in the database. It returns the number of packages.
+func (db *Database) RepairPackageCount() (int, error) {
+	c := db.Pool.Get()
+	defer c.Close()
+	return redis.Int(repairPackageCountScript.Do(c))
+}
+
+// CSynopsis is the synopsis of the "C" pseudo-package, which is not stored.
+const CSynopsis = "Package C is a \"pseudo-package\" used to access the C namespace from a cgo source file."
+
 func packages(reply interface{}, all bool) ([]Package, error) {
 	values, err := redis.Values(reply, nil)
 	if err != nil {
@@ -664,11 +812,11 @@ func packages(reply interface{}, all bool) ([]Package, error) {
 		if err != nil {
 			return nil, err
 		}
-		if !all && kind == "d" {
+		if (!all && kind == "d") || gosrc.IsExcludedPath(pkg.Path) {
 			continue
 		}
 		if pkg.Path == "C" {
-			pkg.Synopsis = "Package C is a \"pseudo-package\" used to access the C namespace from a cgo source file."
+			pkg.Synopsis = CSynopsis
 		}
 		result = append(result, pkg)
 	}
@@ -676,7 +824,7 @@ func packages(reply interface{}, all bool) ([]Package, error) {
 }
 
 func (db *Database) getPackages(key string, all bool) ([]Package, error) {
-	c := db.Pool.Get()
+	c := db.readConn()
 	defer c.Close()
 	reply, err := c.Do("SORT", key, "ALPHA", "BY", "pkg:*->path", "GET", "pkg:*->path", "GET", "pkg:*->synopsis", "GET", "pkg:*->kind")
 	if err != nil {
@@ -701,6 +849,95 @@ func (db *Database) Project(projectRoot string) ([]Package, error) {
 	return db.getPackages("index:project:"+normalizeProjectRoot(projectRoot), true)
 }
 
+// ProjectPackages returns the packages in the project with root projectRoot,
+// excluding directories without Go code.
+func (db *Database) ProjectPackages(projectRoot string) ([]Package, error) {
+	return db.getPackages("index:project:"+normalizeProjectRoot(projectRoot), false)
+}
+
+// ProjectTree returns the directory tree of the repository of the project
+// with root projectRoot, or nil if no tree is stored.
+func (db *Database) ProjectTree(projectRoot string) ([]string, error) {
+	c := db.readConn()
+	defer c.Close()
+	s, err := redis.String(c.Do("GET", "tree:"+normalizeProjectRoot(projectRoot)))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(s, "\n"), nil
+}
+
+// suggestScript returns the paths and popular scores of the indexed packages
+// with an import path or last path element starting with the prefix ARGV[1].
+// At most ARGV[2] entries of the suggest index are scanned. Hidden packages,
+// which have a zero document score, are excluded.
+var suggestScript = redis.NewScript(0, `
+    local prefix = ARGV[1]
+    local members = redis.call('ZRANGEBYLEX', 'index:suggest', '[' .. prefix, '[' .. prefix .. '\255', 'LIMIT', 0, ARGV[2])
+    local seen = {}
+    local result = {}
+    for i=1,#members do
+        local path = string.match(members[i], '%z(.*)$')
+        if path and not seen[path] then
+            seen[path] = true
+            local id = redis.call('HGET', 'ids', path)
+            if id and tonumber(redis.call('HGET', 'pkg:' .. id, 'score') or '0') > 0 then
+                result[#result+1] = path
+                result[#result+1] = redis.call('ZSCORE', 'popular', id) or '0'
+            end
+        end
+    end
+    return result
+`)
+
+// maxSuggestScan is the maximum number of entries of the suggest index
+// scanned for a prefix.
+const maxSuggestScan = 200
+
+// Suggest returns up to n import paths of packages with an import path or
+// last path element starting with prefix, ignoring case. The paths are
+// ordered by popularity.
+func (db *Database) Suggest(prefix string, n int) ([]string, error) {
+	c := db.readConn()
+	defer c.Close()
+	values, err := redis.Values(suggestScript.Do(c, strings.ToLower(prefix), maxSuggestScan))
+	if err != nil {
+		return nil, err
+	}
+	var pkgs []Package
+	for len(values) > 0 {
+		var pkg Package
+		values, err = redis.Scan(values, &pkg.Path, &pkg.Score)
+		if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return rankSuggestions(pkgs, n), nil
+}
+
+// rankSuggestions returns the paths of the n most popular packages, ordered
+// by decreasing popularity and then by path.
+func rankSuggestions(pkgs []Package, n int) []string {
+	sort.Slice(pkgs, func(i, j int) bool {
+		if pkgs[i].Score != pkgs[j].Score {
+			return pkgs[i].Score > pkgs[j].Score
+		}
+		return pkgs[i].Path < pkgs[j].Path
+	})
+	if len(pkgs) > n {
+		pkgs = pkgs[:n]
+	}
+	paths := make([]string, len(pkgs))
+	for i, pkg := range pkgs {
+		paths[i] = pkg.Path
+	}
+	return paths
+}
+
 func (db *Database) AllPackages() ([]Package, error) {
 	c := db.Pool.Get()
 	defer c.Close()
@@ -724,6 +961,60 @@ func (db *Database) AllPackages() ([]Package, error) {
 	return result, nil
 }
 
+// IndexedPackages returns the packages and commands of the standard library
+// and the indexed packages with their synopses, ordered by import path.
+// Packages with a zero document score, packages hidden by SetHidden,
+// directories without Go code and packages under a blocked path are
+// excluded.
+func (db *Database) IndexedPackages() ([]Package, error) {
+	std, err := db.GoIndex()
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := db.Index()
+	if err != nil {
+		return nil, err
+	}
+	c := db.readConn()
+	defer c.Close()
+	blocked, err := redis.Strings(c.Do("SMEMBERS", "block"))
+	if err != nil {
+		return nil, err
+	}
+	paths, err := redis.Strings(c.Do("SORT", "hidden", "BY", "nosort", "GET", "pkg:*->path"))
+	if err != nil {
+		return nil, err
+	}
+	hidden := make(map[string]bool)
+	for _, p := range paths {
+		hidden[p] = true
+	}
+	var result []Package
+	for _, pkg := range std {
+		if !hidden[pkg.Path] {
+			result = append(result, pkg)
+		}
+	}
+	for _, pkg := range pkgs {
+		if !hidden[pkg.Path] && !isBlockedPath(pkg.Path, blocked) {
+			result = append(result, pkg)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result, nil
+}
+
+// isBlockedPath returns whether path is one of the blocked paths or is under
+// one of them.
+func isBlockedPath(path string, blocked []string) bool {
+	for _, b := range blocked {
+		if path == b || strings.HasPrefix(path, b+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 var packagesScript = redis.NewScript(0, `
     local result = {}
     for i = 1,#ARGV do
@@ -747,7 +1038,7 @@ func (db *Database) Packages(paths []string) ([]Package, error) {
 	for _, p := range paths {
 		args = append(args, p)
 	}
-	c := db.Pool.Get()
+	c := db.readConn()
 	defer c.Close()
 	reply, err := packagesScript.Do(c, args...)
 	if err != nil {
@@ -758,16 +1049,113 @@ func (db *Database) Packages(paths []string) ([]Package, error) {
 	return pkgs, err
 }
 
+var synopsesScript = redis.NewScript(0, `
+    local result = {}
+    for i = 1,#ARGV do
+        local id = redis.call('HGET', 'ids', ARGV[i])
+        if id then
+            result[i] = redis.call('HGET', 'pkg:' .. id, 'synopsis')
+        else
+            result[i] = false
+        end
+    end
+    return result
+`)
+
+// Synopses returns the stored synopses of the packages in paths. Paths not in
+// the database are omitted from the result.
+func (db *Database) Synopses(paths []string) (map[string]string, error) {
+	args := make([]interface{}, len(paths))
+	for i, p := range paths {
+		args[i] = p
+	}
+	c := db.readConn()
+	defer c.Close()
+	values, err := redis.Values(synopsesScript.Do(c, args...))
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string)
+	for i, v := range values {
+		if v == nil || i >= len(paths) {
+			continue
+		}
+		synopsis, err := redis.String(v, nil)
+		if err != nil {
+			return nil, err
+		}
+		result[paths[i]] = synopsis
+	}
+	return result, nil
+}
+
 func (db *Database) ImporterCount(path string) (int, error) {
-	c := db.Pool.Get()
+	c := db.readConn()
 	defer c.Close()
 	return redis.Int(c.Do("SCARD", "index:import:"+path))
 }
 
+// ImporterCounts returns the number of importers of each of the paths.
+func (db *Database) ImporterCounts(paths []string) (map[string]int, error) {
+	c := db.readConn()
+	defer c.Close()
+	for _, p := range paths {
+		c.Send("SCARD", "index:import:"+p)
+	}
+	c.Flush()
+	result := make(map[string]int, len(paths))
+	for _, p := range paths {
+		n, err := redis.Int(c.Receive())
+		if err != nil {
+			return nil, err
+		}
+		result[p] = n
+	}
+	return result, nil
+}
+
 func (db *Database) Importers(path string) ([]Package, error) {
 	return db.getPackages("index:import:"+path, false)
 }
 
+// Dependent is a package with a go.mod file requiring a module.
+type Dependent struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+
+	// Module path and version, or directory, replacing the required module
+	// in the go.mod file of the package.
+	Replace string `json:"replace,omitempty"`
+}
+
+// Dependents returns the packages with a go.mod file requiring the module
+// with path modulePath at a version for which match returns true. Packages
+// without a go.mod file are not included.
+func (db *Database) Dependents(modulePath string, match func(version string) bool) ([]Dependent, error) {
+	c := db.readConn()
+	defer c.Close()
+	values, err := redis.Values(c.Do("SORT", "index:require:"+modulePath, "ALPHA", "BY", "pkg:*->path", "GET", "pkg:*->path", "GET", "pkg:*->requires"))
+	if err != nil {
+		return nil, err
+	}
+	var result []Dependent
+	for len(values) > 0 {
+		var path, requires string
+		values, err = redis.Scan(values, &path, &requires)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(requires, "\n") {
+			f := strings.SplitN(line, "\t", 3)
+			if len(f) != 3 || f[0] != modulePath || !match(f[1]) {
+				continue
+			}
+			result = append(result, Dependent{Path: path, Version: f[1], Replace: f[2]})
+		}
+	}
+	return result, nil
+}
+
 // Block puts a domain, repo or package into the block set, removes all the
 // packages under it from the database and prevents future crawling from it.
 func (db *Database) Block(root string) error {
@@ -828,15 +1216,179 @@ var isBlockedScript = redis.NewScript(0, `
 // IsBlocked returns whether the package is blocked or belongs to a blocked
 // domain/repo.
 func (db *Database) IsBlocked(path string) (bool, error) {
-	c := db.Pool.Get()
+	c := db.readConn()
 	defer c.Close()
 	return redis.Bool(isBlockedScript.Do(c, path))
 }
 
+var goneScript = redis.NewScript(0, `
+    local path = ''
+    for s in string.gmatch(ARGV[1], '[^/]+') do
+        path = path .. s
+        local reason = redis.call('HGET', 'gone', path)
+        if reason then
+            return reason
+        end
+        path = path .. '/'
+    end
+    return false
+`)
+
+// SetGone marks the packages with the path or under the path as permanently
+// removed for the given reason and deletes them from the database.
+func (db *Database) SetGone(path, reason string) error {
+	c := db.Pool.Get()
+	defer c.Close()
+	if _, err := c.Do("HSET", "gone", path, reason); err != nil {
+		return err
+	}
+	// Remove all packages under the path.
+	keys, err := redis.Strings(c.Do("HKEYS", "ids"))
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	for _, key := range keys {
+		if key == path || strings.HasPrefix(key, path) && key[len(path)] == '/' {
+			id, err := redis.String(c.Do("HGET", "ids", key))
+			if err != nil {
+				return fmt.Errorf("cannot get package id for %s: %v", key, err)
+			}
+			if _, err := deleteScript.Do(c, key); err != nil {
+				return err
+			}
+			if err := db.DeleteIndex(ctx, id); err != nil && err != search.ErrNoSuchDocument {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ClearGone removes the mark set by SetGone on path.
+func (db *Database) ClearGone(path string) error {
+	c := db.Pool.Get()
+	defer c.Close()
+	_, err := c.Do("HDEL", "gone", path)
+	return err
+}
+
+// Gone returns whether the package was permanently removed, directly or as
+// part of a removed domain/repo, and the reason of the removal.
+func (db *Database) Gone(path string) (gone bool, reason string, err error) {
+	c := db.readConn()
+	defer c.Close()
+	reason, err = redis.String(goneScript.Do(c, path))
+	if err == redis.ErrNil {
+		return false, "", nil
+	} else if err != nil {
+		return false, "", err
+	}
+	return true, reason, nil
+}
+
+var isHiddenScript = redis.NewScript(0, `
+    local id = redis.call('HGET', 'ids', ARGV[1])
+    if not id then
+        return 0
+    end
+    return redis.call('SISMEMBER', 'hidden', id)
+`)
+
+// setHiddenScript marks the package with path ARGV[1] as hidden if ARGV[2]
+// is 1 and clears the mark otherwise. The search terms and the score of the
+// package are replaced with ARGV[3] and ARGV[4].
+var setHiddenScript = redis.NewScript(0, `
+    local path = ARGV[1]
+    local hidden = ARGV[2]
+    local terms = ARGV[3]
+    local score = ARGV[4]
+
+    local id = redis.call('HGET', 'ids', path)
+    if not id then
+        return false
+    end
+
+    local update = {}
+    for term in string.gmatch(redis.call('HGET', 'pkg:' .. id, 'terms') or '', '([^ ]+)') do
+        update[term] = 1
+    end
+
+    for term in string.gmatch(terms, '([^ ]+)') do
+        update[term] = (update[term] or 0) + 2
+    end
+
+    for term, x in pairs(update) do
+        if x == 1 then
+            redis.call('SREM', 'index:' .. term, id)
+        elseif x == 2 then
+            redis.call('SADD', 'index:' .. term, id)
+        end
+    end
+
+    if hidden == '1' then
+        redis.call('SADD', 'hidden', id)
+    else
+        redis.call('SREM', 'hidden', id)
+    end
+    redis.call('HMSET', 'pkg:' .. id, 'terms', terms, 'score', score)
+    return id
+`)
+
+// SetHidden marks the package with the import path as hidden or clears the
+// mark. Hidden packages are served on request but are excluded from search
+// results, suggestions, popular packages and the index of packages. The mark
+// is kept when the package is crawled again.
+func (db *Database) SetHidden(ctx context.Context, path string, hidden bool) error {
+	c := db.Pool.Get()
+	defer c.Close()
+
+	pdoc, _, err := db.getDoc(ctx, c, path)
+	if err != nil {
+		return err
+	}
+	if pdoc == nil {
+		return fmt.Errorf("package %q not found", path)
+	}
+
+	score := 0.0
+	if !hidden {
+		score = documentScore(pdoc)
+	}
+	terms := documentTerms(pdoc, score)
+	if _, err := setHiddenScript.Do(c, path, hidden, strings.Join(terms, " "), score); err != nil {
+		return err
+	}
+
+	id, n, err := pkgIDAndImportCount(c, path)
+	if err != nil {
+		return err
+	}
+	if score > 0 {
+		if err := db.PutIndex(ctx, pdoc, id, score, n); err != nil {
+			log.Printf("Cannot put %q in index: %v", path, err)
+		}
+		return nil
+	}
+	if err := db.DeleteIndex(ctx, id); err != nil && err != search.ErrNoSuchDocument {
+		return err
+	}
+	return nil
+}
+
+// HiddenPackages returns the packages marked as hidden by SetHidden, ordered
+// by import path.
+func (db *Database) HiddenPackages() ([]Package, error) {
+	return db.getPackages("hidden", true)
+}
+
 type queryResult struct {
-	Path     string
-	Synopsis string
-	Score    float64
+	Path        string
+	Synopsis    string
+	Score       float64
+	Updated     int64 // Unix time, 0 if unknown
+	Committed   int64 // Unix time of the last commit, 0 if unknown
+	ImportCount int
 }
 
 type byScore []*queryResult
@@ -845,7 +1397,11 @@ func (p byScore) Len() int           { return len(p) }
 func (p byScore) Less(i, j int) bool { return p[j].Score < p[i].Score }
 func (p byScore) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
 
+// Query searches the packages in the Redis index for the terms of q. A term
+// such as updated:<90d restricts the results to the packages updated in that
+// period.
 func (db *Database) Query(q string) ([]Package, error) {
+	q, maxAge := parseRecency(q)
 	terms := parseQuery(q)
 	if len(terms) == 0 {
 		return nil, nil
@@ -863,7 +1419,7 @@ func (db *Database) Query(q string) ([]Package, error) {
 		args = append(args, "index:"+term)
 	}
 	c.Send("SINTERSTORE", args...)
-	c.Send("SORT", id, "DESC", "BY", "nosort", "GET", "pkg:*->path", "GET", "pkg:*->synopsis", "GET", "pkg:*->score")
+	c.Send("SORT", id, "DESC", "BY", "nosort", "GET", "pkg:*->path", "GET", "pkg:*->synopsis", "GET", "pkg:*->score", "GET", "pkg:*->updated", "GET", "pkg:*->committed")
 	c.Send("DEL", id)
 	c.Flush()
 	c.Receive()                              // SINTERSTORE
@@ -874,9 +1430,12 @@ func (db *Database) Query(q string) ([]Package, error) {
 	c.Receive() // DEL
 
 	var queryResults []*queryResult
-	if err := redis.ScanSlice(values, &queryResults, "Path", "Synopsis", "Score"); err != nil {
+	if err := redis.ScanSlice(values, &queryResults, "Path", "Synopsis", "Score", "Updated", "Committed"); err != nil {
 		return nil, err
 	}
+	if maxAge > 0 {
+		queryResults = filterRecent(queryResults, maxAge, time.Now())
+	}
 
 	for _, qr := range queryResults {
 		c.Send("SCARD", "index:import:"+qr.Path)
@@ -888,29 +1447,16 @@ func (db *Database) Query(q string) ([]Package, error) {
 		if err != nil {
 			return nil, err
 		}
-
-		qr.Score *= math.Log(float64(10 + importCount))
-
-		if isStandardPackage(qr.Path) {
-			if strings.HasSuffix(qr.Path, q) {
-				// Big bump for exact match on standard package name.
-				qr.Score *= 10000
-			} else {
-				qr.Score *= 1.2
-			}
-		}
-
-		if q == path.Base(qr.Path) {
-			qr.Score *= 1.1
-		}
+		qr.ImportCount = importCount
 	}
 
-	sort.Sort(byScore(queryResults))
+	rankResults(queryResults, q, db.rankingWeights(), time.Now())
 
-	pkgs := make([]Package, len(queryResults))
-	for i, qr := range queryResults {
-		pkgs[i].Path = qr.Path
-		pkgs[i].Synopsis = qr.Synopsis
+	pkgs := make([]Package, 0, len(queryResults))
+	for _, qr := range queryResults {
+		if !gosrc.IsExcludedPath(qr.Path) {
+			pkgs = append(pkgs, Package{Path: qr.Path, Synopsis: qr.Synopsis})
+		}
 	}
 
 	return pkgs, nil
@@ -972,14 +1518,11 @@ func (db *Database) Do(f func(*PackageInfo) error) error {
 
 			pi.Size = len(path) + len(p) + len(terms) + len(synopsis)
 
-			p, err = snappy.Decode(nil, p)
+			pdoc, err := decodeDoc(p)
 			if err != nil {
-				return fmt.Errorf("snappy decoding %s: %v", path, err)
-			}
-
-			if err := gob.NewDecoder(bytes.NewReader(p)).Decode(&pi.PDoc); err != nil {
-				return fmt.Errorf("gob decoding %s: %v", path, err)
+				return fmt.Errorf("decoding %s: %v", path, err)
 			}
+			pi.PDoc = pdoc
 			if err := f(&pi); err != nil {
 				return fmt.Errorf("func %s: %v", path, err)
 			}
@@ -1014,7 +1557,7 @@ func (db *Database) ImportGraph(pdoc *doc.Package, level DepLevel) ([]Package, [
 	// Redis pipeline as queue. Links to packages with invalid import paths are
 	// only included for the root package.
 
-	c := db.Pool.Get()
+	c := db.readConn()
 	defer c.Close()
 	if err := importGraphScript.Load(c); err != nil {
 		return nil, nil, err
@@ -1128,21 +1671,56 @@ func (db *Database) IncrementPopularScore(path string) error {
 	return db.incrementPopularScoreInternal(path, 1, time.Now())
 }
 
+var incrementViewCountScript = redis.NewScript(0, `
+    local id = redis.call('HGET', 'ids', ARGV[1])
+    if not id then
+        return 0
+    end
+    return redis.call('HINCRBY', 'pkg:' .. id, 'views', 1)
+`)
+
+// IncrementViewCount increments the number of views of the package with the
+// given import path and returns the new count. The count of a package not in
+// the database is not incremented and IncrementViewCount returns zero.
+func (db *Database) IncrementViewCount(path string) (int64, error) {
+	c := db.Pool.Get()
+	defer c.Close()
+	return redis.Int64(incrementViewCountScript.Do(c, path))
+}
+
+var viewCountScript = redis.NewScript(0, `
+    local id = redis.call('HGET', 'ids', ARGV[1])
+    if not id then
+        return 0
+    end
+    return redis.call('HGET', 'pkg:' .. id, 'views') or 0
+`)
+
+// ViewCount returns the number of views of the package with the given import
+// path.
+func (db *Database) ViewCount(path string) (int64, error) {
+	c := db.readConn()
+	defer c.Close()
+	return redis.Int64(viewCountScript.Do(c, path))
+}
+
 var popularScript = redis.NewScript(0, `
     local stop = ARGV[1]
     local ids = redis.call('ZREVRANGE', 'popular', '0', stop)
     local result = {}
     for i=1,#ids do
-        local values = redis.call('HMGET', 'pkg:' .. ids[i], 'path', 'synopsis', 'kind')
-        result[#result+1] = values[1]
-        result[#result+1] = values[2]
-        result[#result+1] = values[3]
+        if redis.call('SISMEMBER', 'hidden', ids[i]) == 0 then
+            local values = redis.call('HMGET', 'pkg:' .. ids[i], 'path', 'synopsis', 'kind')
+            result[#result+1] = values[1]
+            result[#result+1] = values[2]
+            result[#result+1] = values[3]
+        end
     end
     return result
 `)
 
 func (db *Database) Popular(count int) ([]Package, error) {
-	c := db.Pool.Get()
+	c := db.readConn()
 	defer c.Close()
 	reply, err := popularScript.Do(c, count-1)
 	if err != nil {
@@ -1191,6 +1769,90 @@ func (db *Database) PopNewCrawl() (string, bool, error) {
 	return path, len(subdirs) > 0, err
 }
 
+// ScheduledCrawl is a package in the crawl schedule.
+type ScheduledCrawl struct {
+	Path      string    `json:"path"`
+	NextCrawl time.Time `json:"next_crawl"`
+}
+
+// CrawlSchedule describes the packages waiting to be crawled.
+type CrawlSchedule struct {
+	// Packages with the earliest next crawl times, in order.
+	Scheduled      []ScheduledCrawl `json:"scheduled"`
+	ScheduledCount int              `json:"scheduled_count"`
+
+	// New paths waiting for their first crawl, in no particular order.
+	New      []string `json:"new"`
+	NewCount int      `json:"new_count"`
+
+	// Number of paths which returned an error on their first crawl.
+	BadCount int `json:"bad_count"`
+}
+
+// GetCrawlSchedule returns the n packages with the earliest next crawl times
+// and up to n of the new paths to crawl.
+func (db *Database) GetCrawlSchedule(n int) (*CrawlSchedule, error) {
+	c := db.Pool.Get()
+	defer c.Close()
+
+	values, err := redis.Values(c.Do("ZRANGE", "nextCrawl", 0, n-1, "WITHSCORES"))
+	if err != nil {
+		return nil, err
+	}
+	cs := &CrawlSchedule{}
+	for len(values) > 0 {
+		var id string
+		var t int64
+		if values, err = redis.Scan(values, &id, &t); err != nil {
+			return nil, err
+		}
+		path, err := redis.String(c.Do("HGET", "pkg:"+id, "path"))
+		if err == redis.ErrNil {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		cs.Scheduled = append(cs.Scheduled, ScheduledCrawl{Path: path, NextCrawl: time.Unix(t, 0).UTC()})
+	}
+	if cs.New, err = redis.Strings(c.Do("SRANDMEMBER", "newCrawl", n)); err != nil {
+		return nil, err
+	}
+	sort.Strings(cs.New)
+	c.Send("ZCARD", "nextCrawl")
+	c.Send("SCARD", "newCrawl")
+	c.Send("SCARD", "badCrawl")
+	c.Flush()
+	for _, count := range []*int{&cs.ScheduledCount, &cs.NewCount, &cs.BadCount} {
+		if *count, err = redis.Int(c.Receive()); err != nil {
+			return nil, err
+		}
+	}
+	return cs, nil
+}
+
+// DueCrawls returns the paths of up to n packages whose next crawl time is
+// not after t, earliest first, skipping the first offset packages.
+func (db *Database) DueCrawls(offset, n int, t time.Time) ([]string, error) {
+	c := db.Pool.Get()
+	defer c.Close()
+
+	ids, err := redis.Strings(c.Do("ZRANGEBYSCORE", "nextCrawl", "-inf", t.Unix(), "LIMIT", offset, n))
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, id := range ids {
+		path, err := redis.String(c.Do("HGET", "pkg:"+id, "path"))
+		if err == redis.ErrNil {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
 func (db *Database) AddBadCrawl(path string) error {
 	c := db.Pool.Get()
 	defer c.Close()
@@ -1198,6 +1860,65 @@ func (db *Database) AddBadCrawl(path string) error {
 	return err
 }
 
+// PutNotFound records that path was not found. The record expires after ttl.
+func (db *Database) PutNotFound(path string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	c := db.Pool.Get()
+	defer c.Close()
+	_, err := c.Do("SET", "notFound:"+path, 1, "EX", int(ttl/time.Second))
+	return err
+}
+
+// IsNotFound returns whether path was recently recorded as not found by
+// PutNotFound.
+func (db *Database) IsNotFound(path string) (bool, error) {
+	c := db.readConn()
+	defer c.Close()
+	return redis.Bool(c.Do("EXISTS", "notFound:"+path))
+}
+
+// DeleteNotFound removes the not found record for path, if any.
+func (db *Database) DeleteNotFound(path string) error {
+	c := db.Pool.Get()
+	defer c.Close()
+	_, err := c.Do("DEL", "notFound:"+path)
+	return err
+}
+
+// PutRedirect records in the database that the canonical import path of path
+// is target for the duration ttl. A zero ttl does not record anything.
+func (db *Database) PutRedirect(path, target string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	c := db.Pool.Get()
+	defer c.Close()
+	_, err := c.Do("SET", "redirect:"+path, target, "EX", int(ttl/time.Second))
+	return err
+}
+
+// GetRedirect returns the canonical import path recorded for path by
+// PutRedirect or "" if there is none.
+func (db *Database) GetRedirect(path string) (string, error) {
+	c := db.readConn()
+	defer c.Close()
+	target, err := redis.String(c.Do("GET", "redirect:"+path))
+	if err == redis.ErrNil {
+		return "", nil
+	}
+	return target, err
+}
+
+// DeleteRedirect removes the canonical import path recorded for path, if any.
+func (db *Database) DeleteRedirect(path string) error {
+	c := db.Pool.Get()
+	defer c.Close()
+	_, err := c.Do("DEL", "redirect:"+path)
+	return err
+}
+
 var incrementCounterScript = redis.NewScript(0, `
     local key = 'counter:' .. ARGV[1]
     local n = tonumber(ARGV[2])
@@ -1258,6 +1979,8 @@ func (db *Database) Reindex(ctx context.Context) error {
 			"GET", "pkg:*->path",
 			"GET", "pkg:*->synopsis",
 			"GET", "pkg:*->score",
+			"GET", "pkg:*->committed",
+			"GET", "#",
 		))
 		if err != nil {
 			return err
@@ -1272,10 +1995,17 @@ func (db *Database) Reindex(ctx context.Context) error {
 		for ; len(values) > 0; npkgs++ {
 			var pdoc doc.Package
 			var score float64
-			values, err = redis.Scan(values, &pdoc.ImportPath, &pdoc.Synopsis, &score)
+			var committed int64
+			var id string
+			values, err = redis.Scan(values, &pdoc.ImportPath, &pdoc.Synopsis, &score, &committed, &id)
 			if err != nil {
 				return err
 			}
+			if hidden, err := redis.Bool(c.Do("SISMEMBER", "hidden", id)); err != nil {
+				return err
+			} else if hidden {
+				continue
+			}
 			// There are some corrupted data in our current database
 			// that causes an error when putting the package into the
 			// search index which only supports UTF8 encoding.
@@ -1286,12 +2016,16 @@ func (db *Database) Reindex(ctx context.Context) error {
 			if err != nil {
 				return err
 			}
-			if _, err := idx.Put(db.RemoteClient.NewContext(ctx), id, &Package{
+			pkg := &Package{
 				Path:        pdoc.ImportPath,
 				Synopsis:    pdoc.Synopsis,
 				Score:       score,
 				ImportCount: n,
-			}); err != nil {
+			}
+			if committed > 0 {
+				pkg.Updated = time.Unix(committed, 0).UTC()
+			}
+			if _, err := idx.Put(db.RemoteClient.NewContext(ctx), id, pkg); err != nil {
 				if appengine.IsTimeoutError(err) {
 					log.Printf("App Engine timeout: %v. Continue...", err)
 					break
@@ -1311,6 +2045,16 @@ func (db *Database) Search(ctx context.Context, q string) ([]Package, error) {
 	return searchAE(db.RemoteClient.NewContext(ctx), q)
 }
 
+// SearchIter is like Search, but returns an iterator over at most limit
+// results so that the caller can use the first results before the others are
+// read.
+func (db *Database) SearchIter(ctx context.Context, q string, limit int) (*SearchIterator, error) {
+	if db.RemoteClient == nil {
+		return nil, errors.New("remote_api client not setup to use App Engine search")
+	}
+	return searchIterAE(db.RemoteClient.NewContext(ctx), q, limit)
+}
+
 // PutIndex puts a package into App Engine search index. ID is the package ID in the database.
 // It is no-op when running locally without setting up remote_api.
 func (db *Database) PutIndex(ctx context.Context, pdoc *doc.Package, id string, score float64, importCount int) error {
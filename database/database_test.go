@@ -9,12 +9,15 @@ package database
 import (
 	"context"
 	"math"
+	"sort"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/garyburd/redigo/redis"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 
 	"github.com/golang/gddo/doc"
 )
@@ -183,6 +186,7 @@ func TestPutGet(t *testing.T) {
 	c.Send("DEL", "block")
 	c.Send("DEL", "popular:0")
 	c.Send("DEL", "newCrawl")
+	c.Send("DEL", "packageCount")
 	keys, err := redis.Values(c.Do("HKEYS", "ids"))
 	for _, key := range keys {
 		t.Errorf("unexpected id %s", key)
@@ -193,6 +197,36 @@ func TestPutGet(t *testing.T) {
 	}
 }
 
+func TestViewCount(t *testing.T) {
+	db := newDB(t)
+	defer closeDB(db)
+	c := db.Pool.Get()
+	defer c.Close()
+
+	const path = "github.com/user/repo/foo"
+	c.Do("HSET", "ids", path, "1")
+	for i := int64(1); i <= 3; i++ {
+		n, err := db.IncrementViewCount(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != i {
+			t.Errorf("IncrementViewCount(%q) = %d, want %d", path, n, i)
+		}
+	}
+	if n, err := db.ViewCount(path); err != nil || n != 3 {
+		t.Errorf("ViewCount(%q) = %d, %v, want 3, nil", path, n, err)
+	}
+
+	const missing = "github.com/user/repo/missing"
+	if n, err := db.IncrementViewCount(missing); err != nil || n != 0 {
+		t.Errorf("IncrementViewCount(%q) = %d, %v, want 0, nil", missing, n, err)
+	}
+	if n, err := db.ViewCount(missing); err != nil || n != 0 {
+		t.Errorf("ViewCount(%q) = %d, %v, want 0, nil", missing, n, err)
+	}
+}
+
 const epsilon = 0.000001
 
 func TestPopular(t *testing.T) {
@@ -265,3 +299,542 @@ func TestCounter(t *testing.T) {
 		t.Errorf("3: got n=%g, want 2", n)
 	}
 }
+
+func TestPackageCount(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	defer closeDB(db)
+
+	for _, path := range []string{"github.com/user/a", "github.com/user/b", "example.com/c"} {
+		if err := db.Put(ctx, &doc.Package{ImportPath: path, Name: "p"}, time.Time{}, false); err != nil {
+			t.Fatalf("db.Put(%q) returned error %v", path, err)
+		}
+	}
+	if err := db.Delete(ctx, "github.com/user/b"); err != nil {
+		t.Fatalf("db.Delete() returned error %v", err)
+	}
+
+	wantHosts := map[string]int{"github.com": 1, "example.com": 1}
+	n, hosts, err := db.PackageCount()
+	if err != nil {
+		t.Fatalf("db.PackageCount() returned error %v", err)
+	}
+	if n != 2 || !cmp.Equal(hosts, wantHosts) {
+		t.Errorf("db.PackageCount() = %d, %v, want 2, %v", n, hosts, wantHosts)
+	}
+
+	c := db.Pool.Get()
+	c.Do("DEL", "packageCount", "packageCount:host")
+	c.Close()
+	if n, err := db.RepairPackageCount(); n != 2 || err != nil {
+		t.Fatalf("db.RepairPackageCount() = %d, %v, want 2, nil", n, err)
+	}
+	n, hosts, err = db.PackageCount()
+	if err != nil {
+		t.Fatalf("db.PackageCount() returned error %v", err)
+	}
+	if n != 2 || !cmp.Equal(hosts, wantHosts) {
+		t.Errorf("after repair db.PackageCount() = %d, %v, want 2, %v", n, hosts, wantHosts)
+	}
+}
+
+func TestSynopses(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	defer closeDB(db)
+
+	pdoc := &doc.Package{ImportPath: "github.com/user/a", Name: "a", Synopsis: "Package a does things."}
+	if err := db.Put(ctx, pdoc, time.Time{}, false); err != nil {
+		t.Fatalf("db.Put() returned error %v", err)
+	}
+	got, err := db.Synopses([]string{"github.com/user/a", "github.com/user/missing"})
+	if err != nil {
+		t.Fatalf("db.Synopses() returned error %v", err)
+	}
+	want := map[string]string{"github.com/user/a": "Package a does things."}
+	if !cmp.Equal(got, want) {
+		t.Errorf("db.Synopses() = %v, want %v", got, want)
+	}
+}
+
+func TestIndexedPackages(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	defer closeDB(db)
+
+	for _, pdoc := range []*doc.Package{
+		{ImportPath: "fmt", Name: "fmt", Synopsis: "Package fmt implements formatted I/O."},
+		{ImportPath: "github.com/user/a", ProjectRoot: "github.com/user/a", Name: "a", Synopsis: "Package a does things.", Funcs: []*doc.Func{{Name: "Do"}}},
+		{ImportPath: "github.com/user/a/hidden", ProjectRoot: "github.com/user/a", Name: "hidden", Funcs: []*doc.Func{{Name: "Do"}}},
+		{ImportPath: "github.com/user/dir", ProjectRoot: "github.com/user/dir"},
+	} {
+		if err := db.Put(ctx, pdoc, time.Time{}, pdoc.Name == "hidden"); err != nil {
+			t.Fatalf("db.Put(%q) returned error %v", pdoc.ImportPath, err)
+		}
+	}
+	c := db.Pool.Get()
+	defer c.Close()
+
+	got, err := db.IndexedPackages()
+	if err != nil {
+		t.Fatalf("db.IndexedPackages() returned error %v", err)
+	}
+	want := []Package{
+		{Path: "fmt", Synopsis: "Package fmt implements formatted I/O."},
+		{Path: "github.com/user/a", Synopsis: "Package a does things."},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("db.IndexedPackages() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := c.Do("SADD", "block", "github.com/user"); err != nil {
+		t.Fatal(err)
+	}
+	got, err = db.IndexedPackages()
+	if err != nil {
+		t.Fatalf("db.IndexedPackages() returned error %v", err)
+	}
+	if diff := cmp.Diff(want[:1], got); diff != "" {
+		t.Errorf("db.IndexedPackages() with blocked path mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestHidden(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	defer closeDB(db)
+
+	pdoc := &doc.Package{ImportPath: "github.com/user/tool", ProjectRoot: "github.com/user/tool", Name: "tool", Synopsis: "Package tool is experimental.", Funcs: []*doc.Func{{Name: "Run"}}}
+	if err := db.Put(ctx, pdoc, time.Time{}, false); err != nil {
+		t.Fatalf("db.Put() returned error %v", err)
+	}
+	if err := db.IncrementPopularScore(pdoc.ImportPath); err != nil {
+		t.Fatalf("db.IncrementPopularScore() returned error %v", err)
+	}
+	if err := db.SetHidden(ctx, "github.com/user/missing", true); err == nil {
+		t.Errorf("db.SetHidden(missing) returned nil, want error")
+	}
+	if err := db.SetHidden(ctx, pdoc.ImportPath, true); err != nil {
+		t.Fatalf("db.SetHidden() returned error %v", err)
+	}
+
+	check := func(when string, wantListed bool) {
+		t.Helper()
+		var want []Package
+		if wantListed {
+			want = []Package{{Path: pdoc.ImportPath, Synopsis: pdoc.Synopsis}}
+		}
+		if got, err := db.Query("tool"); err != nil || !cmp.Equal(got, want, cmpopts.EquateEmpty()) {
+			t.Errorf("%s: db.Query() = %v, %v, want %v", when, got, err, want)
+		}
+		if got, err := db.IndexedPackages(); err != nil || !cmp.Equal(got, want, cmpopts.EquateEmpty()) {
+			t.Errorf("%s: db.IndexedPackages() = %v, %v, want %v", when, got, err, want)
+		}
+		if got, err := db.Popular(10); err != nil || !cmp.Equal(got, want, cmpopts.EquateEmpty()) {
+			t.Errorf("%s: db.Popular() = %v, %v, want %v", when, got, err, want)
+		}
+		if got, _, _, err := db.Get(ctx, pdoc.ImportPath); err != nil || got == nil {
+			t.Errorf("%s: db.Get() = %v, %v, want package", when, got, err)
+		}
+	}
+
+	check("hidden", false)
+	hidden, err := db.HiddenPackages()
+	if err != nil {
+		t.Fatalf("db.HiddenPackages() returned error %v", err)
+	}
+	if want := []Package{{Path: pdoc.ImportPath, Synopsis: pdoc.Synopsis}}; !cmp.Equal(hidden, want) {
+		t.Errorf("db.HiddenPackages() = %v, want %v", hidden, want)
+	}
+
+	// The mark is kept by a new crawl.
+	if err := db.Put(ctx, pdoc, time.Time{}, false); err != nil {
+		t.Fatalf("db.Put() returned error %v", err)
+	}
+	check("hidden after put", false)
+
+	if err := db.SetHidden(ctx, pdoc.ImportPath, false); err != nil {
+		t.Fatalf("db.SetHidden(false) returned error %v", err)
+	}
+	check("cleared", true)
+	if hidden, err := db.HiddenPackages(); err != nil || len(hidden) != 0 {
+		t.Errorf("db.HiddenPackages() after clear = %v, %v, want none", hidden, err)
+	}
+}
+
+func TestQueryRecency(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	defer closeDB(db)
+
+	now := time.Now()
+	for _, pdoc := range []*doc.Package{
+		{ImportPath: "github.com/user/fresh", Name: "fresh", Synopsis: "Package fresh is a widget.", Updated: now, Committed: now.Add(-10 * 24 * time.Hour)},
+		{ImportPath: "github.com/user/stale", Name: "stale", Synopsis: "Package stale is a widget.", Updated: now, Committed: now.Add(-400 * 24 * time.Hour)},
+		{ImportPath: "example.com/crawled", Name: "crawled", Synopsis: "Package crawled is a widget.", Updated: now.Add(-24 * time.Hour)},
+	} {
+		pdoc.ProjectRoot = pdoc.ImportPath
+		pdoc.Funcs = []*doc.Func{{Name: "New"}}
+		if err := db.Put(ctx, pdoc, time.Time{}, false); err != nil {
+			t.Fatalf("db.Put(%q) returned error %v", pdoc.ImportPath, err)
+		}
+	}
+
+	for _, tt := range []struct {
+		q    string
+		want []string
+	}{
+		{"widget", []string{"example.com/crawled", "github.com/user/fresh", "github.com/user/stale"}},
+		{"widget updated:<90d", []string{"github.com/user/fresh"}},
+		{"widget updated:<2y", []string{"github.com/user/fresh", "github.com/user/stale"}},
+		{"widget updated:<1d", nil},
+	} {
+		pkgs, err := db.Query(tt.q)
+		if err != nil {
+			t.Fatalf("db.Query(%q) returned error %v", tt.q, err)
+		}
+		var got []string
+		for _, pkg := range pkgs {
+			got = append(got, pkg.Path)
+		}
+		sort.Strings(got)
+		if !cmp.Equal(got, tt.want, cmpopts.EquateEmpty()) {
+			t.Errorf("db.Query(%q) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestIsBlockedPath(t *testing.T) {
+	blocked := []string{"example.com", "github.com/user/repo"}
+	for path, want := range map[string]bool{
+		"example.com":                true,
+		"example.com/pkg":            true,
+		"example.community/pkg":      false,
+		"github.com/user/repo/sub":   true,
+		"github.com/user/repository": false,
+		"github.com/other/repo":      false,
+	} {
+		if got := isBlockedPath(path, blocked); got != want {
+			t.Errorf("isBlockedPath(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestImporterCounts(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	defer closeDB(db)
+
+	for _, pdoc := range []*doc.Package{
+		{ImportPath: "github.com/user/a", Name: "a", Imports: []string{"github.com/user/b"}},
+		{ImportPath: "github.com/user/c", Name: "c", Imports: []string{"github.com/user/b"}},
+	} {
+		if err := db.Put(ctx, pdoc, time.Time{}, false); err != nil {
+			t.Fatalf("db.Put(%q) returned error %v", pdoc.ImportPath, err)
+		}
+	}
+	got, err := db.ImporterCounts([]string{"github.com/user/a", "github.com/user/b"})
+	if err != nil {
+		t.Fatalf("db.ImporterCounts() returned error %v", err)
+	}
+	want := map[string]int{"github.com/user/a": 0, "github.com/user/b": 2}
+	if !cmp.Equal(got, want) {
+		t.Errorf("db.ImporterCounts() = %v, want %v", got, want)
+	}
+}
+
+func TestGone(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	defer closeDB(db)
+
+	pdoc := &doc.Package{ImportPath: "github.com/user/repo/foo", Name: "foo"}
+	if err := db.Put(ctx, pdoc, time.Time{}, false); err != nil {
+		t.Fatalf("db.Put() returned error %v", err)
+	}
+	if err := db.SetGone("github.com/user/repo", "DMCA takedown"); err != nil {
+		t.Fatalf("db.SetGone() returned error %v", err)
+	}
+	if pdoc, _, err := db.GetDoc(ctx, "github.com/user/repo/foo"); pdoc != nil || err != nil {
+		t.Errorf("db.GetDoc() after db.SetGone() returned %v, %v, want nil, nil", pdoc, err)
+	}
+	for _, tt := range []struct {
+		path   string
+		gone   bool
+		reason string
+	}{
+		{"github.com/user/repo", true, "DMCA takedown"},
+		{"github.com/user/repo/foo", true, "DMCA takedown"},
+		{"github.com/user/repository", false, ""},
+		{"github.com/user", false, ""},
+	} {
+		gone, reason, err := db.Gone(tt.path)
+		if gone != tt.gone || reason != tt.reason || err != nil {
+			t.Errorf("db.Gone(%q) returned %v, %q, %v, want %v, %q, nil", tt.path, gone, reason, err, tt.gone, tt.reason)
+		}
+	}
+	if err := db.ClearGone("github.com/user/repo"); err != nil {
+		t.Fatalf("db.ClearGone() returned error %v", err)
+	}
+	if gone, _, err := db.Gone("github.com/user/repo/foo"); gone || err != nil {
+		t.Errorf("db.Gone() after db.ClearGone() returned %v, %v, want false, nil", gone, err)
+	}
+}
+
+func TestProjectTree(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	defer closeDB(db)
+
+	tree := []string{"a", "a/b", "docs"}
+	for _, path := range []string{"github.com/user/repo", "github.com/user/repo/a/b"} {
+		pdoc := &doc.Package{ImportPath: path, ProjectRoot: "github.com/user/repo", Name: "x", Tree: tree}
+		if err := db.Put(ctx, pdoc, time.Time{}, false); err != nil {
+			t.Fatalf("db.Put(%q) returned error %v", path, err)
+		}
+	}
+	got, err := db.ProjectTree("github.com/user/repo")
+	if err != nil {
+		t.Fatalf("db.ProjectTree() returned error %v", err)
+	}
+	if diff := cmp.Diff(tree, got); diff != "" {
+		t.Errorf("db.ProjectTree() mismatch (-want +got):\n%s", diff)
+	}
+	pdoc, _, err := db.GetDoc(ctx, "github.com/user/repo/a/b")
+	if err != nil {
+		t.Fatalf("db.GetDoc() returned error %v", err)
+	}
+	if pdoc.Tree != nil {
+		t.Errorf("db.GetDoc() returned package with Tree %v, want nil", pdoc.Tree)
+	}
+
+	if err := db.Delete(ctx, "github.com/user/repo/a/b"); err != nil {
+		t.Fatalf("db.Delete() returned error %v", err)
+	}
+	if got, err := db.ProjectTree("github.com/user/repo"); len(got) == 0 || err != nil {
+		t.Errorf("db.ProjectTree() with a package left returned %v, %v, want tree", got, err)
+	}
+	if err := db.Delete(ctx, "github.com/user/repo"); err != nil {
+		t.Fatalf("db.Delete() returned error %v", err)
+	}
+	if got, err := db.ProjectTree("github.com/user/repo"); got != nil || err != nil {
+		t.Errorf("db.ProjectTree() after deleting the project returned %v, %v, want nil, nil", got, err)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	defer closeDB(db)
+
+	for _, pdoc := range []*doc.Package{
+		{ImportPath: "github.com/user/yaml", Name: "yaml", Synopsis: "Package yaml implements YAML support."},
+		{ImportPath: "github.com/other/yaml", Name: "yaml", Synopsis: "Package yaml parses YAML."},
+		{ImportPath: "github.com/user/hidden", Name: "hidden", Synopsis: "Package hidden is hidden."},
+	} {
+		pdoc.Funcs = []*doc.Func{{Name: "Marshal"}}
+		if err := db.Put(ctx, pdoc, time.Time{}, pdoc.Name == "hidden"); err != nil {
+			t.Fatalf("db.Put(%q) returned error %v", pdoc.ImportPath, err)
+		}
+	}
+	if err := db.IncrementPopularScore("github.com/other/yaml"); err != nil {
+		t.Fatalf("db.IncrementPopularScore() returned error %v", err)
+	}
+
+	for _, tt := range []struct {
+		prefix string
+		want   []string
+	}{
+		{"YA", []string{"github.com/other/yaml", "github.com/user/yaml"}},
+		{"github.com/user/", []string{"github.com/user/yaml"}},
+		{"hid", nil},
+		{"xml", nil},
+	} {
+		got, err := db.Suggest(tt.prefix, 10)
+		if err != nil {
+			t.Fatalf("db.Suggest(%q) returned error %v", tt.prefix, err)
+		}
+		if !cmp.Equal(got, tt.want, cmpopts.EquateEmpty()) {
+			t.Errorf("db.Suggest(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestRankSuggestions(t *testing.T) {
+	pkgs := []Package{
+		{Path: "c", Score: 1},
+		{Path: "b", Score: 3},
+		{Path: "a", Score: 1},
+		{Path: "d"},
+	}
+	got := rankSuggestions(pkgs, 3)
+	want := []string{"b", "a", "c"}
+	if !cmp.Equal(got, want) {
+		t.Errorf("rankSuggestions() = %v, want %v", got, want)
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	db := newDB(t)
+	defer closeDB(db)
+
+	if err := db.PutRedirect("github.com/alice/pkg", "example.com/pkg", time.Hour); err != nil {
+		t.Fatalf("db.PutRedirect() returned error %v", err)
+	}
+	if target, err := db.GetRedirect("github.com/alice/pkg"); target != "example.com/pkg" || err != nil {
+		t.Errorf("db.GetRedirect() returned %q, %v, want %q, nil", target, err, "example.com/pkg")
+	}
+	if err := db.DeleteRedirect("github.com/alice/pkg"); err != nil {
+		t.Fatalf("db.DeleteRedirect() returned error %v", err)
+	}
+	if target, err := db.GetRedirect("github.com/alice/pkg"); target != "" || err != nil {
+		t.Errorf("db.GetRedirect() after db.DeleteRedirect() returned %q, %v, want empty, nil", target, err)
+	}
+}
+
+func TestDependents(t *testing.T) {
+	db := newDB(t)
+	defer closeDB(db)
+
+	ctx := context.Background()
+	for _, pdoc := range []*doc.Package{
+		{
+			ImportPath:  "github.com/alice/a",
+			Name:        "a",
+			ProjectRoot: "github.com/alice/a",
+			Requires:    []doc.Requirement{{Path: "example.com/m", Version: "v1.2.0"}},
+		},
+		{
+			ImportPath:  "github.com/alice/b",
+			Name:        "b",
+			ProjectRoot: "github.com/alice/b",
+			Requires:    []doc.Requirement{{Path: "example.com/m", Version: "v1.0.0", Replace: "../m"}},
+		},
+		{
+			ImportPath:  "github.com/alice/c",
+			Name:        "c",
+			ProjectRoot: "github.com/alice/c",
+			Imports:     []string{"example.com/m"},
+		},
+	} {
+		if err := db.Put(ctx, pdoc, time.Time{}, false); err != nil {
+			t.Fatalf("db.Put(%q) returned error %v", pdoc.ImportPath, err)
+		}
+	}
+
+	got, err := db.Dependents("example.com/m", func(string) bool { return true })
+	if err != nil {
+		t.Fatalf("db.Dependents() returned error %v", err)
+	}
+	want := []Dependent{
+		{Path: "github.com/alice/a", Version: "v1.2.0"},
+		{Path: "github.com/alice/b", Version: "v1.0.0", Replace: "../m"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("db.Dependents() mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = db.Dependents("example.com/m", func(v string) bool { return v == "v1.2.0" })
+	if err != nil {
+		t.Fatalf("db.Dependents() returned error %v", err)
+	}
+	if diff := cmp.Diff(want[:1], got); diff != "" {
+		t.Errorf("db.Dependents(v1.2.0) mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetCrawlSchedule(t *testing.T) {
+	db := newDB(t)
+	defer closeDB(db)
+
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second).UTC()
+	for i, path := range []string{"github.com/alice/b", "github.com/alice/a", "github.com/alice/c"} {
+		pdoc := &doc.Package{ImportPath: path, Name: "p", ProjectRoot: path}
+		if err := db.Put(ctx, pdoc, now.Add(time.Duration(i)*time.Hour), false); err != nil {
+			t.Fatalf("db.Put(%q) returned error %v", path, err)
+		}
+	}
+	if err := db.AddNewCrawl("github.com/bob/new"); err != nil {
+		t.Fatalf("db.AddNewCrawl() returned error %v", err)
+	}
+	if err := db.AddBadCrawl("github.com/bob/bad"); err != nil {
+		t.Fatalf("db.AddBadCrawl() returned error %v", err)
+	}
+
+	got, err := db.GetCrawlSchedule(2)
+	if err != nil {
+		t.Fatalf("db.GetCrawlSchedule() returned error %v", err)
+	}
+	want := &CrawlSchedule{
+		Scheduled: []ScheduledCrawl{
+			{Path: "github.com/alice/b", NextCrawl: now},
+			{Path: "github.com/alice/a", NextCrawl: now.Add(time.Hour)},
+		},
+		ScheduledCount: 3,
+		New:            []string{"github.com/bob/new"},
+		NewCount:       1,
+		BadCount:       1,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("db.GetCrawlSchedule() mismatch (-want +got):\n%s", diff)
+	}
+
+	due, err := db.DueCrawls(0, 5, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("db.DueCrawls() returned error %v", err)
+	}
+	if diff := cmp.Diff([]string{"github.com/alice/b", "github.com/alice/a"}, due); diff != "" {
+		t.Errorf("db.DueCrawls() mismatch (-want +got):\n%s", diff)
+	}
+	due, err = db.DueCrawls(1, 5, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("db.DueCrawls() with offset returned error %v", err)
+	}
+	if diff := cmp.Diff([]string{"github.com/alice/a"}, due); diff != "" {
+		t.Errorf("db.DueCrawls() with offset mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCrawlHistory(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	defer closeDB(db)
+
+	const path = "github.com/user/repo"
+	if err := db.AddCrawlRecord(path, time.Now(), time.Second); err != nil {
+		t.Fatalf("db.AddCrawlRecord of unknown package returned error %v", err)
+	}
+	pdoc := &doc.Package{ImportPath: path, ProjectRoot: path, Name: "repo"}
+	start := time.Unix(time.Now().Unix(), 0).UTC()
+	var want []CrawlRecord
+	for i := 0; i < crawlHistoryLen+2; i++ {
+		pdoc.Synopsis = strings.Repeat("x", i)
+		if err := db.Put(ctx, pdoc, time.Time{}, false); err != nil {
+			t.Fatalf("db.Put() returned error %v", err)
+		}
+		gobBytes, err := encodeDoc(pdoc, db.Gzip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := CrawlRecord{Time: start.Add(time.Duration(i) * time.Minute), Duration: time.Duration(i) * time.Second, Size: len(gobBytes)}
+		if err := db.AddCrawlRecord(path, r.Time, r.Duration); err != nil {
+			t.Fatalf("db.AddCrawlRecord() returned error %v", err)
+		}
+		want = append([]CrawlRecord{r}, want...)
+	}
+	got, err := db.CrawlHistory(path)
+	if err != nil {
+		t.Fatalf("db.CrawlHistory() returned error %v", err)
+	}
+	if diff := cmp.Diff(want[:crawlHistoryLen], got); diff != "" {
+		t.Errorf("db.CrawlHistory() mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := db.Delete(ctx, path); err != nil {
+		t.Fatalf("db.Delete() returned error %v", err)
+	}
+	if got, err := db.CrawlHistory(path); err != nil || len(got) != 0 {
+		t.Errorf("db.CrawlHistory() after delete = %v, %v, want empty", got, err)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"io"
+
+	"github.com/golang/snappy"
+
+	"github.com/golang/gddo/doc"
+)
+
+// gzipMagic starts every gzip stream. Snappy encoded documents start with
+// the uvarint length of the gob and begin with these bytes only for a gob of
+// 31 bytes, which is too short for any package, so the two encodings can be
+// told apart.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// encodeDoc returns the gob encoding of pdoc, compressed with gzip if
+// useGzip is true and with snappy otherwise.
+func encodeDoc(pdoc *doc.Package, useGzip bool) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(pdoc); err != nil {
+		return nil, err
+	}
+	if !useGzip {
+		return snappy.Encode(nil, buf.Bytes()), nil
+	}
+	var zbuf bytes.Buffer
+	w := gzip.NewWriter(&zbuf)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return zbuf.Bytes(), nil
+}
+
+// decodeDoc decodes a document encoded by encodeDoc with either compression.
+func decodeDoc(p []byte) (*doc.Package, error) {
+	var r io.Reader
+	if bytes.HasPrefix(p, gzipMagic) {
+		zr, err := gzip.NewReader(bytes.NewReader(p))
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	} else {
+		p, err := snappy.Decode(nil, p)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(p)
+	}
+	var pdoc doc.Package
+	if err := gob.NewDecoder(r).Decode(&pdoc); err != nil {
+		return nil, err
+	}
+	return &pdoc, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package database
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/golang/gddo/doc"
+)
+
+func testEncodeDoc() *doc.Package {
+	pdoc := &doc.Package{
+		ImportPath: "github.com/user/repo/pkg",
+		Name:       "pkg",
+		Synopsis:   "Package pkg does things.",
+	}
+	for i := 0; i < 200; i++ {
+		pdoc.Funcs = append(pdoc.Funcs, &doc.Func{
+			Name: fmt.Sprintf("F%d", i),
+			Doc:  fmt.Sprintf("F%d does the %d thing and returns an error if the thing cannot be done.", i, i),
+		})
+	}
+	return pdoc
+}
+
+func TestEncodeDoc(t *testing.T) {
+	want := testEncodeDoc()
+	for _, useGzip := range []bool{false, true} {
+		p, err := encodeDoc(want, useGzip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := decodeDoc(p)
+		if err != nil {
+			t.Fatalf("gzip=%v: decodeDoc returned error %v", useGzip, err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("gzip=%v: decodeDoc mismatch (-want +got):\n%s", useGzip, diff)
+		}
+	}
+}
+
+// BenchmarkEncodeDoc compares the CPU time and the size of documents
+// compressed with snappy and gzip.
+func BenchmarkEncodeDoc(b *testing.B) {
+	pdoc := testEncodeDoc()
+	for _, useGzip := range []bool{false, true} {
+		b.Run(fmt.Sprintf("gzip=%v", useGzip), func(b *testing.B) {
+			var p []byte
+			for i := 0; i < b.N; i++ {
+				var err error
+				if p, err = encodeDoc(pdoc, useGzip); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(p)), "stored-bytes")
+		})
+	}
+}
+
+func BenchmarkDecodeDoc(b *testing.B) {
+	pdoc := testEncodeDoc()
+	for _, useGzip := range []bool{false, true} {
+		b.Run(fmt.Sprintf("gzip=%v", useGzip), func(b *testing.B) {
+			p, err := encodeDoc(pdoc, useGzip)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for i := 0; i < b.N; i++ {
+				if _, err := decodeDoc(p); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package database
+
+import (
+	"bytes"
+	"encoding/gob"
+	"strconv"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// The import graphs stored by PutImportGraph are kept in the hash
+// graph:<path>, with a field for each DepLevel. The set graphdeps:<path>
+// holds the roots of the stored graphs that include the package with the
+// given path, so that the graphs are deleted when the package changes. The
+// set graphstale holds the roots of the deleted graphs until they are
+// recomputed.
+
+// storedGraph is the encoding of a stored import graph.
+type storedGraph struct {
+	Nodes []Package
+	Edges [][2]int
+}
+
+// PutImportGraph stores the import graph of the package with the import
+// path root at level, as returned by ImportGraph, for ttl. The graph is
+// deleted when Put or Delete is called for one of its packages.
+func (db *Database) PutImportGraph(root string, level DepLevel, nodes []Package, edges [][2]int, ttl time.Duration) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&storedGraph{Nodes: nodes, Edges: edges}); err != nil {
+		return err
+	}
+	seconds := int64(ttl / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	c := db.Pool.Get()
+	defer c.Close()
+	c.Send("MULTI")
+	c.Send("HSET", "graph:"+root, strconv.Itoa(int(level)), buf.Bytes())
+	c.Send("EXPIRE", "graph:"+root, seconds)
+	for _, node := range nodes {
+		c.Send("SADD", "graphdeps:"+node.Path, root)
+		c.Send("EXPIRE", "graphdeps:"+node.Path, seconds)
+	}
+	_, err := c.Do("EXEC")
+	return err
+}
+
+// GetImportGraph returns the import graph of the package with the import
+// path root at level stored by PutImportGraph. The result ok is false if no
+// graph is stored.
+func (db *Database) GetImportGraph(root string, level DepLevel) (nodes []Package, edges [][2]int, ok bool, err error) {
+	c := db.readConn()
+	defer c.Close()
+	p, err := redis.Bytes(c.Do("HGET", "graph:"+root, strconv.Itoa(int(level))))
+	if err == redis.ErrNil {
+		return nil, nil, false, nil
+	} else if err != nil {
+		return nil, nil, false, err
+	}
+	var g storedGraph
+	if err := gob.NewDecoder(bytes.NewReader(p)).Decode(&g); err != nil {
+		return nil, nil, false, err
+	}
+	return g.Nodes, g.Edges, true, nil
+}
+
+var deleteImportGraphsScript = redis.NewScript(0, `
+    local path = ARGV[1]
+
+    local roots = redis.call('SMEMBERS', 'graphdeps:' .. path)
+    for i=1,#roots do
+        if redis.call('DEL', 'graph:' .. roots[i]) == 1 then
+            redis.call('SADD', 'graphstale', roots[i])
+        end
+    end
+    return redis.call('DEL', 'graphdeps:' .. path)
+`)
+
+// deleteImportGraphs deletes the stored import graphs that include the
+// package with the given import path and marks them stale.
+func deleteImportGraphs(c redis.Conn, path string) error {
+	_, err := deleteImportGraphsScript.Do(c, path)
+	return err
+}
+
+// PopStaleImportGraph removes and returns the root of an import graph
+// deleted because one of its packages changed, or "" if there is none.
+func (db *Database) PopStaleImportGraph() (string, error) {
+	c := db.Pool.Get()
+	defer c.Close()
+	root, err := redis.String(c.Do("SPOP", "graphstale"))
+	if err == redis.ErrNil {
+		return "", nil
+	}
+	return root, err
+}
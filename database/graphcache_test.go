@@ -0,0 +1,61 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/golang/gddo/doc"
+)
+
+func TestImportGraphCache(t *testing.T) {
+	db := newDB(t)
+	defer closeDB(db)
+
+	nodes := []Package{
+		{Path: "github.com/user/repo/a", Synopsis: "Package a."},
+		{Path: "github.com/user/repo/b", Synopsis: "Package b."},
+		{Path: "fmt"},
+	}
+	edges := [][2]int{{0, 1}, {0, 2}, {1, 2}}
+	if err := db.PutImportGraph(nodes[0].Path, ShowAllDeps, nodes, edges, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	gotNodes, gotEdges, ok, err := db.GetImportGraph(nodes[0].Path, ShowAllDeps)
+	if err != nil || !ok {
+		t.Fatalf("GetImportGraph() = %v, %v, want stored graph", ok, err)
+	}
+	if diff := cmp.Diff(nodes, gotNodes); diff != "" {
+		t.Errorf("GetImportGraph() nodes mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(edges, gotEdges); diff != "" {
+		t.Errorf("GetImportGraph() edges mismatch (-want +got):\n%s", diff)
+	}
+	if _, _, ok, err := db.GetImportGraph(nodes[0].Path, HideStandardAll); ok || err != nil {
+		t.Errorf("GetImportGraph() at other level = %v, %v, want no graph", ok, err)
+	}
+
+	// Putting a dependency deletes the graph.
+	pdoc := &doc.Package{ImportPath: nodes[1].Path, ProjectRoot: "github.com/user/repo", Name: "b", Synopsis: "Package b does more."}
+	if err := db.Put(context.Background(), pdoc, time.Time{}, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok, err := db.GetImportGraph(nodes[0].Path, ShowAllDeps); ok || err != nil {
+		t.Errorf("GetImportGraph() after Put of dependency = %v, %v, want no graph", ok, err)
+	}
+	if root, err := db.PopStaleImportGraph(); root != nodes[0].Path || err != nil {
+		t.Errorf("PopStaleImportGraph() = %q, %v, want %q, nil", root, err, nodes[0].Path)
+	}
+	if root, err := db.PopStaleImportGraph(); root != "" || err != nil {
+		t.Errorf("PopStaleImportGraph() with no stale graph = %q, %v, want \"\", nil", root, err)
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package database
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/garyburd/redigo/redis"
+
+	"github.com/golang/gddo/doc"
+)
+
+// Implementation is a type implementing an interface.
+type Implementation struct {
+	ImportPath string `json:"importPath"`
+	Name       string `json:"name"`
+
+	// True if only the pointer type implements the interface.
+	Pointer bool `json:"pointer,omitempty"`
+}
+
+func (impl Implementation) String() string {
+	s := impl.ImportPath + "." + impl.Name
+	if impl.Pointer {
+		s = "*" + s
+	}
+	return s
+}
+
+func parseImplementation(s string) Implementation {
+	var impl Implementation
+	if strings.HasPrefix(s, "*") {
+		s = s[1:]
+		impl.Pointer = true
+	}
+	if i := strings.LastIndex(s, "."); i >= 0 {
+		impl.ImportPath, impl.Name = s[:i], s[i+1:]
+	}
+	return impl
+}
+
+// implementationScope reports whether the interfaces and types of the
+// package with importPath are included in the implementation relation. The
+// scope is limited to the standard library and the golang.org/x/
+// repositories to keep the computation tractable.
+func implementationScope(importPath string) bool {
+	return isStandardPackage(importPath) || strings.HasPrefix(importPath, "golang.org/x/")
+}
+
+// implementationIndex finds the implementations of interfaces by comparing
+// the normalized method signatures of the types of packages. Types are
+// identified by "<import path>.<name>".
+type implementationIndex struct {
+	interfaces map[string][]string        // method signatures of interfaces
+	types      map[string]map[string]bool // method signatures of other types
+	bySig      map[string][]string        // types by signature without "*"
+}
+
+func newImplementationIndex() *implementationIndex {
+	return &implementationIndex{
+		interfaces: make(map[string][]string),
+		types:      make(map[string]map[string]bool),
+		bySig:      make(map[string][]string),
+	}
+}
+
+func (x *implementationIndex) add(pdoc *doc.Package) {
+	for _, t := range pdoc.Types {
+		if len(t.MethodSigs) == 0 {
+			continue
+		}
+		key := pdoc.ImportPath + "." + t.Name
+		if t.Interface {
+			x.interfaces[key] = t.MethodSigs
+			continue
+		}
+		sigs := make(map[string]bool)
+		for _, s := range t.MethodSigs {
+			sigs[s] = true
+			s = strings.TrimPrefix(s, "*")
+			x.bySig[s] = append(x.bySig[s], key)
+		}
+		x.types[key] = sigs
+	}
+}
+
+// implementations returns the types implementing the interface iface sorted
+// by import path and name.
+func (x *implementationIndex) implementations(iface string) []Implementation {
+	sigs := x.interfaces[iface]
+	if len(sigs) == 0 {
+		return nil
+	}
+	// The candidates are the types declaring the least common method.
+	candidates := x.bySig[sigs[0]]
+	for _, s := range sigs[1:] {
+		if len(x.bySig[s]) < len(candidates) {
+			candidates = x.bySig[s]
+		}
+	}
+	var result []Implementation
+	for _, key := range candidates {
+		methods := x.types[key]
+		impl := parseImplementation(key)
+		ok := true
+		for _, s := range sigs {
+			switch {
+			case methods[s]:
+			case methods["*"+s]:
+				impl.Pointer = true
+			default:
+				ok = false
+			}
+		}
+		if ok {
+			result = append(result, impl)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ImportPath != result[j].ImportPath {
+			return result[i].ImportPath < result[j].ImportPath
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// UpdateImplementations rebuilds the known implementations of the
+// interfaces from the documentation in the database. Only the packages in
+// the standard library and the golang.org/x/ repositories are included. It
+// returns the number of interfaces with implementations.
+func (db *Database) UpdateImplementations() (int, error) {
+	x := newImplementationIndex()
+	err := db.Do(func(pi *PackageInfo) error {
+		if implementationScope(pi.PDoc.ImportPath) {
+			x.add(pi.PDoc)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	c := db.Pool.Get()
+	defer c.Close()
+
+	// Build the relation in a temporary key and replace the old relation
+	// at once.
+	const tmp = "implementations:new"
+	if _, err := c.Do("DEL", tmp); err != nil {
+		return 0, err
+	}
+	n := 0
+	for iface := range x.interfaces {
+		impls := x.implementations(iface)
+		if len(impls) == 0 {
+			continue
+		}
+		s := make([]string, len(impls))
+		for i, impl := range impls {
+			s[i] = impl.String()
+		}
+		if _, err := c.Do("HSET", tmp, iface, strings.Join(s, " ")); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	if n == 0 {
+		_, err = c.Do("DEL", "implementations")
+	} else {
+		_, err = c.Do("RENAME", tmp, "implementations")
+	}
+	return n, err
+}
+
+// Implementations returns the known implementations of the interfaces
+// with names declared in the package with importPath, by interface name.
+func (db *Database) Implementations(importPath string, names []string) (map[string][]Implementation, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	c := db.readConn()
+	defer c.Close()
+	args := redis.Args{"implementations"}
+	for _, name := range names {
+		args = args.Add(importPath + "." + name)
+	}
+	values, err := redis.Strings(c.Do("HMGET", args...))
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]Implementation)
+	for i, v := range values {
+		for _, s := range strings.Fields(v) {
+			result[names[i]] = append(result[names[i]], parseImplementation(s))
+		}
+	}
+	return result, nil
+}
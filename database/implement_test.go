@@ -0,0 +1,45 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package database
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/golang/gddo/doc"
+)
+
+func TestImplementationIndex(t *testing.T) {
+	x := newImplementationIndex()
+	x.add(&doc.Package{ImportPath: "io", Types: []*doc.Type{
+		{Name: "Reader", Interface: true, MethodSigs: []string{"Read([]byte) (int, error)"}},
+		{Name: "ReadCloser", Interface: true, MethodSigs: []string{"Close() error", "Read([]byte) (int, error)"}},
+		{Name: "SectionReader", MethodSigs: []string{"*Read([]byte) (int, error)", "*Size() int64"}},
+	}})
+	x.add(&doc.Package{ImportPath: "golang.org/x/p", Types: []*doc.Type{
+		{Name: "File", MethodSigs: []string{"Close() error", "Read([]byte) (int, error)"}},
+		{Name: "Writer", MethodSigs: []string{"Close() error"}},
+	}})
+
+	want := []Implementation{
+		{ImportPath: "golang.org/x/p", Name: "File"},
+		{ImportPath: "io", Name: "SectionReader", Pointer: true},
+	}
+	if diff := cmp.Diff(want, x.implementations("io.Reader")); diff != "" {
+		t.Errorf("implementations(io.Reader) mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(want[:1], x.implementations("io.ReadCloser")); diff != "" {
+		t.Errorf("implementations(io.ReadCloser) mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, impl := range want {
+		if got := parseImplementation(impl.String()); got != impl {
+			t.Errorf("parseImplementation(%q) = %+v, want %+v", impl.String(), got, impl)
+		}
+	}
+}
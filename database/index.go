@@ -127,6 +127,14 @@ func documentTerms(pdoc *doc.Package, score float64) []string {
 		}
 	}
 
+	// Module requirements
+
+	for _, r := range pdoc.Requires {
+		if gosrc.IsValidPath(r.Path) {
+			terms["require:"+r.Path] = true
+		}
+	}
+
 	if score > 0 {
 
 		for _, term := range parseQuery(pdoc.ImportPath) {
@@ -160,6 +168,7 @@ var vendorPat = regexp.MustCompile(
 
 func documentScore(pdoc *doc.Package) float64 {
 	if pdoc.Name == "" ||
+		pdoc.TestOnly ||
 		pdoc.Status != gosrc.Active ||
 		len(pdoc.Errors) > 0 ||
 		strings.HasSuffix(pdoc.ImportPath, ".go") ||
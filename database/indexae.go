@@ -14,11 +14,13 @@ import (
 	"log"
 	"math"
 	"strings"
+	"time"
 	"unicode"
 
 	"google.golang.org/appengine/search"
 
 	"github.com/golang/gddo/doc"
+	"github.com/golang/gddo/gosrc"
 )
 
 func (p *Package) Load(fields []search.Field, meta *search.DocumentMetadata) error {
@@ -48,6 +50,10 @@ func (p *Package) Load(fields []search.Field, meta *search.DocumentMetadata) err
 			if v, ok := f.Value.(float64); ok {
 				p.Score = v
 			}
+		case "Updated":
+			if v, ok := f.Value.(time.Time); ok {
+				p.Updated = v
+			}
 		}
 	}
 	if p.Path == "" {
@@ -70,6 +76,9 @@ func (p *Package) Save() ([]search.Field, *search.DocumentMetadata, error) {
 		{Name: "ImportCount", Value: float64(p.ImportCount)},
 		{Name: "Stars", Value: float64(p.Stars)},
 	}
+	if !p.Updated.IsZero() {
+		fields = append(fields, search.Field{Name: "Updated", Value: p.Updated})
+	}
 	fork := fmt.Sprint(p.Fork) // "true" or "false"
 	meta := &search.DocumentMetadata{
 		// Customize the rank property by the product of the package score and
@@ -115,6 +124,7 @@ func putIndex(c context.Context, pdoc *doc.Package, id string, score float64, im
 		pkg.Synopsis = pdoc.Synopsis
 		pkg.Stars = pdoc.Stars
 		pkg.Fork = pdoc.Fork
+		pkg.Updated = pdoc.Committed
 	}
 	if score >= 0 {
 		pkg.Score = score
@@ -130,26 +140,73 @@ func putIndex(c context.Context, pdoc *doc.Package, id string, score float64, im
 // searchAE searches the packages index for a given query. A path-like query string
 // will be passed in unchanged, whereas single words will be stemmed.
 func searchAE(c context.Context, q string) ([]Package, error) {
-	index, err := search.Open("packages")
+	it, err := searchIterAE(c, q, 100)
 	if err != nil {
 		return nil, err
 	}
 	var pkgs []Package
+	for {
+		p, ok := it.Next()
+		if !ok {
+			break
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, it.Err()
+}
+
+// searchIterAE is like searchAE, but returns an iterator over at most limit
+// results instead of reading all of them before returning.
+func searchIterAE(c context.Context, q string, limit int) (*SearchIterator, error) {
+	index, err := search.Open("packages")
+	if err != nil {
+		return nil, err
+	}
 	opt := &search.SearchOptions{
-		Limit: 100,
+		Limit: limit,
 	}
-	for it := index.Search(c, parseQuery2(q), opt); ; {
+	q, maxAge := parseRecency(q)
+	query := parseQuery2(q)
+	if maxAge > 0 {
+		query = strings.TrimSpace(query + " Updated >= " + time.Now().Add(-maxAge).UTC().Format("2006-01-02"))
+	}
+	it := index.Search(c, query, opt)
+	return &SearchIterator{next: it.Next, limit: limit}, nil
+}
+
+// SearchIterator is an iterator over the results of a search.
+type SearchIterator struct {
+	next  func(dst interface{}) (string, error)
+	limit int
+	n     int
+	err   error
+}
+
+// Next returns the next result. The returned bool is false when there are no
+// more results or an error occurred. Packages excluded by
+// gosrc.SetExcludedPaths are skipped.
+func (it *SearchIterator) Next() (Package, bool) {
+	for it.err == nil && it.n < it.limit {
 		var p Package
-		_, err := it.Next(&p)
-		if err == search.Done {
+		if _, err := it.next(&p); err != nil {
+			if err != search.Done {
+				it.err = err
+			}
+			it.n = it.limit
 			break
 		}
-		if err != nil {
-			return nil, err
+		if gosrc.IsExcludedPath(p.Path) {
+			continue
 		}
-		pkgs = append(pkgs, p)
+		it.n++
+		return p, true
 	}
-	return pkgs, nil
+	return Package{}, false
+}
+
+// Err returns the error, if any, that stopped the iteration.
+func (it *SearchIterator) Err() error {
+	return it.err
 }
 
 func parseQuery2(q string) string {
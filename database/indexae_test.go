@@ -7,8 +7,10 @@
 package database
 
 import (
+	"errors"
 	"math"
 	"strconv"
+	"strings"
 	"testing"
 
 	"google.golang.org/appengine/aetest"
@@ -124,3 +126,43 @@ func TestSearchResultSorted(t *testing.T) {
 		}
 	}
 }
+
+func TestSearchIterator(t *testing.T) {
+	paths := []string{"a", "b", "c"}
+	next := func(dst interface{}) (string, error) {
+		if len(paths) == 0 {
+			return "", search.Done
+		}
+		dst.(*Package).Path = paths[0]
+		paths = paths[1:]
+		return "", nil
+	}
+	for _, tt := range []struct {
+		limit int
+		want  []string
+	}{
+		{limit: 2, want: []string{"a", "b"}},
+		{limit: 10, want: []string{"c"}},
+	} {
+		it := &SearchIterator{next: next, limit: tt.limit}
+		var got []string
+		for {
+			p, ok := it.Next()
+			if !ok {
+				break
+			}
+			got = append(got, p.Path)
+		}
+		if it.Err() != nil {
+			t.Fatal(it.Err())
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("limit %d: got %q, want %q", tt.limit, got, tt.want)
+		}
+	}
+
+	it := &SearchIterator{next: func(interface{}) (string, error) { return "", errors.New("boom") }, limit: 10}
+	if _, ok := it.Next(); ok || it.Err() == nil {
+		t.Errorf("Next() with failing search returned ok = %v, Err() = %v, want false and an error", ok, it.Err())
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package database
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RankingWeights are the weights of the signals combined by Query into the
+// score of a search result. The score is the product of the signals, each
+// raised to the power of its weight, so that a weight of 0 ignores the signal
+// and a weight of 1 applies it as is. Weights from 0 to 10 are accepted.
+type RankingWeights struct {
+	// Weight of the document score computed from the search terms of the
+	// package when it is indexed.
+	Terms float64
+
+	// Weight of the popularity of the package, log(10 + number of importers).
+	Popularity float64
+
+	// Weight of the recency of the last commit of the package. The recency
+	// signal is 2 for a package committed to now and halves every 90 days
+	// towards 1. Packages with an unknown commit time get 1. The weight is 0
+	// by default.
+	Recency float64
+
+	// Weight of the boost of standard library packages, 1.2 or 10000 for a
+	// standard package whose path ends with the query.
+	Standard float64
+}
+
+// DefaultRankingWeights are the weights of the search ranking used when the
+// Ranking field of the Database is nil.
+var DefaultRankingWeights = RankingWeights{Terms: 1, Popularity: 1, Recency: 0, Standard: 1}
+
+// maxRankingWeight is the largest weight accepted by RankingWeights.Validate.
+const maxRankingWeight = 10
+
+// recencyHalfLife is the time in which the recency signal halves towards 1.
+const recencyHalfLife = 90 * 24 * time.Hour
+
+// Validate returns an error if a weight is outside the accepted range.
+func (w RankingWeights) Validate() error {
+	for _, f := range []struct {
+		name  string
+		value float64
+	}{
+		{"terms", w.Terms},
+		{"popularity", w.Popularity},
+		{"recency", w.Recency},
+		{"standard", w.Standard},
+	} {
+		if !(f.value >= 0 && f.value <= maxRankingWeight) {
+			return fmt.Errorf("%s ranking weight %v is not between 0 and %d", f.name, f.value, maxRankingWeight)
+		}
+	}
+	return nil
+}
+
+func (db *Database) rankingWeights() RankingWeights {
+	if db.Ranking == nil {
+		return DefaultRankingWeights
+	}
+	return *db.Ranking
+}
+
+// rankResults sets the score of the results of the query q from the signals
+// weighted by w and sorts the results by decreasing score.
+func rankResults(results []*queryResult, q string, w RankingWeights, now time.Time) {
+	for _, qr := range results {
+		score := math.Pow(qr.Score, w.Terms)
+		score *= math.Pow(math.Log(float64(10+qr.ImportCount)), w.Popularity)
+
+		if qr.Committed > 0 {
+			age := now.Sub(time.Unix(qr.Committed, 0))
+			if age < 0 {
+				age = 0
+			}
+			score *= math.Pow(1+math.Exp2(-float64(age)/float64(recencyHalfLife)), w.Recency)
+		}
+
+		if isStandardPackage(qr.Path) {
+			if strings.HasSuffix(qr.Path, q) {
+				// Big bump for exact match on standard package name.
+				score *= math.Pow(10000, w.Standard)
+			} else {
+				score *= math.Pow(1.2, w.Standard)
+			}
+		}
+
+		if q == path.Base(qr.Path) {
+			score *= 1.1
+		}
+		qr.Score = score
+	}
+	sort.Sort(byScore(results))
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func rankedPaths(q string, w RankingWeights, now time.Time) []string {
+	results := []*queryResult{
+		{Path: "github.com/a/relevant", Score: 4, ImportCount: 0, Updated: now.Unix(), Committed: now.Add(-2 * 365 * 24 * time.Hour).Unix()},
+		{Path: "github.com/b/popular", Score: 2, ImportCount: 100000, Updated: now.Unix()},
+		{Path: "github.com/c/fresh", Score: 3, ImportCount: 10, Updated: now.Add(-30 * 24 * time.Hour).Unix(), Committed: now.Unix()},
+	}
+	rankResults(results, q, w, now)
+	var paths []string
+	for _, qr := range results {
+		paths = append(paths, qr.Path)
+	}
+	return paths
+}
+
+func TestRankResults(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		name string
+		w    RankingWeights
+		want []string
+	}{
+		{
+			name: "default",
+			w:    DefaultRankingWeights,
+			want: []string{"github.com/b/popular", "github.com/a/relevant", "github.com/c/fresh"},
+		},
+		{
+			name: "no popularity",
+			w:    RankingWeights{Terms: 1, Popularity: 0, Standard: 1},
+			want: []string{"github.com/a/relevant", "github.com/c/fresh", "github.com/b/popular"},
+		},
+		{
+			name: "more popularity",
+			w:    RankingWeights{Terms: 3, Popularity: 2, Standard: 1},
+			want: []string{"github.com/b/popular", "github.com/a/relevant", "github.com/c/fresh"},
+		},
+		{
+			name: "recency",
+			w:    RankingWeights{Terms: 1, Popularity: 0, Recency: 2, Standard: 1},
+			want: []string{"github.com/c/fresh", "github.com/a/relevant", "github.com/b/popular"},
+		},
+	} {
+		if got := rankedPaths("x", tt.w, now); !cmp.Equal(got, tt.want) {
+			t.Errorf("%s: ranked %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRankResultsPopularityWeight(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	w := RankingWeights{Terms: 3, Popularity: 1, Standard: 1}
+	before := rankedPaths("x", w, now)
+	w.Popularity = 3
+	after := rankedPaths("x", w, now)
+	if before[0] == after[0] {
+		t.Errorf("increasing the popularity weight did not reorder the results: %v", after)
+	}
+	if after[0] != "github.com/b/popular" {
+		t.Errorf("first result with popularity weight 3 is %q, want github.com/b/popular", after[0])
+	}
+}
+
+func TestRankingWeightsValidate(t *testing.T) {
+	if err := DefaultRankingWeights.Validate(); err != nil {
+		t.Errorf("DefaultRankingWeights.Validate() returned %v", err)
+	}
+	for _, w := range []RankingWeights{
+		{Terms: -1, Popularity: 1, Standard: 1},
+		{Terms: 1, Popularity: 11, Standard: 1},
+	} {
+		if err := w.Validate(); err == nil {
+			t.Errorf("%+v.Validate() returned nil, want error", w)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package database
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recencyPat matches the updated:<N term of a search query restricting the
+// results to packages updated in the last N days (d), weeks (w), months (m)
+// or years (y).
+var recencyPat = regexp.MustCompile(`(?i)(?:^|\s)updated:<(\d+)([dwmy])(?:\s|$)`)
+
+var recencyUnits = map[string]time.Duration{
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+	"m": 30 * 24 * time.Hour,
+	"y": 365 * 24 * time.Hour,
+}
+
+// QueryRecency returns the recency of the updated:< term of the search query
+// q, such as "90d", or "" if q has no such term.
+func QueryRecency(q string) string {
+	m := recencyPat.FindStringSubmatch(q)
+	if m == nil {
+		return ""
+	}
+	return m[1] + strings.ToLower(m[2])
+}
+
+// WithRecency returns the search query q with its updated:< term replaced
+// by one for recency, such as "90d". An empty recency removes the term.
+func WithRecency(q, recency string) string {
+	q = strings.TrimSpace(recencyPat.ReplaceAllString(q, " "))
+	if recency == "" {
+		return q
+	}
+	if q == "" {
+		return "updated:<" + recency
+	}
+	return q + " updated:<" + recency
+}
+
+// parseRecency splits the search query q into the query without its
+// updated:< term and the maximum age of the packages selected by the term.
+// The age is zero if q has no such term.
+func parseRecency(q string) (string, time.Duration) {
+	m := recencyPat.FindStringSubmatch(q)
+	if m == nil {
+		return q, 0
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return q, 0
+	}
+	return WithRecency(q, ""), time.Duration(n) * recencyUnits[strings.ToLower(m[2])]
+}
+
+// filterRecent returns the results whose last commit is at most maxAge before
+// now. Results with an unknown commit time are dropped.
+func filterRecent(results []*queryResult, maxAge time.Duration, now time.Time) []*queryResult {
+	since := now.Add(-maxAge).Unix()
+	var recent []*queryResult
+	for _, qr := range results {
+		if qr.Committed > 0 && qr.Committed >= since {
+			recent = append(recent, qr)
+		}
+	}
+	return recent
+}
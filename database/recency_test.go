@@ -0,0 +1,75 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseRecency(t *testing.T) {
+	const day = 24 * time.Hour
+	for _, tt := range []struct {
+		q       string
+		rest    string
+		maxAge  time.Duration
+		recency string
+	}{
+		{"http router", "http router", 0, ""},
+		{"http updated:<90d", "http", 90 * day, "90d"},
+		{"updated:<2W router", "router", 14 * day, "2w"},
+		{"a updated:<6m b", "a b", 180 * day, "6m"},
+		{"updated:<1y", "", 365 * day, "1y"},
+		{"updated:<90 router", "updated:<90 router", 0, ""},
+		{"notupdated:<1y", "notupdated:<1y", 0, ""},
+	} {
+		rest, maxAge := parseRecency(tt.q)
+		if rest != tt.rest || maxAge != tt.maxAge {
+			t.Errorf("parseRecency(%q) = %q, %v, want %q, %v", tt.q, rest, maxAge, tt.rest, tt.maxAge)
+		}
+		if r := QueryRecency(tt.q); r != tt.recency {
+			t.Errorf("QueryRecency(%q) = %q, want %q", tt.q, r, tt.recency)
+		}
+	}
+}
+
+func TestWithRecency(t *testing.T) {
+	for _, tt := range []struct {
+		q, recency, want string
+	}{
+		{"router", "90d", "router updated:<90d"},
+		{"router updated:<1y", "30d", "router updated:<30d"},
+		{"router updated:<1y", "", "router"},
+		{"", "1y", "updated:<1y"},
+	} {
+		if got := WithRecency(tt.q, tt.recency); got != tt.want {
+			t.Errorf("WithRecency(%q, %q) = %q, want %q", tt.q, tt.recency, got, tt.want)
+		}
+	}
+}
+
+func TestFilterRecent(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	ago := func(days int) int64 { return now.Add(-time.Duration(days) * 24 * time.Hour).Unix() }
+	results := []*queryResult{
+		{Path: "github.com/a/fresh", Committed: ago(3), Updated: ago(1)},
+		{Path: "github.com/b/stale", Committed: ago(400), Updated: ago(1)},
+		{Path: "example.com/c/crawled", Updated: ago(10)},
+		{Path: "example.com/d/unknown"},
+		{Path: "github.com/e/old", Committed: ago(100), Updated: ago(2)},
+	}
+	var got []string
+	for _, qr := range filterRecent(results, 90*24*time.Hour, now) {
+		got = append(got, qr.Path)
+	}
+	want := []string{"github.com/a/fresh"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("filterRecent() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package database
+
+import (
+	"log"
+	"sync/atomic"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// Primary returns the database without its replicas. Paths that read their
+// own writes, such as crawling, use the primary to avoid replication lag.
+func (db *Database) Primary() *Database {
+	if len(db.Replicas) == 0 {
+		return db
+	}
+	return &Database{Pool: db.Pool, RemoteClient: db.RemoteClient}
+}
+
+// readConn returns a connection for read only commands. The connection is to
+// one of the replicas, chosen round robin, if there are any.
+func (db *Database) readConn() redis.Conn {
+	if len(db.Replicas) == 0 {
+		return db.Pool.Get()
+	}
+	n := atomic.AddUint32(&db.next, 1)
+	r := db.Replicas[int(n%uint32(len(db.Replicas)))]
+	return &replicaConn{Conn: r.Get(), primary: db.Pool}
+}
+
+// replicaConn is a connection to a replica that switches to the primary when
+// a command fails with a connection error. Errors returned by the server are
+// returned as is. Pipelined commands are not retried.
+type replicaConn struct {
+	redis.Conn
+	primary interface {
+		Get() redis.Conn
+	}
+	fellBack bool
+}
+
+func (c *replicaConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	reply, err := c.Conn.Do(cmd, args...)
+	if _, ok := err.(redis.Error); err == nil || ok || c.fellBack {
+		return reply, err
+	}
+	log.Printf("replica %s: %v; falling back to primary", cmd, err)
+	c.Conn.Close()
+	c.Conn = c.primary.Get()
+	c.fellBack = true
+	return c.Conn.Do(cmd, args...)
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// fakeConn replies to every command with reply and err.
+type fakeConn struct {
+	redis.Conn
+	reply  interface{}
+	err    error
+	closed bool
+}
+
+func (c *fakeConn) Do(string, ...interface{}) (interface{}, error) { return c.reply, c.err }
+func (c *fakeConn) Close() error                                   { c.closed = true; return nil }
+
+type fakePool struct{ c *fakeConn }
+
+func (p fakePool) Get() redis.Conn { return p.c }
+
+func TestReplicaFallback(t *testing.T) {
+	primary := &fakeConn{reply: "primary"}
+	for _, tt := range []struct {
+		name    string
+		replica *fakeConn
+		want    string
+		wantErr bool
+	}{
+		{"healthy replica", &fakeConn{reply: "replica"}, "replica", false},
+		{"broken replica", &fakeConn{err: errors.New("connection refused")}, "primary", false},
+		{"server error", &fakeConn{err: redis.Error("ERR unknown command")}, "", true},
+	} {
+		db := &Database{
+			Pool:     fakePool{primary},
+			Replicas: []interface{ Get() redis.Conn }{fakePool{tt.replica}},
+		}
+		c := db.readConn()
+		reply, err := c.Do("GET", "key")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Do returned error %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if got, _ := reply.(string); got != tt.want {
+			t.Errorf("%s: Do = %q, want %q", tt.name, got, tt.want)
+		}
+		if tt.want == "primary" && !tt.replica.closed {
+			t.Errorf("%s: replica connection not closed", tt.name)
+		}
+	}
+
+	db := &Database{Pool: fakePool{primary}}
+	if db.Primary() != db {
+		t.Error("Primary() of a database without replicas is not the database")
+	}
+	db.Replicas = []interface{ Get() redis.Conn }{fakePool{&fakeConn{}}}
+	if p := db.Primary(); len(p.Replicas) != 0 || p.Pool != db.Pool {
+		t.Errorf("Primary() = %+v, want database without replicas", p)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"sort"
+	"strings"
+)
+
+// knownArch is the set of GOARCH values recognized in the names of assembly
+// files.
+var knownArch = map[string]bool{
+	"386":      true,
+	"amd64":    true,
+	"arm":      true,
+	"arm64":    true,
+	"loong64":  true,
+	"mips":     true,
+	"mipsle":   true,
+	"mips64":   true,
+	"mips64le": true,
+	"ppc64":    true,
+	"ppc64le":  true,
+	"riscv64":  true,
+	"s390x":    true,
+	"wasm":     true,
+}
+
+// asmArchs reports whether the directory has assembly files, given their
+// names, and returns the sorted architectures the files are written for,
+// taken from name suffixes such as sum_amd64.s.
+func asmArchs(names []string) (bool, []string) {
+	archs := make(map[string]bool)
+	for _, name := range names {
+		if arch := nameArch(name); arch != "" {
+			archs[arch] = true
+		}
+	}
+	var result []string
+	for arch := range archs {
+		result = append(result, arch)
+	}
+	sort.Strings(result)
+	return len(names) > 0, result
+}
+
+// nameArch returns the architecture in the _GOARCH or _GOOS_GOARCH suffix of
+// the file name or "" if there is no such suffix.
+func nameArch(name string) string {
+	name = name[:strings.LastIndex(name, ".")]
+	i := strings.LastIndex(name, "_")
+	if i < 0 || !knownArch[name[i+1:]] {
+		return ""
+	}
+	return name[i+1:]
+}
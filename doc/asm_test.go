@@ -0,0 +1,31 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAsmArchs(t *testing.T) {
+	for _, tt := range []struct {
+		names    []string
+		want     bool
+		wantArch []string
+	}{
+		{nil, false, nil},
+		{[]string{"sum_arm64.s", "sum_amd64.s"}, true, []string{"amd64", "arm64"}},
+		{[]string{"sys_linux_386.s"}, true, []string{"386"}},
+		{[]string{"asm.s", "sum_amd64.s"}, true, []string{"amd64"}},
+		{[]string{"asm.s", "stub_linux.s"}, true, nil},
+	} {
+		got, gotArch := asmArchs(tt.names)
+		if got != tt.want || !reflect.DeepEqual(gotArch, tt.wantArch) {
+			t.Errorf("asmArchs(%v) = %v, %v, want %v, %v", tt.names, got, gotArch, tt.want, tt.wantArch)
+		}
+	}
+}
@@ -7,7 +7,6 @@
 package doc
 
 import (
-	"bytes"
 	"errors"
 	"go/ast"
 	"go/build"
@@ -16,6 +15,7 @@ import (
 	"go/parser"
 	"go/token"
 	"regexp"
+	"runtime/debug"
 	"sort"
 	"strings"
 	"time"
@@ -76,17 +76,7 @@ Loop:
 		}
 	}
 
-	// Ensure that synopsis fits an App Engine datastore text property.
-	const m = 400
-	if len(buf) > m {
-		buf = buf[:m]
-		if i := bytes.LastIndex(buf, []byte{' '}); i >= 0 {
-			buf = buf[:i]
-		}
-		buf = append(buf, " ..."...)
-	}
-
-	s = string(buf)
+	s = TruncateSynopsis(string(buf), maxSynopsisLength)
 
 	r, n := utf8.DecodeRuneInString(s)
 	if n < 0 || unicode.IsPunct(r) || unicode.IsSymbol(r) {
@@ -104,6 +94,49 @@ Loop:
 	return s
 }
 
+// defaultMaxSynopsisLength is the default maximum length of a synopsis, in
+// runes. It ensures that the synopsis fits an App Engine datastore text
+// property.
+const defaultMaxSynopsisLength = 400
+
+var maxSynopsisLength = defaultMaxSynopsisLength
+
+// SetMaxSynopsisLength sets the maximum length, in runes, of the synopses of
+// the package documents built after the call. Longer synopses are truncated
+// with TruncateSynopsis. SetMaxSynopsisLength has no effect if n is not
+// positive.
+func SetMaxSynopsisLength(n int) {
+	if n <= 0 {
+		return
+	}
+	maxSynopsisLength = n
+}
+
+// TruncateSynopsis returns s truncated to at most n runes followed by an
+// ellipsis. The truncated synopsis ends at the last clause boundary within
+// the limit in the second half of the text, or else at the last word
+// boundary. TruncateSynopsis returns s unchanged if it has at most n runes.
+func TruncateSynopsis(s string, n int) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	end := 0
+	for i := range s {
+		if n == 0 {
+			end = i
+			break
+		}
+		n--
+	}
+	t := s[:end]
+	if i := strings.LastIndexAny(t, ",;:"); i >= end/2 && i+1 < len(s) && s[i+1] == ' ' {
+		t = t[:i]
+	} else if i := strings.LastIndexByte(t, ' '); i >= 0 && s[end] != ' ' {
+		t = t[:i]
+	}
+	return strings.TrimRight(t, " ") + " ..."
+}
+
 var referencesPats = []*regexp.Regexp{
 	regexp.MustCompile(`"([-a-zA-Z0-9~+_./]+)"`), // quoted path
 	regexp.MustCompile(`https://drone\.io/([-a-zA-Z0-9~+_./]+)/status\.png`),
@@ -145,26 +178,51 @@ func removeAssociations(dpkg *doc.Package) {
 
 // builder holds the state used when building the documentation.
 type builder struct {
-	srcs     map[string]*source
-	fset     *token.FileSet
-	examples []*doc.Example
-	buf      []byte // scratch space for printNode method.
+	srcs       map[string]*source
+	fset       *token.FileSet
+	examples   []*doc.Example
+	exfiles    map[*doc.Example]*ast.File // test files declaring the examples
+	structs    map[string]*structInfo
+	sigs       map[string][]string // normalized method signatures by type name
+	interfaces map[string]bool     // names of the interface types
+	consts     map[string]string   // computed values of constants declared with iota
+	links      map[*ast.Ident]bool // identifiers in examples linking to declarations
+	buf        []byte              // scratch space for printNode method.
+
+	importPath string
+	unexported bool // include unexported declarations
 }
 
 type Value struct {
-	Decl Code
-	Pos  Pos
-	Doc  string
+	Decl      Code
+	Pos       Pos
+	Doc       string
+	Generated bool // declared in a generated file
+
+	// Computed values of the constants of a const block using iota, in
+	// declaration order.
+	Values []ConstValue
 }
 
 func (b *builder) values(vdocs []*doc.Value) []*Value {
 	var result []*Value
 	for _, d := range vdocs {
-		result = append(result, &Value{
-			Decl: b.printDecl(d.Decl),
-			Pos:  b.position(d.Decl),
-			Doc:  d.Doc,
-		})
+		v := &Value{
+			Decl:      b.printDecl(d.Decl),
+			Pos:       b.position(d.Decl),
+			Doc:       d.Doc,
+			Generated: b.generated(d.Decl),
+		}
+		if d.Decl.Tok == token.CONST {
+			for _, spec := range d.Decl.Specs {
+				for _, n := range spec.(*ast.ValueSpec).Names {
+					if value, ok := b.consts[n.Name]; ok {
+						v.Values = append(v.Values, ConstValue{Name: n.Name, Value: value})
+					}
+				}
+			}
+		}
+		result = append(result, v)
 	}
 	return result
 }
@@ -205,6 +263,14 @@ type Example struct {
 	Code   Code
 	Play   string
 	Output string
+
+	// True if the lines of the output may be in any order.
+	Unordered bool
+
+	// Name of the test file declaring the example and the line of the
+	// example function in the file.
+	File string
+	Line int32
 }
 
 var exampleOutputRx = regexp.MustCompile(`(?i)//[[:space:]]*output:`)
@@ -226,37 +292,73 @@ func (b *builder) getExamples(name string) []*Example {
 			}
 			n = strings.Title(n)
 		}
+		docs = append(docs, b.example(e, n))
+	}
+	return docs
+}
 
-		code, output := b.printExample(e)
+// allExamples returns all examples named with the name of the example
+// function without the Example prefix. It is used for directories with only
+// test files, which have no declarations to associate the examples with.
+func (b *builder) allExamples() []*Example {
+	var docs []*Example
+	for _, e := range b.examples {
+		docs = append(docs, b.example(e, e.Name))
+	}
+	return docs
+}
 
-		play := ""
-		if e.Play != nil {
-			b.buf = b.buf[:0]
-			if err := format.Node(sliceWriter{&b.buf}, b.fset, e.Play); err != nil {
-				play = err.Error()
-			} else {
-				play = string(b.buf)
-			}
+// example returns the documentation of the example e with the name n.
+func (b *builder) example(e *doc.Example, n string) *Example {
+	code, output := b.printExample(e)
+
+	play := ""
+	if f := b.exfiles[e]; f != nil && !strings.HasSuffix(f.Name.Name, "_test") {
+		play = b.playExample(b.importPath, e, f)
+	} else if e.Play != nil {
+		b.buf = b.buf[:0]
+		if err := format.Node(sliceWriter{&b.buf}, b.fset, e.Play); err != nil {
+			play = err.Error()
+		} else {
+			play = string(b.buf)
 		}
+	}
+
+	pos := b.fset.Position(examplePos(e))
+	return &Example{
+		Name:      n,
+		Doc:       e.Doc,
+		Code:      code,
+		Output:    output,
+		Unordered: e.Unordered,
+		Play:      play,
+		File:      pos.Filename,
+		Line:      int32(pos.Line)}
+}
 
-		docs = append(docs, &Example{
-			Name:   n,
-			Doc:    e.Doc,
-			Code:   code,
-			Output: output,
-			Play:   play})
+// examplePos returns the position of the example function. The code of
+// whole file examples is the file declaring the function.
+func examplePos(e *doc.Example) token.Pos {
+	if f, ok := e.Code.(*ast.File); ok {
+		for _, d := range f.Decls {
+			if d, ok := d.(*ast.FuncDecl); ok && d.Recv == nil && d.Name.Name == "Example"+e.Name {
+				return d.Pos()
+			}
+		}
 	}
-	return docs
+	return e.Code.Pos()
 }
 
 type Func struct {
-	Decl     Code
-	Pos      Pos
-	Doc      string
-	Name     string
-	Recv     string // Actual receiver "T" or "*T".
-	Orig     string // Original receiver "T" or "*T". This can be different from Recv due to embedding.
-	Examples []*Example
+	Decl      Code
+	Pos       Pos
+	Doc       string
+	Name      string
+	Recv      string // Actual receiver "T" or "*T".
+	Orig      string // Original receiver "T" or "*T". This can be different from Recv due to embedding.
+	Via       string // Embedded type the method is promoted from, or "" if declared on Recv.
+	Generated bool   // Declared in a generated file.
+	Examples  []*Example
 }
 
 func (b *builder) funcs(fdocs []*doc.Func) []*Func {
@@ -272,44 +374,79 @@ func (b *builder) funcs(fdocs []*doc.Func) []*Func {
 			exampleName = d.Recv + "_" + d.Name
 		}
 		result = append(result, &Func{
-			Decl:     b.printDecl(d.Decl),
-			Pos:      b.position(d.Decl),
-			Doc:      d.Doc,
-			Name:     d.Name,
-			Recv:     d.Recv,
-			Orig:     d.Orig,
-			Examples: b.getExamples(exampleName),
+			Decl:      b.printDecl(d.Decl),
+			Pos:       b.position(d.Decl),
+			Doc:       d.Doc,
+			Name:      d.Name,
+			Recv:      d.Recv,
+			Orig:      d.Orig,
+			Generated: b.generated(d.Decl),
+			Examples:  b.getExamples(exampleName),
 		})
 	}
 	return result
 }
 
 type Type struct {
-	Doc      string
-	Name     string
-	Decl     Code
-	Pos      Pos
-	Consts   []*Value
-	Vars     []*Value
-	Funcs    []*Func
-	Methods  []*Func
-	Examples []*Example
+	Doc       string
+	Name      string
+	Decl      Code
+	Pos       Pos
+	Generated bool // declared in a generated file
+	Consts    []*Value
+	Vars      []*Value
+	Funcs     []*Func
+	Methods   []*Func
+	Examples  []*Example
+
+	// Methods promoted from embedded types declared in the same package.
+	PromotedMethods []*Func
+
+	// Exported fields of a struct type and fields promoted to it from
+	// embedded types declared in the same package.
+	Fields         []*Field
+	PromotedFields []*Field
+
+	// Types from other packages embedded in a struct type, directly or
+	// through embedded types declared in the same package.
+	Embedded []*Embedded
+
+	// Normalized method signatures used to find the implementations of
+	// interface types. See methodSigs.
+	Interface  bool
+	MethodSigs []string
 }
 
 func (b *builder) types(tdocs []*doc.Type) []*Type {
 	var result []*Type
 	for _, d := range tdocs {
-		result = append(result, &Type{
-			Doc:      d.Doc,
-			Name:     d.Name,
-			Decl:     b.printDecl(d.Decl),
-			Pos:      b.position(d.Decl),
-			Consts:   b.values(d.Consts),
-			Vars:     b.values(d.Vars),
-			Funcs:    b.funcs(d.Funcs),
-			Methods:  b.funcs(d.Methods),
-			Examples: b.getExamples(d.Name),
-		})
+		t := &Type{
+			Doc:       d.Doc,
+			Name:      d.Name,
+			Decl:      b.printDecl(d.Decl),
+			Pos:       b.position(d.Decl),
+			Generated: b.generated(d.Decl),
+			Consts:    b.values(d.Consts),
+			Vars:      b.values(d.Vars),
+			Funcs:     b.funcs(d.Funcs),
+			Examples:  b.getExamples(d.Name),
+		}
+		for _, m := range b.funcs(d.Methods) {
+			if orig := strings.TrimPrefix(m.Orig, "*"); orig != "" && orig != d.Name {
+				m.Via = orig
+				t.PromotedMethods = append(t.PromotedMethods, m)
+			} else {
+				t.Methods = append(t.Methods, m)
+			}
+		}
+		if info := b.structs[d.Name]; info != nil {
+			t.Fields = info.fields
+			t.PromotedFields = info.promoted
+			t.Embedded = info.imported
+		}
+		t.Interface = b.interfaces[d.Name]
+		t.MethodSigs = b.sigs[d.Name]
+		result = append(result, t)
 	}
 	return result
 }
@@ -355,8 +492,9 @@ func simpleImporter(imports map[string]*ast.Object, path string) (*ast.Object, e
 }
 
 type File struct {
-	Name string
-	URL  string
+	Name      string
+	URL       string
+	Generated bool // the file has a "Code generated ... DO NOT EDIT." comment
 }
 
 type Pos struct {
@@ -370,10 +508,11 @@ type source struct {
 	browseURL string
 	data      []byte
 	index     int
+	generated bool
 }
 
 // PackageVersion is modified when previously stored packages are invalid.
-const PackageVersion = "8"
+const PackageVersion = "32"
 
 type Package struct {
 	// The import path for this package.
@@ -388,12 +527,31 @@ type Package struct {
 	// Project home page.
 	ProjectURL string
 
+	// Home page of the repository containing the package and the path of the
+	// package directory relative to the repository root.
+	RepoURL string
+	RepoDir string
+
 	// Errors found when fetching or parsing this package.
 	Errors []string
 
 	// Packages referenced in README files.
 	References []string
 
+	// README files of the package directory: the default README, if any,
+	// followed by the translations named with a language suffix.
+	Readmes []*Readme
+
+	// Changelog file of the package directory, or nil if there is none.
+	Changelog *Changelog
+
+	// Owners of the package from the CODEOWNERS file in the repository
+	// root, or nil if the package has no owners. CodeOwners holds the rules
+	// of the file and is only set for the package in the repository root,
+	// from which the owners of the other packages are found when crawled.
+	Owners     []string
+	CodeOwners []CodeOwnersRule
+
 	// Version control system: git, hg, bzr, ...
 	VCS string
 
@@ -403,6 +561,10 @@ type Package struct {
 	// Whether the package is a fork of another one.
 	Fork bool
 
+	// Project root of the repository the repository of the package is a fork
+	// of, or "" if unknown.
+	ForkOf string
+
 	// How many stars (for a GitHub project) or followers (for a BitBucket
 	// project) the repository of this package has.
 	Stars int
@@ -410,13 +572,34 @@ type Package struct {
 	// The time this object was created.
 	Updated time.Time
 
+	// Time of the last commit to the package directory reported by the
+	// version control service. Zero if unknown.
+	Committed time.Time
+
 	// Cache validation tag. This tag is not necessarily an HTTP entity tag.
 	// The tag is "" if there is no meaningful cache validation for the VCS.
 	Etag string
 
+	// Highest semantic version tag of the repository, or "" if the
+	// repository has no such tags or the tags were not fetched.
+	LatestVersion string
+
+	// True if the documentation includes unexported declarations. Such
+	// documentation is not stored in the database.
+	Unexported bool
+
 	// Subdirectories, possibly containing Go code.
 	Subdirectories []string
 
+	// Slash separated paths of the directories in the repository relative to
+	// the repository root, bounded in depth and size. The database stores the
+	// tree once for the project root instead of with each package.
+	Tree []string
+
+	// True if the directory has test files but no package files, such as a
+	// directory of examples. The documentation only has the examples.
+	TestOnly bool
+
 	// Package name or "" if no package for this import path. The proceeding
 	// fields are set even if a package is not found for the import path.
 	Name string
@@ -425,15 +608,54 @@ type Package struct {
 	Synopsis string
 	Doc      string
 
+	// Source of the synopsis: SynopsisDoc, SynopsisReadme, SynopsisProject
+	// or "" if the package has no synopsis.
+	SynopsisSource string
+
 	// Format this package as a command.
 	IsCmd bool
 
 	// True if package documentation is incomplete.
 	Truncated bool
 
+	// True if the package has build errors, such as syntax errors or files
+	// of several packages. The documentation is built from the declarations
+	// that could be parsed and may be incomplete.
+	Partial bool
+
 	// Environment
 	GOOS, GOARCH string
 
+	// Minimum Go version required by the package, such as "1.16".
+	// GoModVersion is declared by the go directive of the go.mod file of the
+	// module. GoTagVersion is inferred from the build constraints of the
+	// package files. The versions are "" when unknown.
+	GoModVersion string
+	GoTagVersion string
+
+	// True if the package directory has assembly (.s) files. AsmArchs lists
+	// the architectures of the assembly files, such as amd64 and arm64, when
+	// they can be told from the file names.
+	Assembly bool
+	AsmArchs []string
+
+	// Deprecation message of the module, from the "Deprecated:" comment of
+	// the module directive of the go.mod file, and the versions retracted by
+	// the retract directives. Packages in a directory without a go.mod file
+	// get these from the go.mod file in the project root when crawled.
+	Deprecated  string
+	Retractions []Retraction
+
+	// Modules required by the go.mod file in the directory of the package.
+	// Packages without a go.mod file have no requirements.
+	Requires []Requirement
+
+	// Module path and go.sum hash, such as "h1:...", of the go.mod file in
+	// the directory of the package. Empty if the directory does not have a
+	// go.mod file.
+	ModulePath string
+	GoModHash  string
+
 	// Top-level declarations.
 	Consts []*Value
 	Funcs  []*Func
@@ -455,6 +677,13 @@ type Package struct {
 	SourceSize     int
 	TestSourceSize int
 
+	// Lines of code, excluding blank and comment lines, of the package
+	// files, the test files and the generated package files. The package
+	// and test line counts exclude generated files.
+	CodeLines      int
+	TestLines      int
+	GeneratedLines int
+
 	// Imports
 	Imports      []string
 	TestImports  []string
@@ -502,7 +731,37 @@ var windowsOnlyPackages = map[string]bool{
 	"golang.org/x/sys/windows/registry":            true,
 }
 
+// excludeFiles returns the names not in exclude.
+func excludeFiles(names, exclude []string) []string {
+	if len(exclude) == 0 {
+		return names
+	}
+	skip := make(map[string]bool)
+	for _, name := range exclude {
+		skip[name] = true
+	}
+	var result []string
+	for _, name := range names {
+		if !skip[name] {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
 func newPackage(dir *gosrc.Directory) (*Package, error) {
+	return buildPackage(dir, false)
+}
+
+// buildPackage builds the documentation for the files in dir, including
+// unexported declarations if unexported is true. A panic while building the
+// documentation is returned as a *PanicError.
+func buildPackage(dir *gosrc.Directory, unexported bool) (_ *Package, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{ImportPath: dir.ImportPath, Value: v, Stack: debug.Stack()}
+		}
+	}()
 
 	pkg := &Package{
 		Updated:        time.Now().UTC(),
@@ -511,22 +770,41 @@ func newPackage(dir *gosrc.Directory) (*Package, error) {
 		ProjectRoot:    dir.ProjectRoot,
 		ProjectName:    dir.ProjectName,
 		ProjectURL:     dir.ProjectURL,
+		RepoURL:        dir.RepoURL,
+		RepoDir:        dir.RepoDir,
 		BrowseURL:      dir.BrowseURL,
 		Etag:           PackageVersion + "-" + dir.Etag,
 		VCS:            dir.VCS,
 		Status:         dir.Status,
 		Subdirectories: dir.Subdirectories,
+		Tree:           dir.Tree,
 		Fork:           dir.Fork,
+		ForkOf:         dir.ForkOf,
 		Stars:          dir.Stars,
+		Committed:      dir.Committed,
+		Unexported:     unexported,
 	}
 
-	var b builder
+	b := builder{importPath: dir.ImportPath, unexported: unexported}
 	b.srcs = make(map[string]*source)
+	b.exfiles = make(map[*doc.Example]*ast.File)
 	references := make(map[string]bool)
+	var modulePath string
 	for _, file := range dir.Files {
 		if strings.HasSuffix(file.Name, ".go") {
 			gosrc.OverwriteLineComments(file.Data)
 			b.srcs[file.Name] = &source{name: file.Name, browseURL: file.BrowseURL, data: file.Data}
+		} else if file.Name == "go.mod" {
+			pkg.GoModVersion = modGoVersion(file.Data)
+			pkg.Requires = modRequires(file.Data)
+			pkg.Deprecated, pkg.Retractions = modDeprecation(file.Data)
+			modulePath = gosrc.ModulePath(file.Data)
+			pkg.ModulePath = modulePath
+			pkg.GoModHash = modHash(file.Data)
+		} else if file.Name == "CODEOWNERS" {
+			if dir.ImportPath == dir.ProjectRoot {
+				pkg.CodeOwners = ParseCodeOwners(file.Data)
+			}
 		} else {
 			addReferences(references, file.Data)
 		}
@@ -535,6 +813,9 @@ func newPackage(dir *gosrc.Directory) (*Package, error) {
 	for r := range references {
 		pkg.References = append(pkg.References, r)
 	}
+	pkg.Readmes = readmes(dir.Files)
+	pkg.Changelog = changelog(dir.Files)
+	pkg.Assembly, pkg.AsmArchs = asmArchs(dir.AsmFiles)
 
 	if len(b.srcs) == 0 {
 		return pkg, nil
@@ -553,7 +834,6 @@ func newPackage(dir *gosrc.Directory) (*Package, error) {
 		Compiler:    "gc",
 	}
 
-	var err error
 	var bpkg *build.Package
 
 	for _, env := range goEnvs {
@@ -574,12 +854,20 @@ func newPackage(dir *gosrc.Directory) (*Package, error) {
 		if _, ok := err.(*build.NoGoError); !ok {
 			pkg.Errors = append(pkg.Errors, err.Error())
 		}
-		return pkg, nil
+		// Document the valid files of packages with build errors, such as
+		// files with syntax errors or files of several packages.
+		if bpkg == nil || len(bpkg.GoFiles)+len(bpkg.CgoFiles) == 0 {
+			return pkg, nil
+		}
+		pkg.Partial = true
 	}
 
-	// Use information we have by now (import comment and resolved GitHub path)
-	// to redirect to a canonical import path, when it's possible to do so reliably.
+	// Use information we have by now (import comment, module path and resolved
+	// GitHub path) to redirect to a canonical import path, when it's possible to do so reliably.
 	err = gosrc.MaybeRedirect(dir.ImportPath, bpkg.ImportComment, dir.ResolvedGitHubPath)
+	if err == nil && bpkg.ImportComment == "" {
+		err = gosrc.ModuleRedirect(dir.ImportPath, modulePath)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -587,26 +875,40 @@ func newPackage(dir *gosrc.Directory) (*Package, error) {
 	// Parse the Go files
 
 	files := make(map[string]*ast.File)
-	names := append(bpkg.GoFiles, bpkg.CgoFiles...)
+	names := excludeFiles(append(bpkg.GoFiles, bpkg.CgoFiles...), bpkg.InvalidGoFiles)
 	sort.Strings(names)
 	pkg.Files = make([]*File, len(names))
 	for i, name := range names {
 		file, err := parser.ParseFile(b.fset, name, b.srcs[name].data, parser.ParseComments)
 		if err != nil {
 			pkg.Errors = append(pkg.Errors, err.Error())
-		} else {
+			pkg.Partial = true
+		}
+		if file != nil && file.Name != nil {
+			// The parser returns the declarations read before the error.
 			files[name] = file
 		}
 		src := b.srcs[name]
 		src.index = i
-		pkg.Files[i] = &File{Name: name, URL: src.browseURL}
+		src.generated = isGenerated(src.data)
+		pkg.Files[i] = &File{Name: name, URL: src.browseURL, Generated: src.generated}
 		pkg.SourceSize += len(src.data)
+		if src.generated {
+			pkg.GeneratedLines += codeLines(src.data)
+		} else {
+			pkg.CodeLines += codeLines(src.data)
+		}
 	}
 
 	apkg, _ := ast.NewPackage(b.fset, files, simpleImporter, nil)
 
 	// Find examples in the test files.
 
+	xtest := make(map[string]bool)
+	for _, name := range bpkg.XTestGoFiles {
+		xtest[name] = true
+	}
+	var testFiles, xtestFiles []*ast.File
 	names = append(bpkg.TestGoFiles, bpkg.XTestGoFiles...)
 	sort.Strings(names)
 	pkg.TestFiles = make([]*File, len(names))
@@ -615,16 +917,42 @@ func newPackage(dir *gosrc.Directory) (*Package, error) {
 		if err != nil {
 			pkg.Errors = append(pkg.Errors, err.Error())
 		} else {
-			b.examples = append(b.examples, doc.Examples(file)...)
+			for _, e := range doc.Examples(file) {
+				b.examples = append(b.examples, e)
+				b.exfiles[e] = file
+			}
+			if xtest[name] {
+				xtestFiles = append(xtestFiles, file)
+			} else {
+				testFiles = append(testFiles, file)
+			}
 		}
 		pkg.TestFiles[i] = &File{Name: name, URL: b.srcs[name].browseURL}
 		pkg.TestSourceSize += len(b.srcs[name].data)
+		if data := b.srcs[name].data; !isGenerated(data) {
+			pkg.TestLines += codeLines(data)
+		}
+	}
+
+	var pkgFiles []*ast.File
+	for _, file := range pkg.Files {
+		if f := files[file.Name]; f != nil {
+			pkgFiles = append(pkgFiles, f)
+		}
+	}
+
+	if len(b.examples) > 0 {
+		b.resolveExampleLinks(pkg.ImportPath, pkgFiles, testFiles, xtestFiles)
 	}
 
 	b.vetPackage(pkg, apkg)
 
-	mode := doc.Mode(0)
-	if pkg.ImportPath == "builtin" {
+	b.structs = b.structInfos(files)
+	b.sigs, b.interfaces = b.methodSigs(files)
+	b.consts = constValues(files)
+
+	mode := doc.AllMethods
+	if pkg.ImportPath == "builtin" || b.unexported {
 		mode |= doc.AllDecls
 	}
 
@@ -636,12 +964,20 @@ func newPackage(dir *gosrc.Directory) (*Package, error) {
 
 	pkg.Name = dpkg.Name
 	pkg.Doc = strings.TrimRight(dpkg.Doc, " \t\n\r")
-	pkg.Synopsis = synopsis(pkg.Doc)
+	pkg.Synopsis, pkg.SynopsisSource = packageSynopsis(pkg)
 
 	pkg.Examples = b.getExamples("")
+	if len(pkgFiles) == 0 && len(pkg.TestFiles) > 0 && !pkg.Partial {
+		// The directory only has tests or examples. Name the page after the
+		// package of the test files and show all of the examples.
+		pkg.TestOnly = true
+		pkg.Name = bpkg.Name
+		pkg.Examples = b.allExamples()
+	}
 	pkg.IsCmd = bpkg.IsCommand()
 	pkg.GOOS = ctxt.GOOS
 	pkg.GOARCH = ctxt.GOARCH
+	pkg.GoTagVersion = tagGoVersion(pkgFiles)
 
 	pkg.Consts = b.values(dpkg.Consts)
 	pkg.Funcs = b.funcs(dpkg.Funcs)
@@ -7,8 +7,14 @@
 package doc
 
 import (
+	"bytes"
+	"flag"
+	"fmt"
 	"go/ast"
+	"io/ioutil"
 	"testing"
+
+	"github.com/golang/gddo/gosrc"
 )
 
 var badSynopsis = []string{
@@ -27,6 +33,29 @@ func TestBadSynopsis(t *testing.T) {
 	}
 }
 
+var truncateSynopsisTests = []struct {
+	s    string
+	n    int
+	want string
+}{
+	{"Package foo does things.", 100, "Package foo does things."},
+	{"Package foo does things.", 0, "Package foo does things."},
+	{"Package foo does many things", 20, "Package foo does ..."},
+	{"Package foo does things, quickly and well", 30, "Package foo does things ..."},
+	{"Package foo does things", 16, "Package foo does ..."},
+	{"Paquete foo hace cosas útiles", 26, "Paquete foo hace cosas ..."},
+	{"包foo做很多有用的事情", 8, "包foo做很多有 ..."},
+	{"Packagefoodoesthings", 7, "Package ..."},
+}
+
+func TestTruncateSynopsis(t *testing.T) {
+	for _, tt := range truncateSynopsisTests {
+		if got := TruncateSynopsis(tt.s, tt.n); got != tt.want {
+			t.Errorf("TruncateSynopsis(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
 const readme = `
     $ go get github.com/user/repo/pkg1
     [foo](http://gopkgdoc.appspot.com/pkg/github.com/user/repo/pkg2)
@@ -112,3 +141,324 @@ func TestSimpleImporter(t *testing.T) {
 		}
 	}
 }
+
+const unexportedSource = `package p
+
+type T struct {
+	X int
+	y int
+}
+
+func F() {}
+
+func f() {}
+
+type t int
+`
+
+func TestUnexported(t *testing.T) {
+	dir := &gosrc.Directory{
+		ImportPath: "example.com/p",
+		Files:      []*gosrc.File{{Name: "p.go", Data: []byte(unexportedSource)}},
+	}
+	for _, tt := range []struct {
+		unexported bool
+		funcs      []string
+		types      []string
+		fields     []string
+	}{
+		{false, []string{"F"}, []string{"T"}, []string{"X"}},
+		{true, []string{"F", "f"}, []string{"T", "t"}, []string{"X", "y"}},
+	} {
+		pdoc, err := buildPackage(dir, tt.unexported)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pdoc.Unexported != tt.unexported {
+			t.Errorf("unexported=%v: Unexported = %v", tt.unexported, pdoc.Unexported)
+		}
+		var funcs, types, fields []string
+		for _, f := range pdoc.Funcs {
+			funcs = append(funcs, f.Name)
+		}
+		for _, typ := range pdoc.Types {
+			types = append(types, typ.Name)
+			if typ.Name == "T" {
+				for _, f := range typ.Fields {
+					fields = append(fields, f.Name)
+				}
+			}
+		}
+		for _, c := range []struct {
+			name      string
+			got, want []string
+		}{
+			{"funcs", funcs, tt.funcs},
+			{"types", types, tt.types},
+			{"fields of T", fields, tt.fields},
+		} {
+			if fmt.Sprint(c.got) != fmt.Sprint(c.want) {
+				t.Errorf("unexported=%v: %s = %v, want %v", tt.unexported, c.name, c.got, c.want)
+			}
+		}
+	}
+}
+
+var playExampleFiles = map[string]string{
+	"p.go": `package p
+
+func F() string { return "F" }
+
+func g() string { return "g" }
+`,
+	"p_test.go": `package p
+
+import (
+	"fmt"
+	"strings"
+)
+
+type helper struct{ s string }
+
+func (h helper) upper() string { return strings.ToUpper(h.s) }
+
+func ExampleF() {
+	// Print in upper case.
+	h := helper{s: F()}
+	fmt.Println(h.upper())
+	// Output: F
+}
+
+func ExampleF_unexported() {
+	fmt.Println(F(), g())
+	// Output: F g
+}
+`,
+}
+
+const playExampleWant = `package main
+
+import (
+	. "example.com/p"
+	"fmt"
+	"strings"
+)
+
+type helper struct{ s string }
+
+func (h helper) upper() string { return strings.ToUpper(h.s) }
+
+func main() {
+	// Print in upper case.
+	h := helper{s: F()}
+	fmt.Println(h.upper())
+}
+`
+
+func TestPlayExample(t *testing.T) {
+	dir := &gosrc.Directory{ImportPath: "example.com/p"}
+	for _, name := range []string{"p.go", "p_test.go"} {
+		dir.Files = append(dir.Files, &gosrc.File{Name: name, Data: []byte(playExampleFiles[name])})
+	}
+	pdoc, err := newPackage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pdoc.Funcs) != 1 || len(pdoc.Funcs[0].Examples) != 2 {
+		t.Fatalf("got funcs %v, want F with two examples", pdoc.Funcs)
+	}
+	if got := pdoc.Funcs[0].Examples[0].Play; got != playExampleWant {
+		t.Errorf("Play =\n%s\nwant\n%s", got, playExampleWant)
+	}
+	// Programs using the unexported identifiers of the package do not build.
+	if got := pdoc.Funcs[0].Examples[1].Play; got != "" {
+		t.Errorf("Play of example using unexported identifiers =\n%s\nwant empty", got)
+	}
+}
+
+func TestExampleOutput(t *testing.T) {
+	dir := &gosrc.Directory{
+		ImportPath: "example.com/p",
+		Files: []*gosrc.File{
+			{Name: "p.go", Data: []byte("package p\n")},
+			{Name: "p_test.go", Data: []byte("package p_test\n\nimport \"fmt\"\n\nfunc Example() {\n\tfmt.Println(1)\n\t// Output: 1\n}\n\nfunc Example_unordered() {\n\tfmt.Println(1)\n\tfmt.Println(2)\n\t// Unordered output:\n\t// 2\n\t// 1\n}\n")},
+		},
+	}
+	pdoc, err := newPackage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range pdoc.Examples {
+		got = append(got, fmt.Sprintf("%q %q unordered=%v", e.Name, e.Output, e.Unordered))
+	}
+	want := []string{
+		`"" "1\n" unordered=false`,
+		`"Unordered" "2\n1\n" unordered=true`,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("examples = %q, want %q", got, want)
+	}
+}
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestConstValues(t *testing.T) {
+	src, err := ioutil.ReadFile("testdata/iota.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := &gosrc.Directory{
+		ImportPath: "example.com/iota",
+		Files:      []*gosrc.File{{Name: "iota.go", Data: src}},
+	}
+	pdoc, err := newPackage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	values := append([]*Value(nil), pdoc.Consts...)
+	for _, typ := range pdoc.Types {
+		values = append(values, typ.Consts...)
+	}
+	for _, v := range values {
+		fmt.Fprintf(&buf, "%s\n", v.Decl.Text)
+		for _, cv := range v.Values {
+			fmt.Fprintf(&buf, "\t%s = %s\n", cv.Name, cv.Value)
+		}
+		buf.WriteString("\n")
+	}
+
+	const golden = "testdata/iota.golden"
+	if *update {
+		if err := ioutil.WriteFile(golden, buf.Bytes(), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != string(want) {
+		t.Errorf("constants of testdata/iota.go:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPartialPackage(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		files map[string]string
+		funcs []string
+	}{
+		{
+			name: "syntax error",
+			files: map[string]string{
+				"a.go": "// Package p does things.\npackage p\n\n// A does a.\nfunc A() {}\n",
+				"b.go": "package p\n\n// B does b.\nfunc B() {}\n\nfunc C() {\n",
+			},
+			funcs: []string{"A", "B", "C"},
+		},
+		{
+			name: "several packages",
+			files: map[string]string{
+				"a.go": "// Package p does things.\npackage p\n\n// A does a.\nfunc A() {}\n",
+				"b.go": "package q\n\n// B does b.\nfunc B() {}\n",
+			},
+			funcs: []string{"A"},
+		},
+	} {
+		dir := &gosrc.Directory{ImportPath: "example.com/p"}
+		for _, name := range []string{"a.go", "b.go"} {
+			dir.Files = append(dir.Files, &gosrc.File{Name: name, Data: []byte(tt.files[name])})
+		}
+		pdoc, err := newPackage(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pdoc.Partial || len(pdoc.Errors) == 0 {
+			t.Errorf("%s: Partial = %v, Errors = %q, want partial package with errors", tt.name, pdoc.Partial, pdoc.Errors)
+		}
+		if pdoc.Name != "p" || pdoc.Synopsis != "Package p does things." {
+			t.Errorf("%s: Name, Synopsis = %q, %q, want %q, %q", tt.name, pdoc.Name, pdoc.Synopsis, "p", "Package p does things.")
+		}
+		var funcs []string
+		for _, f := range pdoc.Funcs {
+			funcs = append(funcs, f.Name)
+		}
+		if fmt.Sprint(funcs) != fmt.Sprint(tt.funcs) {
+			t.Errorf("%s: funcs = %v, want %v", tt.name, funcs, tt.funcs)
+		}
+	}
+}
+
+func TestModuleRedirect(t *testing.T) {
+	dir := &gosrc.Directory{
+		ImportPath:  "github.com/alice/pkg",
+		ProjectRoot: "github.com/alice/pkg",
+		Files: []*gosrc.File{
+			{Name: "go.mod", Data: []byte("module example.com/pkg/v2\n\ngo 1.16\n")},
+			{Name: "pkg.go", Data: []byte("// Package pkg does things.\npackage pkg\n")},
+		},
+	}
+	_, err := newPackage(dir)
+	want := gosrc.NotFoundError{Message: "not at canonical import path", Redirect: "example.com/pkg"}
+	if err != want {
+		t.Errorf("newPackage returned error %v, want %v", err, want)
+	}
+}
+
+func TestTestOnlyPackage(t *testing.T) {
+	dir := &gosrc.Directory{
+		ImportPath: "example.com/examples",
+		Files: []*gosrc.File{
+			{Name: "a_test.go", Data: []byte("package examples_test\n\nimport \"fmt\"\n\nfunc Example() {\n\tfmt.Println(1)\n\t// Output: 1\n}\n\nfunc ExampleHello_world() {\n\tfmt.Println(\"hello\")\n}\n")},
+			{Name: "b_test.go", Data: []byte("package examples\n\nimport \"testing\"\n\nfunc TestB(t *testing.T) {}\n")},
+		},
+	}
+	pdoc, err := newPackage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pdoc.TestOnly || pdoc.Name != "examples" {
+		t.Errorf("TestOnly, Name = %v, %q, want true, %q", pdoc.TestOnly, pdoc.Name, "examples")
+	}
+	if len(pdoc.Files) != 0 || len(pdoc.TestFiles) != 2 {
+		t.Errorf("got %d files and %d test files, want 0 and 2", len(pdoc.Files), len(pdoc.TestFiles))
+	}
+	var names []string
+	for _, e := range pdoc.Examples {
+		names = append(names, e.Name)
+	}
+	if want := []string{"", "Hello_world"}; fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Errorf("examples = %q, want %q", names, want)
+	}
+
+	dir.Files = append(dir.Files, &gosrc.File{Name: "p.go", Data: []byte("package examples\n")})
+	if pdoc, err := newPackage(dir); err != nil {
+		t.Fatal(err)
+	} else if pdoc.TestOnly {
+		t.Error("TestOnly set for a directory with package files")
+	}
+}
+
+func TestBuildPanic(t *testing.T) {
+	// The package does not type check, but is documented anyway. Ordering
+	// complex constants panics in go/constant.
+	dir := &gosrc.Directory{
+		ImportPath: "example.com/broken",
+		Files: []*gosrc.File{
+			{Name: "a.go", Data: []byte("package broken\n\nconst C = 1i < 2i\n")},
+		},
+	}
+	pdoc, err := newPackage(dir)
+	e, ok := err.(*PanicError)
+	if !ok {
+		t.Fatalf("newPackage returned %v, %v, want a *PanicError", pdoc, err)
+	}
+	if pdoc != nil {
+		t.Errorf("newPackage returned package %+v with the panic error", pdoc)
+	}
+	if e.ImportPath != dir.ImportPath || e.Value == nil || len(e.Stack) == 0 {
+		t.Errorf("PanicError = {%q, %v, %d bytes of stack}, want the import path, panic value and stack", e.ImportPath, e.Value, len(e.Stack))
+	}
+}
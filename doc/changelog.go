@@ -0,0 +1,183 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+// Changelog is a changelog file of the package directory, such as
+// CHANGELOG.md, HISTORY.md or NEWS.md.
+type Changelog struct {
+	// File name.
+	Name string
+
+	// URL of the file on the version control service, or "" if unknown.
+	URL string
+
+	// Contents of the file, with invalid UTF-8 sequences replaced, if the
+	// file has no sections for releases.
+	Text string
+
+	// Sections of the file for the releases, in the order of the file.
+	Entries []*ChangelogEntry
+
+	// Whether the end of the file is left out of Text or Entries because
+	// the file is larger than maxChangelogText.
+	Truncated bool
+}
+
+// ChangelogEntry is the section of a changelog for a release.
+type ChangelogEntry struct {
+	// Heading of the section without the Markdown markup, such as
+	// "v1.2.0 - 2021-05-14".
+	Title string
+
+	// Release date found in the heading, or the zero time.
+	Date time.Time
+
+	// Text of the section after the heading.
+	Text string
+}
+
+// maxChangelogSize is the size above which changelog files are not parsed.
+const maxChangelogSize = 256 << 10
+
+// maxChangelogText is the size of the text of a changelog stored with the
+// package documentation. The rest of the file is left out.
+const maxChangelogText = 16 << 10
+
+// maxChangelogEntries is the maximum number of entries parsed from a
+// changelog.
+const maxChangelogEntries = 100
+
+// changelogNames ranks the base names of the changelog files, ignoring case,
+// used when a directory has several changelog files. Lower is preferred.
+var changelogNames = map[string]int{
+	"changelog":     0,
+	"change_log":    0,
+	"change-log":    0,
+	"changes":       1,
+	"history":       2,
+	"news":          3,
+	"releasenotes":  4,
+	"release_notes": 4,
+	"release-notes": 4,
+}
+
+var (
+	changelogExtPat = regexp.MustCompile(`(?i)\.(?:md|markdown|rst|txt)$`)
+
+	// changelogHeadingPat matches the Markdown headings of the sections of
+	// the releases, which contain a version number or "Unreleased".
+	changelogHeadingPat = regexp.MustCompile(`^#{1,3}\s+(.*(?:\d+\.\d+|(?i:unreleased)).*?)\s*#*\s*$`)
+
+	changelogDatePat = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+)
+
+// changelog returns the changelog file of a directory or nil if there is no
+// such file.
+func changelog(files []*gosrc.File) *Changelog {
+	var best *gosrc.File
+	bestRank := 0
+	for _, f := range files {
+		if len(f.Data) > maxChangelogSize {
+			continue
+		}
+		name := changelogExtPat.ReplaceAllString(f.Name, "")
+		if name == f.Name && strings.Contains(name, ".") {
+			continue
+		}
+		r, ok := changelogNames[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+		if best == nil || r < bestRank || r == bestRank && f.Name < best.Name {
+			best, bestRank = f, r
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	text := strings.ToValidUTF8(string(best.Data), "�")
+	cl := &Changelog{Name: best.Name, URL: best.BrowseURL, Entries: changelogEntries(text)}
+	if len(cl.Entries) == 0 {
+		cl.Text, cl.Truncated = truncateChangelog(text, maxChangelogText)
+		return cl
+	}
+	size := 0
+	for i, e := range cl.Entries {
+		if size+len(e.Title) > maxChangelogText {
+			cl.Entries, cl.Truncated = cl.Entries[:i], true
+			break
+		}
+		size += len(e.Title)
+		var truncated bool
+		e.Text, truncated = truncateChangelog(e.Text, maxChangelogText-size)
+		size += len(e.Text)
+		if truncated {
+			cl.Entries, cl.Truncated = cl.Entries[:i+1], true
+			break
+		}
+	}
+	return cl
+}
+
+// truncateChangelog returns the lines of text fitting in n bytes and whether
+// lines were left out.
+func truncateChangelog(text string, n int) (string, bool) {
+	if len(text) <= n {
+		return text, false
+	}
+	i := strings.LastIndexByte(text[:n], '\n')
+	if i < 0 {
+		return "", true
+	}
+	return text[:i], true
+}
+
+// changelogEntries splits the Markdown text of a changelog into the sections
+// of the releases.
+func changelogEntries(text string) []*ChangelogEntry {
+	var entries []*ChangelogEntry
+	var body []string
+	flush := func() {
+		if len(entries) > 0 {
+			entries[len(entries)-1].Text = strings.TrimSpace(strings.Join(body, "\n"))
+		}
+		body = body[:0]
+	}
+	inCode := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+		}
+		m := changelogHeadingPat.FindStringSubmatch(line)
+		if inCode || m == nil {
+			if len(entries) > 0 {
+				body = append(body, line)
+			}
+			continue
+		}
+		flush()
+		if len(entries) == maxChangelogEntries {
+			return entries
+		}
+		e := &ChangelogEntry{Title: strings.NewReplacer("[", "", "]", "", "`", "").Replace(m[1])}
+		if d := changelogDatePat.FindString(e.Title); d != "" {
+			e.Date, _ = time.Parse("2006-01-02", d)
+		}
+		entries = append(entries, e)
+	}
+	flush()
+	return entries
+}
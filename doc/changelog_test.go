@@ -0,0 +1,98 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+const testChangelog = `# Changelog
+
+All notable changes are documented here.
+
+## [Unreleased]
+
+- Work in progress.
+
+## [1.2.0] - 2021-05-14
+
+### Added
+
+- New API.
+
+` + "```" + `
+## 9.9.9 in a code block
+` + "```" + `
+
+## v1.1.0 (2021-01-02)
+Fixes.
+`
+
+func TestChangelog(t *testing.T) {
+	files := []*gosrc.File{
+		{Name: "doc.go", Data: []byte("package p\n")},
+		{Name: "HISTORY.md", Data: []byte("## 0.1\n")},
+		{Name: "CHANGELOG.md", Data: []byte(testChangelog), BrowseURL: "https://github.com/user/repo/blob/master/CHANGELOG.md"},
+		{Name: "CHANGELOG.html", Data: []byte("<h1>1.0</h1>")},
+	}
+	want := &Changelog{
+		Name: "CHANGELOG.md",
+		URL:  "https://github.com/user/repo/blob/master/CHANGELOG.md",
+		Entries: []*ChangelogEntry{
+			{Title: "Unreleased", Text: "- Work in progress."},
+			{Title: "1.2.0 - 2021-05-14", Date: time.Date(2021, 5, 14, 0, 0, 0, 0, time.UTC), Text: "### Added\n\n- New API.\n\n```\n## 9.9.9 in a code block\n```"},
+			{Title: "v1.1.0 (2021-01-02)", Date: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), Text: "Fixes."},
+		},
+	}
+	if diff := cmp.Diff(want, changelog(files)); diff != "" {
+		t.Errorf("changelog() mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, name := range []string{"NEWS", "changes.txt", "Release-Notes.md", "history.rst"} {
+		got := changelog([]*gosrc.File{{Name: name, Data: []byte("x")}})
+		if got == nil || got.Name != name {
+			t.Errorf("changelog(%q) = %v, want changelog", name, got)
+		}
+	}
+	if got := changelog([]*gosrc.File{{Name: "README.md", Data: []byte("# 1.0")}, {Name: "NEWS.html", Data: []byte("x")}}); got != nil {
+		t.Errorf("changelog without changelog file = %+v, want nil", got)
+	}
+}
+
+func TestChangelogTruncated(t *testing.T) {
+	line := strings.Repeat("x", 99) + "\n"
+	long := strings.Repeat(line, maxChangelogText/len(line)+10)
+
+	cl := changelog([]*gosrc.File{{Name: "NEWS", Data: []byte(long)}})
+	if !cl.Truncated || len(cl.Text) > maxChangelogText || !strings.HasPrefix(long, cl.Text) {
+		t.Errorf("changelog without sections: truncated = %v, len(Text) = %d, want truncated to %d bytes", cl.Truncated, len(cl.Text), maxChangelogText)
+	}
+
+	var b strings.Builder
+	for i := 0; b.Len() < 2*maxChangelogText; i++ {
+		fmt.Fprintf(&b, "## 1.%d\n\n%s\n", i, strings.Repeat(line, 20))
+	}
+	cl = changelog([]*gosrc.File{{Name: "CHANGELOG.md", Data: []byte(b.String())}})
+	size := 0
+	for _, e := range cl.Entries {
+		size += len(e.Title) + len(e.Text)
+	}
+	if !cl.Truncated || size > maxChangelogText || len(cl.Entries) == 0 || cl.Text != "" {
+		t.Errorf("changelog with sections: truncated = %v, %d entries of %d bytes, want truncated to %d bytes", cl.Truncated, len(cl.Entries), size, maxChangelogText)
+	}
+
+	if cl := changelog([]*gosrc.File{{Name: "CHANGELOG.md", Data: []byte(testChangelog)}}); cl.Truncated {
+		t.Errorf("small changelog truncated")
+	}
+}
@@ -301,6 +301,12 @@ func (b *builder) position(n ast.Node) Pos {
 	return position
 }
 
+// generated returns whether n is declared in a generated file.
+func (b *builder) generated(n ast.Node) bool {
+	src := b.srcs[b.fset.Position(n.Pos()).Filename]
+	return src != nil && src.generated
+}
+
 func (b *builder) printExample(e *doc.Example) (code Code, output string) {
 	output = e.Output
 
@@ -331,6 +337,17 @@ func (b *builder) printExample(e *doc.Example) (code Code, output string) {
 		output = ""
 	}
 
+	// Identifiers are printed in the order of the AST, so the identifiers
+	// scanned from the output can be matched to those in the AST.
+	var idents []*ast.Ident
+	if len(b.links) > 0 {
+		if f, ok := n.(*ast.File); ok {
+			idents = exampleIdents(f)
+		} else {
+			idents = exampleIdents(e.Code)
+		}
+	}
+
 	var annotations []Annotation
 	var s scanner.Scanner
 	fset := token.NewFileSet()
@@ -343,6 +360,21 @@ scanLoop:
 		switch tok {
 		case token.EOF:
 			break scanLoop
+		case token.IDENT:
+			if len(idents) == 0 {
+				break
+			}
+			id := idents[0]
+			idents = idents[1:]
+			if id.Name != lit {
+				// Out of sync with the AST; stop linking.
+				idents = nil
+				break
+			}
+			if b.links[id] {
+				p := file.Offset(pos)
+				annotations = append(annotations, Annotation{Kind: LinkAnnotation, Pos: int32(p), End: int32(p + len(lit)), PathIndex: -1})
+			}
 		case token.COMMENT:
 			p := file.Offset(pos)
 			e := p + len(lit)
@@ -0,0 +1,131 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// CodeOwnersRule is a line of a CODEOWNERS file: a pattern of the paths
+// owned and the owners of the matching paths. The owners are GitHub users,
+// such as @alice, teams, such as @org/team, or email addresses. A rule
+// without owners leaves the matching paths without owners.
+type CodeOwnersRule struct {
+	Pattern string
+	Owners  []string
+}
+
+// maxCodeOwnersSize is the size above which CODEOWNERS files are ignored,
+// as GitHub does.
+const maxCodeOwnersSize = 3 << 20
+
+// ParseCodeOwners parses the rules of the CODEOWNERS file data.
+func ParseCodeOwners(data []byte) []CodeOwnersRule {
+	if len(data) > maxCodeOwnersSize {
+		return nil
+	}
+	var rules []CodeOwnersRule
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Buffer(nil, maxCodeOwnersSize)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		for i, f := range fields {
+			if strings.HasPrefix(f, "#") {
+				fields = fields[:i]
+				break
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		r := CodeOwnersRule{Pattern: fields[0]}
+		if len(fields) > 1 {
+			r.Owners = fields[1:]
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// codeOwnersRegexp compiles a CODEOWNERS pattern, which follows the rules
+// of gitignore patterns, to a regular expression matching slash separated
+// paths relative to the repository root.
+func codeOwnersRegexp(pattern string) (*regexp.Regexp, error) {
+	// Patterns with a slash other than a trailing slash are relative to the
+	// root. Other patterns match names in any directory.
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	// A pattern ending with /* matches the files of a directory but not
+	// those of its subdirectories. Other patterns matching a directory match
+	// everything below it.
+	shallow := strings.HasSuffix(pattern, "/*")
+	pattern = strings.Trim(pattern, "/")
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+	lit := 0
+	for i := 0; i < len(pattern); i++ {
+		var re string
+		n := 1
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			re, n = "(?:.*/)?", 3
+		case strings.HasPrefix(pattern[i:], "**"):
+			re, n = ".*", 2
+		case pattern[i] == '*':
+			re = "[^/]*"
+		case pattern[i] == '?':
+			re = "[^/]"
+		default:
+			continue
+		}
+		b.WriteString(regexp.QuoteMeta(pattern[lit:i]))
+		b.WriteString(re)
+		i += n - 1
+		lit = i + 1
+	}
+	b.WriteString(regexp.QuoteMeta(pattern[lit:]))
+	if !shallow {
+		b.WriteString("(?:/.*)?")
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// MatchCodeOwners returns the owners of the package in the directory dir,
+// relative to the repository root, with the given file names. As in GitHub,
+// the last rule matching a file determines its owners. The owners of the
+// package are those of the last rule matching one of its files. Rules with
+// invalid patterns are ignored.
+func MatchCodeOwners(rules []CodeOwnersRule, dir string, files []string) []string {
+	paths := []string{dir}
+	if len(files) > 0 {
+		paths = paths[:0]
+		for _, f := range files {
+			paths = append(paths, path.Join(dir, f))
+		}
+	}
+	for i := len(rules) - 1; i >= 0; i-- {
+		re, err := codeOwnersRegexp(rules[i].Pattern)
+		if err != nil {
+			continue
+		}
+		for _, p := range paths {
+			if re.MatchString(p) {
+				return rules[i].Owners
+			}
+		}
+	}
+	return nil
+}
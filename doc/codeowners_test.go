@@ -0,0 +1,96 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+const testCodeOwners = `# Default owners.
+*       @acme/core
+
+*.s     @alice # assembly experts
+/cmd/   @acme/tools tools@example.com
+/internal/*  @bob
+docs/   @acme/docs
+**/testdata @carol
+/legacy/
+`
+
+func TestParseCodeOwners(t *testing.T) {
+	want := []CodeOwnersRule{
+		{Pattern: "*", Owners: []string{"@acme/core"}},
+		{Pattern: "*.s", Owners: []string{"@alice"}},
+		{Pattern: "/cmd/", Owners: []string{"@acme/tools", "tools@example.com"}},
+		{Pattern: "/internal/*", Owners: []string{"@bob"}},
+		{Pattern: "docs/", Owners: []string{"@acme/docs"}},
+		{Pattern: "**/testdata", Owners: []string{"@carol"}},
+		{Pattern: "/legacy/"},
+	}
+	if diff := cmp.Diff(want, ParseCodeOwners([]byte(testCodeOwners))); diff != "" {
+		t.Errorf("ParseCodeOwners mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMatchCodeOwners(t *testing.T) {
+	rules := ParseCodeOwners([]byte(testCodeOwners))
+	for _, tt := range []struct {
+		dir   string
+		files []string
+		want  []string
+	}{
+		{"", []string{"a.go"}, []string{"@acme/core"}},
+		{"", []string{"a.go", "a_amd64.s"}, []string{"@alice"}},
+		{"cmd/tool", []string{"main.go"}, []string{"@acme/tools", "tools@example.com"}},
+		{"pkg/cmd", []string{"cmd.go"}, []string{"@acme/core"}},
+		{"internal", []string{"x.go"}, []string{"@bob"}},
+		{"internal/sub", []string{"x.go"}, []string{"@acme/core"}},
+		{"site/docs", []string{"doc.go"}, []string{"@acme/docs"}},
+		{"a/b/testdata/p", []string{"p.go"}, []string{"@carol"}},
+		{"legacy/old", []string{"old.go"}, nil},
+		{"cmd", nil, []string{"@acme/tools", "tools@example.com"}},
+	} {
+		if diff := cmp.Diff(tt.want, MatchCodeOwners(rules, tt.dir, tt.files)); diff != "" {
+			t.Errorf("MatchCodeOwners(%q, %q) mismatch (-want +got):\n%s", tt.dir, tt.files, diff)
+		}
+	}
+	if got := MatchCodeOwners(nil, "p", []string{"p.go"}); got != nil {
+		t.Errorf("MatchCodeOwners without rules = %q, want nil", got)
+	}
+}
+
+func TestCodeOwnersFile(t *testing.T) {
+	dir := &gosrc.Directory{
+		ImportPath:  "github.com/acme/p",
+		ProjectRoot: "github.com/acme/p",
+		Files: []*gosrc.File{
+			{Name: "p.go", Data: []byte("package p\n")},
+			{Name: "CODEOWNERS", Data: []byte("* @acme/core\n")},
+		},
+	}
+	pdoc, err := newPackage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]CodeOwnersRule{{Pattern: "*", Owners: []string{"@acme/core"}}}, pdoc.CodeOwners); diff != "" {
+		t.Errorf("CodeOwners mismatch (-want +got):\n%s", diff)
+	}
+
+	// GitHub only reads the CODEOWNERS file of the repository root.
+	dir.ImportPath = "github.com/acme/p/sub"
+	pdoc, err = newPackage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pdoc.CodeOwners != nil {
+		t.Errorf("CodeOwners = %v for a package below the repository root, want nil", pdoc.CodeOwners)
+	}
+}
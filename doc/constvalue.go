@@ -0,0 +1,192 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"sort"
+)
+
+// ConstValue is the computed value of a constant declared with iota.
+type ConstValue struct {
+	Name  string
+	Value string
+}
+
+// constValues computes the values of the constants declared in const blocks
+// using iota. The values are computed from the declarations before go/doc
+// filters unexported constants, which would change the value of iota.
+// Constants with values that cannot be computed from the AST alone, such as
+// values depending on the size of a type, are omitted.
+func constValues(files map[string]*ast.File) map[string]string {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := make(map[string]constant.Value)
+	values := make(map[string]string)
+	for _, name := range names {
+		for _, decl := range files[name].Decls {
+			d, ok := decl.(*ast.GenDecl)
+			if !ok || d.Tok != token.CONST {
+				continue
+			}
+			hasIota := usesIota(d)
+			var last []ast.Expr
+			for i, spec := range d.Specs {
+				s := spec.(*ast.ValueSpec)
+				if len(s.Values) > 0 {
+					last = s.Values
+				}
+				for j, n := range s.Names {
+					if j >= len(last) {
+						break
+					}
+					v := evalConst(last[j], int64(i), env)
+					if v == nil || n.Name == "_" {
+						continue
+					}
+					env[n.Name] = v
+					if hasIota {
+						if v.Kind() == constant.Int {
+							values[n.Name] = v.ExactString()
+						} else {
+							values[n.Name] = v.String()
+						}
+					}
+				}
+			}
+		}
+	}
+	return values
+}
+
+func usesIota(d *ast.GenDecl) bool {
+	found := false
+	ast.Inspect(d, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "iota" {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// evalConst returns the value of the constant expression e or nil if the
+// value cannot be computed. Conversions are ignored, so operators whose
+// result depends on the type of the operands are not supported.
+func evalConst(e ast.Expr, iota int64, env map[string]constant.Value) constant.Value {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		v := constant.MakeFromLiteral(e.Value, e.Kind, 0)
+		if v.Kind() == constant.Unknown {
+			return nil
+		}
+		return v
+	case *ast.Ident:
+		if v, ok := env[e.Name]; ok {
+			return v
+		}
+		switch e.Name {
+		case "iota":
+			return constant.MakeInt64(iota)
+		case "true", "false":
+			return constant.MakeBool(e.Name == "true")
+		}
+	case *ast.ParenExpr:
+		return evalConst(e.X, iota, env)
+	case *ast.CallExpr:
+		// Conversions to named or predeclared numeric types.
+		if len(e.Args) != 1 || e.Ellipsis.IsValid() {
+			return nil
+		}
+		switch fun := e.Fun.(type) {
+		case *ast.Ident:
+			switch fun.Name {
+			case "len", "cap", "real", "imag", "string", "unsafe":
+				return nil
+			}
+		case *ast.SelectorExpr:
+			if x, ok := fun.X.(*ast.Ident); ok && x.Name == "unsafe" {
+				return nil
+			}
+		default:
+			return nil
+		}
+		return evalConst(e.Args[0], iota, env)
+	case *ast.UnaryExpr:
+		x := evalConst(e.X, iota, env)
+		switch {
+		case x == nil:
+		case (e.Op == token.ADD || e.Op == token.SUB) && isNumeric(x),
+			e.Op == token.NOT && x.Kind() == constant.Bool:
+			return constant.UnaryOp(e.Op, x, 0)
+		}
+	case *ast.BinaryExpr:
+		x := evalConst(e.X, iota, env)
+		y := evalConst(e.Y, iota, env)
+		if x == nil || y == nil {
+			return nil
+		}
+		return binaryOp(x, e.Op, y)
+	}
+	return nil
+}
+
+func isNumeric(v constant.Value) bool {
+	return v.Kind() == constant.Int || v.Kind() == constant.Float
+}
+
+func binaryOp(x constant.Value, op token.Token, y constant.Value) constant.Value {
+	ints := x.Kind() == constant.Int && y.Kind() == constant.Int
+	switch op {
+	case token.SHL, token.SHR:
+		s, ok := constant.Uint64Val(y)
+		if !ok || s > 1024 || x.Kind() != constant.Int {
+			return nil
+		}
+		return constant.Shift(x, op, uint(s))
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		if !(isNumeric(x) && isNumeric(y)) && x.Kind() != y.Kind() {
+			return nil
+		}
+		if x.Kind() == constant.Bool && op != token.EQL && op != token.NEQ {
+			return nil
+		}
+		return constant.MakeBool(constant.Compare(x, op, y))
+	case token.QUO:
+		if !isNumeric(x) || !isNumeric(y) || constant.Sign(y) == 0 {
+			return nil
+		}
+		if ints {
+			op = token.QUO_ASSIGN // integer division
+		}
+	case token.ADD:
+		if !(isNumeric(x) && isNumeric(y)) && !(x.Kind() == constant.String && y.Kind() == constant.String) {
+			return nil
+		}
+	case token.SUB, token.MUL:
+		if !isNumeric(x) || !isNumeric(y) {
+			return nil
+		}
+	case token.REM, token.AND, token.OR, token.XOR, token.AND_NOT:
+		if !ints || (op == token.REM && constant.Sign(y) == 0) {
+			return nil
+		}
+	case token.LAND, token.LOR:
+		if x.Kind() != constant.Bool || y.Kind() != constant.Bool {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return constant.BinaryOp(x, op, y)
+}
@@ -0,0 +1,182 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// Embedded is a type from another package embedded in a struct type.
+type Embedded struct {
+	ImportPath string
+	Name       string
+
+	// Name of the embedded type in the same package through which the type
+	// is embedded, or "" if the type is embedded directly.
+	Via string
+}
+
+// Field is an exported field of a struct type, or any field when the
+// documentation includes unexported declarations.
+type Field struct {
+	Name string
+	Type string
+	Pos  Pos
+
+	// Name of the embedded type the field is promoted from, or "" if the
+	// field is declared in the struct type.
+	Via string
+}
+
+// structInfo holds the fields and embedded types of a struct type,
+// collected before go/doc filters unexported declarations from the AST.
+type structInfo struct {
+	fields   []*Field
+	promoted []*Field
+	embedded []*Embedded // direct embeds, including types in the same package
+	imported []*Embedded
+}
+
+// embeddedName returns the import path and name of the type of an embedded
+// field, or "" for the name if the field type is not a named type.
+func embeddedName(x ast.Expr) (importPath, name string) {
+	if star, ok := x.(*ast.StarExpr); ok {
+		x = star.X
+	}
+	switch x := x.(type) {
+	case *ast.Ident:
+		return "", x.Name
+	case *ast.SelectorExpr:
+		if pkg, ok := x.X.(*ast.Ident); ok && pkg.Obj != nil && pkg.Obj.Kind == ast.Pkg {
+			if spec, _ := pkg.Obj.Decl.(*ast.ImportSpec); spec != nil {
+				if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+					return path, x.Sel.Name
+				}
+			}
+		}
+	}
+	return "", ""
+}
+
+// structInfos collects the fields of the struct types declared in files
+// and the fields promoted to them from embedded struct types declared in
+// the same package. Fields promoted from types in other packages are not
+// known here; the embedded types are recorded instead.
+func (b *builder) structInfos(files map[string]*ast.File) map[string]*structInfo {
+	structs := make(map[string]*ast.StructType)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			d, ok := decl.(*ast.GenDecl)
+			if !ok || d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if st, ok := ts.Type.(*ast.StructType); ok {
+					structs[ts.Name.Name] = st
+				}
+			}
+		}
+	}
+
+	infos := make(map[string]*structInfo)
+	for name, st := range structs {
+		info := &structInfo{}
+		for _, f := range st.Fields.List {
+			if len(f.Names) == 0 {
+				importPath, name := embeddedName(f.Type)
+				if name == "" {
+					continue
+				}
+				info.embedded = append(info.embedded, &Embedded{ImportPath: importPath, Name: name})
+				if ast.IsExported(name) || b.unexported {
+					info.fields = append(info.fields, &Field{Name: name, Type: b.printExpr(f.Type), Pos: b.position(f)})
+				}
+				continue
+			}
+			for _, n := range f.Names {
+				if ast.IsExported(n.Name) || b.unexported {
+					info.fields = append(info.fields, &Field{Name: n.Name, Type: b.printExpr(f.Type), Pos: b.position(n)})
+				}
+			}
+		}
+		infos[name] = info
+	}
+
+	for name, info := range infos {
+		info.promoted, info.imported = promoteFields(name, infos)
+	}
+	return infos
+}
+
+// promoteFields returns the fields promoted to the struct type name from
+// embedded struct types in infos, in breadth first order. A field is omitted
+// if a field with the same name is found at a shallower depth. The embedded
+// types from other packages found along the way are also returned.
+func promoteFields(name string, infos map[string]*structInfo) ([]*Field, []*Embedded) {
+	var promoted []*Field
+	var imported []*Embedded
+	for _, e := range infos[name].embedded {
+		if e.ImportPath != "" {
+			imported = append(imported, e)
+		}
+	}
+	seen := map[string]bool{name: true}
+	names := make(map[string]bool)
+	for _, f := range infos[name].fields {
+		names[f.Name] = true
+	}
+	level := infos[name].embedded
+	for len(level) > 0 {
+		var next []*Embedded
+		var candidates []*Field
+		count := make(map[string]int)
+		for _, e := range level {
+			info := infos[e.Name]
+			if e.ImportPath != "" || info == nil || seen[e.Name] {
+				continue
+			}
+			seen[e.Name] = true
+			for _, f := range info.fields {
+				if !names[f.Name] {
+					count[f.Name]++
+					candidates = append(candidates, &Field{Name: f.Name, Type: f.Type, Pos: f.Pos, Via: e.Name})
+				}
+			}
+			for _, ee := range info.embedded {
+				if ee.ImportPath != "" {
+					imported = append(imported, &Embedded{ImportPath: ee.ImportPath, Name: ee.Name, Via: e.Name})
+				}
+			}
+			next = append(next, info.embedded...)
+		}
+		for _, f := range candidates {
+			// Fields found more than once at the same depth are ambiguous
+			// and not promoted.
+			if count[f.Name] == 1 {
+				promoted = append(promoted, f)
+			}
+		}
+		for n := range count {
+			names[n] = true
+		}
+		level = next
+	}
+	return promoted, imported
+}
+
+func (b *builder) printExpr(x ast.Expr) string {
+	var sb strings.Builder
+	if err := (&printer.Config{Mode: printer.UseSpaces, Tabwidth: 4}).Fprint(&sb, b.fset, x); err != nil {
+		return ""
+	}
+	return sb.String()
+}
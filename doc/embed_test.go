@@ -0,0 +1,100 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+const embedSource = `package p
+
+import "io"
+
+type A struct {
+	B
+	X int
+}
+
+func (A) M() {}
+
+type B struct {
+	*C
+	Y, X string
+}
+
+func (*B) MB() {}
+
+type C struct {
+	io.Reader
+	Z bool
+	A
+}
+
+func (C) MC() {}
+
+// Ambiguous embeds D and E, which both declare F.
+type Ambiguous struct {
+	D
+	E
+}
+
+type D struct{ F int }
+type E struct{ F int }
+`
+
+func TestPromotedMembers(t *testing.T) {
+	pdoc, err := newPackage(&gosrc.Directory{
+		ImportPath: "example.com/p",
+		Files:      []*gosrc.File{{Name: "p.go", Data: []byte(embedSource)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := make(map[string]*Type)
+	for _, typ := range pdoc.Types {
+		types[typ.Name] = typ
+	}
+
+	type member struct{ Name, Via string }
+	methods := func(fs []*Func) (ms []member) {
+		for _, f := range fs {
+			ms = append(ms, member{f.Name, f.Via})
+		}
+		return ms
+	}
+	fields := func(fs []*Field) (ms []member) {
+		for _, f := range fs {
+			ms = append(ms, member{f.Name, f.Via})
+		}
+		return ms
+	}
+
+	a := types["A"]
+	if diff := cmp.Diff([]member{{"M", ""}}, methods(a.Methods)); diff != "" {
+		t.Errorf("A methods mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]member{{"MB", "B"}, {"MC", "C"}}, methods(a.PromotedMethods)); diff != "" {
+		t.Errorf("A promoted methods mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]member{{"B", ""}, {"X", ""}}, fields(a.Fields)); diff != "" {
+		t.Errorf("A fields mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]member{{"C", "B"}, {"Y", "B"}, {"Reader", "C"}, {"Z", "C"}, {"A", "C"}}, fields(a.PromotedFields)); diff != "" {
+		t.Errorf("A promoted fields mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]*Embedded{{ImportPath: "io", Name: "Reader", Via: "C"}}, a.Embedded); diff != "" {
+		t.Errorf("A embedded mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := fields(types["Ambiguous"].PromotedFields); len(got) != 0 {
+		t.Errorf("Ambiguous promoted fields = %v, want none", got)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+// WithoutGenerated returns a copy of pdoc without the declarations of
+// generated files, leaving the hand-written additions to generated code. A
+// generated type is kept if it has constructors or methods declared in other
+// files, with only those declarations. The declarations of pdoc are shared
+// with the copy and must not be modified.
+func (pdoc *Package) WithoutGenerated() *Package {
+	p := *pdoc
+	p.Consts = handWrittenValues(pdoc.Consts)
+	p.Vars = handWrittenValues(pdoc.Vars)
+	p.Funcs = handWrittenFuncs(pdoc.Funcs)
+	p.Types = nil
+	for _, t := range pdoc.Types {
+		t2 := *t
+		t2.Consts = handWrittenValues(t.Consts)
+		t2.Vars = handWrittenValues(t.Vars)
+		t2.Funcs = handWrittenFuncs(t.Funcs)
+		t2.Methods = handWrittenFuncs(t.Methods)
+		t2.PromotedMethods = handWrittenFuncs(t.PromotedMethods)
+		if t.Generated && len(t2.Consts) == 0 && len(t2.Vars) == 0 &&
+			len(t2.Funcs) == 0 && len(t2.Methods) == 0 {
+			continue
+		}
+		p.Types = append(p.Types, &t2)
+	}
+	return &p
+}
+
+func handWrittenValues(values []*Value) []*Value {
+	var result []*Value
+	for _, v := range values {
+		if !v.Generated {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func handWrittenFuncs(funcs []*Func) []*Func {
+	var result []*Func
+	for _, f := range funcs {
+		if !f.Generated {
+			result = append(result, f)
+		}
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"testing"
+
+	"github.com/golang/gddo/gosrc"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWithoutGenerated(t *testing.T) {
+	dir := &gosrc.Directory{
+		ImportPath: "example.com/pb",
+		Files: []*gosrc.File{
+			{Name: "pb.pb.go", Data: []byte(`// Code generated by protoc-gen-go. DO NOT EDIT.
+
+// Package pb does things.
+package pb
+
+const Version = 1
+
+type Request struct{}
+
+func (*Request) Reset() {}
+
+type Response struct{}
+
+func (*Response) Reset() {}
+`)},
+			{Name: "pb.go", Data: []byte(`package pb
+
+// Hand is hand-written.
+func Hand() {}
+
+// Validate is a hand-written method of a generated type.
+func (*Request) Validate() error { return nil }
+`)},
+		},
+	}
+	pdoc, err := newPackage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"pb.go": false, "pb.pb.go": true}
+	for _, f := range pdoc.Files {
+		if f.Generated != want[f.Name] {
+			t.Errorf("file %s: Generated = %v, want %v", f.Name, f.Generated, want[f.Name])
+		}
+	}
+
+	decls := func(pdoc *Package) map[string]bool {
+		m := make(map[string]bool)
+		for _, v := range pdoc.Consts {
+			m[v.Decl.Text] = v.Generated
+		}
+		for _, f := range pdoc.Funcs {
+			m[f.Name] = f.Generated
+		}
+		for _, t := range pdoc.Types {
+			m[t.Name] = t.Generated
+			for _, f := range t.Methods {
+				m[t.Name+"."+f.Name] = f.Generated
+			}
+		}
+		return m
+	}
+	all := map[string]bool{
+		"const Version = 1": true,
+		"Hand":              false,
+		"Request":           true,
+		"Request.Reset":     true,
+		"Request.Validate":  false,
+		"Response":          true,
+		"Response.Reset":    true,
+	}
+	if diff := cmp.Diff(all, decls(pdoc)); diff != "" {
+		t.Errorf("declarations mismatch (-want +got):\n%s", diff)
+	}
+	handWritten := map[string]bool{
+		"Hand":             false,
+		"Request":          true,
+		"Request.Validate": false,
+	}
+	if diff := cmp.Diff(handWritten, decls(pdoc.WithoutGenerated())); diff != "" {
+		t.Errorf("WithoutGenerated declarations mismatch (-want +got):\n%s", diff)
+	}
+	if len(pdoc.Types) != 2 || len(pdoc.Types[0].Methods) != 2 {
+		t.Error("WithoutGenerated modified the package")
+	}
+}
@@ -36,8 +36,7 @@ func Get(ctx context.Context, client *http.Client, importPath string, etag strin
 		return pdoc, err
 	}
 
-	if pdoc.Synopsis == "" &&
-		pdoc.Doc == "" &&
+	if preferSynopsis(pdoc, SynopsisProject) &&
 		!pdoc.IsCmd &&
 		pdoc.Name != "" &&
 		dir.ImportPath == dir.ProjectRoot &&
@@ -45,7 +44,10 @@ func Get(ctx context.Context, client *http.Client, importPath string, etag strin
 		project, err := gosrc.GetProject(ctx, client, dir.ResolvedPath)
 		switch {
 		case err == nil:
-			pdoc.Synopsis = doc.Synopsis(project.Description)
+			if s := TruncateSynopsis(doc.Synopsis(project.Description), maxSynopsisLength); s != "" {
+				pdoc.Synopsis = s
+				pdoc.SynopsisSource = SynopsisProject
+			}
 		case gosrc.IsNotFound(err):
 			// ok
 		default:
@@ -55,3 +57,24 @@ func Get(ctx context.Context, client *http.Client, importPath string, etag strin
 
 	return pdoc, nil
 }
+
+// GetVersion gets the package documentation at the given tag of the
+// repository containing importPath.
+func GetVersion(ctx context.Context, client *http.Client, importPath, tag string) (*Package, error) {
+	dir, err := gosrc.GetVersion(ctx, client, importPath, tag)
+	if err != nil {
+		return nil, err
+	}
+	return newPackage(dir)
+}
+
+// GetUnexported gets the package documentation, including unexported
+// declarations, at the default branch of the repository containing
+// importPath.
+func GetUnexported(ctx context.Context, client *http.Client, importPath string) (*Package, error) {
+	dir, err := gosrc.Get(ctx, client, importPath, "")
+	if err != nil {
+		return nil, err
+	}
+	return buildPackage(dir, true)
+}
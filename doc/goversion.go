@@ -0,0 +1,115 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"go/ast"
+	"go/build/constraint"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// goDirectivePat matches the go directive of a go.mod file.
+var goDirectivePat = regexp.MustCompile(`(?m)^go[ \t]+(1\.[0-9]+)(?:\.[0-9]+)?[ \t]*(?://.*)?\r?$`)
+
+// modGoVersion returns the Go version declared by the go directive of the
+// go.mod file data or "" if there is no go directive.
+func modGoVersion(data []byte) string {
+	m := goDirectivePat.FindSubmatch(data)
+	if m == nil {
+		return ""
+	}
+	return string(m[1])
+}
+
+// tagGoVersion returns the minimum Go version required by the build
+// constraints of the package files or "" if at least one file builds with
+// any version of Go. A file requires Go 1.N when its constraints are only
+// satisfied with the go1.N release tag.
+func tagGoVersion(files []*ast.File) string {
+	min := 0
+	for _, file := range files {
+		v := fileGoVersion(file)
+		if v == 0 {
+			return ""
+		}
+		if min == 0 || v < min {
+			min = v
+		}
+	}
+	if min == 0 {
+		return ""
+	}
+	return "1." + strconv.Itoa(min)
+}
+
+// fileGoVersion returns the minor Go version required by the build
+// constraints of file or 0 if the constraints do not require a version.
+func fileGoVersion(file *ast.File) int {
+	var goBuild, plusBuild int
+	hasGoBuild := false
+	for _, g := range file.Comments {
+		if g.Pos() >= file.Package {
+			break
+		}
+		for _, c := range g.List {
+			switch {
+			case constraint.IsGoBuild(c.Text):
+				if x, err := constraint.Parse(c.Text); err == nil {
+					hasGoBuild = true
+					goBuild = maxInt(goBuild, exprGoVersion(x))
+				}
+			case constraint.IsPlusBuild(c.Text):
+				if x, err := constraint.Parse(c.Text); err == nil {
+					plusBuild = maxInt(plusBuild, exprGoVersion(x))
+				}
+			}
+		}
+	}
+	if hasGoBuild {
+		// The //go:build line takes precedence over // +build lines.
+		return goBuild
+	}
+	return plusBuild
+}
+
+// exprGoVersion returns the minor Go version required to satisfy the build
+// constraint x or 0 if x is satisfied with any version.
+func exprGoVersion(x constraint.Expr) int {
+	switch x := x.(type) {
+	case *constraint.TagExpr:
+		if !strings.HasPrefix(x.Tag, "go1.") {
+			return 0
+		}
+		n, err := strconv.Atoi(x.Tag[len("go1."):])
+		if err != nil {
+			return 0
+		}
+		return n
+	case *constraint.AndExpr:
+		return maxInt(exprGoVersion(x.X), exprGoVersion(x.Y))
+	case *constraint.OrExpr:
+		return minInt(exprGoVersion(x.X), exprGoVersion(x.Y))
+	}
+	// Negated tags restrict the maximum version, not the minimum.
+	return 0
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
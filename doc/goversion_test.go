@@ -0,0 +1,64 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestModGoVersion(t *testing.T) {
+	for _, tt := range []struct {
+		mod  string
+		want string
+	}{
+		{"module example.com/m\n\ngo 1.16\n", "1.16"},
+		{"module example.com/m\r\n\r\ngo 1.21.3\r\n", "1.21"},
+		{"module example.com/m\n\ngo 1.18 // comment\n\nrequire example.com/n v1.0.0\n", "1.18"},
+		{"module example.com/m\n\ntoolchain go1.21.0\n", ""},
+		{"module example.com/m\n", ""},
+	} {
+		if got := modGoVersion([]byte(tt.mod)); got != tt.want {
+			t.Errorf("modGoVersion(%q) = %q, want %q", tt.mod, got, tt.want)
+		}
+	}
+}
+
+func TestTagGoVersion(t *testing.T) {
+	for _, tt := range []struct {
+		srcs []string
+		want string
+	}{
+		{[]string{"package p\n"}, ""},
+		{[]string{"//go:build go1.18\n\npackage p\n"}, "1.18"},
+		{[]string{"// +build go1.9\n\npackage p\n"}, "1.9"},
+		{[]string{"//go:build go1.21\n// +build go1.20\n\npackage p\n"}, "1.21"},
+		{[]string{"//go:build linux && go1.18 && !go1.21\n\npackage p\n"}, "1.18"},
+		{[]string{"//go:build go1.18 || go1.16\n\npackage p\n"}, "1.16"},
+		{[]string{"//go:build go1.18 || linux\n\npackage p\n"}, ""},
+		{[]string{"//go:build !go1.18\n\npackage p\n"}, ""},
+		{[]string{"// +build linux\n// +build go1.12\n\npackage p\n"}, "1.12"},
+		{[]string{"package p\n\n//go:build go1.18\n"}, ""},
+		{[]string{"//go:build go1.18\n\npackage p\n", "//go:build go1.20\n\npackage p\n"}, "1.18"},
+		{[]string{"//go:build go1.18\n\npackage p\n", "package p\n"}, ""},
+	} {
+		fset := token.NewFileSet()
+		var files []*ast.File
+		for _, src := range tt.srcs {
+			file, err := parser.ParseFile(fset, "p.go", src, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, file)
+		}
+		if got := tagGoVersion(files); got != tt.want {
+			t.Errorf("tagGoVersion(%q) = %q, want %q", tt.srcs, got, tt.want)
+		}
+	}
+}
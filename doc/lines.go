@@ -0,0 +1,54 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"bytes"
+	"go/scanner"
+	"go/token"
+	"regexp"
+)
+
+// generatedPat matches the comment marking generated files. See
+// https://golang.org/s/generatedcode.
+var generatedPat = regexp.MustCompile(`(?m)^// Code generated .* DO NOT EDIT\.$`)
+
+func isGenerated(src []byte) bool {
+	return generatedPat.Match(src)
+}
+
+// codeLines returns the number of lines of Go source src containing code.
+// Blank lines and lines containing only comments are not counted. Lines
+// inside multi-line raw strings are counted.
+func codeLines(src []byte) int {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, 0)
+	n := 0
+	last := 0 // last counted line
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			// Automatically inserted semicolon.
+			continue
+		}
+		start := file.Line(pos)
+		end := start + bytes.Count([]byte(lit), []byte("\n"))
+		if start <= last {
+			start = last + 1
+		}
+		if end >= start {
+			n += end - start + 1
+			last = end
+		}
+	}
+	return n
+}
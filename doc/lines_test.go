@@ -0,0 +1,50 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import "testing"
+
+const linesSource = `// Package p does things.
+package p
+
+/*
+Block comment.
+*/
+
+import "fmt" // trailing comment
+
+var s = ` + "`" + `one
+two` + "`" + `
+
+func F() {
+
+	fmt.Println(s) /* inline */
+}
+`
+
+func TestCodeLines(t *testing.T) {
+	if got, want := codeLines([]byte(linesSource)), 7; got != want {
+		t.Errorf("codeLines() = %d, want %d", got, want)
+	}
+}
+
+func TestIsGenerated(t *testing.T) {
+	for _, tt := range []struct {
+		src  string
+		want bool
+	}{
+		{"// Code generated by stringer. DO NOT EDIT\n\npackage p\n", false},
+		{"// Code generated by stringer. DO NOT EDIT.\n\npackage p\n", true},
+		{"// Code generated by protoc-gen-go. DO NOT EDIT.\n// source: a.proto\n\npackage p\n", true},
+		{"package p\n\n// Code generated by hand, do not edit.\n", false},
+		{linesSource, false},
+	} {
+		if got := isGenerated([]byte(tt.src)); got != tt.want {
+			t.Errorf("isGenerated(%q) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
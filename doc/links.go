@@ -0,0 +1,70 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"errors"
+	"go/ast"
+	"go/types"
+)
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+// resolveExampleLinks type checks the package and its test files and records
+// in b.links the identifiers that refer to exported package-level
+// declarations of the package. Type errors, including those caused by
+// imports that cannot be resolved, are ignored.
+func (b *builder) resolveExampleLinks(importPath string, files, testFiles, xtestFiles []*ast.File) {
+	b.links = make(map[*ast.Ident]bool)
+
+	var pkg *types.Package
+	conf := types.Config{
+		FakeImportC: true,
+		Error:       func(error) {},
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if path == importPath && pkg != nil {
+				return pkg, nil
+			}
+			if path == "unsafe" {
+				return types.Unsafe, nil
+			}
+			return nil, errors.New("package not available")
+		}),
+	}
+
+	info := &types.Info{Uses: make(map[*ast.Ident]types.Object)}
+	pkg, _ = conf.Check(importPath, b.fset, append(files[:len(files):len(files)], testFiles...), info)
+	if len(xtestFiles) > 0 && pkg != nil {
+		conf.Check(importPath+"_test", b.fset, xtestFiles, info)
+	}
+
+	// Only declarations in the package files are documented.
+	documented := make(map[string]bool)
+	for _, f := range files {
+		documented[b.fset.File(f.Pos()).Name()] = true
+	}
+	for id, obj := range info.Uses {
+		if obj.Pkg() == pkg && obj.Exported() && obj.Parent() == pkg.Scope() &&
+			documented[b.fset.Position(obj.Pos()).Filename] {
+			b.links[id] = true
+		}
+	}
+}
+
+// exampleIdents returns the identifiers in n in the order they are printed.
+func exampleIdents(n ast.Node) []*ast.Ident {
+	var idents []*ast.Ident
+	ast.Inspect(n, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			idents = append(idents, id)
+		}
+		return true
+	})
+	return idents
+}
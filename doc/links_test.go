@@ -0,0 +1,71 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+func TestExampleLinks(t *testing.T) {
+	pdoc, err := newPackage(&gosrc.Directory{
+		ImportPath: "example.com/p",
+		Files: []*gosrc.File{
+			{Name: "p.go", Data: []byte("package p\n\nimport \"example.com/q\"\n\nfunc Bar(q.T) int { return 0 }\n\ntype T int\n\nconst C = 1\n")},
+			{Name: "p_test.go", Data: []byte("package p\n\nfunc ExampleT() {\n\tvar x T = C\n\tHelper(x)\n}\n\nfunc Helper(T) {}\n")},
+			{Name: "x_test.go", Data: []byte("package p_test\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/p\"\n)\n\nfunc ExampleBar() {\n\tBar := 1\n\tfmt.Println(p.Bar(p.T(Bar)), p.Missing)\n}\n")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links := func(c Code) []string {
+		var names []string
+		for _, a := range c.Annotations {
+			if a.Kind == LinkAnnotation {
+				names = append(names, c.Text[a.Pos:a.End])
+			}
+		}
+		return names
+	}
+
+	if len(pdoc.Funcs) != 1 || len(pdoc.Funcs[0].Examples) != 1 {
+		t.Fatalf("got funcs %v, want Bar with one example", pdoc.Funcs)
+	}
+	if diff := cmp.Diff([]string{"Bar", "T"}, links(pdoc.Funcs[0].Examples[0].Code)); diff != "" {
+		t.Errorf("ExampleBar links mismatch (-want +got):\n%s", diff)
+	}
+	if len(pdoc.Types) != 1 || len(pdoc.Types[0].Examples) != 1 {
+		t.Fatalf("got types %v, want T with one example", pdoc.Types)
+	}
+	if diff := cmp.Diff([]string{"T", "C", "T"}, links(pdoc.Types[0].Examples[0].Code)); diff != "" {
+		t.Errorf("ExampleT links mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestExamplePosition(t *testing.T) {
+	pdoc, err := newPackage(&gosrc.Directory{
+		ImportPath: "example.com/p",
+		Files: []*gosrc.File{
+			{Name: "p.go", Data: []byte("package p\n\nfunc F() {}\n")},
+			{Name: "p_test.go", Data: []byte("package p\n\nvar fixture = 1\n\nfunc ExampleF() {\n\tF()\n}\n")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pdoc.Funcs) != 1 || len(pdoc.Funcs[0].Examples) != 1 {
+		t.Fatalf("got funcs %v, want F with one example", pdoc.Funcs)
+	}
+	if e := pdoc.Funcs[0].Examples[0]; e.File != "p_test.go" || e.Line != 5 {
+		t.Errorf("ExampleF at %s:%d, want p_test.go:5", e.File, e.Line)
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// methodSigs computes the normalized method signatures of the type
+// declarations in files, which are used to find the implementations of
+// interfaces across packages. A normalized signature omits the parameter
+// names and qualifies named types with their import path, for example
+// "WriteTo(io.Writer) (int64, error)".
+//
+// The signatures of an interface type are its exported methods, including
+// the methods of embedded interfaces declared in the same package or
+// predeclared. The signatures of other types are their exported methods,
+// prefixed with "*" for methods with pointer receivers; methods promoted from
+// embedded fields are not included. Types with a method that cannot be
+// normalized, and interfaces embedding interfaces from other packages or
+// declaring unexported methods, are omitted.
+func (b *builder) methodSigs(files map[string]*ast.File) (sigs map[string][]string, interfaces map[string]bool) {
+	specs := make(map[string]*ast.TypeSpec)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.TYPE {
+				for _, spec := range d.Specs {
+					ts := spec.(*ast.TypeSpec)
+					specs[ts.Name.Name] = ts
+				}
+			}
+		}
+	}
+
+	sigs = make(map[string][]string)
+	interfaces = make(map[string]bool)
+	bad := make(map[string]bool)
+	for name, ts := range specs {
+		if it, ok := ts.Type.(*ast.InterfaceType); ok {
+			interfaces[name] = true
+			if s, ok := b.interfaceSigs(it, specs, map[string]bool{name: true}); ok && len(s) > 0 {
+				sigs[name] = s
+			}
+		}
+	}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			d, ok := decl.(*ast.FuncDecl)
+			if !ok || d.Recv == nil || len(d.Recv.List) != 1 || !ast.IsExported(d.Name.Name) {
+				continue
+			}
+			recv := d.Recv.List[0].Type
+			ptr := ""
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv, ptr = star.X, "*"
+			}
+			id, ok := recv.(*ast.Ident)
+			if !ok || interfaces[id.Name] || specs[id.Name] == nil {
+				continue
+			}
+			s, ok := b.funcSig(d.Type)
+			if !ok {
+				bad[id.Name] = true
+				continue
+			}
+			sigs[id.Name] = append(sigs[id.Name], ptr+d.Name.Name+s)
+		}
+	}
+	for name := range bad {
+		delete(sigs, name)
+	}
+	for _, s := range sigs {
+		sort.Strings(s)
+	}
+	return sigs, interfaces
+}
+
+func (b *builder) interfaceSigs(it *ast.InterfaceType, specs map[string]*ast.TypeSpec, seen map[string]bool) ([]string, bool) {
+	var result []string
+	for _, f := range it.Methods.List {
+		if len(f.Names) == 0 {
+			// Embedded interface.
+			id, ok := f.Type.(*ast.Ident)
+			if !ok {
+				return nil, false
+			}
+			if id.Name == "error" && specs["error"] == nil {
+				result = append(result, "Error() string")
+				continue
+			}
+			ts := specs[id.Name]
+			if ts == nil || seen[id.Name] {
+				return nil, false
+			}
+			eit, ok := ts.Type.(*ast.InterfaceType)
+			if !ok {
+				return nil, false
+			}
+			seen[id.Name] = true
+			s, ok := b.interfaceSigs(eit, specs, seen)
+			if !ok {
+				return nil, false
+			}
+			result = append(result, s...)
+			continue
+		}
+		ft, ok := f.Type.(*ast.FuncType)
+		if !ok {
+			return nil, false
+		}
+		s, ok := b.funcSig(ft)
+		if !ok {
+			return nil, false
+		}
+		for _, n := range f.Names {
+			if !ast.IsExported(n.Name) {
+				return nil, false
+			}
+			result = append(result, n.Name+s)
+		}
+	}
+	return result, true
+}
+
+// funcSig returns the normalized signature of ft without the func keyword.
+func (b *builder) funcSig(ft *ast.FuncType) (string, bool) {
+	params, ok := b.fieldTypes(ft.Params)
+	if !ok {
+		return "", false
+	}
+	results, ok := b.fieldTypes(ft.Results)
+	if !ok {
+		return "", false
+	}
+	s := "(" + strings.Join(params, ", ") + ")"
+	switch len(results) {
+	case 0:
+	case 1:
+		s += " " + results[0]
+	default:
+		s += " (" + strings.Join(results, ", ") + ")"
+	}
+	return s, true
+}
+
+func (b *builder) fieldTypes(fields *ast.FieldList) ([]string, bool) {
+	if fields == nil {
+		return nil, true
+	}
+	var result []string
+	for _, f := range fields.List {
+		t, ok := b.typeString(f.Type)
+		if !ok {
+			return nil, false
+		}
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			result = append(result, t)
+		}
+	}
+	return result, true
+}
+
+// typeString returns the normalized string of the type expression x.
+func (b *builder) typeString(x ast.Expr) (string, bool) {
+	switch x := x.(type) {
+	case *ast.Ident:
+		if x.Obj == nil || x.Obj.Decl == nil {
+			if types.Universe.Lookup(x.Name) == nil {
+				return "", false
+			}
+			return x.Name, true
+		}
+		if _, ok := x.Obj.Decl.(*ast.TypeSpec); !ok || x.Obj.Kind != ast.Typ {
+			return "", false
+		}
+		return b.importPath + "." + x.Name, true
+	case *ast.SelectorExpr:
+		importPath, name := embeddedName(x)
+		if importPath == "" {
+			return "", false
+		}
+		return importPath + "." + name, true
+	case *ast.StarExpr:
+		s, ok := b.typeString(x.X)
+		return "*" + s, ok
+	case *ast.ParenExpr:
+		return b.typeString(x.X)
+	case *ast.Ellipsis:
+		s, ok := b.typeString(x.Elt)
+		return "..." + s, ok
+	case *ast.ArrayType:
+		s, ok := b.typeString(x.Elt)
+		switch n := x.Len.(type) {
+		case nil:
+			return "[]" + s, ok
+		case *ast.BasicLit:
+			if v, err := strconv.ParseInt(n.Value, 0, 64); err == nil {
+				return "[" + strconv.FormatInt(v, 10) + "]" + s, ok
+			}
+		}
+	case *ast.MapType:
+		k, ok := b.typeString(x.Key)
+		if !ok {
+			return "", false
+		}
+		v, ok := b.typeString(x.Value)
+		return "map[" + k + "]" + v, ok
+	case *ast.ChanType:
+		s, ok := b.typeString(x.Value)
+		switch x.Dir {
+		case ast.SEND:
+			return "chan<- " + s, ok
+		case ast.RECV:
+			return "<-chan " + s, ok
+		default:
+			return "chan " + s, ok
+		}
+	case *ast.FuncType:
+		s, ok := b.funcSig(x)
+		return "func" + s, ok
+	case *ast.InterfaceType:
+		if len(x.Methods.List) == 0 {
+			return "interface{}", true
+		}
+	case *ast.StructType:
+		if len(x.Fields.List) == 0 {
+			return "struct{}", true
+		}
+	}
+	return "", false
+}
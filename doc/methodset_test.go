@@ -0,0 +1,91 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+const methodSetSource = `package p
+
+import "io"
+
+type Reader interface {
+	Read(p []byte) (n int, err error)
+}
+
+type ReadCloser interface {
+	Reader
+	Close() error
+}
+
+type Errorer interface {
+	error
+	Code() int
+}
+
+type Remote interface {
+	io.Writer
+}
+
+type private interface {
+	m()
+}
+
+type Empty interface{}
+
+type File struct{}
+
+func (f *File) Read(p []byte) (int, error) { return 0, nil }
+func (File) Close() error                  { return nil }
+func (File) WriteTo(w io.Writer) (n int64, err error) { return 0, nil }
+func (File) Map(m map[string][]*File, c <-chan struct{}, f ...func(x, y int) bool) {}
+func (File) unexported() {}
+
+type Generic struct{}
+
+func (Generic) Bad(x struct{ A int }) {}
+`
+
+func TestMethodSigs(t *testing.T) {
+	pdoc, err := newPackage(&gosrc.Directory{
+		ImportPath: "example.com/p",
+		Files:      []*gosrc.File{{Name: "p.go", Data: []byte(methodSetSource)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]string)
+	interfaces := make(map[string]bool)
+	for _, typ := range pdoc.Types {
+		if typ.MethodSigs != nil {
+			got[typ.Name] = typ.MethodSigs
+		}
+		interfaces[typ.Name] = typ.Interface
+	}
+	want := map[string][]string{
+		"Reader":     {"Read([]byte) (int, error)"},
+		"ReadCloser": {"Close() error", "Read([]byte) (int, error)"},
+		"Errorer":    {"Code() int", "Error() string"},
+		"File": {
+			"*Read([]byte) (int, error)",
+			"Close() error",
+			"Map(map[string][]*example.com/p.File, <-chan struct{}, ...func(int, int) bool)",
+			"WriteTo(io.Writer) (int64, error)",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("MethodSigs mismatch (-want +got):\n%s", diff)
+	}
+	if !interfaces["Empty"] || !interfaces["Remote"] || interfaces["File"] {
+		t.Errorf("Interface = %v, want true for Empty and Remote only", interfaces)
+	}
+}
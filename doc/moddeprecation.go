@@ -0,0 +1,63 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// Retraction is a version or a range of versions of a module retracted by
+// a retract directive of the go.mod file of the module.
+type Retraction struct {
+	// Lowest and highest retracted versions. Low and High are equal when a
+	// single version is retracted.
+	Low, High string
+
+	// Rationale is the comment of the retract directive, if any.
+	Rationale string
+}
+
+// Contains reports whether version is retracted by r.
+func (r Retraction) Contains(version string) bool {
+	return semver.Compare(r.Low, version) <= 0 && semver.Compare(version, r.High) <= 0
+}
+
+// FindRetraction returns the retraction of retractions containing version
+// or nil if version is not retracted.
+func FindRetraction(retractions []Retraction, version string) *Retraction {
+	if !semver.IsValid(version) {
+		return nil
+	}
+	for i := range retractions {
+		if retractions[i].Contains(version) {
+			return &retractions[i]
+		}
+	}
+	return nil
+}
+
+// modDeprecation returns the deprecation message of the module and the
+// retracted versions declared by the go.mod file data. The message is the
+// paragraph starting with "Deprecated:" of the comments of the module
+// directive, without the prefix, or "" if the module is not deprecated.
+// Files that cannot be parsed declare neither.
+func modDeprecation(data []byte) (string, []Retraction) {
+	f, err := modfile.ParseLax("go.mod", data, nil)
+	if err != nil {
+		return "", nil
+	}
+	var deprecated string
+	if f.Module != nil {
+		deprecated = f.Module.Deprecated
+	}
+	var retractions []Retraction
+	for _, r := range f.Retract {
+		retractions = append(retractions, Retraction{Low: r.Low, High: r.High, Rationale: r.Rationale})
+	}
+	return deprecated, retractions
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestModDeprecation(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		mod         string
+		deprecated  string
+		retractions []Retraction
+	}{
+		{
+			name: "none",
+			mod:  "module example.com/m\n\ngo 1.16\n",
+		},
+		{
+			name:       "comment before module",
+			mod:        "// Package m is old.\n//\n// Deprecated: use example.com/m/v2 instead.\nmodule example.com/m\n",
+			deprecated: "use example.com/m/v2 instead.",
+		},
+		{
+			name:       "trailing comment",
+			mod:        "module example.com/m // Deprecated: unmaintained.\n",
+			deprecated: "unmaintained.",
+		},
+		{
+			name: "not a deprecation paragraph",
+			mod:  "// This module is not Deprecated: at all.\nmodule example.com/m\n",
+		},
+		{
+			name: "comment on another directive",
+			mod:  "module example.com/m\n\n// Deprecated: misplaced.\ngo 1.16\n",
+		},
+		{
+			name: "retract",
+			mod: `module example.com/m
+
+retract v1.0.1 // Published accidentally.
+
+retract (
+	[v1.1.0, v1.1.3] // Data race in Close.
+	v1.2.0
+)
+`,
+			retractions: []Retraction{
+				{Low: "v1.0.1", High: "v1.0.1", Rationale: "Published accidentally."},
+				{Low: "v1.1.0", High: "v1.1.3", Rationale: "Data race in Close."},
+				{Low: "v1.2.0", High: "v1.2.0"},
+			},
+		},
+		{
+			name: "syntax error",
+			mod:  "// Deprecated: broken.\nmodule example.com/m\nrequire (\n\texample.com/a v1.0.0\n",
+		},
+	} {
+		deprecated, retractions := modDeprecation([]byte(tt.mod))
+		if deprecated != tt.deprecated {
+			t.Errorf("%s: deprecated = %q, want %q", tt.name, deprecated, tt.deprecated)
+		}
+		if diff := cmp.Diff(tt.retractions, retractions); diff != "" {
+			t.Errorf("%s: retractions mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestFindRetraction(t *testing.T) {
+	retractions := []Retraction{
+		{Low: "v1.0.1", High: "v1.0.1"},
+		{Low: "v1.1.0", High: "v1.1.3", Rationale: "Data race."},
+	}
+	for _, tt := range []struct {
+		version string
+		want    *Retraction
+	}{
+		{"v1.0.0", nil},
+		{"v1.0.1", &retractions[0]},
+		{"v1.1.0", &retractions[1]},
+		{"v1.1.2-pre", &retractions[1]},
+		{"v1.1.3", &retractions[1]},
+		{"v1.1.4", nil},
+		{"master", nil},
+	} {
+		if got := FindRetraction(retractions, tt.version); got != tt.want {
+			t.Errorf("FindRetraction(%q) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// Requirement is a module required by the require directives of a go.mod
+// file.
+type Requirement struct {
+	Path    string
+	Version string
+
+	// Replace is the module path and version, or the directory, replacing
+	// the required module in the replace directives of the go.mod file. It
+	// is "" when the module is not replaced.
+	Replace string
+}
+
+// modRequires returns the requirements declared by the go.mod file data.
+// Lines that cannot be parsed are ignored.
+func modRequires(data []byte) []Requirement {
+	var (
+		requires []Requirement
+		replaces [][]string
+		block    string
+	)
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		verb := block
+		switch {
+		case block != "" && fields[0] == ")":
+			block = ""
+			continue
+		case block == "" && len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+			continue
+		case block == "":
+			verb = fields[0]
+			fields = fields[1:]
+		}
+		for i := range fields {
+			if s, err := strconv.Unquote(fields[i]); err == nil {
+				fields[i] = s
+			}
+		}
+		switch verb {
+		case "require":
+			if len(fields) == 2 {
+				requires = append(requires, Requirement{Path: fields[0], Version: fields[1]})
+			}
+		case "replace":
+			replaces = append(replaces, fields)
+		}
+	}
+
+	for _, fields := range replaces {
+		// replace old [version] => new [version]
+		i := 0
+		for i < len(fields) && fields[i] != "=>" {
+			i++
+		}
+		if (i != 1 && i != 2) || i+1 == len(fields) {
+			continue
+		}
+		for j := range requires {
+			r := &requires[j]
+			if r.Path == fields[0] && (i == 1 || r.Version == fields[1]) {
+				r.Replace = strings.Join(fields[i+1:], " ")
+			}
+		}
+	}
+	return requires
+}
+
+// modHash returns the hash of the go.mod file data recorded in go.sum files
+// and the checksum database or "" if the hash cannot be computed.
+func modHash(data []byte) string {
+	h, err := dirhash.Hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	})
+	if err != nil {
+		return ""
+	}
+	return h
+}
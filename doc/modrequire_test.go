@@ -0,0 +1,56 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestModRequires(t *testing.T) {
+	mod := `module example.com/m
+
+go 1.16
+
+require example.com/a v1.2.0
+
+require (
+	example.com/b v0.3.1 // indirect
+	"example.com/c" v2.0.0+incompatible
+	example.com/d v1.0.0
+)
+
+replace example.com/b => ../b
+
+replace (
+	example.com/c v2.0.0+incompatible => example.com/c2 v2.0.1
+	example.com/d v1.1.0 => example.com/d2 v1.1.0
+)
+`
+	want := []Requirement{
+		{Path: "example.com/a", Version: "v1.2.0"},
+		{Path: "example.com/b", Version: "v0.3.1", Replace: "../b"},
+		{Path: "example.com/c", Version: "v2.0.0+incompatible", Replace: "example.com/c2 v2.0.1"},
+		{Path: "example.com/d", Version: "v1.0.0"},
+	}
+	if diff := cmp.Diff(want, modRequires([]byte(mod))); diff != "" {
+		t.Errorf("modRequires mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := modRequires([]byte("module example.com/m\n")); got != nil {
+		t.Errorf("modRequires without require directives = %v, want nil", got)
+	}
+}
+
+func TestModHash(t *testing.T) {
+	// The go.sum hash of the go.mod file of golang.org/x/text v0.3.0.
+	const want = "h1:NqM8EUOU14njkJ3fqMW+pc6Ldnwhi/IjpwHt7yyuwOQ="
+	if got := modHash([]byte("module golang.org/x/text\n")); got != want {
+		t.Errorf("modHash() = %q, want %q", got, want)
+	}
+}
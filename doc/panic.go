@@ -0,0 +1,26 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import "fmt"
+
+// PanicError is returned when building the documentation of a package
+// panics, as malformed or adversarial source files occasionally make the
+// parser or the go/doc package do.
+type PanicError struct {
+	ImportPath string
+
+	// Value is the value passed to panic.
+	Value interface{}
+
+	// Stack is the stack of the goroutine at the time of the panic.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic building documentation for %s: %v", e.ImportPath, e.Value)
+}
@@ -0,0 +1,241 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/format"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// playExample returns a program running the example e declared in file for
+// the Go Playground. It is used for the examples declared in the package
+// itself rather than in the _test package, which go/doc does not build a
+// working program for.
+//
+// The exported identifiers of the package are made available with a dot
+// import of importPath and the declarations of the test file used by the
+// example are copied to the program. The unexported identifiers of the
+// package are not available in the playground, so no program is built for
+// the examples using them. An empty string is returned if no program can be
+// built.
+func (b *builder) playExample(importPath string, e *doc.Example, file *ast.File) string {
+	if file == nil || strings.HasSuffix(file.Name.Name, "_test") {
+		return ""
+	}
+	body, ok := e.Code.(*ast.BlockStmt)
+	if !ok {
+		// The code of whole file examples is the file.
+		for _, d := range file.Decls {
+			if d, ok := d.(*ast.FuncDecl); ok && d.Recv == nil && d.Name.Name == "Example"+e.Name {
+				body = d.Body
+			}
+		}
+		if body == nil {
+			return ""
+		}
+	}
+
+	imports := make(map[string]*ast.ImportSpec)
+	pkgs := make(map[string]*ast.Object)
+	for _, s := range file.Imports {
+		p, err := strconv.Unquote(s.Path.Value)
+		if err != nil {
+			return ""
+		}
+		var name string
+		if s.Name != nil {
+			name = s.Name.Name
+		} else if obj, err := simpleImporter(pkgs, p); err == nil {
+			name = obj.Name
+		} else {
+			return ""
+		}
+		if name == "_" || name == "." {
+			// Side effects and dot imports are not reproduced.
+			return ""
+		}
+		imports[name] = s
+	}
+
+	var (
+		usedImports = make(map[string]bool)
+		usedDecls   = make(map[ast.Decl]bool)
+		dotImport   bool
+		unexported  bool
+	)
+	var visit func(n ast.Node)
+	visit = func(n ast.Node) {
+		skip := make(map[*ast.Ident]bool)
+		ast.Inspect(n, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				skip[n.Sel] = true
+			case *ast.FuncDecl:
+				// Method names are not resolved by the parser.
+				skip[n.Name] = true
+			case *ast.KeyValueExpr:
+				// Keys of struct literals are field names.
+				if id, ok := n.Key.(*ast.Ident); ok && id.Obj == nil {
+					skip[id] = true
+				}
+			case *ast.Ident:
+				if skip[n] || n.Name == "_" {
+					break
+				}
+				if n.Obj != nil {
+					if obj := file.Scope.Lookup(n.Name); obj == n.Obj {
+						if d := topLevelDecl(file, obj); d != nil && !usedDecls[d] {
+							usedDecls[d] = true
+							visit(d)
+						}
+					}
+					break
+				}
+				switch {
+				case imports[n.Name] != nil:
+					usedImports[n.Name] = true
+				case types.Universe.Lookup(n.Name) != nil:
+				case ast.IsExported(n.Name):
+					dotImport = true
+				default:
+					unexported = true
+				}
+			}
+			return true
+		})
+	}
+	visit(body)
+
+	// Copy the methods of the copied types.
+	for changed := true; changed; {
+		changed = false
+		for _, d := range file.Decls {
+			if d, ok := d.(*ast.FuncDecl); ok && d.Recv != nil && !usedDecls[d] {
+				if t := recvTypeDecl(file, d); t != nil && usedDecls[t] {
+					usedDecls[d] = true
+					visit(d)
+					changed = true
+				}
+			}
+		}
+	}
+
+	if unexported {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("package main\n\nimport (\n")
+	var paths []string
+	for name := range usedImports {
+		s := imports[name]
+		if s.Name != nil {
+			paths = append(paths, s.Name.Name+" "+s.Path.Value)
+		} else {
+			paths = append(paths, s.Path.Value)
+		}
+	}
+	if dotImport {
+		paths = append(paths, ". "+strconv.Quote(importPath))
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		fmt.Fprintf(&buf, "\t%s\n", p)
+	}
+	buf.WriteString(")\n\n")
+
+	for _, d := range file.Decls {
+		if usedDecls[d] {
+			if err := format.Node(&buf, b.fset, d); err != nil {
+				return ""
+			}
+			buf.WriteString("\n\n")
+		}
+	}
+
+	// Keep the comments of the example except for the output comment, which
+	// is not checked in the playground.
+	var comments []*ast.CommentGroup
+	for _, c := range file.Comments {
+		if body.Pos() < c.Pos() && c.End() < body.End() && !exampleOutputRx.MatchString(c.List[0].Text) {
+			comments = append(comments, c)
+		}
+	}
+	main := &ast.BlockStmt{Lbrace: body.Lbrace, List: body.List}
+	buf.WriteString("func main() ")
+	if err := format.Node(&buf, b.fset, &printer.CommentedNode{Node: main, Comments: comments}); err != nil {
+		return ""
+	}
+	p, err := format.Source(buf.Bytes())
+	if err != nil {
+		return ""
+	}
+	return string(p)
+}
+
+// topLevelDecl returns the declaration of obj in file if it is a top level
+// declaration other than a test, benchmark or example function.
+func topLevelDecl(file *ast.File, obj *ast.Object) ast.Decl {
+	for _, d := range file.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil && d.Name.Obj == obj {
+				for _, prefix := range []string{"Test", "Benchmark", "Example"} {
+					if strings.HasPrefix(d.Name.Name, prefix) {
+						return nil
+					}
+				}
+				return d
+			}
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			for _, s := range d.Specs {
+				switch s := s.(type) {
+				case *ast.TypeSpec:
+					if s.Name.Obj == obj {
+						return d
+					}
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						if n.Obj == obj {
+							return d
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// recvTypeDecl returns the declaration in file of the receiver type of the
+// method d.
+func recvTypeDecl(file *ast.File, d *ast.FuncDecl) ast.Decl {
+	if len(d.Recv.List) != 1 {
+		return nil
+	}
+	t := d.Recv.List[0].Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	id, ok := t.(*ast.Ident)
+	if !ok || id.Obj == nil {
+		return nil
+	}
+	return topLevelDecl(file, id.Obj)
+}
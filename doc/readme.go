@@ -0,0 +1,89 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+// Readme is a README file of the package directory.
+type Readme struct {
+	// Language of a translation, such as "zh" or "pt-BR", or "" for the
+	// default README.
+	Lang string
+
+	// File name.
+	Name string
+
+	// Contents of the file, with invalid UTF-8 sequences replaced.
+	Text string
+}
+
+// maxReadmeSize is the size above which README files are not stored.
+const maxReadmeSize = 256 << 10
+
+var (
+	readmeDefaultPat = regexp.MustCompile(`(?i)^readme(?:\.(md|markdown|rst|txt|org|adoc))?$`)
+
+	// Translations are named README.<lang>.md, README-<lang>.md or
+	// README_<lang>.md where lang is an ISO 639-1 language code with an
+	// optional region or script subtag, such as zh, zh-CN or zh_Hans.
+	readmeLangPat = regexp.MustCompile(`(?i)^readme[._-]([a-z]{2})(?:[-_]([a-z]{2}|[a-z]{4}))?\.(md|markdown|rst|txt|org|adoc)$`)
+)
+
+// readmeExtRank ranks the file extensions used when a directory has several
+// README files for the same language. Lower is preferred.
+var readmeExtRank = map[string]int{"md": 0, "markdown": 1, "rst": 2, "org": 3, "adoc": 4, "txt": 5, "": 6}
+
+// readmes returns the README files of a directory, the default README first
+// followed by the translations ordered by language.
+func readmes(files []*gosrc.File) []*Readme {
+	best := make(map[string]*Readme)
+	rank := make(map[string]int)
+	for _, f := range files {
+		if len(f.Data) > maxReadmeSize {
+			continue
+		}
+		var lang, ext string
+		if m := readmeDefaultPat.FindStringSubmatch(f.Name); m != nil {
+			ext = m[1]
+		} else if m := readmeLangPat.FindStringSubmatch(f.Name); m != nil {
+			lang, ext = readmeLang(m[1], m[2]), m[3]
+		} else {
+			continue
+		}
+		r := readmeExtRank[strings.ToLower(ext)]
+		if b, ok := best[lang]; ok && (rank[lang] < r || rank[lang] == r && b.Name < f.Name) {
+			continue
+		}
+		best[lang] = &Readme{Lang: lang, Name: f.Name, Text: strings.ToValidUTF8(string(f.Data), "�")}
+		rank[lang] = r
+	}
+	var result []*Readme
+	for _, r := range best {
+		result = append(result, r)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Lang < result[j].Lang })
+	return result
+}
+
+// readmeLang returns the canonical form of the language tag with the primary
+// language and the region or script subtag: zh-CN, zh-Hans.
+func readmeLang(lang, sub string) string {
+	lang = strings.ToLower(lang)
+	switch len(sub) {
+	case 2:
+		lang += "-" + strings.ToUpper(sub)
+	case 4:
+		lang += "-" + strings.ToUpper(sub[:1]) + strings.ToLower(sub[1:])
+	}
+	return lang
+}
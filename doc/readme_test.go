@@ -0,0 +1,38 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+func TestReadmes(t *testing.T) {
+	files := []*gosrc.File{
+		{Name: "doc.go", Data: []byte("package p\n")},
+		{Name: "README.txt", Data: []byte("text")},
+		{Name: "README.md", Data: []byte("# p")},
+		{Name: "README.zh.md", Data: []byte("# 中文")},
+		{Name: "README-ja.md", Data: []byte("# 日本語")},
+		{Name: "readme_pt_br.md", Data: []byte("# pt\xff")},
+		{Name: "README.zh-hans.rst", Data: []byte("zh-Hans")},
+		{Name: "README.old.md", Data: []byte("old")},
+	}
+	want := []*Readme{
+		{Lang: "", Name: "README.md", Text: "# p"},
+		{Lang: "ja", Name: "README-ja.md", Text: "# 日本語"},
+		{Lang: "pt-BR", Name: "readme_pt_br.md", Text: "# pt�"},
+		{Lang: "zh", Name: "README.zh.md", Text: "# 中文"},
+		{Lang: "zh-Hans", Name: "README.zh-hans.rst", Text: "zh-Hans"},
+	}
+	if diff := cmp.Diff(want, readmes(files)); diff != "" {
+		t.Errorf("readmes() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Sources of package synopses.
+const (
+	// SynopsisDoc is the first sentence of the package comment.
+	SynopsisDoc = "doc"
+
+	// SynopsisReadme is the first sentence of the first paragraph of the
+	// default README of the package directory.
+	SynopsisReadme = "readme"
+
+	// SynopsisProject is the description of the repository reported by the
+	// version control service. It is only used for packages at the root of
+	// the repository.
+	SynopsisProject = "project"
+)
+
+var synopsisSources = []string{SynopsisDoc, SynopsisProject}
+
+// SetSynopsisSources sets the order in which the synopses of the package
+// documents fetched after the call are derived from sources. The synopsis is
+// taken from the first source that has one; a package comment is used even
+// if its first sentence is not a suitable synopsis. Sources not in the list
+// are not used. The default order is doc, project.
+func SetSynopsisSources(sources []string) error {
+	seen := make(map[string]bool)
+	for _, src := range sources {
+		switch src {
+		case SynopsisDoc, SynopsisReadme, SynopsisProject:
+		default:
+			return fmt.Errorf("unknown synopsis source %q", src)
+		}
+		if seen[src] {
+			return fmt.Errorf("duplicate synopsis source %q", src)
+		}
+		seen[src] = true
+	}
+	synopsisSources = append([]string(nil), sources...)
+	return nil
+}
+
+// packageSynopsis returns the synopsis of pkg and its source, derived from
+// the sources that are part of the package directory. The project source is
+// handled by Get.
+func packageSynopsis(pkg *Package) (string, string) {
+	for _, src := range synopsisSources {
+		switch src {
+		case SynopsisDoc:
+			if pkg.Doc != "" {
+				return synopsis(pkg.Doc), SynopsisDoc
+			}
+		case SynopsisReadme:
+			if s := readmeSynopsis(pkg.Readmes); s != "" {
+				return s, SynopsisReadme
+			}
+		}
+	}
+	return "", ""
+}
+
+// preferSynopsis returns whether source comes before the source of the
+// synopsis of pkg in the order set by SetSynopsisSources.
+func preferSynopsis(pkg *Package, source string) bool {
+	for _, src := range synopsisSources {
+		if src == pkg.SynopsisSource {
+			return false
+		}
+		if src == source {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// readmeSkipPat matches the lines preceding the text of a README:
+	// headings, badges, images, HTML tags, reStructuredText directives and
+	// heading underlines.
+	readmeSkipPat = regexp.MustCompile(`^(?:#|!\[|\[!\[|<|\.\. |[-=~*^]+$)`)
+
+	// readmeUnderlinePat matches the underline of a Setext or
+	// reStructuredText heading.
+	readmeUnderlinePat = regexp.MustCompile(`^[-=~*^]+$`)
+
+	readmeLinkPat     = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	readmeEmphasisPat = regexp.MustCompile("\\*\\*|__|`")
+)
+
+// readmeSynopsis returns the first sentence of the first paragraph of text
+// of the default README in readmes, with Markdown links and emphasis
+// removed.
+func readmeSynopsis(readmes []*Readme) string {
+	var text string
+	for _, r := range readmes {
+		if r.Lang == "" {
+			text = r.Text
+			break
+		}
+	}
+	lines := strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+	var para []string
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if len(para) > 0 {
+				break
+			}
+			continue
+		}
+		if i+1 < len(lines) && readmeUnderlinePat.MatchString(strings.TrimSpace(lines[i+1])) {
+			// The line is a heading.
+			if len(para) > 0 {
+				break
+			}
+			continue
+		}
+		if len(para) == 0 && readmeSkipPat.MatchString(line) {
+			continue
+		}
+		para = append(para, line)
+	}
+	s := strings.Join(para, " ")
+	s = readmeLinkPat.ReplaceAllString(s, "$1")
+	s = readmeEmphasisPat.ReplaceAllString(s, "")
+	return synopsis(s)
+}
@@ -0,0 +1,104 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package doc
+
+import (
+	"testing"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+var readmeSynopsisTests = []struct {
+	text string
+	want string
+}{
+	{"", ""},
+	{"# foo\n\nFoo parses bar files. It is fast.\n", "Foo parses bar files."},
+	{"# foo\n[![Build](https://ci/badge.svg)](https://ci)\n\nFoo parses\n**bar** files.\n", "Foo parses bar files."},
+	{"foo\n===\n\nA [Go](https://go.dev) library for `bar`.\n", "A Go library for bar."},
+	{"<p align=\"center\"><img src=\"logo.png\"></p>\n\nFoo does bar.\n", "Foo does bar."},
+	{"# foo\n\n## Install\n", ""},
+	{"# foo\n\n- a list\n", ""},
+}
+
+func TestReadmeSynopsis(t *testing.T) {
+	for _, tt := range readmeSynopsisTests {
+		readmes := []*Readme{{Name: "README.md", Text: tt.text}, {Lang: "zh", Name: "README.zh.md", Text: "Chinese text."}}
+		if got := readmeSynopsis(readmes); got != tt.want {
+			t.Errorf("readmeSynopsis(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestPackageSynopsisSources(t *testing.T) {
+	defer SetSynopsisSources([]string{SynopsisDoc, SynopsisProject})
+
+	readme := &gosrc.File{Name: "README.md", Data: []byte("# p\n\nP does things.\n")}
+	tests := []struct {
+		sources    []string
+		src        string
+		wantSyn    string
+		wantSource string
+	}{
+		{[]string{"doc", "project"}, "// Package p does stuff.\npackage p\n", "Package p does stuff.", SynopsisDoc},
+		{[]string{"doc", "project"}, "package p\n", "", ""},
+		{[]string{"doc", "readme"}, "package p\n", "P does things.", SynopsisReadme},
+		{[]string{"doc", "readme"}, "// Package p does stuff.\npackage p\n", "Package p does stuff.", SynopsisDoc},
+		{[]string{"doc", "readme"}, "// Copyright 2021 Alice.\npackage p\n", "", SynopsisDoc},
+		{[]string{"readme", "doc"}, "// Package p does stuff.\npackage p\n", "P does things.", SynopsisReadme},
+		{nil, "// Package p does stuff.\npackage p\n", "", ""},
+	}
+	for _, tt := range tests {
+		if err := SetSynopsisSources(tt.sources); err != nil {
+			t.Fatal(err)
+		}
+		dir := &gosrc.Directory{
+			ImportPath: "example.com/p",
+			Files:      []*gosrc.File{{Name: "p.go", Data: []byte(tt.src)}, readme},
+		}
+		pdoc, err := newPackage(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pdoc.Synopsis != tt.wantSyn || pdoc.SynopsisSource != tt.wantSource {
+			t.Errorf("sources %q, %q: synopsis = %q from %q, want %q from %q", tt.sources, tt.src, pdoc.Synopsis, pdoc.SynopsisSource, tt.wantSyn, tt.wantSource)
+		}
+	}
+}
+
+func TestPreferSynopsis(t *testing.T) {
+	defer SetSynopsisSources([]string{SynopsisDoc, SynopsisProject})
+
+	tests := []struct {
+		sources []string
+		current string
+		want    bool
+	}{
+		{[]string{"doc", "project"}, "", true},
+		{[]string{"doc", "project"}, SynopsisDoc, false},
+		{[]string{"doc", "readme", "project"}, SynopsisReadme, false},
+		{[]string{"doc", "project", "readme"}, SynopsisReadme, true},
+		{[]string{"doc", "readme"}, "", false},
+	}
+	for _, tt := range tests {
+		if err := SetSynopsisSources(tt.sources); err != nil {
+			t.Fatal(err)
+		}
+		pdoc := &Package{SynopsisSource: tt.current}
+		if got := preferSynopsis(pdoc, SynopsisProject); got != tt.want {
+			t.Errorf("sources %q: preferSynopsis(%q, project) = %v, want %v", tt.sources, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestSetSynopsisSourcesInvalid(t *testing.T) {
+	for _, sources := range [][]string{{"doc", "wiki"}, {"doc", "doc"}} {
+		if err := SetSynopsisSources(sources); err == nil {
+			t.Errorf("SetSynopsisSources(%q) returned no error", sources)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package iota
+
+import "unsafe"
+
+// Weekday is a day of the week.
+type Weekday int
+
+// Days of the week.
+const (
+	Sunday Weekday = iota
+	Monday
+	Tuesday
+	_
+	thursday
+	Friday
+	Saturday
+)
+
+// Flags for opening.
+const (
+	ReadOnly  = 1 << iota // open read only
+	WriteOnly             // open write only
+	Append                // append to the file
+)
+
+// Sizes in bytes.
+const (
+	_  = iota
+	KB = 1 << (10 * iota)
+	MB
+	GB
+)
+
+// Size depends on the type and is not computed.
+const (
+	Size  = unsafe.Sizeof(Weekday(0)) * iota
+	Total = Size + 1
+)
+
+const (
+	Max = 10
+	Min = -Max
+)
@@ -0,0 +1,49 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/golang/gddo/database"
+)
+
+var (
+	goneCommand = &command{
+		name:  "gone",
+		usage: "gone [-clear] path [reason]",
+	}
+	goneClear = goneCommand.flag.Bool("clear", false, "Remove the mark on path instead of marking it as gone.")
+)
+
+func init() {
+	goneCommand.run = gone
+}
+
+// gone marks the packages under a path as permanently removed. The server
+// responds to requests for these packages with status 410 Gone.
+func gone(c *command) {
+	args := c.flag.Args()
+	if len(args) < 1 || (*goneClear && len(args) != 1) {
+		c.printUsage()
+		os.Exit(1)
+	}
+	db, err := database.New(*redisServer, *dbIdleTimeout, false, gaeEndpoint)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *goneClear {
+		err = db.ClearGone(args[0])
+	} else {
+		err = db.SetGone(args[0], strings.Join(args[1:], " "))
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/golang/gddo/database"
+)
+
+var (
+	hideCommand = &command{
+		name:  "hide",
+		usage: "hide [-clear] path | hide -list",
+	}
+	hideClear = hideCommand.flag.Bool("clear", false, "Remove the mark on path instead of hiding it.")
+	hideList  = hideCommand.flag.Bool("list", false, "List the hidden packages.")
+)
+
+func init() {
+	hideCommand.run = hide
+}
+
+// hide hides a package from search results and listings. The page of the
+// package is still served on request.
+func hide(c *command) {
+	args := c.flag.Args()
+	if *hideList && len(args) != 0 || !*hideList && len(args) != 1 {
+		c.printUsage()
+		os.Exit(1)
+	}
+	db, err := database.New(*redisServer, *dbIdleTimeout, false, gaeEndpoint)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *hideList {
+		pkgs, err := db.HiddenPackages()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, pkg := range pkgs {
+			fmt.Println(pkg.Path)
+		}
+		return
+	}
+	if err := db.SetHidden(context.Background(), args[0], !*hideClear); err != nil {
+		log.Fatal(err)
+	}
+}
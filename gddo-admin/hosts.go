@@ -0,0 +1,118 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang/gddo/database"
+)
+
+var (
+	hostsCommand = &command{
+		name:  "hosts",
+		usage: "hosts [-n count]",
+	}
+	hostsTop = hostsCommand.flag.Int("n", 20, "Number of largest packages to print.")
+)
+
+func init() {
+	hostsCommand.run = hosts
+}
+
+type hostSize struct {
+	host  string
+	count int
+	size  int
+}
+
+// smallestFirst is a heap of the largest packages seen so far with the
+// smallest package at the root.
+type smallestFirst []itemSize
+
+func (h smallestFirst) Len() int            { return len(h) }
+func (h smallestFirst) Less(i, j int) bool  { return h[i].size < h[j].size }
+func (h smallestFirst) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *smallestFirst) Push(x interface{}) { *h = append(*h, x.(itemSize)) }
+func (h *smallestFirst) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// hosts prints the number of packages and the stored size of the packages
+// of each host followed by the largest packages. The packages are streamed
+// from the database, only the totals of each host and the largest packages
+// are kept in memory.
+func hosts(c *command) {
+	if len(c.flag.Args()) != 0 || *hostsTop < 0 {
+		c.printUsage()
+		os.Exit(1)
+	}
+	db, err := database.New(*redisServer, *dbIdleTimeout, false, gaeEndpoint)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	totals := make(map[string]*hostSize)
+	var largest smallestFirst
+	err = db.Do(func(pi *database.PackageInfo) error {
+		path := pi.PDoc.ImportPath
+		host := path
+		if i := strings.Index(path, "/"); i >= 0 {
+			host = path[:i]
+		}
+		t := totals[host]
+		if t == nil {
+			t = &hostSize{host: host}
+			totals[host] = t
+		}
+		t.count++
+		t.size += pi.Size
+
+		if *hostsTop > 0 {
+			if len(largest) < *hostsTop {
+				heap.Push(&largest, itemSize{path, pi.Size})
+			} else if pi.Size > largest[0].size {
+				largest[0] = itemSize{path, pi.Size}
+				heap.Fix(&largest, 0)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var sizes []*hostSize
+	for _, t := range totals {
+		sizes = append(sizes, t)
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		if sizes[i].size != sizes[j].size {
+			return sizes[i].size > sizes[j].size
+		}
+		return sizes[i].host < sizes[j].host
+	})
+	fmt.Println("HOSTS")
+	fmt.Printf("%8s %12s %s\n", "PACKAGES", "BYTES", "HOST")
+	for _, t := range sizes {
+		fmt.Printf("%8d %12d %s\n", t.count, t.size, t.host)
+	}
+
+	sort.Sort(bySizeDesc(largest))
+	fmt.Println("LARGEST PACKAGES")
+	for _, size := range largest {
+		fmt.Printf("%12d %s\n", size.size, size.path)
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/golang/gddo/database"
+)
+
+var implementationsCommand = &command{
+	name:  "implementations",
+	run:   implementations,
+	usage: "implementations",
+}
+
+// implementations rebuilds the known implementations of the interfaces in
+// the standard library and the golang.org/x/ repositories shown on package
+// pages.
+func implementations(c *command) {
+	if len(c.flag.Args()) != 0 {
+		c.printUsage()
+		os.Exit(1)
+	}
+	db, err := database.New(*redisServer, *dbIdleTimeout, false, gaeEndpoint)
+	if err != nil {
+		log.Fatal(err)
+	}
+	n, err := db.UpdateImplementations()
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Found implementations of %d interfaces", n)
+}
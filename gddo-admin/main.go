@@ -39,10 +39,15 @@ var commands = []*command{
 	blockCommand,
 	reindexCommand,
 	deleteCommand,
+	goneCommand,
+	hideCommand,
 	popularCommand,
 	dangleCommand,
 	crawlCommand,
 	statsCommand,
+	hostsCommand,
+	recountCommand,
+	implementationsCommand,
 }
 
 func printUsage() {
@@ -0,0 +1,37 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/golang/gddo/database"
+)
+
+var recountCommand = &command{
+	name:  "recount",
+	run:   recount,
+	usage: "recount",
+}
+
+// recount repairs the package counts served by the stats API.
+func recount(c *command) {
+	if len(c.flag.Args()) != 0 {
+		c.printUsage()
+		os.Exit(1)
+	}
+	db, err := database.New(*redisServer, *dbIdleTimeout, false, gaeEndpoint)
+	if err != nil {
+		log.Fatal(err)
+	}
+	n, err := db.RepairPackageCount()
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Counted %d packages", n)
+}
@@ -0,0 +1,83 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang/gddo/httputil"
+)
+
+// apiKey is a key granting an API client a higher rate limit.
+type apiKey struct {
+	name  string
+	limit float64
+}
+
+// parseAPIKeys parses API keys specified as name:key:limit.
+func parseAPIKeys(specs []string) (map[string]apiKey, error) {
+	keys := make(map[string]apiKey)
+	for _, spec := range specs {
+		parts := strings.Split(spec, ":")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid API key %q, want name:key:limit", spec)
+		}
+		limit, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit for API key %q: %v", parts[0], err)
+		}
+		keys[parts[1]] = apiKey{name: parts[0], limit: limit}
+	}
+	return keys, nil
+}
+
+// apiKeyFromRequest returns the API key passed in the X-API-Key header or
+// the api_key query parameter.
+func apiKeyFromRequest(req *http.Request) string {
+	if key := req.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	return req.Form.Get("api_key")
+}
+
+// apiRateLimit wraps an API handler to limit the request rate of each client.
+// Clients are identified by API key if one is given, otherwise by remote
+// address. Requests with an unknown API key are rejected.
+func (s *server) apiRateLimit(f func(http.ResponseWriter, *http.Request) error) func(http.ResponseWriter, *http.Request) error {
+	return func(resp http.ResponseWriter, req *http.Request) error {
+		var name string
+		limit := s.v.GetFloat64(ConfigAPIRateLimit)
+		counter := "api:" + httputil.StripPort(req.RemoteAddr)
+		if key := apiKeyFromRequest(req); key != "" {
+			k, ok := s.apiKeys[key]
+			if !ok {
+				return &httpError{status: http.StatusUnauthorized}
+			}
+			name, limit, counter = k.name, k.limit, "apikey:"+k.name
+		} else if limit <= 0 {
+			return f(resp, req)
+		}
+
+		n, err := s.db.IncrementCounter(counter, 1)
+		if err != nil {
+			log.Printf("error incrementing counter for %s, %v", counter, err)
+			return f(resp, req)
+		}
+		if name != "" {
+			log.Printf("DEBUG api key %s %.2f %s", name, n, req.URL.Path)
+		}
+		if limit > 0 && n > limit {
+			log.Printf("api rate limit %.2f %s %s", n, counter, req.Header.Get("User-Agent"))
+			return &httpError{status: http.StatusTooManyRequests}
+		}
+		return f(resp, req)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseAPIKeys(t *testing.T) {
+	got, err := parseAPIKeys([]string{"acme:s3cret:1000", "other:k2:0.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]apiKey{
+		"s3cret": {name: "acme", limit: 1000},
+		"k2":     {name: "other", limit: 0.5},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(apiKey{})); diff != "" {
+		t.Errorf("parseAPIKeys mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, spec := range []string{"acme", "acme:key", ":key:10", "acme::10", "acme:key:lots"} {
+		if _, err := parseAPIKeys([]string{spec}); err == nil {
+			t.Errorf("parseAPIKeys(%q) returned nil error", spec)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+// Google Analytics, with the account set by the data-account attribute of
+// the script element. The snippet is loaded from a file for compatibility
+// with a Content-Security-Policy without 'unsafe-inline'.
+var _gaq = _gaq || [];
+_gaq.push(['_setAccount', document.currentScript.getAttribute('data-account')]);
+_gaq.push(['_trackPageview']);
+(function() {
+    var ga = document.createElement('script'); ga.type = 'text/javascript'; ga.async = true;
+    ga.src = ('https:' == document.location.protocol ? 'https://ssl' : 'http://www') + '.google-analytics.com/ga.js';
+    var s = document.getElementsByTagName('script')[0]; s.parentNode.insertBefore(ga, s);
+})();
@@ -137,6 +137,35 @@ $(function() {
 
 });
 
+// collapsible type declarations
+$(function() {
+    var expand = function($decl) {
+        $decl.removeClass('collapsed').next('.decl-toggle').text('Hide');
+    };
+
+    $('.decl[data-collapse]').each(function() {
+        var $decl = $(this).addClass('collapsed');
+        $('<a/>', {href: '#', 'class': 'decl-toggle', text: 'Show all'}).insertAfter($decl).on('click', function(e) {
+            e.preventDefault();
+            if ($decl.hasClass('collapsed')) {
+                expand($decl);
+            } else {
+                $decl.addClass('collapsed');
+                $(this).text('Show all');
+            }
+        });
+    });
+
+    // Expand a declaration when linking to one of its fields or methods.
+    $(window).on('hashchange', function() {
+        var id = window.location.hash.substring(1);
+        var target = id && document.getElementById(id);
+        if (target) {
+            expand($(target).closest('.decl.collapsed'));
+        }
+    }).trigger('hashchange');
+});
+
 // keyboard shortcuts
 $(function() {
     var prevCh = null, prevTime = 0, modal = false;
@@ -215,10 +244,21 @@ $(function() {
 // misc
 $(function() {
     $('span.timeago').timeago();
-    if (window.location.hash.substring(0, 9) == '#example-') {
-        var id = '#ex-' + window.location.hash.substring(9);
-        $(id).addClass('in').removeClass('collapse').height('auto');
-    }
+    var expandExample = function(hash) {
+        if (hash.substring(0, 9) == '#example-') {
+            $('#ex-' + hash.substring(9)).addClass('in').removeClass('collapse').height('auto');
+        }
+    };
+    expandExample(window.location.hash);
+    $(document).on('click', 'a[href^="#example-"]', function() {
+        expandExample($(this).attr('href'));
+    });
+
+    // Links submitting the form named by the data-submit attribute.
+    $(document).on('click', 'a[data-submit]', function(e) {
+        e.preventDefault();
+        document.getElementsByName($(this).attr('data-submit'))[0].submit();
+    });
 
     $(document).on("click", "input.click-select", function(e) {
         $(e.target).select();
@@ -239,3 +279,63 @@ $(function() {
         redirectToastEl.style.display = 'none';
     });
 });
+
+// Symbol tooltips: show the signature and synopsis of the symbols of other
+// packages referenced by declarations when hovering over the links. The
+// documentation of a symbol is fetched on first hover and kept for the page.
+$(function() {
+    if (!$('body').is('[data-symbol-tips]')) {
+        return;
+    }
+    var tips = {};
+    $(document).on('mouseenter', 'pre a[href^="/"]', function() {
+        var $a = $(this);
+        var href = $a.attr('href');
+        var i = href.indexOf('#');
+        if (i < 0) {
+            return;
+        }
+        var key = href.substring(1);
+        if (!tips[key]) {
+            tips[key] = $.getJSON('/-/symbol', {
+                path: decodeURIComponent(href.substring(1, i)),
+                name: decodeURIComponent(href.substring(i + 1))
+            });
+        }
+        $a.data('symbol-hover', true);
+        tips[key].done(function(tip) {
+            if (!$a.data('symbol-hover')) {
+                return;
+            }
+            var $content = $('<div class="symbol-tip"/>').append($('<pre/>').text(tip.signature));
+            if (tip.synopsis) {
+                $content.append($('<p/>').text(tip.synopsis));
+            }
+            $a.popover({
+                trigger: 'manual',
+                placement: 'bottom',
+                container: 'body',
+                html: true,
+                template: '<div class="popover symbol-tip-popover" role="tooltip"><div class="arrow"></div><h3 class="popover-title"></h3><div class="popover-content"></div></div>',
+                title: $('<span/>').text(tip.path + '.' + tip.name),
+                content: $content
+            }).popover('show');
+        });
+    }).on('mouseleave', 'pre a[href^="/"]', function() {
+        $(this).data('symbol-hover', false).popover('hide');
+    });
+});
+
+// Show the number of views of the package page, loaded separately so that
+// the page does not change with each view.
+$(function() {
+    var $views = $('#x-views');
+    if ($views.length === 0) {
+        return;
+    }
+    $.getJSON('/-/views', {path: $views.data('path')}).done(function(data) {
+        if (data.views > 0) {
+            $views.text('Viewed ' + data.views + (data.views === 1 ? ' time.' : ' times.'));
+        }
+    });
+});
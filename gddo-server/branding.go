@@ -0,0 +1,82 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"fmt"
+	htemp "html/template"
+	"net/http"
+
+	"github.com/spf13/viper"
+)
+
+// branding holds the site name, logo and colors used by the page layout. The
+// colors are CSS colors; empty colors keep the colors of site.css.
+type branding struct {
+	Name        string
+	LogoURL     string
+	LinkColor   htemp.CSS
+	NavbarColor htemp.CSS
+	ActiveColor htemp.CSS // background of the active navigation bar item
+}
+
+func newBranding(v *viper.Viper) *branding {
+	return &branding{
+		Name:        v.GetString(ConfigSiteName),
+		LogoURL:     v.GetString(ConfigLogoURL),
+		LinkColor:   cssColor(v.GetString(ConfigLinkColor)),
+		NavbarColor: cssColor(v.GetString(ConfigNavbarColor)),
+		ActiveColor: cssColor(v.GetString(ConfigNavActiveColor)),
+	}
+}
+
+// Colors returns whether any color is customized.
+func (b *branding) Colors() bool {
+	return b.LinkColor != "" || b.NavbarColor != "" || b.ActiveColor != ""
+}
+
+// ServeHTTP serves the style sheet with the customized colors. The colors
+// are served from a file rather than an inline style element for
+// compatibility with a Content-Security-Policy without 'unsafe-inline'.
+func (b *branding) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "text/css; charset=utf-8")
+	if b.LinkColor != "" {
+		fmt.Fprintf(resp, "a, .navbar-default .navbar-brand { color: %s; }\n", b.LinkColor)
+	}
+	if b.NavbarColor != "" {
+		fmt.Fprintf(resp, ".navbar-default, #x-footer { background-color: %s; }\n", b.NavbarColor)
+	}
+	if b.ActiveColor != "" {
+		fmt.Fprintf(resp, ".navbar-default .navbar-nav > .active > a, .navbar-default .navbar-nav > .active > a:hover, .navbar-default .navbar-nav > .active > a:focus { background-color: %s; }\n", b.ActiveColor)
+	}
+}
+
+// cssColor returns s as CSS if it is a plain color value: a name, a hex
+// color or a functional notation such as rgb(1, 2, 3). Other values, which
+// could inject arbitrary CSS, are dropped.
+func cssColor(s string) htemp.CSS {
+	depth := 0
+	for _, r := range s {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == '#' || r == '%' || r == '.' || r == ',' || r == ' ' || r == '-':
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+		default:
+			return ""
+		}
+		if depth < 0 || depth > 1 {
+			return ""
+		}
+	}
+	if depth != 0 {
+		return ""
+	}
+	return htemp.CSS(s)
+}
@@ -0,0 +1,47 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	htemp "html/template"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCSSColor(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want htemp.CSS
+	}{
+		{"", ""},
+		{"red", "red"},
+		{"#375eab", "#375eab"},
+		{"hsl(209, 51%, 92%)", "hsl(209, 51%, 92%)"},
+		{"red; } body { display: none", ""},
+		{"red)", ""},
+		{"url(x)", "url(x)"},
+		{"url(http://x)", ""},
+	} {
+		if got := cssColor(tt.in); got != tt.want {
+			t.Errorf("cssColor(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBrandingCSS(t *testing.T) {
+	b := &branding{LinkColor: "red", ActiveColor: "#375eab"}
+	w := httptest.NewRecorder()
+	b.ServeHTTP(w, httptest.NewRequest("GET", "/-/branding.css", nil))
+	want := "a, .navbar-default .navbar-brand { color: red; }\n" +
+		".navbar-default .navbar-nav > .active > a, .navbar-default .navbar-nav > .active > a:hover, .navbar-default .navbar-nav > .active > a:focus { background-color: #375eab; }\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("css = %q, want %q", got, want)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/css; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/css", ct)
+	}
+}
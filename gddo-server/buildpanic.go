@@ -0,0 +1,34 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang/gddo/doc"
+	"github.com/golang/gddo/log"
+)
+
+// errBuildPanic is returned for packages whose documentation could not be
+// built because the build panicked.
+var errBuildPanic = errors.New("building the documentation panicked")
+
+// buildPanicked logs the stack of a panic while crawling a package, with the
+// ID of the request that started the crawl if any, and records that the
+// package is not to be crawled again for ConfigBuildPanicTTL. Until then,
+// requests for the package are served the documentation from the database
+// or an error page.
+func (s *server) buildPanicked(ctx context.Context, e *doc.PanicError) {
+	log.Error(ctx, "panic building documentation",
+		"path", e.ImportPath,
+		"panic", fmt.Sprint(e.Value),
+		"stack", string(e.Stack))
+	s.crawls.setPanicked(e.ImportPath, time.Now().Add(s.v.GetDuration(ConfigBuildPanicTTL)))
+}
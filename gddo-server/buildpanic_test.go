@@ -0,0 +1,80 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/golang/gddo/doc"
+	"github.com/golang/gddo/httputil"
+)
+
+func TestCrawlTrackerPanicked(t *testing.T) {
+	var tr crawlTracker
+	tr.start("web  ", "example.com/broken")
+	tr.finish("example.com/broken", &doc.PanicError{ImportPath: "example.com/broken", Value: "boom"})
+	if tr.panicked("example.com/broken", time.Now()) {
+		t.Error("panicked = true without a retry time, want false")
+	}
+	retry := time.Now().Add(time.Minute)
+	tr.setPanicked("example.com/broken", retry)
+
+	// The panic outlives the failures pushed out of the recent failures.
+	for i := 0; i < 2*maxCrawlFailures; i++ {
+		p := fmt.Sprintf("example.com/missing%d", i)
+		tr.start("web  ", p)
+		tr.finish(p, errors.New("not found"))
+	}
+	if !tr.panicked("example.com/broken", time.Now()) {
+		t.Error("panicked = false before the retry time, want true")
+	}
+	if !tr.backingOff("example.com/broken", time.Now()) {
+		t.Error("backingOff = false before the retry time, want true")
+	}
+	if tr.panicked("example.com/broken", retry.Add(time.Second)) {
+		t.Error("panicked = true after the retry time, want false")
+	}
+	if tr.panicked("example.com/missing0", time.Now()) {
+		t.Error("panicked = true for a crawl that did not panic, want false")
+	}
+}
+
+func TestBuildPanicPage(t *testing.T) {
+	templates, err := parseTemplates("assets", &httputil.CacheBusters{}, viper.New(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{templates: templates, v: viper.New()}
+	req := httptest.NewRequest("GET", "/example.com/broken", nil)
+	req.Header.Set("Accept", "text/plain")
+	resp := httptest.NewRecorder()
+	s.handleError(resp, req, http.StatusInternalServerError, errBuildPanic)
+	if resp.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", resp.Code, http.StatusInternalServerError)
+	}
+	if want := "The documentation for example.com/broken could not be built."; !strings.Contains(resp.Body.String(), want) {
+		t.Errorf("body does not contain %q:\n%s", want, resp.Body.String())
+	}
+
+	var buf strings.Builder
+	err = templates["unavailable.html"].(*template.Template).ExecuteTemplate(&buf, "Body", map[string]interface{}{"path": "example.com/broken"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "The documentation for <code>example.com/broken</code> could not be built."; !strings.Contains(buf.String(), want) {
+		t.Errorf("unavailable.html does not contain %q:\n%s", want, buf.String())
+	}
+}
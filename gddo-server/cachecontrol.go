@@ -0,0 +1,110 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// routeClass identifies a group of routes sharing a cache control policy.
+type routeClass int
+
+const (
+	routeStatic routeClass = iota
+	routePackage
+	routeSearch
+	routeAPI
+	routeFeed
+	routeSuggest
+)
+
+// cachePolicies maps route classes to Cache-Control header values. An empty
+// value leaves the header unset.
+type cachePolicies map[routeClass]string
+
+func newCachePolicies(v *viper.Viper) cachePolicies {
+	return cachePolicies{
+		routeStatic:  v.GetString(ConfigCacheControlStatic),
+		routePackage: v.GetString(ConfigCacheControlPackage),
+		routeSearch:  v.GetString(ConfigCacheControlSearch),
+		routeAPI:     v.GetString(ConfigCacheControlAPI),
+		routeFeed:    v.GetString(ConfigCacheControlFeed),
+		routeSuggest: v.GetString(ConfigCacheControlSuggest),
+	}
+}
+
+// handler returns h with the policy for class applied.
+func (p cachePolicies) handler(class routeClass, h http.Handler) http.Handler {
+	return cacheControlHandler{h: h, policy: func(*http.Request) string { return p[class] }}
+}
+
+// homeHandler returns h with the search policy applied to search queries,
+// the feed policy applied to the feeds of search results and the package
+// policy applied to everything else.
+func (p cachePolicies) homeHandler(h http.Handler) http.Handler {
+	return cacheControlHandler{h: h, policy: func(req *http.Request) string {
+		if req.URL.Path == "/" && strings.TrimSpace(req.URL.Query().Get("q")) != "" {
+			if searchFeedFormat(req) != "" {
+				return p[routeFeed]
+			}
+			return p[routeSearch]
+		}
+		return p[routePackage]
+	}}
+}
+
+// cacheControlHandler sets the Cache-Control header of successful responses
+// that do not set the header themselves.
+type cacheControlHandler struct {
+	h      http.Handler
+	policy func(*http.Request) string
+}
+
+func (h cacheControlHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	policy := h.policy(req)
+	if policy == "" {
+		h.h.ServeHTTP(resp, req)
+		return
+	}
+	h.h.ServeHTTP(&cacheControlWriter{ResponseWriter: resp, policy: policy}, req)
+}
+
+type cacheControlWriter struct {
+	http.ResponseWriter
+	policy      string
+	wroteHeader bool
+}
+
+func (w *cacheControlWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		// Errors are not cached so that they go away once fixed.
+		if status < 400 && w.Header().Get("Cache-Control") == "" {
+			w.Header().Set("Cache-Control", w.policy)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *cacheControlWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *cacheControlWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCacheControlHandler(t *testing.T) {
+	p := cachePolicies{
+		routePackage: "public, max-age=60",
+		routeSearch:  "no-cache",
+		routeFeed:    "public, max-age=300",
+	}
+	h := p.homeHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/own":
+			w.Header().Set("Cache-Control", "private")
+			w.Write([]byte("ok"))
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+
+	for _, tt := range []struct {
+		url  string
+		want string
+	}{
+		{"/github.com/user/repo", "public, max-age=60"},
+		{"/?q=http", "no-cache"},
+		{"/?q=http&format=rss", "public, max-age=300"},
+		{"/", "public, max-age=60"},
+		{"/missing", ""},
+		{"/own", "private"},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", tt.url, nil))
+		if got := w.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("%s: Cache-Control = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
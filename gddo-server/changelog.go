@@ -0,0 +1,143 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/golang/gddo/doc"
+)
+
+const atomMIMEType = "application/atom+xml; charset=utf-8"
+
+// changelogSection is an entry of a changelog with the anchor of the entry on
+// the changelog page.
+type changelogSection struct {
+	ID string
+	*doc.ChangelogEntry
+}
+
+// changelogSections returns the entries of cl with unique anchors derived
+// from the titles of the entries.
+func changelogSections(cl *doc.Changelog) []changelogSection {
+	seen := make(map[string]bool)
+	var sections []changelogSection
+	for _, e := range cl.Entries {
+		id := "changelog-" + strings.Trim(strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' {
+				return unicode.ToLower(r)
+			}
+			return '-'
+		}, e.Title), "-")
+		for base, i := id, 2; seen[id]; i++ {
+			id = base + "-" + strconv.Itoa(i)
+		}
+		seen[id] = true
+		sections = append(sections, changelogSection{ID: id, ChangelogEntry: e})
+	}
+	return sections
+}
+
+// changelogUpdated returns the date of the most recent entry of the changelog
+// of pdoc, or the time of the crawl if the entries have no dates.
+func changelogUpdated(pdoc *doc.Package) time.Time {
+	var t time.Time
+	for _, e := range pdoc.Changelog.Entries {
+		if e.Date.After(t) {
+			t = e.Date
+		}
+	}
+	if t.IsZero() {
+		t = pdoc.Updated
+	}
+	return t
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title   string    `xml:"title"`
+	ID      string    `xml:"id"`
+	Link    atomLink  `xml:"link"`
+	Updated time.Time `xml:"updated"`
+	Content atomText  `xml:"content"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Links   []atomLink  `xml:"link"`
+	Updated time.Time   `xml:"updated"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// newChangelogFeed returns the Atom feed of the entries of the changelog of
+// pdoc. pageURL is the absolute URL of the changelog page.
+func newChangelogFeed(siteName, pageURL string, pdoc *doc.Package) *atomFeed {
+	feed := &atomFeed{
+		Title:   pdoc.ImportPath + " changelog - " + siteName,
+		ID:      pageURL,
+		Links:   []atomLink{{Href: pageURL}, {Rel: "self", Href: pageURL + ".atom"}},
+		Updated: changelogUpdated(pdoc).UTC(),
+	}
+	for _, s := range changelogSections(pdoc.Changelog) {
+		updated := s.Date
+		if updated.IsZero() {
+			updated = pdoc.Updated
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   s.Title,
+			ID:      pageURL + "#" + s.ID,
+			Link:    atomLink{Href: pageURL + "#" + s.ID},
+			Updated: updated.UTC(),
+			Content: atomText{Type: "text", Body: s.Text},
+		})
+	}
+	return feed
+}
+
+// serveChangelog serves the changelog page of the package or, for the
+// changelog.atom view, the Atom feed of the entries of the changelog.
+func (s *server) serveChangelog(resp http.ResponseWriter, req *http.Request, pdoc *doc.Package, flashMessages []flashMessage) error {
+	if pdoc.Changelog == nil {
+		return &httpError{status: http.StatusNotFound}
+	}
+	if !isView(req, "changelog.atom") {
+		return s.templates.execute(resp, "changelog.html", http.StatusOK, nil, map[string]interface{}{
+			"flashMessages": flashMessages,
+			"pdoc":          newTDoc(s.v, pdoc),
+			"sections":      changelogSections(pdoc.Changelog),
+			"feed":          s.v.GetBool(ConfigChangelogFeed),
+		})
+	}
+	if !s.v.GetBool(ConfigChangelogFeed) {
+		return &httpError{status: http.StatusNotFound}
+	}
+	if !checkFeedModified(resp, req, "changelog.atom "+pdoc.ImportPath, changelogUpdated(pdoc)) {
+		return nil
+	}
+	pageURL := s.siteURL(resp, req) + "/" + pdoc.ImportPath + "?changelog"
+	resp.Header().Set("Content-Type", atomMIMEType)
+	if _, err := resp.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	return xml.NewEncoder(resp).Encode(newChangelogFeed(s.v.GetString(ConfigSiteName), pageURL, pdoc))
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/golang/gddo/doc"
+)
+
+func TestChangelogFeed(t *testing.T) {
+	crawled := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	released := time.Date(2021, 5, 14, 0, 0, 0, 0, time.UTC)
+	pdoc := &doc.Package{
+		ImportPath: "github.com/alice/pkg",
+		Updated:    crawled,
+		Changelog: &doc.Changelog{
+			Name: "CHANGELOG.md",
+			Entries: []*doc.ChangelogEntry{
+				{Title: "Unreleased", Text: "- Work in progress."},
+				{Title: "1.2.0 - 2021-05-14", Date: released, Text: "- New API."},
+				{Title: "1.2.0 - 2021-05-14", Date: released, Text: "- Duplicate."},
+			},
+		},
+	}
+
+	var ids []string
+	for _, s := range changelogSections(pdoc.Changelog) {
+		ids = append(ids, s.ID)
+	}
+	if want := []string{"changelog-unreleased", "changelog-1.2.0---2021-05-14", "changelog-1.2.0---2021-05-14-2"}; !cmp.Equal(ids, want) {
+		t.Errorf("changelogSections IDs = %q, want %q", ids, want)
+	}
+
+	if got := changelogUpdated(pdoc); !got.Equal(released) {
+		t.Errorf("changelogUpdated() = %v, want %v", got, released)
+	}
+
+	const pageURL = "https://godoc.org/github.com/alice/pkg?changelog"
+	feed := newChangelogFeed("GoDoc", pageURL, pdoc)
+	p, err := xml.Marshal(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got atomFeed
+	if err := xml.Unmarshal(p, &got); err != nil {
+		t.Fatalf("feed does not parse: %v\n%s", err, p)
+	}
+	if got.XMLName.Space != "http://www.w3.org/2005/Atom" {
+		t.Errorf("feed namespace %q, want Atom", got.XMLName.Space)
+	}
+	if got.Title != "github.com/alice/pkg changelog - GoDoc" || !got.Updated.Equal(released) || len(got.Entries) != 3 {
+		t.Fatalf("feed = %+v", got)
+	}
+	if e := got.Entries[0]; e.ID != pageURL+"#changelog-unreleased" || !e.Updated.Equal(crawled) || e.Content.Body != "- Work in progress." {
+		t.Errorf("first entry = %+v", e)
+	}
+	if e := got.Entries[1]; e.Title != "1.2.0 - 2021-05-14" || !e.Updated.Equal(released) {
+		t.Errorf("second entry = %+v", e)
+	}
+	if want := []atomLink{{Href: pageURL}, {Rel: "self", Href: pageURL + ".atom"}}; !cmp.Equal(got.Links, want) {
+		t.Errorf("feed links = %+v, want %+v", got.Links, want)
+	}
+
+	pdoc.Changelog.Entries = nil
+	if got := changelogUpdated(pdoc); !got.Equal(crawled) {
+		t.Errorf("changelogUpdated() without dates = %v, want %v", got, crawled)
+	}
+}
@@ -21,17 +21,23 @@ import (
 	"github.com/golang/gddo/httputil"
 )
 
-func newHTTPClient(v *viper.Viper) *http.Client {
+// newHTTPClient returns the client used to fetch packages and the transport
+// limiting the number of concurrent requests to each host.
+func newHTTPClient(v *viper.Viper) (*http.Client, *httputil.HostLimitTransport) {
 	requestTimeout := v.GetDuration(ConfigRequestTimeout)
-	var t http.RoundTripper = &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		Dial: (&net.Dialer{
-			Timeout:   v.GetDuration(ConfigDialTimeout),
-			KeepAlive: requestTimeout / 2,
-		}).Dial,
-		ResponseHeaderTimeout: requestTimeout / 2,
-		TLSHandshakeTimeout:   requestTimeout / 2,
+	limits := &httputil.HostLimitTransport{
+		MaxPerHost: v.GetInt(ConfigHostConcurrency),
+		Base: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			Dial: (&net.Dialer{
+				Timeout:   v.GetDuration(ConfigDialTimeout),
+				KeepAlive: requestTimeout / 2,
+			}).Dial,
+			ResponseHeaderTimeout: requestTimeout / 2,
+			TLSHandshakeTimeout:   requestTimeout / 2,
+		},
 	}
+	var t http.RoundTripper = limits
 	if addr := v.GetString(ConfigMemcacheAddr); addr != "" {
 		ct := httpcache.NewTransport(memcache.New(addr))
 		ct.Transport = t
@@ -49,5 +55,5 @@ func newHTTPClient(v *viper.Viper) *http.Client {
 	return &http.Client{
 		Transport: t,
 		Timeout:   requestTimeout,
-	}
+	}, limits
 }
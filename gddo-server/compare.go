@@ -0,0 +1,99 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/gddo/doc"
+	"github.com/golang/gddo/gosrc"
+)
+
+// maxCompareSymbols is the number of exported functions and types of each
+// package listed in the comparison of two packages.
+const maxCompareSymbols = 20
+
+// compareColumn is a package in the comparison of two packages.
+type compareColumn struct {
+	Path      string
+	Found     bool
+	Synopsis  string
+	Importers int
+	License   string
+	Updated   time.Time
+	Funcs     []string
+	MoreFuncs int
+	Types     []string
+	MoreTypes int
+}
+
+func newCompareColumn(path string, pdoc *doc.Package, importers int) *compareColumn {
+	c := &compareColumn{Path: path, Importers: importers}
+	if pdoc == nil || pdoc.Name == "" {
+		return c
+	}
+	c.Found = true
+	c.Synopsis = pdoc.Synopsis
+	c.Updated = pdoc.Updated
+	if pdoc.ProjectRoot == "" {
+		c.License = stdLicense
+	}
+	for _, f := range pdoc.Funcs {
+		c.Funcs = append(c.Funcs, f.Name)
+	}
+	for _, t := range pdoc.Types {
+		c.Types = append(c.Types, t.Name)
+	}
+	if len(c.Funcs) > maxCompareSymbols {
+		c.Funcs, c.MoreFuncs = c.Funcs[:maxCompareSymbols], len(c.Funcs)-maxCompareSymbols
+	}
+	if len(c.Types) > maxCompareSymbols {
+		c.Types, c.MoreTypes = c.Types[:maxCompareSymbols], len(c.Types)-maxCompareSymbols
+	}
+	return c
+}
+
+// serveCompare serves the comparison of the packages with the import paths
+// given by the a and b query parameters. Packages not in the database are
+// crawled unless the server only serves cached packages.
+func (s *server) serveCompare(resp http.ResponseWriter, req *http.Request) error {
+	paths := []string{strings.TrimSpace(req.Form.Get("a")), strings.TrimSpace(req.Form.Get("b"))}
+	data := map[string]interface{}{"a": paths[0], "b": paths[1]}
+	if paths[0] == "" || paths[1] == "" {
+		return s.templates.execute(resp, "compare.html", http.StatusOK, nil, data)
+	}
+
+	requestType := queryRequest
+	if s.isRobot(req) {
+		requestType = robotRequest
+	}
+	pdocs := make([]*doc.Package, len(paths))
+	for i, p := range paths {
+		if !gosrc.IsValidPath(p) {
+			continue
+		}
+		pdoc, _, err := s.getDoc(req.Context(), p, requestType)
+		if e, ok := err.(*httpError); ok && e.status == http.StatusNotFound || gosrc.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		pdocs[i] = pdoc
+	}
+	importers, err := s.db.ImporterCounts(paths)
+	if err != nil {
+		return err
+	}
+	var columns []*compareColumn
+	for i, p := range paths {
+		columns = append(columns, newCompareColumn(p, pdocs[i], importers[p]))
+	}
+	data["columns"] = columns
+	return s.templates.execute(resp, "compare.html", http.StatusOK, nil, data)
+}
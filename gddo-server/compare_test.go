@@ -0,0 +1,52 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/gddo/doc"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewCompareColumn(t *testing.T) {
+	updated := time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)
+	pdoc := &doc.Package{
+		ImportPath: "strings",
+		Name:       "strings",
+		Synopsis:   "Package strings implements simple functions to manipulate UTF-8 encoded strings.",
+		Updated:    updated,
+		Types:      []*doc.Type{{Name: "Builder"}, {Name: "Reader"}},
+	}
+	for i := 0; i < maxCompareSymbols+2; i++ {
+		pdoc.Funcs = append(pdoc.Funcs, &doc.Func{Name: "F" + strconv.Itoa(i)})
+	}
+	got := newCompareColumn("strings", pdoc, 3)
+	want := &compareColumn{
+		Path:      "strings",
+		Found:     true,
+		Synopsis:  pdoc.Synopsis,
+		Importers: 3,
+		License:   stdLicense,
+		Updated:   updated,
+		Types:     []string{"Builder", "Reader"},
+		MoreFuncs: 2,
+	}
+	for _, f := range pdoc.Funcs[:maxCompareSymbols] {
+		want.Funcs = append(want.Funcs, f.Name)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("newCompareColumn mismatch (-want +got):\n%s", diff)
+	}
+
+	got = newCompareColumn("example.com/missing", nil, 0)
+	if diff := cmp.Diff(&compareColumn{Path: "example.com/missing"}, got); diff != "" {
+		t.Errorf("newCompareColumn(nil) mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
+	"github.com/golang/gddo/database"
 	"github.com/golang/gddo/log"
 )
 
@@ -29,32 +30,125 @@ const (
 	// Server Config
 	ConfigProject           = "project"
 	ConfigTrustProxyHeaders = "trust_proxy_headers"
+	ConfigForceHTTPS        = "force_https"
+	ConfigBaseURL           = "base_url"
 	ConfigBindAddress       = "http"
 	ConfigAssetsDir         = "assets"
 	ConfigRobotThreshold    = "robot"
+	ConfigRobotUserAgents   = "robot_user_agents"
+	ConfigRobotCIDRs        = "robot_cidrs"
 	ConfigGCELogName        = "gce_log_name"
+	ConfigAPIRateLimit      = "api_rate_limit"
+	ConfigAPIKeys           = "api_keys"
+	ConfigAPIMaxBodyBytes   = "api_max_body_bytes"
+	ConfigAPIMaxBatch       = "api_max_batch"
+	ConfigRenderCacheSize   = "render_cache_size"
+	ConfigRenderCacheStore  = "render_cache_store"
+	ConfigRenderCacheRedis  = "render_cache_redis"
+	ConfigRenderCacheTTL    = "render_cache_ttl"
+	ConfigMaxRenders        = "max_renders"
+	ConfigRenderQueueWait   = "render_queue_wait"
+	ConfigCrawlHostRate     = "crawl_host_rate"
+	ConfigCrawlHostBudgets  = "crawl_host_budgets"
+	ConfigRedirectDefault   = "pkggodev_redirect_default"
+	ConfigTeeExcludeExts    = "tee_exclude_exts"
+	ConfigTeeExcludePaths   = "tee_exclude_paths"
+	ConfigRedirectHosts     = "redirect_hosts"
+	ConfigDisableListing    = "disable_listing"
+	ConfigDebugKey          = "debug_key"
+	ConfigDebugCIDRs        = "debug_cidrs"
+	ConfigSiteAuthUsers     = "site_auth_users"
+	ConfigSiteAuthToken     = "site_auth_token"
+	ConfigLLMsTxt           = "llms_txt"
+	ConfigLogLevel          = "log_level"
+	ConfigLogFormat         = "log_format"
+
+	// Cache Control Config
+	ConfigCacheControlStatic  = "cache_control_static"
+	ConfigCacheControlPackage = "cache_control_package"
+	ConfigCacheControlSearch  = "cache_control_search"
+	ConfigCacheControlAPI     = "cache_control_api"
+	ConfigCacheControlFeed    = "cache_control_feed"
+	ConfigCacheControlSuggest = "cache_control_suggest"
 
 	// Database Config
 	ConfigDBServer      = "db-server"
 	ConfigDBIdleTimeout = "db-idle-timeout"
 	ConfigDBLog         = "db-log"
+	ConfigDBReplicas    = "db-replicas"
+	ConfigDBReadReplica = "db-read-replicas"
+	ConfigDBGzip        = "db-gzip"
 	ConfigGAERemoteAPI  = "remoteapi-endpoint"
 
 	// Display Config
-	ConfigSidebar        = "sidebar"
-	ConfigSourcegraphURL = "sourcegraph_url"
-	ConfigDefaultGOOS    = "default_goos"
-	ConfigGAAccount      = "ga_account"
+	ConfigSidebar         = "sidebar"
+	ConfigSourcegraphURL  = "sourcegraph_url"
+	ConfigDefaultGOOS     = "default_goos"
+	ConfigSynopsisLength  = "synopsis_length"
+	ConfigSynopsisSources = "synopsis_sources"
+	ConfigGAAccount       = "ga_account"
+	ConfigProxySource     = "proxy_source"
+	ConfigLatestVersion   = "latest_version_redirect"
+	ConfigUnexported      = "allow_unexported"
+	ConfigRecentlyViewed  = "recently_viewed"
+	ConfigPlayAll         = "play_all"
+	ConfigViewCount       = "view_count"
+	ConfigChangelogFeed   = "changelog_feed"
+	ConfigSymbolTips      = "symbol_tips"
+	ConfigSiteName        = "site_name"
+	ConfigLogoURL         = "logo_url"
+	ConfigLinkColor       = "theme_link_color"
+	ConfigNavbarColor     = "theme_navbar_color"
+	ConfigNavActiveColor  = "theme_nav_active_color"
+	ConfigSearchLimit     = "search_limit"
+	ConfigFeatured        = "featured_packages"
+	ConfigNoticeMessage   = "notice_message"
+	ConfigNoticeLevel     = "notice_level"
+	ConfigNoticeLink      = "notice_link"
+
+	// Search Ranking Config
+	ConfigRankTerms      = "rank_terms"
+	ConfigRankPopularity = "rank_popularity"
+	ConfigRankRecency    = "rank_recency"
+	ConfigRankStandard   = "rank_standard"
 
 	// Crawl Config
-	ConfigMaxAge          = "max_age"
-	ConfigGetTimeout      = "get_timeout"
-	ConfigFirstGetTimeout = "first_get_timeout"
-	ConfigGithubInterval  = "github_interval"
-	ConfigCrawlInterval   = "crawl_interval"
-	ConfigDialTimeout     = "dial_timeout"
-	ConfigRequestTimeout  = "request_timeout"
-	ConfigMemcacheAddr    = "memcache_addr"
+	ConfigMaxAge             = "max_age"
+	ConfigGetTimeout         = "get_timeout"
+	ConfigFirstGetTimeout    = "first_get_timeout"
+	ConfigGithubInterval     = "github_interval"
+	ConfigCrawlInterval      = "crawl_interval"
+	ConfigGraphCacheSize     = "graph_cache_size"
+	ConfigGraphCacheInterval = "graph_cache_interval"
+	ConfigCrawlBatch         = "crawl_batch"
+	ConfigCrawlBatchDelay    = "crawl_batch_delay"
+	ConfigCrawlConcurrency   = "crawl_concurrency"
+	ConfigDialTimeout        = "dial_timeout"
+	ConfigRequestTimeout     = "request_timeout"
+	ConfigMemcacheAddr       = "memcache_addr"
+	ConfigAllowedHosts       = "allowed_hosts"
+	ConfigInsecureHosts      = "insecure_hosts"
+	ConfigExcludeFile        = "exclude_file"
+	ConfigMetaCacheTTL       = "meta_cache_ttl"
+	ConfigMetaFailureTTL     = "meta_failure_ttl"
+	ConfigResolveCacheTTL    = "resolve_cache_ttl"
+	ConfigNotFoundTTL        = "not_found_ttl"
+	ConfigRedirectTTL        = "redirect_ttl"
+	ConfigBuildPanicTTL      = "build_panic_ttl"
+	ConfigCachedOnly         = "cached_only"
+	ConfigWarmFile           = "warm_file"
+	ConfigOwnersFile         = "owners_file"
+	ConfigWarmPopular        = "warm_popular"
+	ConfigWarmConcurrency    = "warm_concurrency"
+	ConfigLocalModule        = "local_module"
+	ConfigSumDB              = "sumdb"
+	ConfigSumDBKey           = "sumdb_key"
+
+	// Response Headers Config
+	ConfigContentSecurityPolicy = "content_security_policy"
+	ConfigContentTypeOptions    = "content_type_options"
+	ConfigReferrerPolicy        = "referrer_policy"
+	ConfigHSTS                  = "strict_transport_security"
 
 	// Trace Config
 	ConfigTraceSamplerFraction = "trace_fraction"
@@ -62,6 +156,7 @@ const (
 
 	// Outbound HTTP Config
 	ConfigUserAgent          = "user_agent"
+	ConfigHostConcurrency    = "host_concurrency"
 	ConfigGithubToken        = "github_token"
 	ConfigGithubClientID     = "github_client_id"
 	ConfigGithubClientSecret = "github_client_secret"
@@ -120,6 +215,10 @@ func loadConfig(ctx context.Context, args []string) (*viper.Viper, error) {
 	// Set defaults based on other configs
 	setDefaults(v)
 
+	if err := log.Configure(os.Stderr, v.GetString(ConfigLogLevel), v.GetString(ConfigLogFormat)); err != nil {
+		return nil, err
+	}
+
 	log.Debug(ctx, "config values loaded", "values", v.AllSettings())
 	return v, nil
 }
@@ -153,6 +252,19 @@ func buildFlags() *pflag.FlagSet {
 	flags.StringP("config", "c", "", "path to motd config file")
 	flags.String(ConfigProject, "", "Google Cloud Platform project used for Google services")
 	flags.Float64(ConfigRobotThreshold, 100, "Request counter threshold for robots.")
+	flags.StringSlice(ConfigRobotUserAgents, nil, "Classify requests with a User-Agent containing one of these strings as robots (comma separated).")
+	flags.StringSlice(ConfigRobotCIDRs, nil, "Classify requests from these CIDR blocks as robots (comma separated).")
+	flags.Float64(ConfigAPIRateLimit, 0, "Request counter threshold for API clients without an API key. Zero disables the limit.")
+	flags.StringSlice(ConfigAPIKeys, nil, "API keys granting a higher request counter threshold, as name:key:threshold (comma separated).")
+	flags.Int64(ConfigAPIMaxBodyBytes, 1<<20, "Maximum size in bytes of the body of API requests. Larger requests are rejected with status 413.")
+	flags.Int(ConfigAPIMaxBatch, 100, "Maximum number of import paths in a request to the batch API endpoints, such as /synopses.")
+	flags.Bool(ConfigDisableListing, false, "Disable the surfaces enumerating packages: search, suggestions, popular packages, directory listings, other packages of the project, importers and the package lists of the API and /llms.txt. Packages are still served by import path.")
+	flags.String(ConfigCacheControlStatic, "public, max-age=3600", "Cache-Control header for static files. Files requested with a cache busting token are always cached as immutable.")
+	flags.String(ConfigCacheControlPackage, "no-cache", "Cache-Control header for package and directory pages. Empty leaves the header unset.")
+	flags.String(ConfigCacheControlSearch, "no-cache", "Cache-Control header for search results. Empty leaves the header unset.")
+	flags.String(ConfigCacheControlAPI, "no-cache", "Cache-Control header for API responses. Empty leaves the header unset.")
+	flags.String(ConfigCacheControlFeed, "no-cache", "Cache-Control header for feeds. Empty leaves the header unset.")
+	flags.String(ConfigCacheControlSuggest, "public, max-age=3600", "Cache-Control header for search suggestions. Empty leaves the header unset.")
 	flags.String(ConfigAssetsDir, filepath.Join(defaultBase("github.com/golang/gddo/gddo-server"), "assets"), "Base directory for templates and static files.")
 	flags.Duration(ConfigGetTimeout, 8*time.Second, "Time to wait for package update from the VCS.")
 	flags.Duration(ConfigFirstGetTimeout, 5*time.Second, "Time to wait for first fetch of package from the VCS.")
@@ -160,17 +272,92 @@ func buildFlags() *pflag.FlagSet {
 	flags.String(ConfigBindAddress, ":8080", "Listen for HTTP connections on this address.")
 	flags.Bool(ConfigSidebar, false, "Enable package page sidebar.")
 	flags.String(ConfigDefaultGOOS, "", "Default GOOS to use when building package documents.")
-	flags.Bool(ConfigTrustProxyHeaders, false, "If enabled, identify the remote address of the request using X-Real-Ip in header.")
+	flags.Int(ConfigSynopsisLength, 400, "Maximum length in characters of the package synopses shown in listings. Longer synopses are truncated at a word boundary.")
+	flags.StringSlice(ConfigSynopsisSources, []string{"doc", "project"}, "Sources of package synopses in order of preference (comma separated): doc for the package comment, readme for the first sentence of the README and project for the repository description of packages at the repository root.")
+	flags.Bool(ConfigTrustProxyHeaders, false, "If enabled, identify the remote address of the request using the address added to X-Forwarded-For by the proxy, the scheme of the request using X-Forwarded-Proto and the host of the request using X-Forwarded-Host.")
+	flags.Bool(ConfigForceHTTPS, false, "Use https in the absolute URLs of this server regardless of the scheme of the request.")
+	flags.String(ConfigBaseURL, "", "Canonical URL of this server, such as https://godoc.org, used in the absolute URLs of cached pages and feeds. If empty, the URLs use the host of the request and the pages are neither cached by this server nor by shared caches.")
 	flags.String(ConfigSourcegraphURL, "https://sourcegraph.com", "Link to global uses on Sourcegraph based at this URL (no need for trailing slash).")
+	flags.Bool(ConfigProxySource, false, "Serve source files through this server instead of linking to the VCS host.")
+	flags.Bool(ConfigLatestVersion, false, "Redirect package pages to the latest semantic version tag of the repository. The default branch remains available at @master or @main.")
+	flags.Bool(ConfigPlayAll, true, "Link the examples of all packages to the Go Playground, which fetches imported packages from the module proxy. If disabled, only examples in the standard library are linked.")
+	flags.Bool(ConfigRecentlyViewed, true, "Show recently viewed packages on package pages, stored in a cookie. Disable to set no cookie.")
+	flags.Bool(ConfigViewCount, false, "Count the views of package pages by people and show the count on the page and in the API. A view is counted once per package and day for a browser using a cookie.")
+	flags.Bool(ConfigChangelogFeed, false, "Serve the entries of the changelog files of packages, such as CHANGELOG.md, as Atom feeds at ?changelog.atom.")
+	flags.Bool(ConfigSymbolTips, false, "Show the signature and synopsis of the symbols of other packages referenced by declarations in a tooltip on hover, fetched from /-/symbol when first shown.")
+	flags.String(ConfigSiteName, "GoDoc", "Name of the site shown in the navigation bar and page titles.")
+	flags.String(ConfigLogoURL, "", "URL of a logo image shown in the navigation bar before the site name.")
+	flags.String(ConfigLinkColor, "", "CSS color of links and the site name. Empty uses the default theme color.")
+	flags.String(ConfigNavbarColor, "", "CSS background color of the navigation bar and footer. Empty uses the default theme color.")
+	flags.String(ConfigNavActiveColor, "", "CSS background color of the active navigation bar item. Empty uses the default theme color.")
+	flags.String(ConfigNoticeMessage, "", "Message shown at the top of every page, such as an announcement of maintenance. Empty shows no notice. Changes in the config file take effect without a restart.")
+	flags.String(ConfigNoticeLevel, "info", "Severity of the notice, which sets its color: info, success, warning or danger.")
+	flags.String(ConfigNoticeLink, "", "URL of a page with more information linked from the notice. Empty shows no link.")
+	flags.StringSlice(ConfigFeatured, nil, "Import paths of packages featured on the home page with their synopses, in order (comma separated). Packages not in the database are not shown.")
+	flags.Float64(ConfigRankTerms, database.DefaultRankingWeights.Terms, "Weight from 0 to 10 of the match of the search terms in the ranking of search results served from Redis. Zero ignores the signal.")
+	flags.Float64(ConfigRankPopularity, database.DefaultRankingWeights.Popularity, "Weight from 0 to 10 of the number of importers in the ranking of search results served from Redis. Zero ignores the signal.")
+	flags.Float64(ConfigRankRecency, database.DefaultRankingWeights.Recency, "Weight from 0 to 10 of the time since the last commit to the package in the ranking of search results served from Redis. Zero ignores the signal.")
+	flags.Float64(ConfigRankStandard, database.DefaultRankingWeights.Standard, "Weight from 0 to 10 of the boost of standard library packages in the ranking of search results served from Redis. Zero ignores the signal.")
+	flags.Int(ConfigSearchLimit, 1000, "Maximum number of results shown on search result pages. The results are sent to the browser as they are read from the search index.")
+	flags.Bool(ConfigUnexported, false, "Allow rendering documentation with unexported declarations using the ?unexported query. The documentation is fetched from the VCS on each request.")
+	flags.Bool(ConfigRedirectDefault, false, "Redirect users to pkg.go.dev unless they opt out with ?redirect=off. If disabled, users are only redirected after opting in with ?redirect=on.")
+	flags.StringSlice(ConfigTeeExcludeExts, defaultDoNotTeeExts, "Do not tee requests for URLs with these extensions to pkg.go.dev (comma separated).")
+	flags.StringSlice(ConfigTeeExcludePaths, nil, "Do not tee requests for these paths to pkg.go.dev in addition to /-/bot and /-/refresh (comma separated).")
+	flags.StringSlice(ConfigRedirectHosts, defaultRedirectHosts, "Hosts other than this server that redirects may send users to (comma separated). Redirects to other hosts are rejected with status 400.")
+	flags.String(ConfigDebugKey, "", "Secret allowing operators to use the /debug/ endpoints with the X-Debug-Key header.")
+	flags.StringSlice(ConfigDebugCIDRs, nil, "Allow requests from these CIDR blocks to use the /debug/ endpoints (comma separated).")
+	flags.StringSlice(ConfigSiteAuthUsers, nil, "Require HTTP basic authentication with one of these users, as name:password (comma separated), to use the site and the API. The health checks and the /debug/ endpoints are exempt. Responses are marked private to shared caches.")
+	flags.String(ConfigSiteAuthToken, "", "Require this shared token, sent in an Authorization: Bearer header, to use the site and the API. Basic authentication with site_auth_users is also accepted if set.")
+	flags.String(ConfigLogLevel, "info", "Log messages at this level or above: debug, info, warn or error.")
+	flags.String(ConfigLogFormat, "text", "Format of the log messages: text for logfmt or json for JSON objects with the level in the lvl field.")
+	flags.Bool(ConfigLLMsTxt, false, "Serve an index of the packages shown in search results with their synopses and documentation URLs for AI crawlers at /llms.txt, or as JSON with ?format=json.")
+	flags.Int(ConfigRenderCacheSize, 32<<20, "Maximum size in bytes of the in-memory cache of rendered package pages. Zero disables the cache.")
+	flags.String(ConfigRenderCacheStore, "memory", "Storage of rendered package pages: memory, or redis to share the pages between instances. With redis, render_cache_size bounds the local cache of recently used pages.")
+	flags.String(ConfigRenderCacheRedis, "", "URI of the Redis server of the redis render_cache_store. Empty uses db-server.")
+	flags.Duration(ConfigRenderCacheTTL, 24*time.Hour, "Time the pages of a package are kept in the redis render_cache_store after the last page is added. Zero keeps them until the package changes.")
+	flags.Int(ConfigMaxRenders, 16, "Maximum number of packages fetched and built concurrently for requests. Zero disables the limit.")
+	flags.Duration(ConfigRenderQueueWait, 2*time.Second, "Time a request waits for one of the max_renders slots before the server responds that it is busy.")
+	flags.Float64(ConfigCrawlHostRate, 0, "Maximum number of packages of each host fetched per minute for requests, in bursts of up to a minute of fetches. Further requests are served from the database or asked to try again shortly. The fetches in progress are limited by host_concurrency. Zero disables the limit.")
+	flags.StringSlice(ConfigCrawlHostBudgets, nil, "Rates of the hosts fetched for requests overriding crawl_host_rate, as host:rate (comma separated). Use to give reputable hosts a larger budget.")
 	flags.Duration(ConfigGithubInterval, 0, "Github updates crawler sleeps for this duration between fetches. Zero disables the crawler.")
-	flags.Duration(ConfigCrawlInterval, 0, "Package updater sleeps for this duration between package updates. Zero disables updates.")
+	flags.Duration(ConfigCrawlInterval, 0, "Package updater starts a cycle of package updates with this period, crawling new paths and packages due to be crawled until none are left. Zero disables updates.")
+	flags.Int(ConfigGraphCacheSize, 0, "Precompute the import graphs of this many of the most popular packages. Zero disables precomputed graphs.")
+	flags.Duration(ConfigGraphCacheInterval, 1*time.Hour, "Precompute the import graphs of the most popular packages with this period. Zero disables precomputed graphs.")
+	flags.Int(ConfigCrawlBatch, 1, "Number of packages crawled by each batch of package updates.")
+	flags.Int(ConfigCrawlConcurrency, 1, "Maximum number of packages of a batch of package updates crawled concurrently. The per-host limit of host_concurrency also applies.")
+	flags.Duration(ConfigCrawlBatchDelay, 0, "Package updater sleeps for this duration between the batches of a cycle. Zero uses crawl_interval.")
 	flags.Duration(ConfigDialTimeout, 5*time.Second, "Timeout for dialing an HTTP connection.")
 	flags.Duration(ConfigRequestTimeout, 20*time.Second, "Time out for roundtripping an HTTP request.")
+	flags.Int(ConfigHostConcurrency, 8, "Maximum number of concurrent requests to each VCS host. Further requests wait for a request to complete. Zero disables the limit.")
 	flags.String(ConfigDBServer, "redis://127.0.0.1:6379", "URI of Redis server.")
 	flags.Duration(ConfigDBIdleTimeout, 250*time.Second, "Close Redis connections after remaining idle for this duration.")
 	flags.Bool(ConfigDBLog, false, "Log database commands")
+	flags.Bool(ConfigDBGzip, false, "Compress stored package documents with gzip instead of snappy, trading CPU time for memory. Existing documents are rewritten on their next crawl.")
+	flags.StringSlice(ConfigDBReplicas, nil, "URIs of Redis read replicas (comma separated).")
+	flags.Bool(ConfigDBReadReplica, false, "Serve read only queries from the Redis read replicas. Crawls and refreshes always use the primary.")
 	flags.String(ConfigMemcacheAddr, "", "Address in the format host:port gddo uses to point to the memcache backend.")
+	flags.StringSlice(ConfigAllowedHosts, nil, "If set, only crawl packages from these VCS hosts (comma separated). Standard packages are always allowed.")
+	flags.StringSlice(ConfigInsecureHosts, nil, "Hosts of import paths whose go-import meta tags and repositories may be fetched over plain HTTP when HTTPS fails (comma separated). Use only for trusted internal hosts.")
+	flags.String(ConfigExcludeFile, "", "Never crawl, document or list the import paths matching the glob patterns in this file, one per line, and the directories and files below them, such as github.com/org/repo/experimental or **/vendor.")
+	flags.Duration(ConfigMetaCacheTTL, 1*time.Hour, "Cache the go-import meta tags of vanity import paths for this long, or less if the Cache-Control header of the page asks for it. Zero disables the cache.")
+	flags.Duration(ConfigMetaFailureTTL, 1*time.Minute, "Cache lookups of pages without go-import meta tags for this long. Failures to fetch the pages are not cached.")
+	flags.Duration(ConfigResolveCacheTTL, 1*time.Hour, "Cache the sources served by the /resolve/ API endpoint for this long, and the import paths it cannot resolve for meta_failure_ttl. Clients may cache the sources as long. Zero disables the cache.")
+	flags.Duration(ConfigNotFoundTTL, 10*time.Minute, "Serve packages not found by the last crawl as not found for this long without crawling again. Zero disables the cache.")
+	flags.Duration(ConfigRedirectTTL, 7*24*time.Hour, "Redirect requests for import paths with a different canonical import path for this long without crawling again. Zero disables the cache.")
+	flags.Duration(ConfigBuildPanicTTL, 5*time.Minute, "Serve an error page, or the documentation from the database if any, for packages whose documentation build panicked for this long without crawling again.")
+	flags.Bool(ConfigCachedOnly, false, "Serve only packages already in the database and never crawl on request. Refreshes require an API key.")
+	flags.String(ConfigLocalModule, "", "Read the packages of the module in this directory from the file system instead of version control services.")
+	flags.String(ConfigSumDB, "", "Verify the go.mod files of package versions against the checksum database at this URL, such as https://sum.golang.org, and show the result on the page. Disabled if empty.")
+	flags.String(ConfigSumDBKey, "sum.golang.org+033de0ae+Ac4zctda0e5eza+HJyk9SxEdh+s3Ko/bZNqV56MDQkJX6", "Verifier key of the checksum database set by sumdb.")
+	flags.String(ConfigWarmFile, "", "Warm the render cache at startup with the packages listed in this file, one import path per line.")
+	flags.String(ConfigOwnersFile, "", "Show the owners declared by this file, in the CODEOWNERS format with import path patterns such as example.com/team/, for packages whose repository has no CODEOWNERS file. Owners are updated when packages are crawled.")
+	flags.Int(ConfigWarmPopular, 0, "Warm the render cache at startup with this many of the most popular packages.")
+	flags.Int(ConfigWarmConcurrency, 4, "Maximum number of packages crawled and rendered concurrently when warming the render cache.")
 	flags.String(ConfigGAERemoteAPI, "", "Remoteapi endpoint for App Engine Search. Defaults to serviceproxy-dot-${project}.appspot.com.")
+	flags.String(ConfigContentSecurityPolicy, defaultContentSecurityPolicy, "Content-Security-Policy header of all responses. The templates do not use inline scripts or styles. Empty disables the header.")
+	flags.String(ConfigContentTypeOptions, "nosniff", "X-Content-Type-Options header of all responses. Empty disables the header.")
+	flags.String(ConfigReferrerPolicy, "strict-origin-when-cross-origin", "Referrer-Policy header of all responses. Empty disables the header.")
+	flags.String(ConfigHSTS, "", "Strict-Transport-Security header of all responses, such as max-age=31536000 on a server only reachable over HTTPS. Empty disables the header.")
 	flags.Float64(ConfigTraceSamplerFraction, 0.1, "Fraction of the requests sampled by the trace API.")
 	flags.Float64(ConfigTraceSamplerMaxQPS, 5, "Max number of requests sampled every second by the trace API.")
 
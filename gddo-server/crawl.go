@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"log"
 	"regexp"
+	"runtime/debug"
 	"strings"
 	"time"
 
@@ -47,11 +48,24 @@ func (s *server) publishCrawl(ctx context.Context, importPath string) {
 }
 
 // crawlDoc fetches the package documentation from the VCS and updates the database.
-func (s *server) crawlDoc(ctx context.Context, source string, importPath string, pdoc *doc.Package, hasSubdirs bool, nextCrawl time.Time) (*doc.Package, error) {
+func (s *server) crawlDoc(ctx context.Context, source string, importPath string, pdoc *doc.Package, hasSubdirs bool, nextCrawl time.Time) (_ *doc.Package, err error) {
 	message := []interface{}{source}
+	s.crawls.start(source, importPath)
 	defer func() {
 		message = append(message, importPath)
 		log.Println(message...)
+		s.crawls.finish(importPath, err)
+		if e, ok := err.(*doc.PanicError); ok {
+			s.buildPanicked(ctx, e)
+		}
+	}()
+	defer func() {
+		// Convert panics outside of the documentation builder too, so
+		// that a crawl started by a request does not crash the server.
+		if v := recover(); v != nil {
+			err = &doc.PanicError{ImportPath: importPath, Value: v, Stack: debug.Stack()}
+			message = append(message, "ERROR:", err)
+		}
 	}()
 
 	if !nextCrawl.IsZero() {
@@ -68,14 +82,16 @@ func (s *server) crawlDoc(ctx context.Context, source string, importPath string,
 	}
 
 	start := time.Now()
-	var err error
 	if strings.HasPrefix(importPath, "code.google.com/p/go.") {
 		// Old import path for Go sub-repository.
 		pdoc = nil
 		err = gosrc.NotFoundError{Message: "old Go sub-repo", Redirect: "golang.org/x/" + importPath[len("code.google.com/p/go."):]}
-	} else if blocked, e := s.db.IsBlocked(importPath); blocked && e == nil {
+	} else if blocked, e := s.db.Primary().IsBlocked(importPath); blocked && e == nil {
 		pdoc = nil
 		err = gosrc.NotFoundError{Message: "blocked."}
+	} else if gone, _, e := s.db.Primary().Gone(importPath); gone && e == nil {
+		pdoc = nil
+		err = gosrc.NotFoundError{Message: "gone."}
 	} else if testdataPat.MatchString(importPath) {
 		pdoc = nil
 		err = gosrc.NotFoundError{Message: "testdata."}
@@ -92,6 +108,43 @@ func (s *server) crawlDoc(ctx context.Context, source string, importPath string,
 		} else if _, ok := err.(gosrc.NotModifiedError); !ok {
 			pdoc = pdocNew
 		}
+		if err == nil && pdoc.ProjectRoot != "" && pdoc.ProjectRoot != importPath {
+			// Packages of a project with a canonical import path found by a
+			// crawl of the project root are redirected to the same path.
+			if target, e := s.db.GetRedirect(pdoc.ProjectRoot); e != nil {
+				log.Printf("ERROR db.GetRedirect(%q): %v", pdoc.ProjectRoot, e)
+			} else if target != "" {
+				err = gosrc.NotFoundError{Message: "not at canonical import path", Redirect: target + importPath[len(pdoc.ProjectRoot):]}
+				pdoc = nil
+			}
+		}
+		if err == nil {
+			rules := pdoc.CodeOwners
+			if pdoc.ProjectRoot != "" && pdoc.ProjectRoot != importPath {
+				// The go.mod and CODEOWNERS files are usually in the
+				// project root directory.
+				if root, _, err := s.db.GetDoc(ctx, pdoc.ProjectRoot); err != nil {
+					log.Printf("ERROR db.GetDoc(%q): %v", pdoc.ProjectRoot, err)
+				} else if root != nil {
+					if pdoc.GoModVersion == "" {
+						pdoc.GoModVersion = root.GoModVersion
+					}
+					if pdoc.ModulePath == "" {
+						pdoc.Deprecated = root.Deprecated
+						pdoc.Retractions = root.Retractions
+					}
+					rules = root.CodeOwners
+				}
+			}
+			pdoc.Owners = s.packageOwners(pdoc, rules)
+		}
+		if err == nil && pdoc.ProjectRoot != "" && s.v.GetBool(ConfigLatestVersion) {
+			tags, err := gosrc.GetTags(ctx, s.httpClient, importPath)
+			if err != nil && !gosrc.IsNotFound(err) {
+				message = append(message, "tags:", err)
+			}
+			pdoc.LatestVersion = gosrc.LatestVersion(unretractedTags(tags, pdoc.Retractions))
+		}
 	}
 
 	maxAge := s.v.GetDuration(ConfigMaxAge)
@@ -109,6 +162,7 @@ func (s *server) crawlDoc(ctx context.Context, source string, importPath string,
 		if err := s.put(ctx, pdoc, nextCrawl); err != nil {
 			log.Println(err)
 		}
+		s.addCrawlRecord(importPath, start)
 		s.publishCrawl(ctx, importPath)
 		return pdoc, nil
 	} else if e, ok := err.(gosrc.NotModifiedError); ok {
@@ -121,6 +175,8 @@ func (s *server) crawlDoc(ctx context.Context, source string, importPath string,
 			if err := s.db.Put(ctx, pdoc, nextCrawl, false); err != nil {
 				log.Printf("ERROR db.Put(%q): %v", importPath, err)
 			}
+			s.renderCache.invalidate(importPath)
+			s.graphsChanged()
 		} else {
 			// Touch the package without updating and move on to next one.
 			message = append(message, "touch")
@@ -128,6 +184,7 @@ func (s *server) crawlDoc(ctx context.Context, source string, importPath string,
 				log.Printf("ERROR db.SetNextCrawl(%q): %v", importPath, err)
 			}
 		}
+		s.addCrawlRecord(importPath, start)
 		s.publishCrawl(ctx, importPath)
 		return pdoc, nil
 	} else if e, ok := err.(gosrc.NotFoundError); ok {
@@ -135,13 +192,64 @@ func (s *server) crawlDoc(ctx context.Context, source string, importPath string,
 		if err := s.db.Delete(ctx, importPath); err != nil {
 			log.Printf("ERROR db.Delete(%q): %v", importPath, err)
 		}
+		s.renderCache.invalidate(importPath)
+		if e.Redirect == "" {
+			s.putNotFound(importPath)
+		} else {
+			s.putRedirect(importPath, e.Redirect)
+		}
 		return nil, e
 	} else {
 		message = append(message, "ERROR:", err)
+		if _, ok := err.(*gosrc.RemoteError); ok && pdoc == nil {
+			s.putNotFound(importPath)
+		}
 		return nil, err
 	}
 }
 
+// unretractedTags returns the tags that are not versions retracted by the
+// go.mod file of the module.
+func unretractedTags(tags []string, retractions []doc.Retraction) []string {
+	if len(retractions) == 0 {
+		return tags
+	}
+	var kept []string
+	for _, tag := range tags {
+		if doc.FindRetraction(retractions, tag) == nil {
+			kept = append(kept, tag)
+		}
+	}
+	return kept
+}
+
+// putNotFound records in the database that importPath could not be fetched,
+// so that requests for it are served as not found until the record expires.
+func (s *server) putNotFound(importPath string) {
+	ttl := s.v.GetDuration(ConfigNotFoundTTL)
+	if err := s.db.PutNotFound(importPath, ttl); err != nil {
+		log.Printf("ERROR db.PutNotFound(%q): %v", importPath, err)
+	}
+	s.crawls.retry(importPath, time.Now().Add(ttl))
+}
+
+// putRedirect records in the database that the canonical import path of
+// importPath is target, so that requests for importPath are redirected
+// without crawling until the record expires.
+func (s *server) putRedirect(importPath, target string) {
+	if err := s.db.PutRedirect(importPath, target, s.v.GetDuration(ConfigRedirectTTL)); err != nil {
+		log.Printf("ERROR db.PutRedirect(%q): %v", importPath, err)
+	}
+}
+
+// addCrawlRecord adds the crawl of importPath started at start to the crawl
+// history of the package.
+func (s *server) addCrawlRecord(importPath string, start time.Time) {
+	if err := s.db.AddCrawlRecord(importPath, start, time.Since(start)); err != nil {
+		log.Printf("ERROR db.AddCrawlRecord(%q): %v", importPath, err)
+	}
+}
+
 func (s *server) put(ctx context.Context, pdoc *doc.Package, nextCrawl time.Time) error {
 	if pdoc.Status == gosrc.NoRecentCommits &&
 		s.isActivePkg(pdoc.ImportPath, gosrc.NoRecentCommits) {
@@ -150,6 +258,8 @@ func (s *server) put(ctx context.Context, pdoc *doc.Package, nextCrawl time.Time
 	if err := s.db.Put(ctx, pdoc, nextCrawl, false); err != nil {
 		return fmt.Errorf("ERROR db.Put(%q): %v", pdoc.ImportPath, err)
 	}
+	s.renderCache.invalidate(pdoc.ImportPath)
+	s.graphsChanged()
 	return nil
 }
 
@@ -161,7 +271,7 @@ func (s *server) isActivePkg(pkg string, status gosrc.DirectoryStatus) bool {
 		return true
 	case gosrc.NoRecentCommits:
 		// It should be inactive only if it has no imports as well.
-		n, err := s.db.ImporterCount(pkg)
+		n, err := s.db.Primary().ImporterCount(pkg)
 		if err != nil {
 			log.Printf("ERROR db.ImporterCount(%q): %v", pkg, err)
 		}
@@ -0,0 +1,31 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/golang/gddo/doc"
+	"github.com/golang/gddo/gosrc"
+)
+
+func TestUnretractedTags(t *testing.T) {
+	tags := []string{"v1.0.0", "v1.1.0", "v1.1.1", "v1.2.0", "release"}
+	retractions := []doc.Retraction{
+		{Low: "v1.1.0", High: "v1.1.1"},
+		{Low: "v1.2.0", High: "v1.2.0", Rationale: "Published accidentally."},
+	}
+	got := unretractedTags(tags, retractions)
+	if diff := cmp.Diff([]string{"v1.0.0", "release"}, got); diff != "" {
+		t.Errorf("unretractedTags() mismatch (-want +got):\n%s", diff)
+	}
+	if v := gosrc.LatestVersion(got); v != "v1.0.0" {
+		t.Errorf("latest unretracted version = %q, want v1.0.0", v)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errHostBusy = errors.New("too many packages of the host were fetched recently")
+
+// maxBudgetHosts is the number of hosts above which the state of the idle
+// hosts is discarded.
+const maxBudgetHosts = 1000
+
+// hostBudget is the budget of the crawls of a host triggered by requests.
+// The crawls in progress are limited by the per-host limit of the HTTP
+// client.
+type hostBudget struct {
+	rate float64 // crawls started per minute; zero is unlimited
+}
+
+// parseHostBudgets parses the budgets of hosts specified as host:rate.
+func parseHostBudgets(specs []string) (map[string]hostBudget, error) {
+	budgets := make(map[string]hostBudget)
+	for _, spec := range specs {
+		parts := strings.Split(spec, ":")
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid host budget %q, want host:rate", spec)
+		}
+		rate, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("invalid rate for host %q: %q", parts[0], parts[1])
+		}
+		budgets[parts[0]] = hostBudget{rate: rate}
+	}
+	return budgets, nil
+}
+
+// hostCrawls is the state of the crawls of a host. The rate is limited with
+// a token bucket holding up to a minute of crawls.
+type hostCrawls struct {
+	tokens   float64
+	updated  time.Time
+	rejected int64
+}
+
+// crawlBudget limits the rate of the crawls of each host triggered by
+// requests, so that requests for many packages of a host do not overload it.
+// Hosts with a good reputation, such as the large code hosting services, can
+// be given a larger budget than the default.
+type crawlBudget struct {
+	def   hostBudget
+	hosts map[string]hostBudget
+
+	mu       sync.Mutex
+	state    map[string]*hostCrawls
+	rejected int64
+}
+
+func newCrawlBudget(def hostBudget, hosts map[string]hostBudget) *crawlBudget {
+	return &crawlBudget{def: def, hosts: hosts, state: make(map[string]*hostCrawls)}
+}
+
+func (b *crawlBudget) budget(host string) hostBudget {
+	if hb, ok := b.hosts[host]; ok {
+		return hb
+	}
+	return b.def
+}
+
+// acquire starts a crawl of the package with the given import path at time
+// now. It returns errHostBusy without waiting if the budget of the host is
+// exhausted.
+func (b *crawlBudget) acquire(importPath string, now time.Time) error {
+	host := sweepHost(importPath)
+	hb := b.budget(host)
+	if hb.rate <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	hc, ok := b.state[host]
+	if !ok {
+		if len(b.state) >= maxBudgetHosts {
+			b.prune(now)
+		}
+		hc = &hostCrawls{tokens: hb.rate, updated: now}
+		b.state[host] = hc
+	}
+	hc.tokens += now.Sub(hc.updated).Minutes() * hb.rate
+	if hc.tokens > hb.rate {
+		hc.tokens = hb.rate
+	}
+	hc.updated = now
+	if hc.tokens < 1 {
+		hc.rejected++
+		b.rejected++
+		return errHostBusy
+	}
+	hc.tokens--
+	return nil
+}
+
+// prune discards the state of the hosts with a full token bucket at time now.
+func (b *crawlBudget) prune(now time.Time) {
+	for host, hc := range b.state {
+		if hb := b.budget(host); hc.tokens+now.Sub(hc.updated).Minutes()*hb.rate < hb.rate {
+			continue
+		}
+		delete(b.state, host)
+	}
+}
+
+// crawlBudgetStats holds the crawls rejected because the budget of the host
+// was exhausted.
+type crawlBudgetStats struct {
+	Rejected int64                      `json:"rejected"`
+	Hosts    map[string]hostBudgetStats `json:"hosts,omitempty"`
+}
+
+type hostBudgetStats struct {
+	Rejected int64 `json:"rejected"`
+}
+
+func (b *crawlBudget) stats() crawlBudgetStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := crawlBudgetStats{Rejected: b.rejected}
+	for host, hc := range b.state {
+		if hc.rejected == 0 {
+			continue
+		}
+		if stats.Hosts == nil {
+			stats.Hosts = make(map[string]hostBudgetStats)
+		}
+		stats.Hosts[host] = hostBudgetStats{Rejected: hc.rejected}
+	}
+	return stats
+}
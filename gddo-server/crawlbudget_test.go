@@ -0,0 +1,142 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/viper"
+)
+
+func TestParseHostBudgets(t *testing.T) {
+	got, err := parseHostBudgets([]string{"github.com:600", "example.com:0.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]hostBudget{
+		"github.com":  {rate: 600},
+		"example.com": {rate: 0.5},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(hostBudget{})); diff != "" {
+		t.Errorf("parseHostBudgets mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, spec := range []string{"github.com", ":1", "github.com:x", "github.com:-1", "github.com:1:1"} {
+		if _, err := parseHostBudgets([]string{spec}); err == nil {
+			t.Errorf("parseHostBudgets(%q) returned no error", spec)
+		}
+	}
+}
+
+func TestCrawlBudgetRate(t *testing.T) {
+	now := time.Now()
+	b := newCrawlBudget(hostBudget{rate: 2}, map[string]hostBudget{"github.com": {}})
+
+	for i := 0; i < 2; i++ {
+		if err := b.acquire("example.com/a", now); err != nil {
+			t.Fatalf("acquire %d returned %v", i, err)
+		}
+	}
+	if err := b.acquire("example.com/a", now); err != errHostBusy {
+		t.Errorf("acquire over the rate returned %v, want %v", err, errHostBusy)
+	}
+	if err := b.acquire("example.org/a", now); err != nil {
+		t.Errorf("acquire for another host returned %v", err)
+	}
+	if err := b.acquire("example.com/a", now.Add(30*time.Second)); err != nil {
+		t.Errorf("acquire after the bucket refilled returned %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := b.acquire("github.com/a/b", now); err != nil {
+			t.Errorf("acquire for a host without limits returned %v", err)
+		}
+	}
+
+	want := crawlBudgetStats{
+		Rejected: 1,
+		Hosts:    map[string]hostBudgetStats{"example.com": {Rejected: 1}},
+	}
+	if diff := cmp.Diff(want, b.stats()); diff != "" {
+		t.Errorf("stats mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAcquireFetchKeepsBudget(t *testing.T) {
+	s := &server{
+		renders:     newRenderLimiter(1, time.Millisecond),
+		crawlBudget: newCrawlBudget(hostBudget{rate: 1}, nil),
+	}
+	ctx := context.Background()
+
+	// A request giving up while waiting for a slot does not spend the
+	// budget of the host.
+	if err := s.renders.acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.acquireFetch(ctx, "example.com/a"); err == nil {
+		t.Fatal("acquireFetch with no slot returned no error")
+	}
+	s.renders.release()
+
+	release, err := s.acquireFetch(ctx, "example.com/a")
+	if err != nil {
+		t.Fatalf("acquireFetch returned %v", err)
+	}
+	release()
+	if _, err := s.acquireFetch(ctx, "example.com/b"); err == nil {
+		t.Error("acquireFetch over the rate returned no error")
+	}
+	if err := s.renders.acquire(ctx); err != nil {
+		t.Errorf("slot not released after a rejected fetch: %v", err)
+	}
+}
+
+func TestRefreshOverBudget(t *testing.T) {
+	db := newTestDB(t)
+	defer closeTestDB(db)
+	if err := db.PutNotFound("example.com/b", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// The server has no HTTP client, so crawling the package would fail.
+	s := &server{
+		v:           viper.New(),
+		db:          db,
+		redirects:   newRedirectTargets(nil),
+		renders:     newRenderLimiter(1, time.Millisecond),
+		crawlBudget: newCrawlBudget(hostBudget{rate: 1}, nil),
+	}
+	if err := s.crawlBudget.acquire("example.com/a", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("POST", "/-/refresh", strings.NewReader("path=example.com/b"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := req.ParseForm(); err != nil {
+		t.Fatal(err)
+	}
+	resp := httptest.NewRecorder()
+	if err := s.serveRefresh(resp, req); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != http.StatusFound || resp.Header().Get("Location") != "/example.com/b" {
+		t.Errorf("status %d, location %q, want %d, %q", resp.Code, resp.Header().Get("Location"), http.StatusFound, "/example.com/b")
+	}
+	req = &http.Request{Header: http.Header{"Cookie": {strings.Split(resp.Header().Get("Set-Cookie"), ";")[0]}}}
+	want := []flashMessage{{ID: "refresh", Args: []string{errorText(errHostBusy)}}}
+	if diff := cmp.Diff(want, getFlashMessages(httptest.NewRecorder(), req)); diff != "" {
+		t.Errorf("flash messages mismatch (-want +got):\n%s", diff)
+	}
+	if notFound, err := db.IsNotFound("example.com/b"); err != nil || !notFound {
+		t.Errorf("IsNotFound() = %v, %v after a rejected refresh, want true", notFound, err)
+	}
+}
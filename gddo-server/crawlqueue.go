@@ -0,0 +1,283 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/gddo/database"
+	"github.com/golang/gddo/doc"
+	"github.com/golang/gddo/httputil"
+)
+
+// maxCrawlFailures is the number of recent crawl failures remembered for
+// /debug/crawl-queue.
+const maxCrawlFailures = 100
+
+// activeCrawl is a crawl in progress.
+type activeCrawl struct {
+	ImportPath string    `json:"import_path"`
+	Source     string    `json:"source"`
+	Started    time.Time `json:"started"`
+}
+
+// crawlFailure is a crawl which returned an error.
+type crawlFailure struct {
+	ImportPath string    `json:"import_path"`
+	Source     string    `json:"source"`
+	Time       time.Time `json:"time"`
+	Error      string    `json:"error"`
+
+	// Panic is set when building the documentation panicked.
+	Panic bool `json:"panic,omitempty"`
+
+	// Time before which the package is not crawled again, if known.
+	RetryAt *time.Time `json:"retry_at,omitempty"`
+}
+
+// crawlTracker records the crawls in progress and the recent crawl failures
+// of this instance of the server. The zero value is ready to use.
+type crawlTracker struct {
+	mu       sync.Mutex
+	active   map[string]activeCrawl // by import path
+	failures []crawlFailure         // most recent last
+
+	// Time before which packages whose build panicked are not crawled
+	// again, by import path. Unlike failures, which hold only the most
+	// recent failures, entries are kept until they expire.
+	panics map[string]time.Time
+}
+
+// start records the start of a crawl of importPath.
+func (t *crawlTracker) start(source, importPath string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.active == nil {
+		t.active = make(map[string]activeCrawl)
+	}
+	t.active[importPath] = activeCrawl{ImportPath: importPath, Source: source, Started: time.Now()}
+}
+
+// finish records the end of the crawl of importPath and the error returned
+// by the crawl, if any.
+func (t *crawlTracker) finish(importPath string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, ok := t.active[importPath]
+	delete(t.active, importPath)
+	if err == nil || !ok {
+		return
+	}
+	if len(t.failures) >= maxCrawlFailures {
+		t.failures = append(t.failures[:0], t.failures[1:]...)
+	}
+	_, panicked := err.(*doc.PanicError)
+	t.failures = append(t.failures, crawlFailure{
+		ImportPath: importPath,
+		Source:     c.Source,
+		Time:       time.Now(),
+		Error:      err.Error(),
+		Panic:      panicked,
+	})
+}
+
+// retry records the time before which a failed crawl of importPath is not
+// retried.
+func (t *crawlTracker) retry(importPath string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := len(t.failures) - 1; i >= 0; i-- {
+		if t.failures[i].ImportPath == importPath {
+			t.failures[i].RetryAt = &at
+			return
+		}
+	}
+}
+
+// setPanicked records that building the documentation of importPath panicked
+// and that the package is not to be crawled again before at.
+func (t *crawlTracker) setPanicked(importPath string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	for p, until := range t.panics {
+		if !until.After(now) {
+			delete(t.panics, p)
+		}
+	}
+	if t.panics == nil {
+		t.panics = make(map[string]time.Time)
+	}
+	t.panics[importPath] = at
+	for i := len(t.failures) - 1; i >= 0; i-- {
+		if t.failures[i].ImportPath == importPath {
+			t.failures[i].RetryAt = &at
+			break
+		}
+	}
+}
+
+// backingOff reports whether importPath is being crawled, its build recently
+// panicked or its most recent failed crawl is not to be retried before now.
+func (t *crawlTracker) backingOff(importPath string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.active[importPath]; ok {
+		return true
+	}
+	if t.panickedLocked(importPath, now) {
+		return true
+	}
+	for i := len(t.failures) - 1; i >= 0; i-- {
+		if f := t.failures[i]; f.ImportPath == importPath {
+			return f.RetryAt != nil && f.RetryAt.After(now)
+		}
+	}
+	return false
+}
+
+// panicked reports whether building the documentation of importPath
+// panicked and the package is not to be crawled again before now.
+func (t *crawlTracker) panicked(importPath string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.panickedLocked(importPath, now)
+}
+
+func (t *crawlTracker) panickedLocked(importPath string, now time.Time) bool {
+	at, ok := t.panics[importPath]
+	if ok && !at.After(now) {
+		delete(t.panics, importPath)
+		return false
+	}
+	return ok
+}
+
+// snapshot returns the crawls in progress, oldest first, and the recent
+// failures, most recent first.
+func (t *crawlTracker) snapshot() ([]activeCrawl, []crawlFailure) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	active := make([]activeCrawl, 0, len(t.active))
+	for _, c := range t.active {
+		active = append(active, c)
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].Started.Before(active[j].Started) })
+	failures := make([]crawlFailure, len(t.failures))
+	for i, f := range t.failures {
+		failures[len(failures)-1-i] = f
+	}
+	return active, failures
+}
+
+// debugAccess holds the clients allowed to use the /debug/ endpoints.
+type debugAccess struct {
+	key  string // value of the X-Debug-Key header
+	nets []*net.IPNet
+}
+
+func parseDebugAccess(key string, cidrs []string) (*debugAccess, error) {
+	a := &debugAccess{key: key}
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid debug CIDR %q: %v", cidr, err)
+		}
+		a.nets = append(a.nets, n)
+	}
+	return a, nil
+}
+
+// allowed reports whether the request is from a client allowed to use the
+// debug endpoints. No client is allowed if neither a key nor CIDR blocks are
+// configured.
+func (a *debugAccess) allowed(req *http.Request) bool {
+	if a == nil {
+		return false
+	}
+	if k := req.Header.Get("X-Debug-Key"); a.key != "" && k != "" {
+		return subtle.ConstantTimeCompare([]byte(k), []byte(a.key)) == 1
+	}
+	ip := remoteIP(req)
+	if ip == nil {
+		return false
+	}
+	for _, n := range a.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// serveCrawlQueue serves the state of the crawler for operators: the
+// packages scheduled for crawling, the crawls in progress and the recent
+// failures of this instance, and the requests waiting for the host limits.
+func (s *server) serveCrawlQueue(resp http.ResponseWriter, req *http.Request) error {
+	if !s.debugAccess.allowed(req) {
+		return &httpError{status: http.StatusForbidden}
+	}
+	schedule, err := s.db.GetCrawlSchedule(100)
+	if err != nil {
+		return err
+	}
+	active, failures := s.crawls.snapshot()
+	data := struct {
+		CrawlInterval string                        `json:"crawl_interval"`
+		Schedule      *database.CrawlSchedule       `json:"schedule"`
+		Active        []activeCrawl                 `json:"active"`
+		Failures      []crawlFailure                `json:"failures"`
+		Fetches       map[string]httputil.HostStats `json:"fetches"`
+		Sweeper       sweeperStats                  `json:"sweeper"`
+	}{
+		s.v.GetDuration(ConfigCrawlInterval).String(),
+		schedule,
+		active,
+		failures,
+		s.hostLimits.Stats(),
+		s.sweeper.snapshot(),
+	}
+	resp.Header().Set("Content-Type", jsonMIMEType)
+	enc := json.NewEncoder(resp)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&data)
+}
+
+// serveCrawlHistory serves the recent crawls of the package named by the
+// path parameter for operators, with the duration of each crawl and the size
+// of the documentation it stored.
+func (s *server) serveCrawlHistory(resp http.ResponseWriter, req *http.Request) error {
+	if !s.debugAccess.allowed(req) {
+		return &httpError{status: http.StatusForbidden}
+	}
+	importPath := req.Form.Get("path")
+	if importPath == "" {
+		return &httpError{status: http.StatusBadRequest}
+	}
+	history, err := s.db.CrawlHistory(importPath)
+	if err != nil {
+		return err
+	}
+	data := struct {
+		Path   string                 `json:"path"`
+		Crawls []database.CrawlRecord `json:"crawls"`
+	}{
+		importPath,
+		history,
+	}
+	resp.Header().Set("Content-Type", jsonMIMEType)
+	enc := json.NewEncoder(resp)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&data)
+}
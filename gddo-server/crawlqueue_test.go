@@ -0,0 +1,99 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCrawlTracker(t *testing.T) {
+	var tr crawlTracker
+	tr.start("crawl", "example.com/a")
+	tr.start("web  ", "example.com/b")
+	tr.finish("example.com/a", errors.New("timeout"))
+
+	active, failures := tr.snapshot()
+	if len(active) != 1 || active[0].ImportPath != "example.com/b" {
+		t.Errorf("active = %+v, want example.com/b", active)
+	}
+	if len(failures) != 1 || failures[0].ImportPath != "example.com/a" || failures[0].Error != "timeout" || failures[0].Source != "crawl" {
+		t.Fatalf("failures = %+v, want example.com/a timeout", failures)
+	}
+
+	retry := time.Now().Add(time.Hour)
+	tr.retry("example.com/a", retry)
+	tr.finish("example.com/b", nil)
+	active, failures = tr.snapshot()
+	if len(active) != 0 {
+		t.Errorf("active after finish = %+v, want none", active)
+	}
+	if failures[0].RetryAt == nil || !failures[0].RetryAt.Equal(retry) {
+		t.Errorf("RetryAt = %v, want %v", failures[0].RetryAt, retry)
+	}
+	if !tr.backingOff("example.com/a", time.Now()) {
+		t.Errorf("backingOff(example.com/a) = false before the retry time, want true")
+	}
+	if tr.backingOff("example.com/a", retry.Add(time.Second)) {
+		t.Errorf("backingOff(example.com/a) = true after the retry time, want false")
+	}
+	tr.start("crawl", "example.com/d")
+	if !tr.backingOff("example.com/d", time.Now()) {
+		t.Errorf("backingOff(example.com/d) = false during the crawl, want true")
+	}
+	tr.finish("example.com/d", nil)
+
+	for i := 0; i < maxCrawlFailures+1; i++ {
+		tr.start("crawl", "example.com/c")
+		tr.finish("example.com/c", errors.New("not found"))
+	}
+	if _, failures := tr.snapshot(); len(failures) != maxCrawlFailures {
+		t.Errorf("len(failures) = %d, want %d", len(failures), maxCrawlFailures)
+	}
+}
+
+func TestDebugAccess(t *testing.T) {
+	a, err := parseDebugAccess("secret", []string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		remoteAddr string
+		key        string
+		want       bool
+	}{
+		{"10.1.2.3:1234", "", true},
+		{"192.168.1.1:1234", "", false},
+		{"192.168.1.1:1234", "secret", true},
+		{"10.1.2.3:1234", "wrong", false},
+		{"10.1.2.3, 192.168.1.1", "", false},
+	} {
+		req := httptest.NewRequest("GET", "/debug/crawl-queue", nil)
+		req.RemoteAddr = tt.remoteAddr
+		if tt.key != "" {
+			req.Header.Set("X-Debug-Key", tt.key)
+		}
+		if got := a.allowed(req); got != tt.want {
+			t.Errorf("allowed(%s, key %q) = %v, want %v", tt.remoteAddr, tt.key, got, tt.want)
+		}
+	}
+
+	a, err = parseDebugAccess("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("GET", "/debug/crawl-queue", nil)
+	req.Header.Set("X-Debug-Key", "")
+	if a.allowed(req) {
+		t.Error("allowed with no key or CIDR configured")
+	}
+	if _, err := parseDebugAccess("", []string{"bogus"}); err == nil {
+		t.Error("parseDebugAccess(bogus) returned nil error")
+	}
+}
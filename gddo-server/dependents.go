@@ -0,0 +1,49 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// versionPredicate returns a function reporting whether a module version
+// satisfies the constraint s. The constraint is a semantic version optionally
+// preceded by one of the operators =, !=, <, <=, > and >=. The empty
+// constraint is satisfied by all versions.
+func versionPredicate(s string) (func(version string) bool, error) {
+	if s == "" {
+		return func(string) bool { return true }, nil
+	}
+	op := strings.TrimRight(s, "v0123456789.-+abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	want := s[len(op):]
+	if !semver.IsValid(want) {
+		return nil, errors.New("invalid version in constraint " + s)
+	}
+	var ok func(c int) bool
+	switch op {
+	case "", "=":
+		ok = func(c int) bool { return c == 0 }
+	case "!=":
+		ok = func(c int) bool { return c != 0 }
+	case "<":
+		ok = func(c int) bool { return c < 0 }
+	case "<=":
+		ok = func(c int) bool { return c <= 0 }
+	case ">":
+		ok = func(c int) bool { return c > 0 }
+	case ">=":
+		ok = func(c int) bool { return c >= 0 }
+	default:
+		return nil, errors.New("invalid operator in constraint " + s)
+	}
+	return func(version string) bool {
+		return semver.IsValid(version) && ok(semver.Compare(version, want))
+	}, nil
+}
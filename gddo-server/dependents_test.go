@@ -0,0 +1,45 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import "testing"
+
+func TestVersionPredicate(t *testing.T) {
+	for _, tt := range []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"", "v1.0.0", true},
+		{"", "master", true},
+		{"v1.2.0", "v1.2.0", true},
+		{"=v1.2.0", "v1.2.1", false},
+		{"!=v1.2.0", "v1.2.1", true},
+		{"<v1.2.0", "v1.1.9", true},
+		{"<v1.2.0", "v1.2.0", false},
+		{"<=v1.2.0", "v1.2.0", true},
+		{">v1.2.0", "v1.10.0", true},
+		{">=v1.2.0", "v1.2.0-pre", false},
+		{">=v1.2.0", "v2.0.0+incompatible", true},
+		{">=v1.2.0", "master", false},
+	} {
+		match, err := versionPredicate(tt.constraint)
+		if err != nil {
+			t.Errorf("versionPredicate(%q) returned error %v", tt.constraint, err)
+			continue
+		}
+		if got := match(tt.version); got != tt.want {
+			t.Errorf("versionPredicate(%q)(%q) = %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+
+	for _, constraint := range []string{"~v1.2.0", ">=1.2.0", "=>v1.2.0", ">="} {
+		if _, err := versionPredicate(constraint); err == nil {
+			t.Errorf("versionPredicate(%q) returned nil error", constraint)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"sort"
+
+	"github.com/golang/gddo/database"
+	"github.com/golang/gddo/doc"
+	"github.com/golang/gddo/gosrc"
+)
+
+// dependency is a package imported by the package shown by the imports view.
+type dependency struct {
+	Path     string
+	Synopsis string
+
+	// Whether the package is in the database. The synopsis of a package not
+	// in the database is unknown.
+	Indexed bool
+}
+
+// directDependencies returns the packages in imports, ordered by path, with
+// their synopses from synopses, the stored synopses by import path.
+func directDependencies(imports []string, synopses map[string]string) []dependency {
+	var deps []dependency
+	for _, p := range imports {
+		if gosrc.IsExcludedPath(p) {
+			continue
+		}
+		d := dependency{Path: p}
+		if p == "C" {
+			d.Synopsis, d.Indexed = database.CSynopsis, true
+		} else {
+			d.Synopsis, d.Indexed = synopses[p]
+		}
+		deps = append(deps, d)
+	}
+	sort.Slice(deps, func(i, j int) bool { return deps[i].Path < deps[j].Path })
+	return deps
+}
+
+// dependencies returns the direct dependencies of pdoc. Dependencies not in
+// the database are not crawled here: database.Put queues them when the
+// package itself is crawled.
+func (s *server) dependencies(pdoc *doc.Package) ([]dependency, error) {
+	synopses, err := s.db.Synopses(pdoc.Imports)
+	if err != nil {
+		return nil, err
+	}
+	return directDependencies(pdoc.Imports, synopses), nil
+}
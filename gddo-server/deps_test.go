@@ -0,0 +1,66 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/viper"
+
+	"github.com/golang/gddo/database"
+	"github.com/golang/gddo/doc"
+	"github.com/golang/gddo/httputil"
+)
+
+func TestDirectDependencies(t *testing.T) {
+	imports := []string{"github.com/bob/new", "fmt", "C", "github.com/alice/old"}
+	synopses := map[string]string{
+		"fmt":                  "Package fmt implements formatted I/O.",
+		"github.com/alice/old": "",
+	}
+	got := directDependencies(imports, synopses)
+	want := []dependency{
+		{Path: "C", Synopsis: database.CSynopsis, Indexed: true},
+		{Path: "fmt", Synopsis: "Package fmt implements formatted I/O.", Indexed: true},
+		{Path: "github.com/alice/old", Indexed: true},
+		{Path: "github.com/bob/new"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("directDependencies() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestImportsTemplate(t *testing.T) {
+	templates, err := parseTemplates("assets", &httputil.CacheBusters{}, viper.New(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdoc := &doc.Package{ImportPath: "github.com/acme/p", ProjectRoot: "github.com/acme/p", Name: "p"}
+	var buf strings.Builder
+	err = templates["imports.html"].(*template.Template).ExecuteTemplate(&buf, "Body", map[string]interface{}{
+		"pdoc": newTDoc(viper.New(), pdoc),
+		"deps": []dependency{
+			{Path: "fmt", Synopsis: "Package fmt implements formatted I/O.", Indexed: true},
+			{Path: "github.com/bob/new"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		`<a href="/fmt">fmt</a></td><td>Package fmt implements formatted I/O.</td>`,
+		`<a href="/github.com/bob/new">github.com/bob/new</a></td><td><span class="text-muted">not indexed</span></td>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("imports page does not contain %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/golang/gddo/doc"
+)
+
+// maxEmbedDepth limits how many packages promoteImported follows embedded
+// types through.
+const maxEmbedDepth = 4
+
+// embedResolver finds the methods and fields promoted from embedded types
+// declared in other packages, using the documentation stored in the
+// database for those packages.
+type embedResolver struct {
+	ctx  context.Context
+	s    *server
+	pkgs map[string]*doc.Package
+}
+
+func (r *embedResolver) lookup(importPath, name string) (*doc.Package, *doc.Type) {
+	pdoc, ok := r.pkgs[importPath]
+	if !ok {
+		var err error
+		pdoc, _, err = r.s.db.GetDoc(r.ctx, importPath)
+		if err != nil {
+			log.Printf("ERROR db.GetDoc(%q): %v", importPath, err)
+		}
+		r.pkgs[importPath] = pdoc
+	}
+	if pdoc == nil {
+		return nil, nil
+	}
+	for _, t := range pdoc.Types {
+		if t.Name == name {
+			return pdoc, t
+		}
+	}
+	return nil, nil
+}
+
+// promoteImported adds to the types of pdoc the methods and fields promoted
+// from embedded types declared in other packages.
+func (s *server) promoteImported(ctx context.Context, pdoc *doc.Package) {
+	r := &embedResolver{ctx: ctx, s: s, pkgs: make(map[string]*doc.Package)}
+	for _, t := range pdoc.Types {
+		if len(t.Embedded) == 0 {
+			continue
+		}
+		names := make(map[string]bool)
+		for _, fs := range [][]*doc.Func{t.Methods, t.PromotedMethods} {
+			for _, f := range fs {
+				names[f.Name] = true
+			}
+		}
+		for _, fs := range [][]*doc.Field{t.Fields, t.PromotedFields} {
+			for _, f := range fs {
+				names[f.Name] = true
+			}
+		}
+		seen := map[string]bool{pdoc.ImportPath + "." + t.Name: true}
+		r.promote(t, t.Embedded, names, seen, 0)
+	}
+}
+
+func (r *embedResolver) promote(t *doc.Type, embedded []*doc.Embedded, names, seen map[string]bool, depth int) {
+	if depth >= maxEmbedDepth {
+		return
+	}
+	for _, e := range embedded {
+		key := e.ImportPath + "." + e.Name
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		epdoc, et := r.lookup(e.ImportPath, e.Name)
+		if et == nil {
+			continue
+		}
+		via := epdoc.Name + "." + e.Name
+		for _, fs := range [][]*doc.Func{et.Methods, et.PromotedMethods} {
+			for _, f := range fs {
+				if names[f.Name] {
+					continue
+				}
+				names[f.Name] = true
+				m := *f
+				m.Decl = qualifyCode(f.Decl, e.ImportPath)
+				m.Pos = doc.Pos{}
+				m.Via = via
+				m.Examples = nil
+				t.PromotedMethods = append(t.PromotedMethods, &m)
+			}
+		}
+		for _, fs := range [][]*doc.Field{et.Fields, et.PromotedFields} {
+			for _, f := range fs {
+				if names[f.Name] {
+					continue
+				}
+				names[f.Name] = true
+				t.PromotedFields = append(t.PromotedFields, &doc.Field{Name: f.Name, Type: f.Type, Via: via})
+			}
+		}
+		r.promote(t, et.Embedded, names, seen, depth+1)
+	}
+}
+
+// qualifyCode returns a copy of c with links to declarations in the package
+// containing c changed to links to the package importPath.
+func qualifyCode(c doc.Code, importPath string) doc.Code {
+	pathIndex := -1
+	for i, p := range c.Paths {
+		if p == importPath {
+			pathIndex = i
+		}
+	}
+	paths := c.Paths
+	if pathIndex < 0 {
+		paths = append(append([]string(nil), c.Paths...), importPath)
+		pathIndex = len(paths) - 1
+	}
+	annotations := make([]doc.Annotation, len(c.Annotations))
+	for i, a := range c.Annotations {
+		if a.Kind == doc.LinkAnnotation && a.PathIndex < 0 {
+			a.PathIndex = int16(pathIndex)
+		}
+		annotations[i] = a
+	}
+	return doc.Code{Text: c.Text, Annotations: annotations, Paths: paths}
+}
@@ -0,0 +1,32 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"os"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+// loadExcludeFile excludes the import paths matching the glob patterns listed
+// in the file name, one per line, from crawling, documentation and listings.
+// Lines starting with # are comments.
+func loadExcludeFile(name string) error {
+	if name == "" {
+		return nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	patterns, err := readWarmPaths(f)
+	if err != nil {
+		return err
+	}
+	return gosrc.SetExcludedPaths(patterns)
+}
@@ -0,0 +1,61 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// feedEtag returns the entity tag of a feed whose most recent item was
+// updated at latest. The key distinguishes the feeds and formats served at
+// the same time, such as the RSS and Atom versions of a feed.
+func feedEtag(key string, latest time.Time) string {
+	h := sha1.New()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatInt(latest.Unix(), 16)))
+	return `"` + hex.EncodeToString(h.Sum(nil)[:10]) + `"`
+}
+
+// checkFeedModified sets the ETag and Last-Modified headers of a feed whose
+// most recent item was updated at latest. If the feed has not changed since
+// the last poll of the reader according to the If-None-Match or
+// If-Modified-Since header of the request, checkFeedModified responds with
+// status 304 and returns false. Feed handlers call checkFeedModified before
+// writing the body of the feed and return if it returns false.
+func checkFeedModified(resp http.ResponseWriter, req *http.Request, key string, latest time.Time) bool {
+	if latest.IsZero() {
+		// An empty feed has no meaningful validator.
+		return true
+	}
+	etag := feedEtag(key, latest)
+	resp.Header().Set("Etag", etag)
+	resp.Header().Set("Last-Modified", latest.UTC().Format(http.TimeFormat))
+	if req.Method != "GET" && req.Method != "HEAD" {
+		return true
+	}
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == etag || tag == "*" {
+				resp.WriteHeader(http.StatusNotModified)
+				return false
+			}
+		}
+		return true
+	}
+	if notModifiedSince(req, latest) {
+		resp.WriteHeader(http.StatusNotModified)
+		return false
+	}
+	return true
+}
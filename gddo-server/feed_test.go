@@ -0,0 +1,67 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckFeedModified(t *testing.T) {
+	latest := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	etag := feedEtag("rss", latest)
+	if etag == feedEtag("atom", latest) {
+		t.Errorf("RSS and Atom feeds have the same entity tag %s", etag)
+	}
+	if etag == feedEtag("rss", latest.Add(time.Second)) {
+		t.Errorf("feed has the same entity tag %s after a new item", etag)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		header   http.Header
+		modified bool
+	}{
+		{"unconditional", nil, true},
+		{"etag match", http.Header{"If-None-Match": {etag}}, false},
+		{"etag in list", http.Header{"If-None-Match": {`"x", ` + etag}}, false},
+		{"weak etag match", http.Header{"If-None-Match": {"W/" + etag}}, false},
+		{"etag mismatch", http.Header{"If-None-Match": {`"x"`}}, true},
+		{"etag mismatch ignores date", http.Header{"If-None-Match": {`"x"`}, "If-Modified-Since": {latest.Format(http.TimeFormat)}}, true},
+		{"not modified since", http.Header{"If-Modified-Since": {latest.Format(http.TimeFormat)}}, false},
+		{"modified since", http.Header{"If-Modified-Since": {latest.Add(-time.Minute).Format(http.TimeFormat)}}, true},
+	} {
+		req := httptest.NewRequest("GET", "/feed", nil)
+		for k, v := range tt.header {
+			req.Header[k] = v
+		}
+		resp := httptest.NewRecorder()
+		modified := checkFeedModified(resp, req, "rss", latest)
+		if modified != tt.modified {
+			t.Errorf("%s: checkFeedModified returned %v, want %v", tt.name, modified, tt.modified)
+		}
+		if !modified && resp.Code != http.StatusNotModified {
+			t.Errorf("%s: status %d, want %d", tt.name, resp.Code, http.StatusNotModified)
+		}
+		if got := resp.Header().Get("Etag"); got != etag {
+			t.Errorf("%s: Etag %q, want %q", tt.name, got, etag)
+		}
+		if got, want := resp.Header().Get("Last-Modified"), latest.Format(http.TimeFormat); got != want {
+			t.Errorf("%s: Last-Modified %q, want %q", tt.name, got, want)
+		}
+	}
+
+	resp := httptest.NewRecorder()
+	if !checkFeedModified(resp, httptest.NewRequest("GET", "/feed", nil), "rss", time.Time{}) {
+		t.Error("checkFeedModified returned false for an empty feed")
+	}
+	if got := resp.Header().Get("Etag"); got != "" {
+		t.Errorf("empty feed has Etag %q", got)
+	}
+}
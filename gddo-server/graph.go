@@ -8,8 +8,10 @@ package main
 
 import (
 	"bytes"
+	"encoding/csv"
 	"errors"
 	"fmt"
+	"io"
 	"os/exec"
 	"strings"
 
@@ -46,3 +48,87 @@ func renderGraph(pdoc *doc.Package, pkgs []database.Package, edges [][2]int) ([]
 	p = p[i:]
 	return p, nil
 }
+
+// limitGraphDepth returns the nodes of the import graph within depth edges
+// of the root package, the first node, and the edges between them. The
+// indexes of the edges refer to the returned nodes.
+func limitGraphDepth(pkgs []database.Package, edges [][2]int, depth int) ([]database.Package, [][2]int) {
+	if len(pkgs) == 0 {
+		return pkgs, edges
+	}
+	dist := make([]int, len(pkgs))
+	for i := range dist {
+		dist[i] = -1
+	}
+	dist[0] = 0
+	// ImportGraph adds the nodes in breadth-first order, but later edges
+	// can still shorten the distance of a node, so iterate to a fixed point.
+	for changed := true; changed; {
+		changed = false
+		for _, e := range edges {
+			if d := dist[e[0]]; d >= 0 && d < depth && (dist[e[1]] < 0 || dist[e[1]] > d+1) {
+				dist[e[1]] = d + 1
+				changed = true
+			}
+		}
+	}
+	index := make([]int, len(pkgs))
+	var nodes []database.Package
+	for i, pkg := range pkgs {
+		index[i] = -1
+		if dist[i] >= 0 {
+			index[i] = len(nodes)
+			nodes = append(nodes, pkg)
+		}
+	}
+	var result [][2]int
+	for _, e := range edges {
+		if index[e[0]] >= 0 && index[e[1]] >= 0 {
+			result = append(result, [2]int{index[e[0]], index[e[1]]})
+		}
+	}
+	return nodes, result
+}
+
+// writeGraphCSV writes the import graph as CSV with a from,to header row and
+// a row for each import.
+func writeGraphCSV(w io.Writer, pkgs []database.Package, edges [][2]int) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"from", "to"})
+	for _, e := range edges {
+		cw.Write([]string{pkgs[e[0]].Path, pkgs[e[1]].Path})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeGraphMatrix writes the import graph as a CSV adjacency matrix. The
+// header row and the first column hold the import paths of the packages and
+// the cell in row i and column j is 1 if package i imports package j.
+func writeGraphMatrix(w io.Writer, pkgs []database.Package, edges [][2]int) error {
+	adj := make([][]bool, len(pkgs))
+	for i := range adj {
+		adj[i] = make([]bool, len(pkgs))
+	}
+	for _, e := range edges {
+		adj[e[0]][e[1]] = true
+	}
+	cw := csv.NewWriter(w)
+	row := make([]string, len(pkgs)+1)
+	for i, pkg := range pkgs {
+		row[i+1] = pkg.Path
+	}
+	cw.Write(row)
+	for i, pkg := range pkgs {
+		row[0] = pkg.Path
+		for j, a := range adj[i] {
+			row[j+1] = "0"
+			if a {
+				row[j+1] = "1"
+			}
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
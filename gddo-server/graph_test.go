@@ -0,0 +1,74 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/golang/gddo/database"
+)
+
+var (
+	testGraphPkgs = []database.Package{
+		{Path: "example.com/a"},
+		{Path: "example.com/b"},
+		{Path: "fmt"},
+		{Path: "example.com/c"},
+	}
+	// a imports b and fmt, b imports c and fmt.
+	testGraphEdges = [][2]int{{0, 1}, {0, 2}, {1, 3}, {1, 2}}
+)
+
+func TestWriteGraphCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeGraphCSV(&buf, testGraphPkgs, testGraphEdges); err != nil {
+		t.Fatal(err)
+	}
+	want := "from,to\n" +
+		"example.com/a,example.com/b\n" +
+		"example.com/a,fmt\n" +
+		"example.com/b,example.com/c\n" +
+		"example.com/b,fmt\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("writeGraphCSV mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteGraphMatrix(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeGraphMatrix(&buf, testGraphPkgs, testGraphEdges); err != nil {
+		t.Fatal(err)
+	}
+	want := ",example.com/a,example.com/b,fmt,example.com/c\n" +
+		"example.com/a,0,1,1,0\n" +
+		"example.com/b,0,0,1,1\n" +
+		"fmt,0,0,0,0\n" +
+		"example.com/c,0,0,0,0\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("writeGraphMatrix mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLimitGraphDepth(t *testing.T) {
+	pkgs, edges := limitGraphDepth(testGraphPkgs, testGraphEdges, 1)
+	wantPkgs := []database.Package{{Path: "example.com/a"}, {Path: "example.com/b"}, {Path: "fmt"}}
+	wantEdges := [][2]int{{0, 1}, {0, 2}, {1, 2}}
+	if diff := cmp.Diff(wantPkgs, pkgs); diff != "" {
+		t.Errorf("limitGraphDepth packages mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(wantEdges, edges); diff != "" {
+		t.Errorf("limitGraphDepth edges mismatch (-want +got):\n%s", diff)
+	}
+
+	pkgs, edges = limitGraphDepth(testGraphPkgs, testGraphEdges, 2)
+	if len(pkgs) != len(testGraphPkgs) || len(edges) != len(testGraphEdges) {
+		t.Errorf("limitGraphDepth(2) = %d packages, %d edges, want the whole graph", len(pkgs), len(edges))
+	}
+}
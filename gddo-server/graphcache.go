@@ -0,0 +1,198 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang/gddo/database"
+	"github.com/golang/gddo/doc"
+)
+
+// graphLevels are the dependency levels of the import graphs precomputed
+// for popular packages, one for each value of the hide parameter.
+var graphLevels = []database.DepLevel{database.ShowAllDeps, database.HideStandardDeps, database.HideStandardAll}
+
+// graphCacheStats holds the counters of the import graph cache.
+type graphCacheStats struct {
+	Hits        int64     `json:"hits"`
+	Misses      int64     `json:"misses"`
+	Precomputed int       `json:"precomputed"`
+	LastRun     time.Time `json:"lastRun,omitempty"`
+}
+
+// graphCache counts the lookups of the import graphs precomputed for the
+// most popular packages and tracks the recomputation of the graphs deleted
+// because one of their packages changed.
+type graphCache struct {
+	mu         sync.Mutex
+	stats      graphCacheStats
+	refreshing bool // stale graphs are being recomputed
+	pending    bool // graphs went stale since the recomputation started
+}
+
+// startRefresh records that graphs went stale. It reports whether the caller
+// is to recompute them, false if a recomputation is in progress.
+func (gc *graphCache) startRefresh() bool {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.pending = true
+	if gc.refreshing {
+		return false
+	}
+	gc.refreshing = true
+	return true
+}
+
+// nextRefresh reports whether the stale graphs are to be recomputed again
+// because graphs went stale since the last recomputation started. Otherwise
+// it ends the recomputation.
+func (gc *graphCache) nextRefresh() bool {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	if gc.pending {
+		gc.pending = false
+		return true
+	}
+	gc.refreshing = false
+	return false
+}
+
+func (gc *graphCache) count(hit bool) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	if hit {
+		gc.stats.Hits++
+	} else {
+		gc.stats.Misses++
+	}
+}
+
+func (gc *graphCache) ran(precomputed int, t time.Time) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.stats.Precomputed = precomputed
+	gc.stats.LastRun = t
+}
+
+func (gc *graphCache) snapshot() graphCacheStats {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.stats
+}
+
+// graphCacheEnabled reports whether import graphs are precomputed.
+func (s *server) graphCacheEnabled() bool {
+	return s.v.GetInt(ConfigGraphCacheSize) > 0 && s.v.GetDuration(ConfigGraphCacheInterval) > 0
+}
+
+// importGraph returns the import graph of pdoc at level, precomputed if the
+// package is popular and built from the database otherwise.
+func (s *server) importGraph(pdoc *doc.Package, level database.DepLevel) ([]database.Package, [][2]int, error) {
+	if s.graphCacheEnabled() {
+		pkgs, edges, ok, err := s.db.GetImportGraph(pdoc.ImportPath, level)
+		if err != nil {
+			log.Printf("ERROR getting import graph of %s: %v", pdoc.ImportPath, err)
+		}
+		s.graphs.count(ok)
+		if ok {
+			return pkgs, edges, nil
+		}
+	}
+	return s.db.ImportGraph(pdoc, level)
+}
+
+// precomputeGraphs stores the import graphs of the most popular packages,
+// up to graph_cache_size of them, until the next run.
+func (s *server) precomputeGraphs(ctx context.Context) error {
+	n := s.v.GetInt(ConfigGraphCacheSize)
+	pkgs, err := s.db.Popular(n)
+	if err != nil {
+		return err
+	}
+	ttl := s.graphTTL()
+	precomputed := 0
+	for _, pkg := range pkgs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		pdoc, _, err := s.db.GetDoc(ctx, pkg.Path)
+		if err != nil {
+			return err
+		}
+		if pdoc == nil || pdoc.Name == "" {
+			continue
+		}
+		if err := s.putImportGraphs(pdoc, ttl); err != nil {
+			return err
+		}
+		precomputed++
+	}
+	s.graphs.ran(precomputed, time.Now())
+	return nil
+}
+
+// graphTTL returns how long precomputed graphs are kept: two intervals, so
+// that they do not expire before the next run replaces them.
+func (s *server) graphTTL() time.Duration {
+	return 2 * s.v.GetDuration(ConfigGraphCacheInterval)
+}
+
+// putImportGraphs stores the import graphs of pdoc at all levels for ttl.
+func (s *server) putImportGraphs(pdoc *doc.Package, ttl time.Duration) error {
+	for _, level := range graphLevels {
+		nodes, edges, err := s.db.ImportGraph(pdoc, level)
+		if err != nil {
+			return err
+		}
+		if err := s.db.PutImportGraph(pdoc.ImportPath, level, nodes, edges, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// graphsChanged recomputes in the background the precomputed import graphs
+// deleted because a package in them was stored, so that popular packages do
+// not wait for the next run of precomputeGraphs.
+func (s *server) graphsChanged() {
+	if !s.graphCacheEnabled() || !s.graphs.startRefresh() {
+		return
+	}
+	go func() {
+		for s.graphs.nextRefresh() {
+			if err := s.refreshStaleGraphs(context.Background()); err != nil {
+				log.Printf("ERROR recomputing import graphs: %v", err)
+			}
+		}
+	}()
+}
+
+// refreshStaleGraphs recomputes the import graphs deleted because a package
+// in them changed.
+func (s *server) refreshStaleGraphs(ctx context.Context) error {
+	ttl := s.graphTTL()
+	for {
+		root, err := s.db.PopStaleImportGraph()
+		if err != nil || root == "" {
+			return err
+		}
+		pdoc, _, err := s.db.GetDoc(ctx, root)
+		if err != nil {
+			return err
+		}
+		if pdoc == nil || pdoc.Name == "" {
+			continue
+		}
+		if err := s.putImportGraphs(pdoc, ttl); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/viper"
+
+	"github.com/golang/gddo/database"
+	"github.com/golang/gddo/doc"
+)
+
+func TestGraphCacheStats(t *testing.T) {
+	var gc graphCache
+	gc.count(true)
+	gc.count(false)
+	gc.count(true)
+	now := time.Now()
+	gc.ran(5, now)
+
+	want := graphCacheStats{Hits: 2, Misses: 1, Precomputed: 5, LastRun: now}
+	if got := gc.snapshot(); got != want {
+		t.Errorf("snapshot() = %+v, want %+v", got, want)
+	}
+}
+
+// newTestDB returns a database in an empty Redis database of the server at
+// :6379. closeTestDB empties it again. The test is skipped if the server
+// cannot be reached.
+func newTestDB(t *testing.T) *database.Database {
+	p := redis.NewPool(func() (redis.Conn, error) {
+		c, err := redis.DialTimeout("tcp", ":6379", 0, 1*time.Second, 1*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := c.Do("SELECT", "10"); err != nil {
+			c.Close()
+			return nil, err
+		}
+		return c, nil
+	}, 1)
+	c := p.Get()
+	defer c.Close()
+	if err := c.Err(); err != nil {
+		t.Skipf("Redis unavailable: %v", err)
+	}
+	if n, err := redis.Int(c.Do("DBSIZE")); n != 0 || err != nil {
+		t.Fatalf("DBSIZE returned %d, %v", n, err)
+	}
+	return &database.Database{Pool: p}
+}
+
+func closeTestDB(db *database.Database) {
+	c := db.Pool.Get()
+	c.Do("FLUSHDB")
+	c.Close()
+}
+
+func TestGraphCacheRefresh(t *testing.T) {
+	var gc graphCache
+	if !gc.startRefresh() {
+		t.Fatal("startRefresh() = false without a recomputation in progress")
+	}
+	if !gc.nextRefresh() {
+		t.Fatal("nextRefresh() = false before the first recomputation")
+	}
+	if gc.startRefresh() {
+		t.Error("startRefresh() = true during a recomputation")
+	}
+	if !gc.nextRefresh() {
+		t.Error("nextRefresh() = false after graphs went stale during the recomputation")
+	}
+	if gc.nextRefresh() {
+		t.Error("nextRefresh() = true without stale graphs")
+	}
+	if !gc.startRefresh() {
+		t.Error("startRefresh() = false after the recomputation ended")
+	}
+}
+
+func TestPrecomputeGraphs(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	defer closeTestDB(db)
+	v := viper.New()
+	v.Set(ConfigGraphCacheSize, 10)
+	v.Set(ConfigGraphCacheInterval, time.Hour)
+	s := &server{v: v, db: db}
+
+	a := &doc.Package{ImportPath: "github.com/user/a", Name: "a", Synopsis: "Package a.", Imports: []string{"github.com/user/b"}, Funcs: []*doc.Func{{Name: "A"}}}
+	b := &doc.Package{ImportPath: "github.com/user/b", Name: "b", Synopsis: "Package b.", Funcs: []*doc.Func{{Name: "B"}}}
+	for _, pdoc := range []*doc.Package{a, b} {
+		if err := db.Put(ctx, pdoc, time.Time{}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.IncrementPopularScore(a.ImportPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.precomputeGraphs(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.graphs.snapshot().Precomputed; got != 1 {
+		t.Errorf("precomputed %d graphs, want 1", got)
+	}
+	want, _, err := db.ImportGraph(a, database.ShowAllDeps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes, _, err := s.importGraph(a, database.ShowAllDeps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, nodes); diff != "" {
+		t.Errorf("importGraph() nodes mismatch (-want +got):\n%s", diff)
+	}
+	if stats := s.graphs.snapshot(); stats.Hits != 1 || stats.Misses != 0 {
+		t.Errorf("importGraph() counted %d hits and %d misses, want the stored graph", stats.Hits, stats.Misses)
+	}
+
+	// Storing a dependency deletes the graph, which is recomputed in the
+	// background.
+	b.Synopsis = "Package b does more."
+	if err := db.Put(ctx, b, time.Time{}, false); err != nil {
+		t.Fatal(err)
+	}
+	s.graphsChanged()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		nodes, _, ok, err := db.GetImportGraph(a.ImportPath, database.ShowAllDeps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			if got := nodes[1].Synopsis; got != b.Synopsis {
+				t.Errorf("recomputed graph has synopsis %q for b, want %q", got, b.Synopsis)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("graph deleted by Put was not recomputed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// defaultContentSecurityPolicy allows the resources used by the templates:
+// the scripts and style sheets served by this server, Google Analytics,
+// images from any HTTPS host for logos and badges, and the lint form posting
+// to go-lint.appspot.com.
+var defaultContentSecurityPolicy = strings.Join([]string{
+	"default-src 'self'",
+	"script-src 'self' https://ssl.google-analytics.com https://www.google-analytics.com",
+	"img-src 'self' data: https:",
+	"connect-src 'self' https://www.google-analytics.com",
+	"form-action 'self' https://go-lint.appspot.com",
+	"frame-ancestors 'none'",
+	"base-uri 'self'",
+	"object-src 'none'",
+}, "; ")
+
+// responseHeaders returns the headers set on all responses of the server.
+// Headers configured with an empty value are omitted.
+func responseHeaders(v *viper.Viper) http.Header {
+	header := make(http.Header)
+	for name, key := range map[string]string{
+		"Content-Security-Policy":   ConfigContentSecurityPolicy,
+		"X-Content-Type-Options":    ConfigContentTypeOptions,
+		"Referrer-Policy":           ConfigReferrerPolicy,
+		"Strict-Transport-Security": ConfigHSTS,
+	} {
+		if value := v.GetString(key); value != "" {
+			header.Set(name, value)
+		}
+	}
+	return header
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/viper"
+)
+
+func TestResponseHeaders(t *testing.T) {
+	v := viper.New()
+	v.Set(ConfigContentSecurityPolicy, "default-src 'self'")
+	v.Set(ConfigContentTypeOptions, "nosniff")
+	v.Set(ConfigReferrerPolicy, "")
+	want := http.Header{
+		"Content-Security-Policy": {"default-src 'self'"},
+		"X-Content-Type-Options":  {"nosniff"},
+	}
+	if diff := cmp.Diff(want, responseHeaders(v)); diff != "" {
+		t.Errorf("responseHeaders mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// inlinePat matches inline scripts, styles and event handlers, which the
+// default Content-Security-Policy blocks.
+var inlinePat = regexp.MustCompile(`<script>|<script type="text/javascript">|<style|\son[a-z]+=|\sstyle=|href="javascript:`)
+
+func TestTemplatesInlineFree(t *testing.T) {
+	files, err := filepath.Glob("assets/templates/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if filepath.Base(file) == "about.html" {
+			// The bookmarklet link is run on other pages.
+			continue
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m := inlinePat.Find(data); m != nil {
+			t.Errorf("%s uses inline code %q", file, m)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/golang/gddo/doc"
+)
+
+const jsonLDMIMEType = "application/ld+json; charset=utf-8"
+
+// stdLicense is the license of the standard library. GoDoc does not detect
+// the license of other packages.
+const stdLicense = "https://golang.org/LICENSE"
+
+// softwareSourceCode is the schema.org SoftwareSourceCode structured data of a
+// package, embedded as JSON-LD in package pages.
+type softwareSourceCode struct {
+	Context             string `json:"@context"`
+	Type                string `json:"@type"`
+	Name                string `json:"name"`
+	Identifier          string `json:"identifier"`
+	Description         string `json:"description,omitempty"`
+	License             string `json:"license,omitempty"`
+	CodeRepository      string `json:"codeRepository,omitempty"`
+	ProgrammingLanguage string `json:"programmingLanguage"`
+}
+
+func newSoftwareSourceCode(pdoc *doc.Package) *softwareSourceCode {
+	ssc := &softwareSourceCode{
+		Context:             "https://schema.org",
+		Type:                "SoftwareSourceCode",
+		Name:                pdoc.Name,
+		Identifier:          pdoc.ImportPath,
+		Description:         pdoc.Synopsis,
+		ProgrammingLanguage: "Go",
+	}
+	if pdoc.ProjectRoot == "" {
+		ssc.License = stdLicense
+	}
+	repo := pdoc.RepoURL
+	if repo == "" {
+		repo = pdoc.ProjectURL
+	}
+	if isWebURL(repo) {
+		ssc.CodeRepository = repo
+	}
+	return ssc
+}
+
+// isWebURL returns true if s is an absolute http or https URL.
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// serveJSONLD serves the structured data of the package page standalone.
+func serveJSONLD(resp http.ResponseWriter, pdoc *doc.Package) error {
+	if pdoc.Name == "" {
+		return &httpError{status: http.StatusNotFound}
+	}
+	resp.Header().Set("Content-Type", jsonLDMIMEType)
+	return json.NewEncoder(resp).Encode(newSoftwareSourceCode(pdoc))
+}
@@ -0,0 +1,57 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	htemp "html/template"
+	"strings"
+	"testing"
+
+	"github.com/golang/gddo/doc"
+)
+
+func TestSoftwareSourceCode(t *testing.T) {
+	for _, tt := range []struct {
+		pdoc *doc.Package
+		want softwareSourceCode
+	}{
+		{
+			&doc.Package{Name: "fmt", ImportPath: "fmt", Synopsis: "Package fmt implements formatted I/O."},
+			softwareSourceCode{Name: "fmt", Identifier: "fmt", Description: "Package fmt implements formatted I/O.", License: stdLicense},
+		},
+		{
+			&doc.Package{Name: "a", ImportPath: "github.com/user/repo/a", ProjectRoot: "github.com/user/repo",
+				ProjectURL: "https://github.com/user/repo", RepoURL: "https://github.com/user/repo"},
+			softwareSourceCode{Name: "a", Identifier: "github.com/user/repo/a", CodeRepository: "https://github.com/user/repo"},
+		},
+		{
+			&doc.Package{Name: "b", ImportPath: "example.com/b", ProjectRoot: "example.com/b", ProjectURL: "javascript:alert(1)"},
+			softwareSourceCode{Name: "b", Identifier: "example.com/b"},
+		},
+	} {
+		tt.want.Context = "https://schema.org"
+		tt.want.Type = "SoftwareSourceCode"
+		tt.want.ProgrammingLanguage = "Go"
+		if got := newSoftwareSourceCode(tt.pdoc); *got != tt.want {
+			t.Errorf("newSoftwareSourceCode(%s) = %+v, want %+v", tt.pdoc.ImportPath, *got, tt.want)
+		}
+	}
+}
+
+func TestJSONLDEscaping(t *testing.T) {
+	tmpl := htemp.Must(htemp.New("").Funcs(htemp.FuncMap{"jsonLD": newSoftwareSourceCode}).Parse(
+		`<script type="application/ld+json">{{jsonLD .}}</script>`))
+	var buf bytes.Buffer
+	pdoc := &doc.Package{Name: "x", ImportPath: "example.com/x", Synopsis: "</script><script>alert(1)</script>"}
+	if err := tmpl.Execute(&buf, pdoc); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); strings.Count(s, "</script>") != 1 {
+		t.Errorf("synopsis not escaped: %s", s)
+	}
+}
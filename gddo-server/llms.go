@@ -0,0 +1,71 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/golang/gddo/database"
+)
+
+// llmsPackage is a package in the JSON index served at /llms.txt.
+type llmsPackage struct {
+	Path     string `json:"path"`
+	Synopsis string `json:"synopsis,omitempty"`
+	URL      string `json:"url"`
+}
+
+// writeLLMsTxt writes the index of pkgs in the llms.txt format: a Markdown
+// document with a link to the documentation of each package followed by its
+// synopsis. The links are relative to baseURL.
+func writeLLMsTxt(w io.Writer, siteName, baseURL string, pkgs []database.Package) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %s\n\n", siteName)
+	fmt.Fprintf(bw, "> Documentation of Go packages. Append ?text to the URL of a package to get its documentation as plain text.\n\n")
+	fmt.Fprintf(bw, "## Packages\n\n")
+	for _, pkg := range pkgs {
+		fmt.Fprintf(bw, "- [%s](%s/%s)", pkg.Path, baseURL, pkg.Path)
+		if s := strings.Join(strings.Fields(pkg.Synopsis), " "); s != "" {
+			fmt.Fprintf(bw, ": %s", s)
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// serveLLMsTxt serves an index of the packages shown in search results for
+// AI crawlers as llms.txt or, with format=json, as JSON.
+func (s *server) serveLLMsTxt(resp http.ResponseWriter, req *http.Request) error {
+	pkgs, err := s.db.IndexedPackages()
+	if err != nil {
+		return err
+	}
+	baseURL := s.siteURL(resp, req)
+	switch req.Form.Get("format") {
+	case "json":
+		data := struct {
+			Packages []llmsPackage `json:"packages"`
+		}{
+			make([]llmsPackage, len(pkgs)),
+		}
+		for i, pkg := range pkgs {
+			data.Packages[i] = llmsPackage{Path: pkg.Path, Synopsis: pkg.Synopsis, URL: baseURL + "/" + pkg.Path}
+		}
+		resp.Header().Set("Content-Type", jsonMIMEType)
+		return json.NewEncoder(resp).Encode(&data)
+	case "":
+		resp.Header().Set("Content-Type", textMIMEType)
+		return writeLLMsTxt(resp, s.v.GetString(ConfigSiteName), baseURL, pkgs)
+	default:
+		return &httpError{status: http.StatusNotFound}
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/gddo/database"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteLLMsTxt(t *testing.T) {
+	pkgs := []database.Package{
+		{Path: "fmt", Synopsis: "Package fmt implements formatted I/O."},
+		{Path: "github.com/user/a", Synopsis: "Package a does\nthings."},
+		{Path: "github.com/user/b"},
+	}
+	var buf bytes.Buffer
+	if err := writeLLMsTxt(&buf, "GoDoc", "https://godoc.org", pkgs); err != nil {
+		t.Fatal(err)
+	}
+	want := `# GoDoc
+
+> Documentation of Go packages. Append ?text to the URL of a package to get its documentation as plain text.
+
+## Packages
+
+- [fmt](https://godoc.org/fmt): Package fmt implements formatted I/O.
+- [github.com/user/a](https://godoc.org/github.com/user/a): Package a does things.
+- [github.com/user/b](https://godoc.org/github.com/user/b)
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("writeLLMsTxt mismatch (-want +got):\n%s", diff)
+	}
+}
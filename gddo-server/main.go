@@ -32,18 +32,22 @@ import (
 	"cloud.google.com/go/pubsub"
 	"cloud.google.com/go/trace"
 	"github.com/spf13/viper"
+	"golang.org/x/mod/semver"
 
 	"github.com/golang/gddo/database"
 	"github.com/golang/gddo/doc"
 	"github.com/golang/gddo/gosrc"
 	"github.com/golang/gddo/httputil"
 	"github.com/golang/gddo/internal/health"
+	gddolog "github.com/golang/gddo/log"
 )
 
 const (
-	jsonMIMEType = "application/json; charset=utf-8"
-	textMIMEType = "text/plain; charset=utf-8"
-	htmlMIMEType = "text/html; charset=utf-8"
+	jsonMIMEType     = "application/json; charset=utf-8"
+	textMIMEType     = "text/plain; charset=utf-8"
+	markdownMIMEType = "text/markdown; charset=utf-8"
+	htmlMIMEType     = "text/html; charset=utf-8"
+	csvMIMEType      = "text/csv; charset=utf-8"
 )
 
 var errUpdateTimeout = errors.New("refresh timeout")
@@ -82,12 +86,23 @@ func (s *server) getDoc(ctx context.Context, path string, requestType int) (*doc
 		// return not found.
 		return nil, nil, &httpError{status: http.StatusNotFound}
 	}
+	path = gosrc.CanonicalPath(path)
+	if gosrc.IsExcludedPath(path) {
+		return nil, nil, &httpError{status: http.StatusNotFound}
+	}
 
 	pdoc, pkgs, nextCrawl, err := s.db.Get(ctx, path)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	if s.v.GetBool(ConfigCachedOnly) {
+		if pdoc == nil && len(pkgs) == 0 {
+			return nil, nil, &httpError{status: http.StatusNotFound}
+		}
+		return pdoc, pkgs, nil
+	}
+
 	needsCrawl := false
 	switch requestType {
 	case queryRequest, apiRequest:
@@ -102,8 +117,36 @@ func (s *server) getDoc(ctx context.Context, path string, requestType int) (*doc
 		return pdoc, pkgs, nil
 	}
 
+	if pdoc == nil && len(pkgs) == 0 {
+		// Don't crawl again if the last crawl recently returned not found.
+		notFound, err := s.db.IsNotFound(path)
+		if err != nil {
+			log.Printf("ERROR db.IsNotFound(%q): %v", path, err)
+		} else if notFound {
+			return nil, nil, &httpError{status: http.StatusNotFound}
+		}
+	}
+
+	if s.crawls.panicked(path, time.Now()) {
+		// Don't crawl again if the last crawl recently panicked.
+		if pdoc != nil {
+			return pdoc, pkgs, nil
+		}
+		return nil, nil, errBuildPanic
+	}
+
+	release, err := s.acquireFetch(ctx, path)
+	if err != nil {
+		if pdoc != nil {
+			log.Printf("Serving %q from database: %v", path, err)
+			return pdoc, pkgs, nil
+		}
+		return nil, nil, err
+	}
+
 	c := make(chan crawlResult, 1)
 	go func() {
+		defer release()
 		pdoc, err := s.crawlDoc(ctx, "web  ", path, pdoc, len(pkgs) > 0, nextCrawl)
 		c <- crawlResult{pdoc, err}
 	}()
@@ -122,6 +165,9 @@ func (s *server) getDoc(ctx context.Context, path string, requestType int) (*doc
 	case <-time.After(timeout):
 		err = errUpdateTimeout
 	}
+	if _, ok := err.(*doc.PanicError); ok {
+		err = errBuildPanic
+	}
 
 	switch {
 	case err == nil:
@@ -139,17 +185,70 @@ func (s *server) getDoc(ctx context.Context, path string, requestType int) (*doc
 	}
 }
 
+// errCommitNotFound is the error shown when the commit of a commit page
+// does not exist.
+var errCommitNotFound = errors.New("commit not found")
+
+// checkFetchable returns an error if the documentation of importPath must not
+// be fetched from the version control system for a request, because the
+// package is excluded, blocked or gone.
+func (s *server) checkFetchable(importPath string) error {
+	if gosrc.IsExcludedPath(importPath) {
+		return &httpError{status: http.StatusNotFound}
+	}
+	if blocked, err := s.db.Primary().IsBlocked(importPath); err != nil {
+		return err
+	} else if blocked {
+		return &httpError{status: http.StatusNotFound}
+	}
+	if gone, reason, err := s.db.Gone(importPath); err != nil {
+		log.Printf("ERROR db.Gone(%q): %v", importPath, err)
+	} else if gone {
+		return &httpError{status: http.StatusGone, err: goneError(reason)}
+	}
+	return nil
+}
+
+// acquireFetch waits for a slot to fetch the documentation of importPath from
+// the version control system for a request, within the limits of the
+// packages fetched concurrently and of the crawl rate of the host. The slot
+// is taken first so that a request giving up while waiting does not spend
+// the budget of the host. Callers must call the returned function once done
+// fetching.
+func (s *server) acquireFetch(ctx context.Context, importPath string) (func(), error) {
+	if err := s.renders.acquire(ctx); err != nil {
+		return nil, &httpError{status: http.StatusServiceUnavailable, err: err}
+	}
+	if err := s.crawlBudget.acquire(importPath, time.Now()); err != nil {
+		s.renders.release()
+		return nil, &httpError{status: http.StatusServiceUnavailable, err: err}
+	}
+	return s.renders.release, nil
+}
+
+// templateExt returns the extension of the templates used to render the
+// response to req. Plain text is served for the ?text view and to clients
+// preferring text/plain, such as curl with an Accept: text/plain header.
 func templateExt(req *http.Request) string {
-	if httputil.NegotiateContentType(req, []string{"text/html", "text/plain"}, "text/html") == "text/plain" {
+	if isView(req, "text") ||
+		httputil.NegotiateContentType(req, []string{"text/html", "text/plain"}, "text/html") == "text/plain" {
 		return ".txt"
 	}
 	return ".html"
 }
 
+// isMarkdown reports whether the request is for the documentation of a
+// package as Markdown, with ?format=md or an Accept header preferring
+// text/markdown.
+func isMarkdown(req *http.Request) bool {
+	return req.Form.Get("format") == "md" ||
+		httputil.NegotiateContentType(req, []string{"text/html", "text/plain", "text/markdown"}, "text/html") == "text/markdown"
+}
+
 var robotPat = regexp.MustCompile(`(:?\+https?://)|(?:\Wbot\W)|(?:^Python-urllib)|(?:^Go )|(?:^Java/)`)
 
 func (s *server) isRobot(req *http.Request) bool {
-	if robotPat.MatchString(req.Header.Get("User-Agent")) {
+	if robotPat.MatchString(req.Header.Get("User-Agent")) || s.robots.match(req) {
 		return true
 	}
 	host := httputil.StripPort(req.RemoteAddr)
@@ -159,7 +258,7 @@ func (s *server) isRobot(req *http.Request) bool {
 		return false
 	}
 	if n > s.v.GetFloat64(ConfigRobotThreshold) {
-		log.Printf("robot %.2f %s %s", n, host, req.Header.Get("User-Agent"))
+		log.Printf("DEBUG robot %.2f %s %s", n, host, req.Header.Get("User-Agent"))
 		return true
 	}
 	return false
@@ -175,8 +274,22 @@ func isView(req *http.Request, key string) bool {
 		(len(rq) == len(key) || rq[len(key)] == '=' || rq[len(key)] == '&')
 }
 
+// notModifiedSince reports whether the request has an If-Modified-Since
+// header at or after the modification time t.
+func notModifiedSince(req *http.Request, t time.Time) bool {
+	if t.IsZero() {
+		return false
+	}
+	ims, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	// HTTP dates have a resolution of one second.
+	return !t.Truncate(time.Second).After(ims)
+}
+
 // httpEtag returns the package entity tag used in HTTP transactions.
-func (s *server) httpEtag(pdoc *doc.Package, pkgs []database.Package, importerCount int, flashMessages []flashMessage) string {
+func (s *server) httpEtag(pdoc *doc.Package, pkgs, siblings []database.Package, importerCount int, implementations map[string][]database.Implementation, readme *doc.Readme, flashMessages []flashMessage, recent []string) string {
 	b := make([]byte, 0, 128)
 	b = strconv.AppendInt(b, pdoc.Updated.Unix(), 16)
 	b = append(b, 0)
@@ -192,6 +305,20 @@ func (s *server) httpEtag(pdoc *doc.Package, pkgs []database.Package, importerCo
 		b = append(b, 0)
 		b = append(b, pkg.Synopsis...)
 	}
+	for _, pkg := range siblings {
+		b = append(b, 3)
+		b = append(b, pkg.Path...)
+	}
+	for _, t := range pdoc.Types {
+		for _, impl := range implementations[t.Name] {
+			b = append(b, 4)
+			b = append(b, impl.String()...)
+		}
+	}
+	if readme != nil {
+		b = append(b, 5)
+		b = append(b, readme.Name...)
+	}
 	if s.v.GetBool(ConfigSidebar) {
 		b = append(b, "\000xsb"...)
 	}
@@ -203,6 +330,18 @@ func (s *server) httpEtag(pdoc *doc.Package, pkgs []database.Package, importerCo
 			b = append(b, a...)
 		}
 	}
+	for _, p := range recent {
+		b = append(b, 2)
+		b = append(b, p...)
+	}
+	if n := s.notices.get(); n != nil {
+		b = append(b, 7)
+		b = append(b, n.Message...)
+		b = append(b, 0)
+		b = append(b, n.Level...)
+		b = append(b, 0)
+		b = append(b, n.Link...)
+	}
 	h := md5.New()
 	h.Write(b)
 	b = h.Sum(b[:0])
@@ -210,13 +349,28 @@ func (s *server) httpEtag(pdoc *doc.Package, pkgs []database.Package, importerCo
 }
 
 func (s *server) servePackage(resp http.ResponseWriter, req *http.Request) error {
+	return s.serveLoadedPackage(resp, req, nil)
+}
+
+// loadedDoc is the documentation of a package returned by getDoc.
+type loadedDoc struct {
+	pdoc *doc.Package
+	pkgs []database.Package
+}
+
+// serveLoadedPackage serves the package page like servePackage, with the
+// documentation in loaded if not nil instead of getting it again.
+func (s *server) serveLoadedPackage(resp http.ResponseWriter, req *http.Request, loaded *loadedDoc) error {
 	p := path.Clean(req.URL.Path)
 	if strings.HasPrefix(p, "/pkg/") {
 		p = p[len("/pkg"):]
 	}
+	p = "/" + gosrc.CanonicalPath(p)
 	if p != req.URL.Path {
-		http.Redirect(resp, req, p, http.StatusMovedPermanently)
-		return nil
+		if req.URL.RawQuery != "" {
+			p += "?" + req.URL.RawQuery
+		}
+		return s.redirects.redirect(resp, req, p, http.StatusMovedPermanently)
 	}
 
 	if isView(req, "status.svg") {
@@ -235,7 +389,45 @@ func (s *server) servePackage(resp http.ResponseWriter, req *http.Request) error
 	}
 
 	importPath := strings.TrimPrefix(req.URL.Path, "/")
-	pdoc, pkgs, err := s.getDoc(req.Context(), importPath, requestType)
+	if i := strings.Index(importPath, "@"); i >= 0 && gosrc.IsCommitSHA(importPath[i+1:]) {
+		return s.serveCommit(resp, req, importPath[:i], importPath[i+1:])
+	}
+	var version string
+	if s.v.GetBool(ConfigLatestVersion) {
+		if i := strings.Index(importPath, "@"); i >= 0 {
+			importPath, version = importPath[:i], importPath[i+1:]
+			switch {
+			case semver.IsValid(version):
+				return s.serveVersion(resp, req, importPath, version)
+			case version != "master" && version != "main":
+				return &httpError{status: http.StatusNotFound}
+			}
+		}
+	}
+	if gone, reason, err := s.db.Gone(importPath); err != nil {
+		log.Printf("ERROR db.Gone(%q): %v", importPath, err)
+	} else if gone {
+		return &httpError{status: http.StatusGone, err: goneError(reason)}
+	}
+
+	var (
+		pdoc *doc.Package
+		pkgs []database.Package
+		err  error
+	)
+	if target, e := s.db.GetRedirect(importPath); e != nil {
+		log.Printf("ERROR db.GetRedirect(%q): %v", importPath, e)
+	} else if target != "" {
+		// Redirect to the canonical import path found by a recent crawl.
+		err = gosrc.NotFoundError{Message: "not at canonical import path", Redirect: target}
+	}
+	if err == nil {
+		if loaded != nil {
+			pdoc, pkgs = loaded.pdoc, loaded.pkgs
+		} else {
+			pdoc, pkgs, err = s.getDoc(req.Context(), importPath, requestType)
+		}
+	}
 
 	if e, ok := err.(gosrc.NotFoundError); ok && e.Redirect != "" {
 		// To prevent dumb clients from following redirect loops, respond with
@@ -248,12 +440,15 @@ func (s *server) servePackage(resp http.ResponseWriter, req *http.Request) error
 			u += "?" + req.URL.RawQuery
 		}
 		setFlashMessages(resp, []flashMessage{{ID: "redir", Args: []string{importPath}}})
-		http.Redirect(resp, req, u, http.StatusFound)
-		return nil
+		return s.redirects.redirect(resp, req, u, http.StatusFound)
 	}
 	if err != nil {
 		return err
 	}
+	if !s.listingEnabled() {
+		// Directory listings enumerate the packages under the path.
+		pkgs = nil
+	}
 
 	flashMessages := getFlashMessages(resp, req)
 
@@ -269,40 +464,45 @@ func (s *server) servePackage(resp http.ResponseWriter, req *http.Request) error
 			ProjectName: pdocChild.ProjectName,
 			ProjectRoot: pdocChild.ProjectRoot,
 			ProjectURL:  pdocChild.ProjectURL,
+			RepoURL:     pdocChild.RepoURL,
 			ImportPath:  importPath,
 		}
 	}
 
+	if version == "" && pdoc.LatestVersion != "" && req.URL.RawQuery == "" &&
+		requestType == humanRequest && s.v.GetBool(ConfigLatestVersion) {
+		return s.redirects.redirect(resp, req, "/"+importPath+"@"+pdoc.LatestVersion, http.StatusFound)
+	}
+
 	showPkgGoDevRedirectToast := userReturningFromPkgGoDev(req)
+	hideBanner := hidePkgGoDevBanner(req)
 
 	switch {
 	case isView(req, "imports"):
 		if pdoc.Name == "" {
 			return &httpError{status: http.StatusNotFound}
 		}
-		pkgs, err = s.db.Packages(pdoc.Imports)
+		deps, err := s.dependencies(pdoc)
 		if err != nil {
 			return err
 		}
 		return s.templates.execute(resp, "imports.html", http.StatusOK, nil, map[string]interface{}{
 			"flashMessages":             flashMessages,
-			"pkgs":                      pkgs,
+			"deps":                      deps,
 			"pdoc":                      newTDoc(s.v, pdoc),
 			"showPkgGoDevRedirectToast": showPkgGoDevRedirectToast,
+			"hidePkgGoDevBanner":        hideBanner,
 		})
 	case isView(req, "tools"):
-		proto := "http"
-		if req.Host == "godoc.org" {
-			proto = "https"
-		}
 		return s.templates.execute(resp, "tools.html", http.StatusOK, nil, map[string]interface{}{
 			"flashMessages":             flashMessages,
-			"uri":                       fmt.Sprintf("%s://%s/%s", proto, req.Host, importPath),
+			"uri":                       absoluteURL(req, "/"+importPath),
 			"pdoc":                      newTDoc(s.v, pdoc),
 			"showPkgGoDevRedirectToast": showPkgGoDevRedirectToast,
+			"hidePkgGoDevBanner":        hideBanner,
 		})
 	case isView(req, "importers"):
-		if pdoc.Name == "" {
+		if pdoc.Name == "" || !s.listingEnabled() {
 			return &httpError{status: http.StatusNotFound}
 		}
 		pkgs, err = s.db.Importers(importPath)
@@ -319,6 +519,7 @@ func (s *server) servePackage(resp http.ResponseWriter, req *http.Request) error
 			"pkgs":                      pkgs,
 			"pdoc":                      newTDoc(s.v, pdoc),
 			"showPkgGoDevRedirectToast": showPkgGoDevRedirectToast,
+			"hidePkgGoDevBanner":        hideBanner,
 		})
 	case isView(req, "import-graph"):
 		if requestType == robotRequest {
@@ -343,10 +544,25 @@ func (s *server) servePackage(resp http.ResponseWriter, req *http.Request) error
 		case "2":
 			hide = database.HideStandardAll
 		}
-		pkgs, edges, err := s.db.ImportGraph(pdoc, hide)
+		pkgs, edges, err := s.importGraph(pdoc, hide)
 		if err != nil {
 			return err
 		}
+		if depth, err := strconv.Atoi(req.Form.Get("depth")); err == nil && depth > 0 {
+			pkgs, edges = limitGraphDepth(pkgs, edges, depth)
+		}
+		switch format := req.Form.Get("format"); format {
+		case "csv", "matrix":
+			resp.Header().Set("Content-Type", csvMIMEType)
+			resp.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", pdoc.Name+"-imports.csv"))
+			if format == "matrix" {
+				return writeGraphMatrix(resp, pkgs, edges)
+			}
+			return writeGraphCSV(resp, pkgs, edges)
+		case "", "svg":
+		default:
+			return &httpError{status: http.StatusNotFound}
+		}
 		b, err := renderGraph(pdoc, pkgs, edges)
 		if err != nil {
 			return err
@@ -357,14 +573,28 @@ func (s *server) servePackage(resp http.ResponseWriter, req *http.Request) error
 			"pdoc":                      newTDoc(s.v, pdoc),
 			"hide":                      hide,
 			"showPkgGoDevRedirectToast": showPkgGoDevRedirectToast,
+			"hidePkgGoDevBanner":        hideBanner,
 		})
+	case isView(req, "example") && s.v.GetBool(ConfigProxySource):
+		if requestType == robotRequest {
+			return &httpError{status: http.StatusForbidden}
+		}
+		return s.serveExampleSource(resp, req, pdoc)
+	case isView(req, "unexported") && s.v.GetBool(ConfigUnexported):
+		if requestType == robotRequest {
+			return &httpError{status: http.StatusForbidden}
+		}
+		return s.serveUnexported(resp, req, importPath)
+	case isView(req, "changelog") || isView(req, "changelog.atom"):
+		return s.serveChangelog(resp, req, pdoc, flashMessages)
+	case isView(req, "jsonld"):
+		return serveJSONLD(resp, pdoc)
 	case isView(req, "play"):
 		u, err := s.playURL(pdoc, req.Form.Get("play"), req.Header.Get("X-AppEngine-Country"))
 		if err != nil {
 			return err
 		}
-		http.Redirect(resp, req, u, http.StatusMovedPermanently)
-		return nil
+		return s.redirects.redirect(resp, req, u, http.StatusMovedPermanently)
 	case req.Form.Get("view") != "":
 		// Redirect deprecated view= queries.
 		var q string
@@ -381,22 +611,80 @@ func (s *server) servePackage(resp http.ResponseWriter, req *http.Request) error
 		if q != "" {
 			u := *req.URL
 			u.RawQuery = q
-			http.Redirect(resp, req, u.String(), http.StatusMovedPermanently)
-			return nil
+			return s.redirects.redirect(resp, req, u.String(), http.StatusMovedPermanently)
 		}
 		return &httpError{status: http.StatusNotFound}
 	default:
 		importerCount := 0
-		if pdoc.Name != "" {
+		if pdoc.Name != "" && s.listingEnabled() {
 			importerCount, err = s.db.ImporterCount(importPath)
 			if err != nil {
 				return err
 			}
 		}
 
-		etag := s.httpEtag(pdoc, pkgs, importerCount, flashMessages)
+		var siblings []database.Package
+		var tree []*treeNode
+		if pdoc.Name != "" && pdoc.ProjectRoot != "" && s.listingEnabled() {
+			project, err := s.db.ProjectPackages(pdoc.ProjectRoot)
+			if err != nil {
+				log.Printf("ERROR db.ProjectPackages(%q): %v", pdoc.ProjectRoot, err)
+			}
+			siblings = siblingPackages(pdoc.ImportPath, project)
+			if s.v.GetBool(ConfigSidebar) {
+				dirs, err := s.db.ProjectTree(pdoc.ProjectRoot)
+				if err != nil {
+					log.Printf("ERROR db.ProjectTree(%q): %v", pdoc.ProjectRoot, err)
+				}
+				tree = packageTree(pdoc, dirs, project)
+			}
+		}
+
+		var implementations map[string][]database.Implementation
+		if names := interfaceNames(pdoc); len(names) > 0 {
+			var e error
+			if implementations, e = s.db.Implementations(pdoc.ImportPath, names); e != nil {
+				log.Printf("ERROR db.Implementations(%q): %v", pdoc.ImportPath, e)
+			}
+		}
+
+		var recent []string
+		if s.v.GetBool(ConfigRecentlyViewed) && requestType == humanRequest && pdoc.Name != "" {
+			for _, p := range getRecent(req) {
+				if p != importPath {
+					recent = append(recent, p)
+				}
+			}
+			setRecent(resp, addRecent(recent, importPath))
+		}
+
+		// The view count is not part of the page so that the page does not
+		// change with each view. It is loaded by the browser from /-/views.
+		if s.v.GetBool(ConfigViewCount) && pdoc.Name != "" &&
+			requestType == humanRequest && req.Method == http.MethodGet && countView(resp, req, pdoc.ImportPath, time.Now()) {
+			if _, err := s.db.IncrementViewCount(pdoc.ImportPath); err != nil {
+				log.Printf("ERROR db.IncrementViewCount(%q): %v", pdoc.ImportPath, err)
+			}
+		}
+
+		readme := selectReadme(req, pdoc.Readmes)
+
+		etag := s.httpEtag(pdoc, pkgs, siblings, importerCount, implementations, readme, flashMessages, recent)
+		// The same URL is rendered as HTML or text depending on the Accept header.
+		header := http.Header{"Etag": {etag}, "Vary": {"Accept"}}
+		if len(pdoc.Readmes) > 1 {
+			// The README translation is selected by the Accept-Language header.
+			header.Add("Vary", "Accept-Language")
+		}
+		if !pdoc.Updated.IsZero() {
+			header.Set("Last-Modified", pdoc.Updated.UTC().Format(http.TimeFormat))
+		}
 		status := http.StatusOK
-		if req.Header.Get("If-None-Match") == etag {
+		if inm := req.Header.Get("If-None-Match"); inm != "" {
+			if inm == etag {
+				status = http.StatusNotModified
+			}
+		} else if len(flashMessages) == 0 && notModifiedSince(req, pdoc.Updated) {
 			status = http.StatusNotModified
 		}
 
@@ -421,26 +709,299 @@ func (s *server) servePackage(resp http.ResponseWriter, req *http.Request) error
 		case pdoc.Name != "":
 			template = "pkg"
 		}
-		template += templateExt(req)
+		if template != "dir" && isMarkdown(req) {
+			template += ".md"
+		} else {
+			template += templateExt(req)
+		}
+
+		// The ?hidegenerated view omits the declarations of generated files.
+		hideGenerated := isView(req, "hidegenerated") && pdoc.GeneratedLines > 0
+		viewDoc := pdoc
+		if hideGenerated {
+			viewDoc = pdoc.WithoutGenerated()
+		}
+
+		// The Markdown pages link to the other pages with absolute URLs.
+		var baseURL string
+		if strings.HasSuffix(template, ".md") {
+			baseURL = s.siteURL(resp, req)
+		}
+
+		// Pages with content specific to the request are not cached.
+		cacheable := status == http.StatusOK && pdoc.Name != "" &&
+			len(flashMessages) == 0 && len(recent) == 0 && !showPkgGoDevRedirectToast &&
+			!hideBanner && !hideGenerated && (baseURL == "" || s.v.GetString(ConfigBaseURL) != "")
+		key := renderKey{importPath: importPath, etag: etag, template: template}
+		if cacheable {
+			if body, ok := s.renderCache.get(key); ok {
+				return s.templates.write(resp, template, status, header, body)
+			}
+		}
 
-		return s.templates.execute(resp, template, status, http.Header{"Etag": {etag}}, map[string]interface{}{
+		if pdoc.Name != "" && !pdoc.IsCmd && status != http.StatusNotModified {
+			s.promoteImported(req.Context(), pdoc)
+		}
+		data := map[string]interface{}{
 			"flashMessages":             flashMessages,
 			"pkgs":                      pkgs,
-			"pdoc":                      newTDoc(s.v, pdoc),
+			"pdoc":                      newTDoc(s.v, viewDoc),
 			"importerCount":             importerCount,
+			"recent":                    recent,
+			"siblings":                  siblings,
+			"siblingsShown":             maxSiblingsShown,
+			"tree":                      tree,
+			"implementations":           implementations,
+			"readme":                    readme,
 			"showPkgGoDevRedirectToast": showPkgGoDevRedirectToast,
-		})
+			"hidePkgGoDevBanner":        hideBanner,
+			"hideGenerated":             hideGenerated,
+			"baseURL":                   baseURL,
+		}
+		if !cacheable {
+			return s.templates.execute(resp, template, status, header, data)
+		}
+		body, err := s.templates.render(template, data)
+		if err != nil {
+			return err
+		}
+		s.renderCache.add(key, body)
+		return s.templates.write(resp, template, status, header, body)
+	}
+}
+
+// interfaceNames returns the names of the interface types of pdoc with known
+// method signatures.
+func interfaceNames(pdoc *doc.Package) []string {
+	var names []string
+	for _, t := range pdoc.Types {
+		if t.Interface && len(t.MethodSigs) > 0 {
+			names = append(names, t.Name)
+		}
+	}
+	return names
+}
+
+// maxSiblingsShown is the number of other packages in the project listed on a
+// package page before the "show all" link.
+const maxSiblingsShown = 10
+
+// siblingPackages returns the packages of project other than the package with
+// importPath and its subdirectories, which are listed separately.
+func siblingPackages(importPath string, project []database.Package) []database.Package {
+	var result []database.Package
+	for _, pkg := range project {
+		if pkg.Path == importPath || strings.HasPrefix(pkg.Path, importPath+"/") {
+			continue
+		}
+		result = append(result, pkg)
+	}
+	return result
+}
+
+// serveVersion serves the documentation for importPath at the given version
+// tag. The documentation is fetched from the VCS and is not stored.
+func (s *server) serveVersion(resp http.ResponseWriter, req *http.Request, importPath, version string) error {
+	if req.URL.RawQuery != "" {
+		// Other views of the package are only available for the default
+		// branch.
+		return s.redirects.redirect(resp, req, "/"+importPath+"?"+req.URL.RawQuery, http.StatusFound)
+	}
+	if err := s.checkFetchable(importPath); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), s.v.GetDuration(ConfigGetTimeout))
+	defer cancel()
+
+	// The deprecation of the module and the retracted versions are declared
+	// by the go.mod file of the latest version, crawled from the default
+	// branch. The tree of a version does not change, so the pages are cached
+	// by version and by entity tag of the latest version.
+	latest, _, err := s.db.GetDoc(ctx, importPath)
+	if err != nil {
+		log.Printf("ERROR db.GetDoc(%q): %v", importPath, err)
+	}
+	etag := version
+	if latest != nil {
+		etag += "-" + latest.Etag
+	}
+	flashMessages := getFlashMessages(resp, req)
+	showPkgGoDevRedirectToast := userReturningFromPkgGoDev(req)
+	hideBanner := hidePkgGoDevBanner(req)
+	cacheable := len(flashMessages) == 0 && !showPkgGoDevRedirectToast && !hideBanner
+	key := func(template string) renderKey {
+		return renderKey{importPath: importPath + "@" + version, etag: etag, template: template + templateExt(req)}
+	}
+	if cacheable {
+		for _, template := range []string{"pkg", "cmd"} {
+			if body, ok := s.renderCache.get(key(template)); ok {
+				return s.templates.write(resp, template+templateExt(req), http.StatusOK, nil, body)
+			}
+		}
+	}
+
+	release, err := s.acquireFetch(ctx, importPath)
+	if err != nil {
+		return err
+	}
+	pdoc, err := doc.GetVersion(ctx, s.httpClient, importPath, version)
+	release()
+	if err != nil {
+		return err
+	}
+	if pdoc.Name == "" {
+		return &httpError{status: http.StatusNotFound}
+	}
+	var retraction *doc.Retraction
+	if latest != nil {
+		pdoc.Deprecated = latest.Deprecated
+		retraction = doc.FindRetraction(latest.Retractions, version)
+	}
+	template := "pkg"
+	if pdoc.IsCmd {
+		template = "cmd"
+	}
+	data := map[string]interface{}{
+		"flashMessages":             flashMessages,
+		"pdoc":                      newTDoc(s.v, pdoc),
+		"showPkgGoDevRedirectToast": showPkgGoDevRedirectToast,
+		"hidePkgGoDevBanner":        hideBanner,
+		"sumStatus":                 string(s.sums.check(ctx, pdoc, version)),
+		"retraction":                retraction,
+	}
+	if !cacheable {
+		return s.templates.execute(resp, template+templateExt(req), http.StatusOK, nil, data)
+	}
+	body, err := s.templates.render(template+templateExt(req), data)
+	if err != nil {
+		return err
+	}
+	s.renderCache.add(key(template), body)
+	return s.templates.write(resp, template+templateExt(req), http.StatusOK, nil, body)
+}
+
+// serveCommit serves the documentation for importPath at the commit with the
+// full or abbreviated SHA sha. Abbreviated SHAs are redirected to the full
+// SHA. The tree of a commit does not change, so the pages are cached by SHA.
+func (s *server) serveCommit(resp http.ResponseWriter, req *http.Request, importPath, sha string) error {
+	if req.URL.RawQuery != "" {
+		// Other views of the package are only available for the default
+		// branch.
+		return s.redirects.redirect(resp, req, "/"+importPath+"?"+req.URL.RawQuery, http.StatusFound)
+	}
+	if err := s.checkFetchable(importPath); err != nil {
+		return err
+	}
+	key := func(template string) renderKey {
+		return renderKey{importPath: importPath + "@" + sha, etag: sha, template: template + templateExt(req)}
+	}
+	if len(sha) == 40 {
+		for _, template := range []string{"pkg", "cmd"} {
+			if body, ok := s.renderCache.get(key(template)); ok {
+				return s.templates.write(resp, template+templateExt(req), http.StatusOK, nil, body)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), s.v.GetDuration(ConfigGetTimeout))
+	defer cancel()
+	release, err := s.acquireFetch(ctx, importPath)
+	if err != nil {
+		return err
+	}
+	defer release()
+	full, err := gosrc.GetCommit(ctx, s.httpClient, importPath, sha)
+	if gosrc.IsNotFound(err) {
+		return &httpError{status: http.StatusNotFound, err: errCommitNotFound}
+	} else if err != nil {
+		return err
+	}
+	if full != sha {
+		return s.redirects.redirect(resp, req, "/"+importPath+"@"+full, http.StatusMovedPermanently)
+	}
+	pdoc, err := doc.GetVersion(ctx, s.httpClient, importPath, sha)
+	if err != nil {
+		return err
+	}
+	if pdoc.Name == "" {
+		return &httpError{status: http.StatusNotFound}
+	}
+	template := "pkg"
+	if pdoc.IsCmd {
+		template = "cmd"
+	}
+	body, err := s.templates.render(template+templateExt(req), map[string]interface{}{
+		"pdoc":   newTDoc(s.v, pdoc),
+		"commit": sha,
+	})
+	if err != nil {
+		return err
+	}
+	s.renderCache.add(key(template), body)
+	return s.templates.write(resp, template+templateExt(req), http.StatusOK, nil, body)
+}
+
+// serveUnexported serves the documentation for importPath including
+// unexported declarations. The documentation is fetched from the VCS and is
+// not stored.
+func (s *server) serveUnexported(resp http.ResponseWriter, req *http.Request, importPath string) error {
+	ctx, cancel := context.WithTimeout(req.Context(), s.v.GetDuration(ConfigGetTimeout))
+	defer cancel()
+	release, err := s.acquireFetch(ctx, importPath)
+	if err != nil {
+		return err
+	}
+	pdoc, err := doc.GetUnexported(ctx, s.httpClient, importPath)
+	release()
+	if err != nil {
+		return err
+	}
+	if pdoc.Name == "" {
+		return &httpError{status: http.StatusNotFound}
+	}
+	template := "pkg"
+	if pdoc.IsCmd {
+		template = "cmd"
 	}
+	return s.templates.execute(resp, template+templateExt(req), http.StatusOK, nil, map[string]interface{}{
+		"flashMessages":             getFlashMessages(resp, req),
+		"pdoc":                      newTDoc(s.v, pdoc),
+		"showPkgGoDevRedirectToast": userReturningFromPkgGoDev(req),
+		"hidePkgGoDevBanner":        hidePkgGoDevBanner(req),
+	})
 }
 
 func (s *server) serveRefresh(resp http.ResponseWriter, req *http.Request) error {
+	if s.v.GetBool(ConfigCachedOnly) {
+		// Only operators populate the database of a cached only server.
+		if _, ok := s.apiKeys[apiKeyFromRequest(req)]; !ok {
+			return &httpError{status: http.StatusForbidden}
+		}
+	}
 	importPath := req.Form.Get("path")
-	_, pkgs, _, err := s.db.Get(req.Context(), importPath)
+	_, pkgs, _, err := s.db.Primary().Get(req.Context(), importPath)
+	if err != nil {
+		return err
+	}
+	release, err := s.acquireFetch(req.Context(), importPath)
 	if err != nil {
+		if e, ok := err.(*httpError); ok && e.err != nil {
+			err = e.err
+		}
+		setFlashMessages(resp, []flashMessage{{ID: "refresh", Args: []string{errorText(err)}}})
+		return s.redirects.redirect(resp, req, "/"+importPath, http.StatusFound)
+	}
+	if err := s.db.DeleteNotFound(importPath); err != nil {
+		release()
+		return err
+	}
+	if err := s.db.DeleteRedirect(importPath); err != nil {
+		release()
 		return err
 	}
 	c := make(chan error, 1)
 	go func() {
+		defer release()
 		_, err := s.crawlDoc(req.Context(), "rfrsh", importPath, nil, len(pkgs) > 0, time.Time{})
 		c <- err
 	}()
@@ -456,8 +1017,7 @@ func (s *server) serveRefresh(resp http.ResponseWriter, req *http.Request) error
 	} else if err != nil {
 		setFlashMessages(resp, []flashMessage{{ID: "refresh", Args: []string{errorText(err)}}})
 	}
-	http.Redirect(resp, req, "/"+importPath, http.StatusFound)
-	return nil
+	return s.redirects.redirect(resp, req, "/"+importPath, http.StatusFound)
 }
 
 func (s *server) serveGoIndex(resp http.ResponseWriter, req *http.Request) error {
@@ -546,6 +1106,48 @@ func (s *server) popular() ([]database.Package, error) {
 	return pkgs, nil
 }
 
+// listingEnabled reports whether the surfaces enumerating the packages in
+// the database are enabled. See ConfigDisableListing.
+func (s *server) listingEnabled() bool {
+	return !s.v.GetBool(ConfigDisableListing)
+}
+
+// listing returns f or, if the enumeration of packages is disabled, a
+// function responding with status 404.
+func (s *server) listing(f func(http.ResponseWriter, *http.Request) error) func(http.ResponseWriter, *http.Request) error {
+	if s.listingEnabled() {
+		return f
+	}
+	return func(http.ResponseWriter, *http.Request) error {
+		return &httpError{status: http.StatusNotFound}
+	}
+}
+
+// featured returns the packages featured on the home page, in the configured
+// order. Packages not in the database are omitted.
+func (s *server) featured() ([]database.Package, error) {
+	var paths []string
+	for _, p := range s.v.GetStringSlice(ConfigFeatured) {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	synopses, err := s.db.Synopses(paths)
+	if err != nil {
+		return nil, err
+	}
+	var pkgs []database.Package
+	for _, p := range paths {
+		if synopsis, ok := synopses[p]; ok {
+			pkgs = append(pkgs, database.Package{Path: p, Synopsis: synopsis})
+		}
+	}
+	return pkgs, nil
+}
+
 func (s *server) serveHome(resp http.ResponseWriter, req *http.Request) error {
 	if req.URL.Path != "/" {
 		return s.servePackage(resp, req)
@@ -553,36 +1155,62 @@ func (s *server) serveHome(resp http.ResponseWriter, req *http.Request) error {
 
 	q := strings.TrimSpace(req.Form.Get("q"))
 	if q == "" {
-		pkgs, err := s.popular()
+		var pkgs []database.Package
+		if s.listingEnabled() {
+			var err error
+			if pkgs, err = s.popular(); err != nil {
+				return err
+			}
+		}
+		featured, err := s.featured()
 		if err != nil {
 			return err
 		}
 
 		return s.templates.execute(resp, "home"+templateExt(req), http.StatusOK, nil,
 			map[string]interface{}{
-				"Popular": pkgs,
+				"Popular":  pkgs,
+				"Featured": featured,
 
 				"showPkgGoDevRedirectToast": userReturningFromPkgGoDev(req),
+				"hidePkgGoDevBanner":        hidePkgGoDevBanner(req),
 			})
 	}
 
+	if format := searchFeedFormat(req); format != "" {
+		if !s.listingEnabled() {
+			return &httpError{status: http.StatusNotFound}
+		}
+		return s.serveSearchFeed(resp, req, q, format)
+	}
+
 	if path, ok := isBrowseURL(q); ok {
 		q = path
 	}
 
-	if gosrc.IsValidRemotePath(q) || (strings.Contains(q, "/") && gosrc.IsGoRepoPath(q)) {
-		pdoc, pkgs, err := s.getDoc(req.Context(), q, queryRequest)
+	if p, ok := queryImportPath(q); ok {
+		pdoc, pkgs, err := s.getDoc(req.Context(), p, queryRequest)
 		if e, ok := err.(gosrc.NotFoundError); ok && e.Redirect != "" {
-			http.Redirect(resp, req, "/"+e.Redirect, http.StatusFound)
-			return nil
+			return s.redirects.redirect(resp, req, "/"+e.Redirect, http.StatusFound)
 		}
 		if err == nil && (pdoc != nil || len(pkgs) > 0) {
-			http.Redirect(resp, req, "/"+q, http.StatusFound)
-			return nil
+			return s.redirects.redirect(resp, req, "/"+p, http.StatusFound)
 		}
 	}
 
-	pkgs, err := s.db.Search(req.Context(), q)
+	if !s.listingEnabled() {
+		return &httpError{status: http.StatusNotFound}
+	}
+
+	it, err := s.db.SearchIter(req.Context(), q, s.v.GetInt(ConfigSearchLimit))
+	if err != nil {
+		return err
+	}
+	// The results after the first batch are rendered as they are read,
+	// flushing the response after each batch.
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+	pkgs, more, err := searchBatches(ctx, it, searchBatchSize)
 	if err != nil {
 		return err
 	}
@@ -595,13 +1223,24 @@ func (s *server) serveHome(resp http.ResponseWriter, req *http.Request) error {
 		s.gceLogger.LogEvent(resp, req, logPkgs)
 	}
 
-	return s.templates.execute(resp, "results"+templateExt(req), http.StatusOK, nil,
+	err = s.templates.execute(resp, "results"+templateExt(req), http.StatusOK, nil,
 		map[string]interface{}{
-			"q":    q,
-			"pkgs": pkgs,
+			"q":     q,
+			"pkgs":  pkgs,
+			"more":  more,
+			"flush": flusher(resp),
 
 			"showPkgGoDevRedirectToast": userReturningFromPkgGoDev(req),
+			"hidePkgGoDevBanner":        hidePkgGoDevBanner(req),
 		})
+	if err == nil {
+		// The status is sent, so errors reading the remaining results can
+		// only be logged.
+		if err := it.Err(); err != nil {
+			log.Printf("ERROR db.SearchIter(%q): %v", q, err)
+		}
+	}
+	return err
 }
 
 func (s *server) serveAbout(resp http.ResponseWriter, req *http.Request) error {
@@ -610,6 +1249,7 @@ func (s *server) serveAbout(resp http.ResponseWriter, req *http.Request) error {
 			"Host": req.Host,
 
 			"showPkgGoDevRedirectToast": userReturningFromPkgGoDev(req),
+			"hidePkgGoDevBanner":        hidePkgGoDevBanner(req),
 		})
 }
 
@@ -629,13 +1269,24 @@ func logError(req *http.Request, err error, rv interface{}) {
 	}
 }
 
+// queryImportPath returns the canonical import path for a search query that
+// is an import path. Standard packages without a slash, such as "http", are
+// searched for instead.
+func queryImportPath(q string) (string, bool) {
+	ip, err := gosrc.ParseImportPath(q)
+	if err != nil || (ip.Host == "" && !strings.Contains(ip.Path, "/")) {
+		return "", false
+	}
+	return ip.Path, true
+}
+
 func (s *server) serveAPISearch(resp http.ResponseWriter, req *http.Request) error {
 	q := strings.TrimSpace(req.Form.Get("q"))
 
 	var pkgs []database.Package
 
-	if gosrc.IsValidRemotePath(q) || (strings.Contains(q, "/") && gosrc.IsGoRepoPath(q)) {
-		pdoc, _, err := s.getDoc(req.Context(), q, apiRequest)
+	if p, ok := queryImportPath(q); ok {
+		pdoc, _, err := s.getDoc(req.Context(), p, apiRequest)
 		if e, ok := err.(gosrc.NotFoundError); ok && e.Redirect != "" {
 			pdoc, _, err = s.getDoc(req.Context(), e.Redirect, robotRequest)
 		}
@@ -661,6 +1312,41 @@ func (s *server) serveAPISearch(resp http.ResponseWriter, req *http.Request) err
 	return json.NewEncoder(resp).Encode(&data)
 }
 
+const (
+	// minSuggestPrefix is the minimum length of the prefix of search
+	// suggestions.
+	minSuggestPrefix = 2
+
+	// maxSuggestions is the maximum number of search suggestions.
+	maxSuggestions = 10
+)
+
+// serveSuggest serves the import paths of the most popular packages with an
+// import path or package name starting with the q query parameter, for
+// completing search queries as they are typed.
+func (s *server) serveSuggest(resp http.ResponseWriter, req *http.Request) error {
+	q := strings.TrimSpace(req.Form.Get("q"))
+	results := []string{}
+	if len(q) >= minSuggestPrefix {
+		paths, err := s.db.Suggest(q, maxSuggestions)
+		if err != nil {
+			return err
+		}
+		if paths != nil {
+			results = paths
+		}
+	}
+	data := struct {
+		Query   string   `json:"q"`
+		Results []string `json:"results"`
+	}{
+		q,
+		results,
+	}
+	resp.Header().Set("Content-Type", jsonMIMEType)
+	return json.NewEncoder(resp).Encode(&data)
+}
+
 func (s *server) serveAPIPackages(resp http.ResponseWriter, req *http.Request) error {
 	pkgs, err := s.db.AllPackages()
 	if err != nil {
@@ -675,6 +1361,68 @@ func (s *server) serveAPIPackages(resp http.ResponseWriter, req *http.Request) e
 	return json.NewEncoder(resp).Encode(&data)
 }
 
+// serveAPISynopses serves the stored synopses of the packages in the JSON
+// array of import paths posted in the request body. Paths not in the
+// database map to null.
+func (s *server) serveAPISynopses(resp http.ResponseWriter, req *http.Request) error {
+	if req.Method != "POST" {
+		return &httpError{status: http.StatusMethodNotAllowed}
+	}
+	var paths []string
+	if err := json.NewDecoder(req.Body).Decode(&paths); err != nil {
+		if isBodyTooLarge(err) {
+			return &httpError{status: http.StatusRequestEntityTooLarge, err: err}
+		}
+		return &httpError{status: http.StatusBadRequest, err: err}
+	}
+	if max := s.v.GetInt(ConfigAPIMaxBatch); len(paths) > max {
+		return &httpError{status: http.StatusBadRequest, err: fmt.Errorf("more than %d paths", max)}
+	}
+	synopses, err := s.db.Synopses(paths)
+	if err != nil {
+		return err
+	}
+	data := make(map[string]*string, len(paths))
+	for _, p := range paths {
+		data[p] = nil
+		if synopsis, ok := synopses[p]; ok {
+			data[p] = &synopsis
+		}
+	}
+	resp.Header().Set("Content-Type", jsonMIMEType)
+	return json.NewEncoder(resp).Encode(data)
+}
+
+func (s *server) serveAPIStats(resp http.ResponseWriter, req *http.Request) error {
+	n, hosts, err := s.db.PackageCount()
+	if err != nil {
+		return err
+	}
+	data := struct {
+		Packages    int                           `json:"packages"`
+		Hosts       map[string]int                `json:"hosts"`
+		RenderCache renderCacheStats              `json:"render_cache"`
+		Fetches     map[string]httputil.HostStats `json:"fetches"`
+		Renders     renderLimiterStats            `json:"renders"`
+		Sweeper     sweeperStats                  `json:"sweeper"`
+		MetaCache   gosrc.MetaCacheStats          `json:"meta_cache"`
+		GraphCache  graphCacheStats               `json:"graph_cache"`
+		CrawlBudget crawlBudgetStats              `json:"crawl_budget"`
+	}{
+		n,
+		hosts,
+		s.renderCache.stats(),
+		s.hostLimits.Stats(),
+		s.renders.stats(),
+		s.sweeper.snapshot(),
+		gosrc.GetMetaCacheStats(),
+		s.graphs.snapshot(),
+		s.crawlBudget.stats(),
+	}
+	resp.Header().Set("Content-Type", jsonMIMEType)
+	return json.NewEncoder(resp).Encode(&data)
+}
+
 func (s *server) serveAPIImporters(resp http.ResponseWriter, req *http.Request) error {
 	importPath := strings.TrimPrefix(req.URL.Path, "/importers/")
 	pkgs, err := s.db.Importers(importPath)
@@ -690,6 +1438,63 @@ func (s *server) serveAPIImporters(resp http.ResponseWriter, req *http.Request)
 	return json.NewEncoder(resp).Encode(&data)
 }
 
+// serveAPIDependents serves the packages with a go.mod file requiring the
+// module with the path following /dependents/. The version query parameter
+// filters the required versions, for example version=>=v1.2.0.
+func (s *server) serveAPIDependents(resp http.ResponseWriter, req *http.Request) error {
+	modulePath := strings.TrimPrefix(req.URL.Path, "/dependents/")
+	match, err := versionPredicate(req.FormValue("version"))
+	if err != nil {
+		return &httpError{status: http.StatusBadRequest, err: err}
+	}
+	pkgs, err := s.db.Dependents(modulePath, match)
+	if err != nil {
+		return err
+	}
+	data := struct {
+		Results []database.Dependent `json:"results"`
+	}{
+		pkgs,
+	}
+	resp.Header().Set("Content-Type", jsonMIMEType)
+	return json.NewEncoder(resp).Encode(&data)
+}
+
+// serveAPIViews serves the number of views of the package page of the import
+// path following /views/, if view counting is enabled.
+func (s *server) serveAPIViews(resp http.ResponseWriter, req *http.Request) error {
+	return s.serveViews(resp, strings.TrimPrefix(req.URL.Path, "/views/"))
+}
+
+// serveViewCount serves the number of views of the package page of the
+// import path in the path parameter, loaded by the package page.
+func (s *server) serveViewCount(resp http.ResponseWriter, req *http.Request) error {
+	importPath := req.Form.Get("path")
+	if importPath == "" {
+		return &httpError{status: http.StatusBadRequest}
+	}
+	return s.serveViews(resp, importPath)
+}
+
+func (s *server) serveViews(resp http.ResponseWriter, importPath string) error {
+	if !s.v.GetBool(ConfigViewCount) {
+		return &httpError{status: http.StatusNotFound}
+	}
+	views, err := s.db.ViewCount(importPath)
+	if err != nil {
+		return err
+	}
+	data := struct {
+		Path  string `json:"path"`
+		Views int64  `json:"views"`
+	}{
+		importPath,
+		views,
+	}
+	resp.Header().Set("Content-Type", jsonMIMEType)
+	return json.NewEncoder(resp).Encode(&data)
+}
+
 func (s *server) serveAPIImports(resp http.ResponseWriter, req *http.Request) error {
 	importPath := strings.TrimPrefix(req.URL.Path, "/imports/")
 	pdoc, _, err := s.getDoc(req.Context(), importPath, robotRequest)
@@ -718,6 +1523,43 @@ func (s *server) serveAPIImports(resp http.ResponseWriter, req *http.Request) er
 	return json.NewEncoder(resp).Encode(&data)
 }
 
+// apiRetraction is a version or range of versions retracted by the go.mod
+// file of a module.
+type apiRetraction struct {
+	Low       string `json:"low"`
+	High      string `json:"high"`
+	Rationale string `json:"rationale,omitempty"`
+}
+
+// serveAPIModule serves the deprecation of the module of a package and the
+// versions retracted by the module.
+func (s *server) serveAPIModule(resp http.ResponseWriter, req *http.Request) error {
+	importPath := strings.TrimPrefix(req.URL.Path, "/module/")
+	pdoc, _, err := s.getDoc(req.Context(), importPath, robotRequest)
+	if err != nil {
+		return err
+	}
+	if pdoc == nil || pdoc.Name == "" {
+		return &httpError{status: http.StatusNotFound}
+	}
+	data := struct {
+		Path        string          `json:"path"`
+		ModulePath  string          `json:"modulePath,omitempty"`
+		Deprecated  string          `json:"deprecated,omitempty"`
+		Retractions []apiRetraction `json:"retractions"`
+	}{
+		Path:        pdoc.ImportPath,
+		ModulePath:  pdoc.ModulePath,
+		Deprecated:  pdoc.Deprecated,
+		Retractions: []apiRetraction{},
+	}
+	for _, r := range pdoc.Retractions {
+		data.Retractions = append(data.Retractions, apiRetraction{r.Low, r.High, r.Rationale})
+	}
+	resp.Header().Set("Content-Type", jsonMIMEType)
+	return json.NewEncoder(resp).Encode(&data)
+}
+
 func serveAPIHome(resp http.ResponseWriter, req *http.Request) error {
 	return &httpError{status: http.StatusNotFound}
 }
@@ -725,21 +1567,122 @@ func serveAPIHome(resp http.ResponseWriter, req *http.Request) error {
 type requestCleaner struct {
 	h                 http.Handler
 	trustProxyHeaders bool
+	forceHTTPS        bool
+
+	// Maximum size of the request body. Zero means 2048 bytes.
+	maxBodyBytes int64
 }
 
 func (rc requestCleaner) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	req2 := new(http.Request)
 	*req2 = *req
 	if rc.trustProxyHeaders {
-		if s := req.Header.Get("X-Forwarded-For"); s != "" {
+		if s := forwardedFor(req.Header.Get("X-Forwarded-For")); s != "" {
 			req2.RemoteAddr = s
 		}
 	}
-	req2.Body = http.MaxBytesReader(w, req.Body, 2048)
-	req2.ParseForm()
+	u := *req.URL
+	u.Scheme = rc.scheme(req)
+	req2.URL = &u
+	maxBodyBytes := rc.maxBodyBytes
+	if maxBodyBytes == 0 {
+		maxBodyBytes = 2048
+	}
+	req2.Body = http.MaxBytesReader(w, req.Body, maxBodyBytes)
+	if err := req2.ParseForm(); isBodyTooLarge(err) {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
 	rc.h.ServeHTTP(w, req2)
 }
 
+// forwardedFor returns the address of the client in the X-Forwarded-For
+// header value s. It is the right-most address, added by the trusted proxy;
+// the addresses before it are sent by the client and cannot be trusted.
+func forwardedFor(s string) string {
+	if i := strings.LastIndexByte(s, ','); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(s)
+}
+
+// isBodyTooLarge reports whether err is the error returned when reading a
+// request body past the limit of http.MaxBytesReader.
+func isBodyTooLarge(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "http: request body too large")
+}
+
+// forwardedHost returns the host requested by the client from the
+// X-Forwarded-Host header set by a proxy or req.Host if the header is missing
+// or invalid. As for X-Forwarded-For, the right-most host is used; the hosts
+// before it are sent by the client and cannot be trusted.
+func forwardedHost(req *http.Request) string {
+	var host string
+	if values := req.Header.Values("X-Forwarded-Host"); len(values) > 0 {
+		host = values[len(values)-1]
+	}
+	if i := strings.LastIndexByte(host, ','); i >= 0 {
+		host = host[i+1:]
+	}
+	host = strings.TrimSpace(host)
+	if host == "" || strings.ContainsAny(host, "/\\@ \t") {
+		return req.Host
+	}
+	return host
+}
+
+// withForwardedHost returns req or a shallow copy of req with the host
+// requested by the client of a trusted proxy.
+func withForwardedHost(req *http.Request) *http.Request {
+	host := forwardedHost(req)
+	if host == req.Host {
+		return req
+	}
+	req2 := new(http.Request)
+	*req2 = *req
+	req2.Host = host
+	if req.URL.Host != "" {
+		u := *req.URL
+		u.Host = host
+		req2.URL = &u
+	}
+	return req2
+}
+
+// scheme returns the scheme of the URL requested by the client.
+func (rc requestCleaner) scheme(req *http.Request) string {
+	switch proto := req.Header.Get("X-Forwarded-Proto"); {
+	case rc.forceHTTPS || req.TLS != nil:
+		return "https"
+	case rc.trustProxyHeaders && (proto == "http" || proto == "https"):
+		return proto
+	default:
+		return "http"
+	}
+}
+
+// absoluteURL returns the absolute URL of path on the host of the request,
+// using the scheme that requestCleaner set in the URL of the request.
+func absoluteURL(req *http.Request, path string) string {
+	scheme := req.URL.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	return scheme + "://" + req.Host + path
+}
+
+// siteURL returns the URL of this server without a trailing slash, for the
+// absolute URLs of the response to req. Unless base_url is configured, the URL
+// is the one of the request, which is controlled by the client, and the
+// response is marked private so that shared caches do not store it.
+func (s *server) siteURL(resp http.ResponseWriter, req *http.Request) string {
+	if base := s.v.GetString(ConfigBaseURL); base != "" {
+		return strings.TrimSuffix(base, "/")
+	}
+	resp.Header().Set("Cache-Control", "private")
+	return absoluteURL(req, "")
+}
+
 type errorHandler struct {
 	fn    func(resp http.ResponseWriter, req *http.Request) error
 	errFn httputil.Error
@@ -754,10 +1697,13 @@ func (eh errorHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 		}
 	}()
 
-	rb := new(httputil.ResponseBuffer)
+	rb := httputil.NewResponseBuffer(resp)
 	err := eh.fn(rb, req)
 	if err == nil {
 		rb.WriteTo(resp)
+	} else if rb.Flushed() {
+		// The status of streamed responses is already sent.
+		logError(req, err, nil)
 	} else if e, ok := err.(*httpError); ok {
 		if e.status >= 500 {
 			logError(req, err, nil)
@@ -771,23 +1717,71 @@ func (eh errorHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// goneError is the reason a package was permanently removed.
+type goneError string
+
+func (e goneError) Error() string { return string(e) }
+
 func errorText(err error) string {
 	if err == errUpdateTimeout {
 		return "Timeout getting package files from the version control system."
 	}
+	if err == errRenderBusy {
+		return "The server is busy fetching other packages. Try again later."
+	}
+	if err == errHostBusy {
+		return "Too many packages from this host are being fetched. Please try again shortly."
+	}
+	if err == errBuildPanic {
+		return "Error building the package documentation. Try again later."
+	}
+	if err == errSourceBackoff {
+		return "The package files cannot be fetched right now. Try again later."
+	}
 	if e, ok := err.(*gosrc.RemoteError); ok {
 		return "Error getting package files from " + e.Host + "."
 	}
 	return "Internal server error."
 }
 
+// notFoundText returns the message shown on the not found page for err. The
+// text of other errors, which can contain the details of requests to remote
+// servers, is not shown.
+func notFoundText(err error) string {
+	if err == errCommitNotFound {
+		return "Commit not found"
+	}
+	return ""
+}
+
 func (s *server) handleError(resp http.ResponseWriter, req *http.Request, status int, err error) {
 	switch status {
 	case http.StatusNotFound:
 		s.templates.execute(resp, "notfound"+templateExt(req), status, nil, map[string]interface{}{
 			"flashMessages": getFlashMessages(resp, req),
+			"message":       notFoundText(err),
+		})
+	case http.StatusGone:
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		}
+		s.templates.execute(resp, "gone"+templateExt(req), status, nil, map[string]interface{}{
+			"path":   strings.TrimPrefix(req.URL.Path, "/"),
+			"reason": reason,
 		})
+	case http.StatusServiceUnavailable:
+		resp.Header().Set("Content-Type", textMIMEType)
+		resp.Header().Set("Retry-After", "10")
+		resp.WriteHeader(status)
+		io.WriteString(resp, errorText(err))
 	default:
+		if err == errBuildPanic {
+			s.templates.execute(resp, "unavailable"+templateExt(req), http.StatusInternalServerError, nil, map[string]interface{}{
+				"path": strings.TrimPrefix(req.URL.Path, "/"),
+			})
+			return
+		}
 		resp.Header().Set("Content-Type", textMIMEType)
 		resp.WriteHeader(http.StatusInternalServerError)
 		io.WriteString(resp, errorText(err))
@@ -801,6 +1795,9 @@ func handleAPIError(resp http.ResponseWriter, req *http.Request, status int, err
 		} `json:"error"`
 	}
 	data.Error.Message = http.StatusText(status)
+	if e, ok := err.(gosrc.NotFoundError); ok && e.Message != "" {
+		data.Error.Message = e.Message
+	}
 	resp.Header().Set("Content-Type", jsonMIMEType)
 	resp.WriteHeader(status)
 	json.NewEncoder(resp).Encode(&data)
@@ -873,20 +1870,100 @@ type server struct {
 	statusPNG http.Handler
 	statusSVG http.Handler
 
-	root rootHandler
+	root http.Handler
 
 	// A semaphore to limit concurrent ?import-graph requests.
 	importGraphSem chan struct{}
+
+	// API keys by key.
+	apiKeys map[string]apiKey
+
+	// Rendered pages of recently viewed packages.
+	renderCache renderStore
+
+	// Clients configured to always be classified as robots.
+	robots *robotList
+
+	// Requests not teed to pkg.go.dev.
+	teeExclusions *teeExclusions
+
+	// Limits of the concurrent requests of httpClient to each host.
+	hostLimits *httputil.HostLimitTransport
+
+	// Limit of the packages fetched and built concurrently for requests.
+	renders *renderLimiter
+
+	// Budgets of the crawls of each host triggered by requests.
+	crawlBudget *crawlBudget
+
+	// Checker of the go.mod files of package versions.
+	sums *sumChecker
+
+	// Results of the resolve API endpoint.
+	resolved *resolveCache
+
+	// Headers set on all responses.
+	responseHeaders http.Header
+
+	// Crawls in progress and recent crawl failures.
+	crawls crawlTracker
+
+	// Background refresh of the packages due to be crawled.
+	sweeper *sweeper
+
+	// Lookups of the import graphs precomputed for popular packages.
+	graphs graphCache
+
+	// Clients allowed to use the /debug/ endpoints.
+	debugAccess *debugAccess
+	redirects   *redirectTargets
+
+	// Site-wide notice shown at the top of every page.
+	notices *noticeSource
+
+	// Owners of the packages without a CODEOWNERS file, by import path
+	// pattern.
+	owners []doc.CodeOwnersRule
 }
 
 func newServer(ctx context.Context, v *viper.Viper) (*server, error) {
 	s := &server{
 		v:              v,
-		httpClient:     newHTTPClient(v),
 		importGraphSem: make(chan struct{}, 10),
+		teeExclusions:  newTeeExclusions(v.GetStringSlice(ConfigTeeExcludeExts), v.GetStringSlice(ConfigTeeExcludePaths)),
 	}
+	s.httpClient, s.hostLimits = newHTTPClient(v)
+	s.renders = newRenderLimiter(v.GetInt(ConfigMaxRenders), v.GetDuration(ConfigRenderQueueWait))
+	s.resolved = newResolveCache(v.GetDuration(ConfigResolveCacheTTL), v.GetDuration(ConfigMetaFailureTTL))
+	s.responseHeaders = responseHeaders(v)
+	s.sweeper = newSweeper(v)
+	s.notices = newNoticeSource(v)
+	s.redirects = newRedirectTargets(v.GetStringSlice(ConfigRedirectHosts))
 
 	var err error
+	if s.renderCache, err = newRenderStore(v); err != nil {
+		return nil, err
+	}
+	if s.apiKeys, err = parseAPIKeys(v.GetStringSlice(ConfigAPIKeys)); err != nil {
+		return nil, err
+	}
+	if s.sums, err = newSumChecker(v.GetString(ConfigSumDB), v.GetString(ConfigSumDBKey), s.httpClient); err != nil {
+		return nil, err
+	}
+	hostBudgets, err := parseHostBudgets(v.GetStringSlice(ConfigCrawlHostBudgets))
+	if err != nil {
+		return nil, err
+	}
+	s.crawlBudget = newCrawlBudget(hostBudget{rate: v.GetFloat64(ConfigCrawlHostRate)}, hostBudgets)
+	if s.debugAccess, err = parseDebugAccess(v.GetString(ConfigDebugKey), v.GetStringSlice(ConfigDebugCIDRs)); err != nil {
+		return nil, err
+	}
+	if s.robots, err = parseRobotList(v.GetStringSlice(ConfigRobotUserAgents), v.GetStringSlice(ConfigRobotCIDRs)); err != nil {
+		return nil, err
+	}
+	if s.owners, err = loadOwnersFile(v.GetString(ConfigOwnersFile)); err != nil {
+		return nil, fmt.Errorf("owners file: %v", err)
+	}
 	if proj := s.v.GetString(ConfigProject); proj != "" {
 		if s.traceClient, err = trace.NewClient(ctx, proj); err != nil {
 			return nil, err
@@ -905,10 +1982,12 @@ func newServer(ctx context.Context, v *viper.Viper) (*server, error) {
 		s.crawlTopic = ps.Topic(ConfigCrawlPubSubTopic)
 	}
 
+	cache := newCachePolicies(v)
 	assets := v.GetString(ConfigAssetsDir)
 	staticServer := httputil.StaticServer{
-		Dir:    assets,
-		MaxAge: time.Hour,
+		Dir:          assets,
+		MaxAge:       time.Hour,
+		CacheControl: cache[routeStatic],
 		MIMETypes: map[string]string{
 			".css": "text/css; charset=utf-8",
 			".js":  "text/javascript; charset=utf-8",
@@ -918,23 +1997,32 @@ func newServer(ctx context.Context, v *viper.Viper) (*server, error) {
 	s.statusSVG = staticServer.FileHandler("status.svg")
 
 	apiHandler := func(f func(http.ResponseWriter, *http.Request) error) http.Handler {
-		return requestCleaner{
+		return cache.handler(routeAPI, requestCleaner{
 			h: errorHandler{
-				fn:    f,
+				fn:    s.apiRateLimit(f),
 				errFn: handleAPIError,
 			},
 			trustProxyHeaders: v.GetBool(ConfigTrustProxyHeaders),
-		}
+			forceHTTPS:        v.GetBool(ConfigForceHTTPS),
+			maxBodyBytes:      v.GetInt64(ConfigAPIMaxBodyBytes),
+		})
 	}
 	apiMux := http.NewServeMux()
 	apiMux.Handle("/favicon.ico", staticServer.FileHandler("favicon.ico"))
 	apiMux.Handle("/google3d2f3cd4cc2bb44b.html", staticServer.FileHandler("google3d2f3cd4cc2bb44b.html"))
 	apiMux.Handle("/humans.txt", staticServer.FileHandler("humans.txt"))
 	apiMux.Handle("/robots.txt", staticServer.FileHandler("apiRobots.txt"))
-	apiMux.Handle("/search", apiHandler(s.serveAPISearch))
-	apiMux.Handle("/packages", apiHandler(s.serveAPIPackages))
-	apiMux.Handle("/importers/", apiHandler(s.serveAPIImporters))
+	apiMux.Handle("/search", apiHandler(s.listing(s.serveAPISearch)))
+	apiMux.Handle("/packages", apiHandler(s.listing(s.serveAPIPackages)))
+	apiMux.Handle("/stats", apiHandler(s.serveAPIStats))
+	apiMux.Handle("/synopses", apiHandler(s.serveAPISynopses))
+	apiMux.Handle("/importers/", apiHandler(s.listing(s.serveAPIImporters)))
+	apiMux.Handle("/dependents/", apiHandler(s.listing(s.serveAPIDependents)))
 	apiMux.Handle("/imports/", apiHandler(s.serveAPIImports))
+	apiMux.Handle("/views/", apiHandler(s.serveAPIViews))
+	apiMux.Handle("/symbols/", apiHandler(s.serveAPISymbols))
+	apiMux.Handle("/module/", apiHandler(s.serveAPIModule))
+	apiMux.Handle("/resolve/", apiHandler(s.serveAPIResolve))
 	apiMux.Handle("/", apiHandler(serveAPIHome))
 
 	mux := http.NewServeMux()
@@ -942,13 +2030,16 @@ func newServer(ctx context.Context, v *viper.Viper) (*server, error) {
 		"third_party/jquery.timeago.js",
 		"site.js"))
 	mux.Handle("/-/site.css", staticServer.FilesHandler("site.css"))
+	mux.Handle("/-/branding.css", newBranding(v))
+	mux.Handle("/-/analytics.js", staticServer.FilesHandler("analytics.js"))
 	mux.Handle("/-/bootstrap.min.css", staticServer.FilesHandler("bootstrap.min.css"))
 	mux.Handle("/-/bootstrap.min.js", staticServer.FilesHandler("bootstrap.min.js"))
 	mux.Handle("/-/jquery-2.0.3.min.js", staticServer.FilesHandler("jquery-2.0.3.min.js"))
 	if s.v.GetBool(ConfigSidebar) {
 		mux.Handle("/-/sidebar.css", staticServer.FilesHandler("sidebar.css"))
 	}
-	mux.Handle("/-/", http.NotFoundHandler())
+	cacheBusters := &httputil.CacheBusters{Handler: mux}
+	mux.Handle("/-/", httputil.FingerprintHandler{CacheBusters: cacheBusters})
 
 	handler := func(f func(http.ResponseWriter, *http.Request) error) http.Handler {
 		return requestCleaner{
@@ -957,14 +2048,35 @@ func newServer(ctx context.Context, v *viper.Viper) (*server, error) {
 				errFn: s.handleError,
 			},
 			trustProxyHeaders: v.GetBool(ConfigTrustProxyHeaders),
+			forceHTTPS:        v.GetBool(ConfigForceHTTPS),
 		}
 	}
+	pageHandler := func(f func(http.ResponseWriter, *http.Request) error) http.Handler {
+		return cache.handler(routePackage, handler(f))
+	}
+	redirectDefault := v.GetBool(ConfigRedirectDefault)
 
-	mux.Handle("/-/about", handler(pkgGoDevRedirectHandler(s.serveAbout)))
+	mux.Handle("/-/about", pageHandler(pkgGoDevRedirectHandler(s.serveAbout, redirectDefault, s.redirects)))
 	mux.Handle("/-/bot", handler(s.serveBot))
-	mux.Handle("/-/go", handler(pkgGoDevRedirectHandler(s.serveGoIndex)))
-	mux.Handle("/-/subrepo", handler(s.serveGoSubrepoIndex))
+	mux.Handle("/-/compare", handler(s.serveCompare))
+	mux.Handle("/-/go", pageHandler(pkgGoDevRedirectHandler(s.serveGoIndex, redirectDefault, s.redirects)))
+	mux.Handle("/-/subrepo", pageHandler(s.serveGoSubrepoIndex))
 	mux.Handle("/-/refresh", handler(s.serveRefresh))
+	mux.Handle("/debug/crawl-queue", handler(s.serveCrawlQueue))
+	mux.Handle("/debug/crawl-history", handler(s.serveCrawlHistory))
+	mux.Handle("/search/suggest", cache.handler(routeSuggest, handler(s.listing(s.serveSuggest))))
+	if s.v.GetBool(ConfigProxySource) {
+		mux.Handle("/-/source", pageHandler(s.serveSource))
+	}
+	if s.v.GetBool(ConfigViewCount) {
+		mux.Handle("/-/views", handler(s.serveViewCount))
+	}
+	if s.v.GetBool(ConfigSymbolTips) {
+		mux.Handle("/-/symbol", cache.handler(routePackage, handler(s.serveSymbolTip)))
+	}
+	if s.v.GetBool(ConfigLLMsTxt) {
+		mux.Handle("/llms.txt", cache.handler(routeFeed, handler(s.listing(s.serveLLMsTxt))))
+	}
 	mux.Handle("/about", http.RedirectHandler("/-/about", http.StatusMovedPermanently))
 	mux.Handle("/favicon.ico", staticServer.FileHandler("favicon.ico"))
 	mux.Handle("/google3d2f3cd4cc2bb44b.html", staticServer.FileHandler("google3d2f3cd4cc2bb44b.html"))
@@ -973,7 +2085,7 @@ func newServer(ctx context.Context, v *viper.Viper) (*server, error) {
 	mux.Handle("/BingSiteAuth.xml", staticServer.FileHandler("BingSiteAuth.xml"))
 	mux.Handle("/C", http.RedirectHandler("http://golang.org/doc/articles/c_go_cgo.html", http.StatusMovedPermanently))
 	mux.Handle("/code.jquery.com/", http.NotFoundHandler())
-	mux.Handle("/", handler(pkgGoDevRedirectHandler(s.serveHome)))
+	mux.Handle("/", cache.homeHandler(handler(pkgGoDevRedirectHandler(s.serveHome, redirectDefault, s.redirects))))
 
 	ahMux := http.NewServeMux()
 	ready := new(health.Handler)
@@ -984,14 +2096,17 @@ func newServer(ctx context.Context, v *viper.Viper) (*server, error) {
 	mainMux.Handle("/_ah/", ahMux)
 	mainMux.Handle("/", s.traceClient.HTTPHandler(mux))
 
-	s.root = rootHandler{
+	s.root, err = newSiteAuth(rootHandler{
 		{"api.", httpsRedirectHandler{s.traceClient.HTTPHandler(apiMux)}},
 		{"talks.godoc.org", otherDomainHandler{"https", "go-talks.appspot.com"}},
 		{"", httpsRedirectHandler{mainMux}},
+	}, v.GetStringSlice(ConfigSiteAuthUsers), v.GetString(ConfigSiteAuthToken), v.GetString(ConfigSiteName))
+	if err != nil {
+		return nil, err
 	}
+	s.root = gddolog.NewHTTPContextHandler(s.root, nil, v.GetBool("on_appengine"))
 
-	cacheBusters := &httputil.CacheBusters{Handler: mux}
-	s.templates, err = parseTemplates(assets, cacheBusters, v)
+	s.templates, err = parseTemplates(assets, cacheBusters, v, s.notices)
 	if err != nil {
 		return nil, err
 	}
@@ -1004,6 +2119,22 @@ func newServer(ctx context.Context, v *viper.Viper) (*server, error) {
 	if err != nil {
 		return nil, fmt.Errorf("open database: %v", err)
 	}
+	s.db.Gzip = v.GetBool(ConfigDBGzip)
+	ranking := database.RankingWeights{
+		Terms:      v.GetFloat64(ConfigRankTerms),
+		Popularity: v.GetFloat64(ConfigRankPopularity),
+		Recency:    v.GetFloat64(ConfigRankRecency),
+		Standard:   v.GetFloat64(ConfigRankStandard),
+	}
+	if err := ranking.Validate(); err != nil {
+		return nil, fmt.Errorf("search ranking: %v", err)
+	}
+	s.db.Ranking = &ranking
+	if v.GetBool(ConfigDBReadReplica) {
+		for _, uri := range v.GetStringSlice(ConfigDBReplicas) {
+			s.db.Replicas = append(s.db.Replicas, database.NewPool(uri, v.GetDuration(ConfigDBIdleTimeout), v.GetBool(ConfigDBLog)))
+		}
+	}
 	ready.Add(s.db)
 	if gceLogName := v.GetString(ConfigGCELogName); gceLogName != "" {
 		logc, err := logging.NewClient(ctx, v.GetString(ConfigProject))
@@ -1029,6 +2160,12 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func translateStatus(code int) int {
 	if code == 0 {
 		return http.StatusOK
@@ -1037,8 +2174,14 @@ func translateStatus(code int) int {
 }
 
 func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.v.GetBool(ConfigTrustProxyHeaders) {
+		r = withForwardedHost(r)
+	}
 	start := time.Now()
 	s.logRequestStart(r)
+	for k, vs := range s.responseHeaders {
+		w.Header()[k] = vs
+	}
 	w2 := &responseWriter{ResponseWriter: w}
 	s.root.ServeHTTP(w2, r)
 	latency := time.Since(start)
@@ -1075,12 +2218,12 @@ func (s *server) logRequestEnd(req *http.Request, latency time.Duration) {
 }
 
 func (s *server) teeRequestToPkgGoDev(r *http.Request, latency time.Duration, status int) {
-	if !shouldTeeRequest(r.URL.Path) {
-		log.Printf("teeRequestToPkgGoDev(%q): not teeing request", r.URL.Path)
+	if !s.teeExclusions.shouldTeeRequest(r.URL.Path) {
+		log.Printf("DEBUG teeRequestToPkgGoDev(%q): not teeing request", r.URL.Path)
 		return
 	}
 	if strings.ToLower(os.Getenv("GDDO_TEE_REQUESTS_TO_PKGGODEV")) == "true" {
-		gddoEvent, pkggodevEvent := teeRequestToPkgGoDev(r, latency, s.isRobot(r), status)
+		gddoEvent, pkggodevEvent := teeRequestToPkgGoDev(r, latency, s.isRobot(r), status, s.v.GetBool(ConfigRedirectDefault))
 		payload := map[string]interface{}{
 			"godoc.org":  gddoEvent,
 			"pkg.go.dev": pkggodevEvent,
@@ -1088,8 +2231,7 @@ func (s *server) teeRequestToPkgGoDev(r *http.Request, latency time.Duration, st
 
 		if s.gceLogger == nil {
 			for k, v := range payload {
-				log.Printf("%q", k)
-				log.Printf("%+v", v)
+				log.Printf("DEBUG %q: %+v", k, v)
 			}
 			return
 		}
@@ -1113,15 +2255,43 @@ func main() {
 		log.Fatal(ctx, "load config", "error", err.Error())
 	}
 	doc.SetDefaultGOOS(v.GetString(ConfigDefaultGOOS))
+	doc.SetMaxSynopsisLength(v.GetInt(ConfigSynopsisLength))
+	if err := doc.SetSynopsisSources(v.GetStringSlice(ConfigSynopsisSources)); err != nil {
+		log.Fatal("error setting synopsis sources:", err)
+	}
+	gosrc.SetAllowedHosts(v.GetStringSlice(ConfigAllowedHosts))
+	gosrc.SetInsecureHosts(v.GetStringSlice(ConfigInsecureHosts))
+	gosrc.SetMetaCacheTTL(v.GetDuration(ConfigMetaCacheTTL), v.GetDuration(ConfigMetaFailureTTL))
+	if err := loadExcludeFile(v.GetString(ConfigExcludeFile)); err != nil {
+		log.Fatal("error reading exclude file:", err)
+	}
+	if root := v.GetString(ConfigLocalModule); root != "" {
+		if err := gosrc.SetLocalModule(root); err != nil {
+			log.Fatal("error reading local module:", err)
+		}
+	}
 
 	s, err := newServer(ctx, v)
 	if err != nil {
 		log.Fatal("error creating server:", err)
 	}
 
+	if c, ok := s.renderCache.(*redisRenderCache); ok {
+		go c.subscribe(ctx)
+	}
+	go func() {
+		paths, err := s.warmPaths()
+		if err != nil {
+			log.Printf("Task Warm: %v", err)
+			return
+		}
+		if len(paths) > 0 {
+			s.warmCache(ctx, paths, s.v.GetInt(ConfigWarmConcurrency))
+		}
+	}()
 	go func() {
 		for range time.Tick(s.v.GetDuration(ConfigCrawlInterval)) {
-			if err := s.doCrawl(ctx); err != nil {
+			if err := s.sweep(ctx); err != nil {
 				log.Printf("Task Crawl: %v", err)
 			}
 		}
@@ -1133,6 +2303,16 @@ func main() {
 			}
 		}
 	}()
+	if s.v.GetInt(ConfigGraphCacheSize) > 0 && s.v.GetDuration(ConfigGraphCacheInterval) > 0 {
+		go func() {
+			for {
+				if err := s.precomputeGraphs(ctx); err != nil {
+					log.Printf("Task Graphs: %v", err)
+				}
+				time.Sleep(s.v.GetDuration(ConfigGraphCacheInterval))
+			}
+		}()
+	}
 	http.Handle("/", s)
 	log.Fatal(http.ListenAndServe(s.v.GetString(ConfigBindAddress), s))
 }
@@ -7,7 +7,18 @@
 package main
 
 import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/golang/gddo/database"
+	"github.com/golang/gddo/gosrc"
+	"github.com/golang/gddo/httputil"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/viper"
 )
 
 var robotTests = []string{
@@ -33,3 +44,281 @@ func TestRobotPat(t *testing.T) {
 		}
 	}
 }
+
+func TestNotModifiedSince(t *testing.T) {
+	updated := time.Date(2020, 3, 4, 5, 6, 7, 800, time.UTC)
+	for _, tt := range []struct {
+		ims  string
+		want bool
+	}{
+		{"", false},
+		{"garbage", false},
+		{"Wed, 04 Mar 2020 05:06:06 GMT", false},
+		{"Wed, 04 Mar 2020 05:06:07 GMT", true},
+		{"Thu, 05 Mar 2020 00:00:00 GMT", true},
+	} {
+		req := httptest.NewRequest("GET", "/github.com/user/repo", nil)
+		if tt.ims != "" {
+			req.Header.Set("If-Modified-Since", tt.ims)
+		}
+		if got := notModifiedSince(req, updated); got != tt.want {
+			t.Errorf("notModifiedSince(%q) = %t, want %t", tt.ims, got, tt.want)
+		}
+	}
+	req := httptest.NewRequest("GET", "/github.com/user/repo", nil)
+	req.Header.Set("If-Modified-Since", updated.Format(http.TimeFormat))
+	if notModifiedSince(req, time.Time{}) {
+		t.Error("notModifiedSince with zero time = true, want false")
+	}
+}
+
+func TestTemplateExt(t *testing.T) {
+	for _, tt := range []struct {
+		url    string
+		accept string
+		want   string
+	}{
+		{"/fmt", "", ".html"},
+		{"/fmt", "text/html,application/xhtml+xml,*/*;q=0.8", ".html"},
+		{"/fmt", "*/*", ".html"},
+		{"/fmt", "text/plain", ".txt"},
+		{"/fmt?text", "", ".txt"},
+		{"/fmt?text", "text/html", ".txt"},
+		{"/fmt?textual", "", ".html"},
+	} {
+		req := httptest.NewRequest("GET", tt.url, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		if got := templateExt(req); got != tt.want {
+			t.Errorf("templateExt(%q, Accept: %q) = %q, want %q", tt.url, tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestRequestCleanerForwardedFor(t *testing.T) {
+	for _, tt := range []struct {
+		rc     requestCleaner
+		header string
+		want   string
+	}{
+		{requestCleaner{}, "10.0.0.1", "192.0.2.1:1234"},
+		{requestCleaner{trustProxyHeaders: true}, "", "192.0.2.1:1234"},
+		{requestCleaner{trustProxyHeaders: true}, "203.0.113.7", "203.0.113.7"},
+		{requestCleaner{trustProxyHeaders: true}, "10.0.0.1, 203.0.113.7", "203.0.113.7"},
+	} {
+		var got string
+		tt.rc.h = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			got = req.RemoteAddr
+		})
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		if tt.header != "" {
+			req.Header.Set("X-Forwarded-For", tt.header)
+		}
+		tt.rc.ServeHTTP(httptest.NewRecorder(), req)
+		if got != tt.want {
+			t.Errorf("%+v with X-Forwarded-For %q: RemoteAddr = %q, want %q", tt.rc, tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestRequestCleanerScheme(t *testing.T) {
+	for _, tt := range []struct {
+		rc    requestCleaner
+		proto string
+		want  string
+	}{
+		{requestCleaner{}, "", "http://example.com/a/b"},
+		{requestCleaner{}, "https", "http://example.com/a/b"},
+		{requestCleaner{trustProxyHeaders: true}, "https", "https://example.com/a/b"},
+		{requestCleaner{trustProxyHeaders: true}, "http", "http://example.com/a/b"},
+		{requestCleaner{trustProxyHeaders: true}, "gopher", "http://example.com/a/b"},
+		{requestCleaner{forceHTTPS: true}, "", "https://example.com/a/b"},
+		{requestCleaner{trustProxyHeaders: true, forceHTTPS: true}, "http", "https://example.com/a/b"},
+	} {
+		var got string
+		tt.rc.h = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			got = absoluteURL(req, "/a/b")
+		})
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		if tt.proto != "" {
+			req.Header.Set("X-Forwarded-Proto", tt.proto)
+		}
+		tt.rc.ServeHTTP(httptest.NewRecorder(), req)
+		if got != tt.want {
+			t.Errorf("%+v with X-Forwarded-Proto %q: absoluteURL = %q, want %q", tt.rc, tt.proto, got, tt.want)
+		}
+	}
+}
+
+func TestRequestCleanerBodyLimit(t *testing.T) {
+	var readErr error
+	rc := requestCleaner{
+		h: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, readErr = ioutil.ReadAll(req.Body)
+		}),
+		maxBodyBytes: 16,
+	}
+
+	for _, tt := range []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		tooLarge    bool
+	}{
+		{"small form", "application/x-www-form-urlencoded", "path=a", http.StatusOK, false},
+		{"large form", "application/x-www-form-urlencoded", "path=" + strings.Repeat("a", 32), http.StatusRequestEntityTooLarge, false},
+		{"small JSON", "application/json", `["a"]`, http.StatusOK, false},
+		{"large JSON", "application/json", `["` + strings.Repeat("a", 32) + `"]`, http.StatusOK, true},
+	} {
+		readErr = nil
+		req := httptest.NewRequest("POST", "http://example.com/", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		w := httptest.NewRecorder()
+		rc.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if got := isBodyTooLarge(readErr); got != tt.tooLarge {
+			t.Errorf("%s: isBodyTooLarge(%v) = %v, want %v", tt.name, readErr, got, tt.tooLarge)
+		}
+	}
+}
+
+func TestListing(t *testing.T) {
+	list := func(http.ResponseWriter, *http.Request) error { return nil }
+	for _, disabled := range []bool{false, true} {
+		v := viper.New()
+		v.Set(ConfigDisableListing, disabled)
+		s := &server{v: v}
+		err := s.listing(list)(httptest.NewRecorder(), httptest.NewRequest("GET", "/packages", nil))
+		if disabled {
+			if e, ok := err.(*httpError); !ok || e.status != http.StatusNotFound {
+				t.Errorf("disabled: listing returned %v, want status 404", err)
+			}
+		} else if err != nil {
+			t.Errorf("enabled: listing returned %v, want nil", err)
+		}
+	}
+}
+
+func TestForwardedHost(t *testing.T) {
+	for _, tt := range []struct {
+		header string // header lines separated by newlines
+		want   string
+	}{
+		{"", "gddo.internal:8080"},
+		{"godoc.org", "godoc.org"},
+		{" godoc.org:443 ", "godoc.org:443"},
+		{"evil.com, godoc.org", "godoc.org"},
+		{"evil.com/path", "gddo.internal:8080"},
+		{"user@evil.com", "gddo.internal:8080"},
+		{"godoc.org, evil.com/path", "gddo.internal:8080"},
+		{"evil.com\ngodoc.org", "godoc.org"},
+	} {
+		req := httptest.NewRequest("GET", "/fmt", nil)
+		req.Host = "gddo.internal:8080"
+		for _, h := range strings.Split(tt.header, "\n") {
+			if h != "" {
+				req.Header.Add("X-Forwarded-Host", h)
+			}
+		}
+		if got := forwardedHost(req); got != tt.want {
+			t.Errorf("forwardedHost(X-Forwarded-Host: %q) = %q, want %q", tt.header, got, tt.want)
+		}
+		if got := withForwardedHost(req); got.Host != tt.want || absoluteURL(got, "/fmt") != "http://"+tt.want+"/fmt" {
+			t.Errorf("withForwardedHost(X-Forwarded-Host: %q) host = %q, want %q", tt.header, got.Host, tt.want)
+		}
+	}
+}
+
+func TestNotFoundPage(t *testing.T) {
+	templates, err := parseTemplates("assets", &httputil.CacheBusters{}, viper.New(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{templates: templates, v: viper.New()}
+	for _, tt := range []struct {
+		err  error
+		want string
+	}{
+		{nil, "NOT FOUND"},
+		{errCommitNotFound, "NOT FOUND: Commit not found"},
+		{gosrc.NotFoundError{Message: "GET https://internal.example/x: secret"}, "NOT FOUND"},
+	} {
+		req := httptest.NewRequest("GET", "/example.com/p", nil)
+		req.Header.Set("Accept", "text/plain")
+		resp := httptest.NewRecorder()
+		s.handleError(resp, req, http.StatusNotFound, tt.err)
+		if got := strings.TrimSpace(resp.Body.String()); got != tt.want {
+			t.Errorf("handleError(404, %v) body = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestSiteURL(t *testing.T) {
+	for _, tt := range []struct {
+		base        string
+		want        string
+		wantPrivate bool
+	}{
+		{"", "http://evil.com", true},
+		{"https://godoc.org/", "https://godoc.org", false},
+	} {
+		v := viper.New()
+		v.Set(ConfigBaseURL, tt.base)
+		s := &server{v: v}
+		req := httptest.NewRequest("GET", "/fmt", nil)
+		req.Host = "evil.com"
+		resp := httptest.NewRecorder()
+		if got := s.siteURL(resp, req); got != tt.want {
+			t.Errorf("base_url %q: siteURL = %q, want %q", tt.base, got, tt.want)
+		}
+		if private := resp.Header().Get("Cache-Control") == "private"; private != tt.wantPrivate {
+			t.Errorf("base_url %q: private = %v, want %v", tt.base, private, tt.wantPrivate)
+		}
+	}
+}
+
+func TestSiblingPackages(t *testing.T) {
+	project := []database.Package{
+		{Path: "github.com/user/repo"},
+		{Path: "github.com/user/repo/a"},
+		{Path: "github.com/user/repo/a/b"},
+		{Path: "github.com/user/repo/ab"},
+		{Path: "github.com/user/repo/c"},
+	}
+	got := siblingPackages("github.com/user/repo/a", project)
+	want := []database.Package{
+		{Path: "github.com/user/repo"},
+		{Path: "github.com/user/repo/ab"},
+		{Path: "github.com/user/repo/c"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("siblingPackages mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestIsMarkdown(t *testing.T) {
+	for _, tt := range []struct {
+		url, accept string
+		want        bool
+	}{
+		{"/fmt", "", false},
+		{"/fmt?format=md", "", true},
+		{"/fmt", "text/markdown", true},
+		{"/fmt", "text/html,text/markdown;q=0.5", false},
+		{"/fmt?format=txt", "", false},
+	} {
+		req := httptest.NewRequest("GET", tt.url, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		req.ParseForm()
+		if got := isMarkdown(req); got != tt.want {
+			t.Errorf("isMarkdown(%q, Accept: %q) = %v, want %v", tt.url, tt.accept, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// noticeCheckInterval is the minimum time between checks of the config file
+// for a changed notice.
+const noticeCheckInterval = 10 * time.Second
+
+// notice is a site-wide message shown at the top of every page, such as an
+// announcement of a maintenance window.
+type notice struct {
+	Message string
+	Level   string // info, success, warning or danger
+	Link    string // URL of more information, if any
+}
+
+// newNotice returns the notice configured in v or nil if the notice message
+// is empty.
+func newNotice(v *viper.Viper) *notice {
+	msg := v.GetString(ConfigNoticeMessage)
+	if msg == "" {
+		return nil
+	}
+	n := &notice{Message: msg, Level: v.GetString(ConfigNoticeLevel), Link: v.GetString(ConfigNoticeLink)}
+	switch n.Level {
+	case "info", "success", "warning", "danger":
+	default:
+		n.Level = "info"
+	}
+	return n
+}
+
+// noticeSource holds the current notice. When the configuration was read
+// from a file, the notice is read again from the file after the file is
+// modified, so that operators can change the notice without a restart.
+// Notice options missing from the file keep the values of the flags and
+// environment the server started with.
+type noticeSource struct {
+	file     string
+	defaults map[string]interface{}
+
+	mu      sync.Mutex
+	checked time.Time
+	modTime time.Time
+	current *notice
+}
+
+func newNoticeSource(v *viper.Viper) *noticeSource {
+	ns := &noticeSource{
+		file:     v.ConfigFileUsed(),
+		defaults: make(map[string]interface{}),
+		checked:  time.Now(),
+		current:  newNotice(v),
+	}
+	for _, key := range []string{ConfigNoticeMessage, ConfigNoticeLevel, ConfigNoticeLink} {
+		ns.defaults[key] = v.Get(key)
+	}
+	if ns.file != "" {
+		if fi, err := os.Stat(ns.file); err == nil {
+			ns.modTime = fi.ModTime()
+		}
+	}
+	return ns
+}
+
+// get returns the current notice or nil if there is none.
+func (ns *noticeSource) get() *notice {
+	if ns == nil {
+		return nil
+	}
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if ns.file == "" || time.Since(ns.checked) < noticeCheckInterval {
+		return ns.current
+	}
+	ns.checked = time.Now()
+	fi, err := os.Stat(ns.file)
+	if err != nil || fi.ModTime().Equal(ns.modTime) {
+		return ns.current
+	}
+	v := viper.New()
+	for key, value := range ns.defaults {
+		v.SetDefault(key, value)
+	}
+	v.SetConfigFile(ns.file)
+	if err := v.ReadInConfig(); err != nil {
+		log.Printf("ERROR reading notice from %s: %v", ns.file, err)
+		return ns.current
+	}
+	ns.modTime = fi.ModTime()
+	ns.current = newNotice(v)
+	return ns.current
+}
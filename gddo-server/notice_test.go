@@ -0,0 +1,69 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestNewNotice(t *testing.T) {
+	v := viper.New()
+	if n := newNotice(v); n != nil {
+		t.Errorf("newNotice() = %+v, want nil", n)
+	}
+	v.Set(ConfigNoticeMessage, "Maintenance at 2am UTC.")
+	v.Set(ConfigNoticeLevel, "<script>")
+	want := notice{Message: "Maintenance at 2am UTC.", Level: "info"}
+	if n := newNotice(v); n == nil || *n != want {
+		t.Errorf("newNotice() = %+v, want %+v", n, want)
+	}
+}
+
+func TestNoticeSourceReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "gddo.toml")
+	if err := ioutil.WriteFile(file, []byte("notice_level = \"warning\"\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	ns := newNoticeSource(v)
+	if n := ns.get(); n != nil {
+		t.Fatalf("get() = %+v, want nil", n)
+	}
+
+	data := "notice_message = \"New mirror.\"\nnotice_level = \"warning\"\nnotice_link = \"https://example.com/\"\n"
+	if err := ioutil.WriteFile(file, []byte(data), 0666); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(time.Minute)
+	if err := os.Chtimes(file, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if n := ns.get(); n != nil {
+		t.Errorf("get() before check interval = %+v, want nil", n)
+	}
+	ns.checked = time.Time{}
+	want := notice{Message: "New mirror.", Level: "warning", Link: "https://example.com/"}
+	if n := ns.get(); n == nil || *n != want {
+		t.Errorf("get() after change = %+v, want %+v", n, want)
+	}
+}
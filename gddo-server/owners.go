@@ -0,0 +1,65 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/golang/gddo/doc"
+)
+
+// loadOwnersFile reads the owners file of the configuration, a CODEOWNERS
+// file with import path patterns, such as example.com/team/. No file
+// declares no owners.
+func loadOwnersFile(name string) ([]doc.CodeOwnersRule, error) {
+	if name == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return doc.ParseCodeOwners(data), nil
+}
+
+// packageOwners returns the owners of pdoc declared by rules, the rules of
+// the CODEOWNERS file in the root of the repository of pdoc, or else by the
+// owners file of the configuration.
+func (s *server) packageOwners(pdoc *doc.Package, rules []doc.CodeOwnersRule) []string {
+	var files []string
+	for _, f := range pdoc.Files {
+		files = append(files, f.Name)
+	}
+	for _, f := range pdoc.TestFiles {
+		files = append(files, f.Name)
+	}
+	dir := strings.TrimPrefix(strings.TrimPrefix(pdoc.ImportPath, pdoc.ProjectRoot), "/")
+	if owners := doc.MatchCodeOwners(rules, dir, files); owners != nil {
+		return owners
+	}
+	return doc.MatchCodeOwners(s.owners, pdoc.ImportPath, files)
+}
+
+// ownerURL returns the URL of the GitHub profile of a user or team owning
+// a package of a GitHub project, such as @alice or @org/team, or a mailto
+// URL for an email address. Owners of other projects are not linked.
+func ownerURL(owner, projectRoot string) string {
+	if strings.HasPrefix(owner, "@") {
+		if !strings.HasPrefix(projectRoot, "github.com/") {
+			return ""
+		}
+		if i := strings.Index(owner, "/"); i >= 0 {
+			return "https://github.com/orgs/" + owner[1:i] + "/teams/" + owner[i+1:]
+		}
+		return "https://github.com/" + owner[1:]
+	}
+	if strings.Contains(owner, "@") {
+		return "mailto:" + owner
+	}
+	return ""
+}
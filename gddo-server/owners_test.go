@@ -0,0 +1,98 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/viper"
+
+	"github.com/golang/gddo/doc"
+	"github.com/golang/gddo/httputil"
+)
+
+func TestPackageOwners(t *testing.T) {
+	s := &server{owners: doc.ParseCodeOwners([]byte("corp.example.com/payments/ @payments payments@corp.example.com\n"))}
+	repoRules := doc.ParseCodeOwners([]byte("*  @acme/core\n/cmd/ @alice\n"))
+	for _, tt := range []struct {
+		pdoc  *doc.Package
+		rules []doc.CodeOwnersRule
+		want  []string
+	}{
+		{
+			pdoc:  &doc.Package{ImportPath: "github.com/acme/p/cmd/tool", ProjectRoot: "github.com/acme/p", Files: []*doc.File{{Name: "main.go"}}},
+			rules: repoRules,
+			want:  []string{"@alice"},
+		},
+		{
+			pdoc:  &doc.Package{ImportPath: "github.com/acme/p", ProjectRoot: "github.com/acme/p", TestFiles: []*doc.File{{Name: "p_test.go"}}},
+			rules: repoRules,
+			want:  []string{"@acme/core"},
+		},
+		{
+			pdoc: &doc.Package{ImportPath: "corp.example.com/payments/ledger", ProjectRoot: "corp.example.com/payments", Files: []*doc.File{{Name: "ledger.go"}}},
+			want: []string{"@payments", "payments@corp.example.com"},
+		},
+		{
+			pdoc: &doc.Package{ImportPath: "corp.example.com/search", ProjectRoot: "corp.example.com/search", Files: []*doc.File{{Name: "search.go"}}},
+		},
+	} {
+		if diff := cmp.Diff(tt.want, s.packageOwners(tt.pdoc, tt.rules)); diff != "" {
+			t.Errorf("packageOwners(%q) mismatch (-want +got):\n%s", tt.pdoc.ImportPath, diff)
+		}
+	}
+}
+
+func TestOwnerURL(t *testing.T) {
+	for _, tt := range []struct {
+		owner, projectRoot, want string
+	}{
+		{"@alice", "github.com/acme/p", "https://github.com/alice"},
+		{"@acme/core", "github.com/acme/p", "https://github.com/orgs/acme/teams/core"},
+		{"@alice", "gitlab.com/acme/p", ""},
+		{"alice@example.com", "gitlab.com/acme/p", "mailto:alice@example.com"},
+		{"alice", "github.com/acme/p", ""},
+	} {
+		if got := ownerURL(tt.owner, tt.projectRoot); got != tt.want {
+			t.Errorf("ownerURL(%q, %q) = %q, want %q", tt.owner, tt.projectRoot, got, tt.want)
+		}
+	}
+}
+
+func TestOwnersSection(t *testing.T) {
+	templates, err := parseTemplates("assets", &httputil.CacheBusters{}, viper.New(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		owners []string
+		want   string
+	}{
+		{[]string{"@acme/core", "core@example.com"}, `<p><a href="https://github.com/orgs/acme/teams/core">@acme/core</a>, <a href="mailto:core@example.com">core@example.com</a></p>`},
+		{nil, ""},
+	} {
+		pdoc := &doc.Package{ImportPath: "github.com/acme/p", ProjectRoot: "github.com/acme/p", Name: "p", Owners: tt.owners}
+		var buf strings.Builder
+		err := templates["pkg.html"].(*template.Template).ExecuteTemplate(&buf, "PkgCmdFooter", map[string]interface{}{
+			"pdoc": newTDoc(viper.New(), pdoc),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := buf.String()
+		if tt.want == "" {
+			if strings.Contains(got, "pkg-owners") {
+				t.Errorf("footer of a package without owners has an Owners section:\n%s", got)
+			}
+		} else if !strings.Contains(got, tt.want) {
+			t.Errorf("footer does not contain %q:\n%s", tt.want, got)
+		}
+	}
+}
@@ -32,8 +32,8 @@ type pkggodevEvent struct {
 	FetchResponse string
 }
 
-func teeRequestToPkgGoDev(godocReq *http.Request, latency time.Duration, isRobot bool, status int) (gddoEvent *gddoEvent, pkgEvent *pkggodevEvent) {
-	gddoEvent = newGDDOEvent(godocReq, latency, isRobot, status)
+func teeRequestToPkgGoDev(godocReq *http.Request, latency time.Duration, isRobot bool, status int, redirectDefault bool) (gddoEvent *gddoEvent, pkgEvent *pkggodevEvent) {
+	gddoEvent = newGDDOEvent(godocReq, latency, isRobot, status, redirectDefault)
 	u := pkgGoDevURL(godocReq.URL)
 
 	// Strip the utm_source from the URL.
@@ -89,28 +89,46 @@ var doNotTeeURLsToPkgGoDev = map[string]bool{
 	"/-/refresh": true,
 }
 
-// doNotTeeExtsToPkgGoDev are URL extensions that should not be teed to
-// pkg.go.dev.
-var doNotTeeExtsToPkgGoDev = map[string]bool{
-	".css":  true,
-	".html": true,
-	".js":   true,
-	".txt":  true,
-	".xml":  true,
-	".ico":  true,
+// defaultDoNotTeeExts are the default URL extensions that should not be teed
+// to pkg.go.dev.
+var defaultDoNotTeeExts = []string{".css", ".html", ".js", ".txt", ".xml", ".ico"}
+
+// teeExclusions holds the requests that should not be teed to pkg.go.dev.
+type teeExclusions struct {
+	exts map[string]bool
+	urls map[string]bool
+}
+
+// newTeeExclusions returns exclusions for the URL extensions exts and for
+// the paths in doNotTeeURLsToPkgGoDev and urls.
+func newTeeExclusions(exts, urls []string) *teeExclusions {
+	e := &teeExclusions{exts: make(map[string]bool), urls: make(map[string]bool)}
+	for _, ext := range exts {
+		if ext != "" && !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		e.exts[ext] = true
+	}
+	for u := range doNotTeeURLsToPkgGoDev {
+		e.urls[u] = true
+	}
+	for _, u := range urls {
+		e.urls[u] = true
+	}
+	return e
 }
 
 // shouldTeeRequest reports whether a request should be teed to pkg.go.dev.
-func shouldTeeRequest(u string) bool {
+func (e *teeExclusions) shouldTeeRequest(u string) bool {
 	// Don't tee App Engine requests to pkg.go.dev.
 	if strings.HasPrefix(u, "/_ah/") {
 		return false
 	}
 	ext := filepath.Ext(u)
-	if doNotTeeExtsToPkgGoDev[ext] {
+	if ext != "" && e.exts[ext] {
 		return false
 	}
-	if doNotTeeURLsToPkgGoDev[u] {
+	if e.urls[u] {
 		return false
 	}
 	return true
@@ -128,7 +146,7 @@ type gddoEvent struct {
 	Error       error
 }
 
-func newGDDOEvent(r *http.Request, latency time.Duration, isRobot bool, status int) *gddoEvent {
+func newGDDOEvent(r *http.Request, latency time.Duration, isRobot bool, status int, redirectDefault bool) *gddoEvent {
 	targetURL := url.URL{
 		Scheme:   "https",
 		Host:     r.URL.Host,
@@ -146,7 +164,7 @@ func newGDDOEvent(r *http.Request, latency time.Duration, isRobot bool, status i
 		Header:      r.Header,
 		Latency:     latency,
 		IsRobot:     isRobot,
-		UsePkgGoDev: shouldRedirectToPkgGoDev(r),
+		UsePkgGoDev: shouldRedirectToPkgGoDev(r, redirectDefault),
 	}
 }
 
@@ -154,15 +172,33 @@ func userReturningFromPkgGoDev(req *http.Request) bool {
 	return req.FormValue("utm_source") == "backtogodoc"
 }
 
+// hidePkgGoDevBanner returns whether the pkg.go.dev banner is hidden for req
+// because the user recently returned from pkg.go.dev.
+func hidePkgGoDevBanner(req *http.Request) bool {
+	if userReturningFromPkgGoDev(req) {
+		return true
+	}
+	_, err := req.Cookie(pkgGoDevReturningCookie)
+	return err == nil
+}
+
 const (
 	pkgGoDevRedirectCookie = "pkggodev-redirect"
-	pkgGoDevRedirectParam  = "redirect"
-	pkgGoDevRedirectOn     = "on"
-	pkgGoDevRedirectOff    = "off"
-	pkgGoDevHost           = "pkg.go.dev"
+
+	// The returning cookie hides the pkg.go.dev banner for users who
+	// returned from pkg.go.dev until it expires.
+	pkgGoDevReturningCookie = "pkggodev-returning"
+	pkgGoDevReturningMaxAge = 24 * 60 * 60
+	pkgGoDevRedirectParam   = "redirect"
+	pkgGoDevRedirectOn      = "on"
+	pkgGoDevRedirectOff     = "off"
+	pkgGoDevHost            = "pkg.go.dev"
 )
 
-func shouldRedirectToPkgGoDev(req *http.Request) bool {
+// shouldRedirectToPkgGoDev returns whether req should be redirected to
+// pkg.go.dev. The redirect query parameter takes precedence over the cookie;
+// without either, redirectDefault is returned.
+func shouldRedirectToPkgGoDev(req *http.Request, redirectDefault bool) bool {
 	// API requests are not redirected.
 	if strings.HasPrefix(req.URL.Host, "api") {
 		return false
@@ -172,15 +208,22 @@ func shouldRedirectToPkgGoDev(req *http.Request) bool {
 		return redirectParam == pkgGoDevRedirectOn
 	}
 	cookie, err := req.Cookie(pkgGoDevRedirectCookie)
-	return (err == nil && cookie.Value == pkgGoDevRedirectOn)
+	if err != nil || (cookie.Value != pkgGoDevRedirectOn && cookie.Value != pkgGoDevRedirectOff) {
+		return redirectDefault
+	}
+	return cookie.Value == pkgGoDevRedirectOn
 }
 
 // pkgGoDevRedirectHandler redirects requests from godoc.org to pkg.go.dev,
 // based on whether a cookie is set for pkggodev-redirect. The cookie
-// can be turned on/off using a query param.
-func pkgGoDevRedirectHandler(f func(http.ResponseWriter, *http.Request) error) func(http.ResponseWriter, *http.Request) error {
+// can be turned on/off using a query param. If redirectDefault is true,
+// requests without the cookie are redirected and the off cookie is kept to
+// record the opt-out. The redirects are checked against targets.
+func pkgGoDevRedirectHandler(f func(http.ResponseWriter, *http.Request) error, redirectDefault bool, targets *redirectTargets) func(http.ResponseWriter, *http.Request) error {
 	return func(w http.ResponseWriter, r *http.Request) error {
 		if userReturningFromPkgGoDev(r) {
+			cookie := &http.Cookie{Name: pkgGoDevReturningCookie, Value: "1", MaxAge: pkgGoDevReturningMaxAge, Path: "/"}
+			http.SetCookie(w, cookie)
 			return f(w, r)
 		}
 
@@ -192,15 +235,17 @@ func pkgGoDevRedirectHandler(f func(http.ResponseWriter, *http.Request) error) f
 		}
 		if redirectParam == pkgGoDevRedirectOff {
 			cookie := &http.Cookie{Name: pkgGoDevRedirectCookie, Value: "", MaxAge: -1, Path: "/"}
+			if redirectDefault {
+				cookie = &http.Cookie{Name: pkgGoDevRedirectCookie, Value: redirectParam, Path: "/"}
+			}
 			http.SetCookie(w, cookie)
 		}
 
-		if !shouldRedirectToPkgGoDev(r) {
+		if !shouldRedirectToPkgGoDev(r, redirectDefault) {
 			return f(w, r)
 		}
 
-		http.Redirect(w, r, pkgGoDevURL(r.URL).String(), http.StatusFound)
-		return nil
+		return targets.redirect(w, r, pkgGoDevURL(r.URL).String(), http.StatusFound)
 	}
 }
 
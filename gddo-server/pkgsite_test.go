@@ -20,7 +20,7 @@ import (
 func TestHandlePkgGoDevRedirect(t *testing.T) {
 	handler := pkgGoDevRedirectHandler(func(w http.ResponseWriter, r *http.Request) error {
 		return nil
-	})
+	}, false, newRedirectTargets(defaultRedirectHosts))
 
 	for _, test := range []struct {
 		name, url, wantLocationHeader, wantSetCookieHeader string
@@ -65,10 +65,11 @@ func TestHandlePkgGoDevRedirect(t *testing.T) {
 			wantStatusCode:      http.StatusFound,
 		},
 		{
-			name:           "do not redirect if user is returning from pkg.go.dev",
-			url:            "http://godoc.org/net/http?utm_source=backtogodoc",
-			cookie:         &http.Cookie{Name: "pkggodev-redirect", Value: "on"},
-			wantStatusCode: http.StatusOK,
+			name:                "do not redirect if user is returning from pkg.go.dev",
+			url:                 "http://godoc.org/net/http?utm_source=backtogodoc",
+			cookie:              &http.Cookie{Name: "pkggodev-redirect", Value: "on"},
+			wantSetCookieHeader: "pkggodev-returning=1; Path=/; Max-Age=86400",
+			wantStatusCode:      http.StatusOK,
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
@@ -99,6 +100,95 @@ func TestHandlePkgGoDevRedirect(t *testing.T) {
 	}
 }
 
+func TestHidePkgGoDevBanner(t *testing.T) {
+	for _, test := range []struct {
+		url    string
+		cookie *http.Cookie
+		want   bool
+	}{
+		{url: "http://godoc.org/net/http", want: false},
+		{url: "http://godoc.org/net/http?utm_source=backtogodoc", want: true},
+		{url: "http://godoc.org/net/http", cookie: &http.Cookie{Name: "pkggodev-returning", Value: "1"}, want: true},
+		{url: "http://godoc.org/net/http", cookie: &http.Cookie{Name: "pkggodev-redirect", Value: "off"}, want: false},
+	} {
+		req := httptest.NewRequest("GET", test.url, nil)
+		if test.cookie != nil {
+			req.AddCookie(test.cookie)
+		}
+		if got := hidePkgGoDevBanner(req); got != test.want {
+			t.Errorf("hidePkgGoDevBanner(%s, cookie %v) = %v, want %v", test.url, test.cookie, got, test.want)
+		}
+	}
+}
+
+func TestHandlePkgGoDevRedirectDefault(t *testing.T) {
+	handler := pkgGoDevRedirectHandler(func(w http.ResponseWriter, r *http.Request) error {
+		return nil
+	}, true, newRedirectTargets(defaultRedirectHosts))
+
+	for _, test := range []struct {
+		name, url, wantLocationHeader, wantSetCookieHeader string
+		wantStatusCode                                     int
+		cookie                                             *http.Cookie
+	}{
+		{
+			name:               "redirect without cookie or param",
+			url:                "http://godoc.org/net/http",
+			wantLocationHeader: "https://pkg.go.dev/net/http?utm_source=godoc",
+			wantStatusCode:     http.StatusFound,
+		},
+		{
+			name:                "opt out with param",
+			url:                 "http://godoc.org/net/http?redirect=off",
+			wantSetCookieHeader: "pkggodev-redirect=off; Path=/",
+			wantStatusCode:      http.StatusOK,
+		},
+		{
+			name:           "opted out with cookie",
+			url:            "http://godoc.org/net/http",
+			cookie:         &http.Cookie{Name: "pkggodev-redirect", Value: "off"},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:                "opt in again with param",
+			url:                 "http://godoc.org/net/http?redirect=on",
+			cookie:              &http.Cookie{Name: "pkggodev-redirect", Value: "off"},
+			wantLocationHeader:  "https://pkg.go.dev/net/http?utm_source=godoc",
+			wantSetCookieHeader: "pkggodev-redirect=on; Path=/",
+			wantStatusCode:      http.StatusFound,
+		},
+		{
+			name:                "do not redirect if user is returning from pkg.go.dev",
+			url:                 "http://godoc.org/net/http?utm_source=backtogodoc",
+			wantSetCookieHeader: "pkggodev-returning=1; Path=/; Max-Age=86400",
+			wantStatusCode:      http.StatusOK,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", test.url, nil)
+			if test.cookie != nil {
+				req.AddCookie(test.cookie)
+			}
+
+			w := httptest.NewRecorder()
+			if err := handler(w, req); err != nil {
+				t.Fatal(err)
+			}
+			resp := w.Result()
+
+			if got, want := resp.Header.Get("Location"), test.wantLocationHeader; got != want {
+				t.Errorf("Location header mismatch: got %q; want %q", got, want)
+			}
+			if got, want := resp.Header.Get("Set-Cookie"), test.wantSetCookieHeader; got != want {
+				t.Errorf("Set-Cookie header mismatch: got %q; want %q", got, want)
+			}
+			if got, want := resp.StatusCode, test.wantStatusCode; got != want {
+				t.Errorf("Status code mismatch: got %d; want %d", got, want)
+			}
+		})
+	}
+}
+
 func TestPkgGoDevURL(t *testing.T) {
 	testCases := []struct {
 		from, to string
@@ -320,7 +410,7 @@ func TestNewGDDOEvent(t *testing.T) {
 				r.AddCookie(test.cookie)
 				want.Header.Add("Cookie", test.cookie.String())
 			}
-			got := newGDDOEvent(r, want.Latency, want.IsRobot, http.StatusOK)
+			got := newGDDOEvent(r, want.Latency, want.IsRobot, http.StatusOK, false)
 			want.Status = http.StatusOK
 			if diff := cmp.Diff(want, got); diff != "" {
 				t.Fatalf("mismatch (-want +got):\n%s", diff)
@@ -457,7 +547,7 @@ func TestNewGDDOEventFromRequests(t *testing.T) {
 			if err != nil {
 				t.Fatal("invalid NewRequest arguments; " + err.Error())
 			}
-			got := newGDDOEvent(req, want.Latency, want.IsRobot, http.StatusOK)
+			got := newGDDOEvent(req, want.Latency, want.IsRobot, http.StatusOK, false)
 			want.Status = http.StatusOK
 			if diff := cmp.Diff(want, got); diff != "" {
 				t.Fatalf("mismatch (-want +got):\n%s", diff)
@@ -466,7 +556,34 @@ func TestNewGDDOEventFromRequests(t *testing.T) {
 	}
 }
 
+func TestNewGDDOEventForwardedHost(t *testing.T) {
+	for _, requestURI := range []string{"/net/http?imports", "http://gddo.internal:8080/net/http?imports"} {
+		requestLine := "GET " + requestURI + " HTTP/1.1\r\nHost: gddo.internal:8080\r\nX-Forwarded-Host: godoc.org\r\n\r\n"
+		req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(requestLine)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = withForwardedHost(req)
+		got := newGDDOEvent(req, 100, false, http.StatusOK, false)
+		want := &gddoEvent{
+			Host:    "godoc.org",
+			Path:    "/net/http",
+			Status:  http.StatusOK,
+			URL:     "https://godoc.org/net/http?imports",
+			Header:  http.Header{"X-Forwarded-Host": {"godoc.org"}},
+			Latency: 100,
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("newGDDOEvent(%q) mismatch (-want +got):\n%s", requestURI, diff)
+		}
+		if got, want := pkgGoDevURL(req.URL).String(), "https://pkg.go.dev/net/http?tab=imports&utm_source=godoc"; got != want {
+			t.Errorf("pkgGoDevURL(%q) = %q, want %q", req.URL, got, want)
+		}
+	}
+}
+
 func TestShouldTeeRequest(t *testing.T) {
+	e := newTeeExclusions(defaultDoNotTeeExts, nil)
 	for _, test := range []struct {
 		urlPath string
 		want    bool
@@ -490,7 +607,29 @@ func TestShouldTeeRequest(t *testing.T) {
 		{"/robots.txt", false},
 		{"/third_party/jquery.timeago.js", false},
 	} {
-		if got := shouldTeeRequest(test.urlPath); got != test.want {
+		if got := e.shouldTeeRequest(test.urlPath); got != test.want {
+			t.Errorf("shouldTeeRequest(%q): %t; want %t", test.urlPath, got, test.want)
+		}
+	}
+}
+
+func TestShouldTeeRequestConfigured(t *testing.T) {
+	e := newTeeExclusions([]string{".css", "png"}, []string{"/-/about"})
+	for _, test := range []struct {
+		urlPath string
+		want    bool
+	}{
+		{"/", true},
+		{"/net/http", true},
+		{"/robots.txt", true},
+		{"/-/site.js", true},
+		{"/-/site.css", false},
+		{"/-/gopher.png", false},
+		{"/-/about", false},
+		{"/-/bot", false},
+		{"/_ah/ready", false},
+	} {
+		if got := e.shouldTeeRequest(test.urlPath); got != test.want {
 			t.Errorf("shouldTeeRequest(%q): %t; want %t", test.urlPath, got, test.want)
 		}
 	}
@@ -0,0 +1,82 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/golang/gddo/doc"
+	"github.com/golang/gddo/httputil/header"
+)
+
+// defaultReadmeLang is the language assumed for the default README of a
+// package, which has no language suffix in its name.
+const defaultReadmeLang = "en"
+
+// selectReadme returns the README of readmes requested with the readme query
+// parameter, "default" for the default README, or else the README in the
+// language preferred by the Accept-Language header of the request. The
+// default README is returned when no translation matches. selectReadme
+// returns nil if there are no README files.
+func selectReadme(req *http.Request, readmes []*doc.Readme) *doc.Readme {
+	if len(readmes) == 0 {
+		return nil
+	}
+	// The default README, if any, is first.
+	def := readmes[0]
+
+	if q := req.Form.Get("readme"); q != "" {
+		if q == "default" {
+			q = ""
+		}
+		for _, r := range readmes {
+			if strings.EqualFold(r.Lang, q) {
+				return r
+			}
+		}
+		return def
+	}
+
+	specs := header.ParseAccept(req.Header, "Accept-Language")
+	sort.SliceStable(specs, func(i, j int) bool { return specs[i].Q > specs[j].Q })
+	for _, spec := range specs {
+		if spec.Q <= 0 || spec.Value == "*" {
+			continue
+		}
+		if r := matchReadmeLang(spec.Value, readmes); r != nil {
+			return r
+		}
+	}
+	return def
+}
+
+// matchReadmeLang returns the README in the language lang or, failing that,
+// in a language with the same primary language subtag.
+func matchReadmeLang(lang string, readmes []*doc.Readme) *doc.Readme {
+	primary := func(s string) string {
+		if i := strings.IndexAny(s, "-_"); i >= 0 {
+			s = s[:i]
+		}
+		return strings.ToLower(s)
+	}
+	var partial *doc.Readme
+	for _, r := range readmes {
+		l := r.Lang
+		if l == "" {
+			l = defaultReadmeLang
+		}
+		if strings.EqualFold(strings.Replace(lang, "_", "-", -1), l) {
+			return r
+		}
+		if partial == nil && primary(lang) == primary(l) {
+			partial = r
+		}
+	}
+	return partial
+}
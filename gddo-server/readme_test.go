@@ -0,0 +1,53 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/gddo/doc"
+)
+
+func TestSelectReadme(t *testing.T) {
+	readmes := []*doc.Readme{
+		{Name: "README.md"},
+		{Lang: "ja", Name: "README.ja.md"},
+		{Lang: "zh-CN", Name: "README.zh-CN.md"},
+	}
+	for _, tt := range []struct {
+		url            string
+		acceptLanguage string
+		want           string
+	}{
+		{"/p", "", "README.md"},
+		{"/p", "ja", "README.ja.md"},
+		{"/p", "en-US,en;q=0.9,ja;q=0.8", "README.md"},
+		{"/p", "fr, ja;q=0.5", "README.ja.md"},
+		{"/p", "zh-TW", "README.zh-CN.md"},
+		{"/p", "zh-cn;q=0.9, ja;q=0.5", "README.zh-CN.md"},
+		{"/p", "ja;q=0.5, zh-CN", "README.zh-CN.md"},
+		{"/p", "fr", "README.md"},
+		{"/p?readme=ja", "zh-CN", "README.ja.md"},
+		{"/p?readme=default", "ja", "README.md"},
+		{"/p?readme=xx", "ja", "README.md"},
+	} {
+		req := httptest.NewRequest("GET", tt.url, nil)
+		if tt.acceptLanguage != "" {
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+		}
+		req.ParseForm()
+		if got := selectReadme(req, readmes); got.Name != tt.want {
+			t.Errorf("selectReadme(%s, Accept-Language %q) = %s, want %s", tt.url, tt.acceptLanguage, got.Name, tt.want)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/p", nil)
+	if got := selectReadme(req, nil); got != nil {
+		t.Errorf("selectReadme(no READMEs) = %v, want nil", got)
+	}
+}
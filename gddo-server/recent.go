@@ -0,0 +1,74 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	recentCookie = "recent"
+
+	// maxRecent is the number of recently viewed packages kept in the
+	// cookie and maxRecentBytes limits the size of the cookie value.
+	maxRecent      = 5
+	maxRecentBytes = 1024
+)
+
+// getRecent returns the import paths of the recently viewed packages stored
+// in the request cookie, most recent first.
+func getRecent(req *http.Request) []string {
+	c, err := req.Cookie(recentCookie)
+	if err != nil {
+		return nil
+	}
+	p, err := base64.URLEncoding.DecodeString(c.Value)
+	if err != nil {
+		return nil
+	}
+	var recent []string
+	for _, s := range strings.Split(string(p), "\000") {
+		if s != "" && len(recent) < maxRecent {
+			recent = append(recent, s)
+		}
+	}
+	return recent
+}
+
+// addRecent returns recent with importPath moved or added to the front,
+// dropping the oldest entries to stay within the count and size limits.
+func addRecent(recent []string, importPath string) []string {
+	result := []string{importPath}
+	for _, s := range recent {
+		if s != importPath && len(result) < maxRecent {
+			result = append(result, s)
+		}
+	}
+	for len(result) > 1 && base64.URLEncoding.EncodedLen(len(strings.Join(result, "\000"))) > maxRecentBytes {
+		result = result[:len(result)-1]
+	}
+	return result
+}
+
+// setRecent sets the cookie storing the recently viewed packages.
+func setRecent(resp http.ResponseWriter, recent []string) {
+	value := base64.URLEncoding.EncodeToString([]byte(strings.Join(recent, "\000")))
+	if len(value) > maxRecentBytes {
+		return
+	}
+	http.SetCookie(resp, &http.Cookie{
+		Name:     recentCookie,
+		Value:    value,
+		Path:     "/",
+		Expires:  time.Now().Add(30 * 24 * time.Hour),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,44 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRecent(t *testing.T) {
+	recent := addRecent(nil, "a")
+	recent = addRecent(recent, "b")
+	recent = addRecent(recent, "a")
+	if diff := cmp.Diff([]string{"a", "b"}, recent); diff != "" {
+		t.Errorf("addRecent mismatch (-want +got):\n%s", diff)
+	}
+	for _, p := range []string{"c", "d", "e", "f"} {
+		recent = addRecent(recent, p)
+	}
+	if diff := cmp.Diff([]string{"f", "e", "d", "c", "a"}, recent); diff != "" {
+		t.Errorf("addRecent mismatch (-want +got):\n%s", diff)
+	}
+
+	w := httptest.NewRecorder()
+	setRecent(w, recent)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	if diff := cmp.Diff(recent, getRecent(req)); diff != "" {
+		t.Errorf("getRecent mismatch (-want +got):\n%s", diff)
+	}
+
+	long := "example.com/" + strings.Repeat("x", 400)
+	recent = addRecent([]string{long + "1", long + "2"}, long+"3")
+	if len(recent) != 1 {
+		t.Errorf("addRecent kept %d long paths, want 1", len(recent))
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/golang/gddo/httputil"
+)
+
+// defaultRedirectHosts are the hosts other than this server that redirects
+// send clients to: pkg.go.dev, golang.org for the issue explaining invalid
+// import paths and the C documentation, and the Go Playground.
+var defaultRedirectHosts = []string{pkgGoDevHost, "golang.org", "play.golang.org"}
+
+// redirectTargets holds the hosts that the server may redirect clients to in
+// addition to its own host. Redirects are checked against the hosts so that
+// a path or query influencing a redirect cannot send clients to another
+// site.
+type redirectTargets struct {
+	hosts map[string]bool
+}
+
+func newRedirectTargets(hosts []string) *redirectTargets {
+	rt := &redirectTargets{hosts: make(map[string]bool)}
+	for _, h := range hosts {
+		rt.hosts[strings.ToLower(h)] = true
+	}
+	return rt
+}
+
+// allowed reports whether target, the URL of a redirect in response to req,
+// is a path of this server or an http or https URL of this server or of one
+// of the allowed hosts.
+func (rt *redirectTargets) allowed(req *http.Request, target string) bool {
+	// Browsers ignore tabs and newlines in URLs and treat backslashes as
+	// slashes, so /\host and /<tab>/host are the URL //host of another host.
+	target = strings.Map(func(r rune) rune {
+		switch r {
+		case '\t', '\n', '\r':
+			return -1
+		case '\\':
+			return '/'
+		}
+		return r
+	}, target)
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" && u.Host == "" {
+		return strings.HasPrefix(u.Path, "/") && !strings.HasPrefix(u.Path, "//")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.User != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return rt.hosts[host] || host == strings.ToLower(httputil.StripPort(req.Host))
+}
+
+// redirect redirects the client to target with the status code. A target
+// that is not allowed is rejected with status 400.
+func (rt *redirectTargets) redirect(resp http.ResponseWriter, req *http.Request, target string, code int) error {
+	if !rt.allowed(req, target) {
+		return &httpError{status: http.StatusBadRequest, err: fmt.Errorf("redirect to %q not allowed", target)}
+	}
+	http.Redirect(resp, req, target, code)
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestRedirectTargetsAllowed(t *testing.T) {
+	rt := newRedirectTargets(defaultRedirectHosts)
+	req := httptest.NewRequest("GET", "https://godoc.org/pkg/x", nil)
+	for _, tt := range []struct {
+		target string
+		want   bool
+	}{
+		{"/github.com/user/repo", true},
+		{"/github.com/user/repo?imports", true},
+		{"/", true},
+		{"https://pkg.go.dev/fmt?utm_source=godoc", true},
+		{"https://golang.org/issue/43036", true},
+		{"http://play.golang.org/p/abc", true},
+		{"https://godoc.org/fmt", true},
+		{"https://GODOC.org:443/fmt", true},
+		{"//evil.com", false},
+		{"///evil.com", false},
+		{`/\evil.com`, false},
+		{`\\evil.com`, false},
+		{"/\t/evil.com", false},
+		{"https://evil.com/", false},
+		{"https://pkg.go.dev.evil.com/", false},
+		{"https://pkg.go.dev@evil.com/", false},
+		{"https://user@pkg.go.dev/", false},
+		{"javascript:alert(1)", false},
+		{"ftp://pkg.go.dev/", false},
+		{"github.com/user/repo", false},
+		{"", false},
+	} {
+		if got := rt.allowed(req, tt.target); got != tt.want {
+			t.Errorf("allowed(%q) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestRedirectTargetsRedirect(t *testing.T) {
+	rt := newRedirectTargets(nil)
+	req := httptest.NewRequest("GET", "/pkg/x", nil)
+
+	resp := httptest.NewRecorder()
+	if err := rt.redirect(resp, req, "/x", http.StatusFound); err != nil {
+		t.Fatalf("redirect(/x) returned %v", err)
+	}
+	if loc := resp.Header().Get("Location"); resp.Code != http.StatusFound || loc != "/x" {
+		t.Errorf("redirect(/x) = %d %q, want 302 /x", resp.Code, loc)
+	}
+
+	resp = httptest.NewRecorder()
+	err := rt.redirect(resp, req, "https://pkg.go.dev/x", http.StatusFound)
+	if e, ok := err.(*httpError); !ok || e.status != http.StatusBadRequest {
+		t.Errorf("redirect to host not allowed returned %v, want status 400", err)
+	}
+	if loc := resp.Header().Get("Location"); loc != "" {
+		t.Errorf("redirect to host not allowed set Location %q", loc)
+	}
+}
+
+func TestPkgGoDevURLHost(t *testing.T) {
+	// The host of the pkg.go.dev redirect must not depend on the request.
+	for _, p := range []string{
+		"//evil.com",
+		"/evil.com/..//x",
+		`/\evil.com`,
+		"/@evil.com",
+		"/%2F%2Fevil.com",
+		"/github.com/user/repo?q=//evil.com",
+	} {
+		u, err := url.Parse("https://godoc.org" + p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := pkgGoDevURL(u)
+		if got.Host != pkgGoDevHost && got.Host != "golang.org" {
+			t.Errorf("pkgGoDevURL(%q) = %s, want host %s or golang.org", p, got, pkgGoDevHost)
+		}
+		if !newRedirectTargets(defaultRedirectHosts).allowed(httptest.NewRequest("GET", "/", nil), got.String()) {
+			t.Errorf("pkgGoDevURL(%q) = %s, which is not an allowed redirect", p, got)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	"github.com/spf13/viper"
+
+	"github.com/golang/gddo/database"
+)
+
+// renderKey identifies a rendered package page.
+type renderKey struct {
+	importPath string
+	etag       string // HTTP entity tag of the page
+	template   string
+}
+
+// renderStore is a cache of rendered package pages.
+type renderStore interface {
+	// get returns the page cached for key.
+	get(key renderKey) ([]byte, bool)
+	// add caches body as the page for key.
+	add(key renderKey, body []byte)
+	// invalidate removes the pages cached for importPath.
+	invalidate(importPath string)
+	stats() renderCacheStats
+}
+
+// newRenderStore returns the render cache selected by ConfigRenderCacheStore.
+func newRenderStore(v *viper.Viper) (renderStore, error) {
+	switch store := v.GetString(ConfigRenderCacheStore); store {
+	case "", "memory":
+		return newRenderCache(v.GetInt(ConfigRenderCacheSize)), nil
+	case "redis":
+		uri := v.GetString(ConfigRenderCacheRedis)
+		if uri == "" {
+			uri = v.GetString(ConfigDBServer)
+		}
+		pool := database.NewPool(uri, v.GetDuration(ConfigDBIdleTimeout), v.GetBool(ConfigDBLog))
+		return newRedisRenderCache(pool, v.GetDuration(ConfigRenderCacheTTL), v.GetInt(ConfigRenderCacheSize)), nil
+	default:
+		return nil, fmt.Errorf("unknown render cache store %q", store)
+	}
+}
+
+type renderEntry struct {
+	key  renderKey
+	body []byte
+}
+
+// renderCache is a size bounded LRU cache of rendered package pages. The
+// zero value and nil caches do not cache anything.
+type renderCache struct {
+	maxBytes int
+
+	mu      sync.Mutex
+	bytes   int
+	ll      list.List
+	entries map[renderKey]*list.Element
+	hits    int64
+	misses  int64
+}
+
+func newRenderCache(maxBytes int) *renderCache {
+	return &renderCache{maxBytes: maxBytes, entries: make(map[renderKey]*list.Element)}
+}
+
+// get returns the page cached for key.
+func (c *renderCache) get(key renderKey) ([]byte, bool) {
+	if c == nil || c.maxBytes <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.ll.MoveToFront(e)
+	return e.Value.(*renderEntry).body, true
+}
+
+// add caches body as the page for key, evicting the least recently used
+// pages as needed to stay within the size limit.
+func (c *renderCache) add(key renderKey, body []byte) {
+	if c == nil || c.maxBytes <= 0 || len(body) > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	c.entries[key] = c.ll.PushFront(&renderEntry{key: key, body: body})
+	c.bytes += len(body)
+	for c.bytes > c.maxBytes {
+		c.remove(c.ll.Back())
+	}
+}
+
+// invalidate removes the pages cached for importPath.
+func (c *renderCache) invalidate(importPath string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for e := c.ll.Front(); e != nil; {
+		next := e.Next()
+		if e.Value.(*renderEntry).key.importPath == importPath {
+			c.remove(e)
+		}
+		e = next
+	}
+}
+
+// clear removes all pages.
+func (c *renderCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	c.entries = make(map[renderKey]*list.Element)
+	c.bytes = 0
+}
+
+func (c *renderCache) remove(e *list.Element) {
+	re := c.ll.Remove(e).(*renderEntry)
+	delete(c.entries, re.key)
+	c.bytes -= len(re.body)
+}
+
+// renderCacheStats holds the counters of a render cache.
+type renderCacheStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Entries int   `json:"entries"`
+	Bytes   int   `json:"bytes"`
+
+	// Counters of the shared cache, if any, consulted on misses of the
+	// in-memory cache.
+	SharedHits   int64 `json:"shared_hits,omitempty"`
+	SharedMisses int64 `json:"shared_misses,omitempty"`
+}
+
+func (c *renderCache) stats() renderCacheStats {
+	if c == nil {
+		return renderCacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return renderCacheStats{Hits: c.hits, Misses: c.misses, Entries: len(c.entries), Bytes: c.bytes}
+}
@@ -0,0 +1,161 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// renderInvalidateChannel is the Redis pub/sub channel of the import paths
+// of the packages whose rendered pages are invalidated.
+const renderInvalidateChannel = "render:invalidate"
+
+// redisRenderCache is a render cache shared by the instances of the server
+// through Redis. The pages of a package are stored in the hash
+// render:<importPath> with the template and entity tag of the page as the
+// field. Recently used pages are also kept in a local in-memory cache, which
+// is invalidated by the messages published on renderInvalidateChannel.
+type redisRenderCache struct {
+	pool interface {
+		Get() redis.Conn
+	}
+	ttl   time.Duration
+	local *renderCache
+
+	mu     sync.Mutex
+	hits   int64
+	misses int64
+}
+
+func newRedisRenderCache(pool interface{ Get() redis.Conn }, ttl time.Duration, localBytes int) *redisRenderCache {
+	return &redisRenderCache{pool: pool, ttl: ttl, local: newRenderCache(localBytes)}
+}
+
+func redisRenderKey(importPath string) string {
+	return "render:" + importPath
+}
+
+func redisRenderField(key renderKey) string {
+	return key.template + " " + key.etag
+}
+
+func (c *redisRenderCache) get(key renderKey) ([]byte, bool) {
+	if body, ok := c.local.get(key); ok {
+		return body, true
+	}
+	conn := c.pool.Get()
+	defer conn.Close()
+	body, err := redis.Bytes(conn.Do("HGET", redisRenderKey(key.importPath), redisRenderField(key)))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		if err != redis.ErrNil {
+			log.Printf("ERROR render cache HGET %q: %v", key.importPath, err)
+		}
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.local.add(key, body)
+	return body, true
+}
+
+func (c *redisRenderCache) add(key renderKey, body []byte) {
+	c.local.add(key, body)
+	conn := c.pool.Get()
+	defer conn.Close()
+	k := redisRenderKey(key.importPath)
+	conn.Send("MULTI")
+	conn.Send("HSET", k, redisRenderField(key), body)
+	if c.ttl > 0 {
+		seconds := int64(c.ttl / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		conn.Send("EXPIRE", k, seconds)
+	}
+	if _, err := conn.Do("EXEC"); err != nil {
+		log.Printf("ERROR render cache HSET %q: %v", key.importPath, err)
+	}
+}
+
+// invalidate removes the pages cached for importPath and notifies the other
+// instances of the server to remove them from their local caches.
+func (c *redisRenderCache) invalidate(importPath string) {
+	c.local.invalidate(importPath)
+	conn := c.pool.Get()
+	defer conn.Close()
+	conn.Send("MULTI")
+	conn.Send("DEL", redisRenderKey(importPath))
+	conn.Send("PUBLISH", renderInvalidateChannel, importPath)
+	if _, err := conn.Do("EXEC"); err != nil {
+		log.Printf("ERROR render cache DEL %q: %v", importPath, err)
+	}
+}
+
+func (c *redisRenderCache) stats() renderCacheStats {
+	stats := c.local.stats()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats.SharedHits = c.hits
+	stats.SharedMisses = c.misses
+	return stats
+}
+
+// subscribe invalidates the local cache on the messages published on
+// renderInvalidateChannel until ctx is done.
+func (c *redisRenderCache) subscribe(ctx context.Context) {
+	for {
+		psc := redis.PubSubConn{Conn: c.pool.Get()}
+		err := psc.Subscribe(renderInvalidateChannel)
+		done := make(chan struct{})
+		stopped := make(chan struct{})
+		go func() {
+			// Only this goroutine writes to the connection while
+			// Receive reads from it.
+			defer close(stopped)
+			select {
+			case <-ctx.Done():
+				// Receive returns the subscription count of zero.
+				psc.Unsubscribe()
+			case <-done:
+			}
+		}()
+	receive:
+		for err == nil {
+			switch v := psc.Receive().(type) {
+			case redis.Message:
+				c.local.invalidate(string(v.Data))
+			case redis.Subscription:
+				if v.Count == 0 {
+					break receive
+				}
+			case error:
+				err = v
+			}
+		}
+		close(done)
+		<-stopped
+		psc.Close()
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("ERROR render cache subscribe: %v", err)
+		// Pages invalidated while unsubscribed may be stale.
+		c.local.clear()
+		select {
+		case <-time.After(5 * time.Second):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
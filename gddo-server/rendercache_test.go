@@ -0,0 +1,137 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/spf13/viper"
+)
+
+func TestRenderCache(t *testing.T) {
+	c := newRenderCache(10)
+	a := renderKey{importPath: "a", etag: "1", template: "pkg.html"}
+	b := renderKey{importPath: "b", etag: "1", template: "pkg.html"}
+	d := renderKey{importPath: "d", etag: "1", template: "pkg.html"}
+
+	c.add(a, []byte("aaaa"))
+	c.add(b, []byte("bbbb"))
+	if _, ok := c.get(a); !ok {
+		t.Fatal("a not cached")
+	}
+	// Adding d evicts b, the least recently used page.
+	c.add(d, []byte("dddd"))
+	if _, ok := c.get(b); ok {
+		t.Error("b not evicted")
+	}
+	if body, ok := c.get(a); !ok || string(body) != "aaaa" {
+		t.Errorf("get(a) = %q, %v, want %q, true", body, ok, "aaaa")
+	}
+
+	c.invalidate("a")
+	if _, ok := c.get(a); ok {
+		t.Error("a not invalidated")
+	}
+
+	c.add(b, []byte("too large to cache"))
+	if _, ok := c.get(b); ok {
+		t.Error("page larger than the cache was cached")
+	}
+
+	want := renderCacheStats{Hits: 2, Misses: 3, Entries: 1, Bytes: 4}
+	if got := c.stats(); got != want {
+		t.Errorf("stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNewRenderStore(t *testing.T) {
+	for _, tt := range []struct {
+		store string
+		ok    func(renderStore) bool
+	}{
+		{"", func(s renderStore) bool { _, ok := s.(*renderCache); return ok }},
+		{"memory", func(s renderStore) bool { _, ok := s.(*renderCache); return ok }},
+		{"redis", func(s renderStore) bool { _, ok := s.(*redisRenderCache); return ok }},
+	} {
+		v := viper.New()
+		v.Set(ConfigRenderCacheStore, tt.store)
+		s, err := newRenderStore(v)
+		if err != nil {
+			t.Errorf("newRenderStore(%q) returned error %v", tt.store, err)
+		} else if !tt.ok(s) {
+			t.Errorf("newRenderStore(%q) = %T", tt.store, s)
+		}
+	}
+
+	v := viper.New()
+	v.Set(ConfigRenderCacheStore, "memcache")
+	if _, err := newRenderStore(v); err == nil {
+		t.Error("newRenderStore(memcache) returned nil error")
+	}
+}
+
+func TestRenderCacheClear(t *testing.T) {
+	c := newRenderCache(10)
+	a := renderKey{importPath: "a", etag: "1", template: "pkg.html"}
+	c.add(a, []byte("aaaa"))
+	c.clear()
+	if _, ok := c.get(a); ok {
+		t.Error("a not cleared")
+	}
+	if got := c.stats(); got.Entries != 0 || got.Bytes != 0 {
+		t.Errorf("stats() after clear = %+v, want no entries", got)
+	}
+}
+
+func TestRedisRenderCache(t *testing.T) {
+	db := newTestDB(t)
+	defer closeTestDB(db)
+	a := renderKey{importPath: "a", etag: "1", template: "pkg.html"}
+
+	// A zero TTL keeps the pages until they are invalidated.
+	c := newRedisRenderCache(db.Pool, 0, 100)
+	c.add(a, []byte("aaaa"))
+	conn := db.Pool.Get()
+	defer conn.Close()
+	if ttl, err := redis.Int(conn.Do("TTL", redisRenderKey("a"))); ttl != -1 || err != nil {
+		t.Errorf("TTL with zero render_cache_ttl = %d, %v, want -1", ttl, err)
+	}
+	other := newRedisRenderCache(db.Pool, time.Hour, 100)
+	other.add(a, []byte("aaaa"))
+	if ttl, err := redis.Int(conn.Do("TTL", redisRenderKey("a"))); ttl <= 0 || err != nil {
+		t.Errorf("TTL = %d, %v, want positive", ttl, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	exited := make(chan struct{})
+	go func() {
+		c.subscribe(ctx)
+		close(exited)
+	}()
+	// Invalidate until the subscription receives the message.
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		c.local.add(a, []byte("aaaa"))
+		other.invalidate("a")
+		time.Sleep(10 * time.Millisecond)
+		if _, ok := c.local.get(a); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("local page not invalidated by the message of another instance")
+		}
+	}
+
+	cancel()
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscribe did not return after the context was canceled")
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+var errRenderBusy = errors.New("too many packages are being fetched")
+
+// renderLimiter limits the number of packages fetched and built concurrently
+// for requests. A nil limiter does not limit anything.
+type renderLimiter struct {
+	sem     chan struct{}
+	wait    time.Duration // maximum time waiting for a slot
+	waiting int32
+}
+
+func newRenderLimiter(max int, wait time.Duration) *renderLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &renderLimiter{sem: make(chan struct{}, max), wait: wait}
+}
+
+// acquire waits for a slot. It returns errRenderBusy if no slot is released
+// in time. Callers must call release once done with an acquired slot.
+func (l *renderLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l.sem <- struct{}{}:
+		return nil
+	default:
+	}
+	atomic.AddInt32(&l.waiting, 1)
+	defer atomic.AddInt32(&l.waiting, -1)
+	t := time.NewTimer(l.wait)
+	defer t.Stop()
+	select {
+	case l.sem <- struct{}{}:
+		return nil
+	case <-t.C:
+		return errRenderBusy
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *renderLimiter) release() {
+	if l != nil {
+		<-l.sem
+	}
+}
+
+// renderLimiterStats holds the current state of a render limiter.
+type renderLimiterStats struct {
+	InFlight int `json:"in_flight"`
+	Waiting  int `json:"waiting"`
+}
+
+func (l *renderLimiter) stats() renderLimiterStats {
+	if l == nil {
+		return renderLimiterStats{}
+	}
+	return renderLimiterStats{InFlight: len(l.sem), Waiting: int(atomic.LoadInt32(&l.waiting))}
+}
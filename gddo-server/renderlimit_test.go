@@ -0,0 +1,70 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestRenderLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := newRenderLimiter(1, 10*time.Millisecond)
+	if err := l.acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.acquire(ctx); err != errRenderBusy {
+		t.Errorf("acquire with no free slot returned %v, want %v", err, errRenderBusy)
+	}
+
+	done := make(chan error)
+	l.wait = time.Minute
+	go func() { done <- l.acquire(ctx) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for l.stats().Waiting != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("stats() = %+v, want one waiting request", l.stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if want := (renderLimiterStats{InFlight: 1, Waiting: 1}); l.stats() != want {
+		t.Errorf("stats() = %+v, want %+v", l.stats(), want)
+	}
+	l.release()
+	if err := <-done; err != nil {
+		t.Errorf("acquire after release returned %v", err)
+	}
+	l.release()
+
+	var nl *renderLimiter
+	if err := nl.acquire(ctx); err != nil {
+		t.Errorf("nil limiter acquire returned %v", err)
+	}
+	nl.release()
+}
+
+func TestServeUnexportedLimited(t *testing.T) {
+	v := viper.New()
+	v.Set(ConfigGetTimeout, time.Minute)
+	s := &server{v: v, renders: newRenderLimiter(1, time.Millisecond)}
+	if err := s.renders.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.renders.release()
+
+	// The server has no HTTP client, so fetching the package would fail.
+	req := httptest.NewRequest("GET", "/example.com/a?unexported", nil)
+	err := s.serveUnexported(httptest.NewRecorder(), req, "example.com/a")
+	if e, ok := err.(*httpError); !ok || e.status != http.StatusServiceUnavailable || e.err != errRenderBusy {
+		t.Errorf("serveUnexported with no free slot returned %v, want status %d and %v", err, http.StatusServiceUnavailable, errRenderBusy)
+	}
+}
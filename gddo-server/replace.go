@@ -0,0 +1,72 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+// replacement is a module required by the go.mod file of the package and
+// replaced by a replace directive.
+type replacement struct {
+	// Required module path and version.
+	Path    string
+	Version string
+
+	// Module path and version, or directory, of the replacement.
+	Target        string
+	TargetVersion string
+
+	// Local is true if the replacement is a directory on the file system of
+	// the module, which cannot be fetched.
+	Local bool
+}
+
+// TargetURL returns the path of the documentation of the replacement or "" if
+// the replacement is not an importable path.
+func (r replacement) TargetURL() string {
+	if r.Local || !gosrc.IsValidPath(r.Target) {
+		return ""
+	}
+	return "/" + r.Target
+}
+
+// isLocalReplacement reports whether the target of a replace directive is a
+// file path rather than a module path. The go command treats a target as a
+// file path if it is absolute or starts with ./ or ../.
+func isLocalReplacement(target string) bool {
+	return target == "." || target == ".." ||
+		strings.HasPrefix(target, "./") || strings.HasPrefix(target, "../") ||
+		strings.HasPrefix(target, ".\\") || strings.HasPrefix(target, "..\\") ||
+		strings.HasPrefix(target, "/") || strings.HasPrefix(target, "\\") ||
+		len(target) >= 2 && target[1] == ':'
+}
+
+// Replacements returns the required modules of the package that are replaced
+// in its go.mod file.
+func (pdoc *tdoc) Replacements() []replacement {
+	var result []replacement
+	for _, r := range pdoc.Requires {
+		if r.Replace == "" {
+			continue
+		}
+		rep := replacement{Path: r.Path, Version: r.Version}
+		if isLocalReplacement(r.Replace) {
+			rep.Target, rep.Local = r.Replace, true
+		} else {
+			fields := strings.Fields(r.Replace)
+			rep.Target = fields[0]
+			if len(fields) > 1 {
+				rep.TargetVersion = fields[1]
+			}
+		}
+		result = append(result, rep)
+	}
+	return result
+}
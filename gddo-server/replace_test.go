@@ -0,0 +1,56 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/golang/gddo/doc"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReplacements(t *testing.T) {
+	pdoc := &tdoc{Package: &doc.Package{Requires: []doc.Requirement{
+		{Path: "example.com/a", Version: "v1.0.0"},
+		{Path: "example.com/b", Version: "v1.2.0", Replace: "github.com/user/b v1.2.1"},
+		{Path: "example.com/c", Version: "v0.1.0", Replace: "../c"},
+		{Path: "example.com/d", Version: "v0.3.0", Replace: "github.com/user/d"},
+	}}}
+	want := []replacement{
+		{Path: "example.com/b", Version: "v1.2.0", Target: "github.com/user/b", TargetVersion: "v1.2.1"},
+		{Path: "example.com/c", Version: "v0.1.0", Target: "../c", Local: true},
+		{Path: "example.com/d", Version: "v0.3.0", Target: "github.com/user/d"},
+	}
+	got := pdoc.Replacements()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Replacements() mismatch (-want +got):\n%s", diff)
+	}
+	if u := got[0].TargetURL(); u != "/github.com/user/b" {
+		t.Errorf("TargetURL() = %q, want %q", u, "/github.com/user/b")
+	}
+	if u := got[1].TargetURL(); u != "" {
+		t.Errorf("local TargetURL() = %q, want \"\"", u)
+	}
+}
+
+func TestIsLocalReplacement(t *testing.T) {
+	for _, tt := range []struct {
+		target string
+		want   bool
+	}{
+		{"./m", true},
+		{"../m", true},
+		{"/home/user/m", true},
+		{`C:\src\m`, true},
+		{"github.com/user/m", false},
+		{"example.com/m", false},
+	} {
+		if got := isLocalReplacement(tt.target); got != tt.want {
+			t.Errorf("isLocalReplacement(%q) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
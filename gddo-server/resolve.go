@@ -0,0 +1,179 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/gddo/gosrc"
+)
+
+// apiSource is the response of the resolve API endpoint.
+type apiSource struct {
+	Path          string `json:"path"`
+	ProjectRoot   string `json:"projectRoot,omitempty"`
+	ResolvedPath  string `json:"resolvedPath"`
+	VCS           string `json:"vcs"`
+	Host          string `json:"host"`
+	RepoURL       string `json:"repoURL"`
+	RepoDir       string `json:"repoDir,omitempty"`
+	DefaultBranch string `json:"defaultBranch,omitempty"`
+	BrowseURL     string `json:"browseURL,omitempty"`
+	DirTemplate   string `json:"dirTemplate,omitempty"`
+	FileTemplate  string `json:"fileTemplate,omitempty"`
+}
+
+func newAPISource(src *gosrc.Source) *apiSource {
+	return &apiSource{
+		Path:          src.ImportPath,
+		ProjectRoot:   src.ProjectRoot,
+		ResolvedPath:  src.ResolvedPath,
+		VCS:           src.VCS,
+		Host:          src.Host,
+		RepoURL:       src.RepoURL,
+		RepoDir:       src.RepoDir,
+		DefaultBranch: src.DefaultBranch,
+		BrowseURL:     src.BrowseURL,
+		DirTemplate:   src.DirTemplate,
+		FileTemplate:  src.FileTemplate,
+	}
+}
+
+// unresolvable returns the error of the resolve API endpoint for an import
+// path that cannot be resolved for the reason msg.
+func unresolvable(importPath, msg string) error {
+	msg = strings.TrimRight(msg, ".: ")
+	return &httpError{status: http.StatusNotFound, err: gosrc.NotFoundError{Message: "cannot resolve " + importPath + ": " + msg}}
+}
+
+// maxResolveCacheEntries bounds the number of import paths in the resolve
+// cache.
+const maxResolveCacheEntries = 10000
+
+// resolveResult is a result of the resolve API endpoint: the source of an
+// import path or the error for an import path that cannot be resolved.
+type resolveResult struct {
+	src     *apiSource
+	err     error
+	expires time.Time
+}
+
+// resolveCache caches the results of the resolve API endpoint so that
+// repeated requests for an import path do not fetch its meta tags and
+// repository again. A nil cache caches nothing.
+type resolveCache struct {
+	ttl        time.Duration
+	failureTTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]resolveResult
+}
+
+func newResolveCache(ttl, failureTTL time.Duration) *resolveCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &resolveCache{ttl: ttl, failureTTL: failureTTL, entries: make(map[string]resolveResult)}
+}
+
+// get returns the unexpired result for importPath.
+func (c *resolveCache) get(importPath string, now time.Time) (resolveResult, bool) {
+	if c == nil {
+		return resolveResult{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.entries[importPath]
+	if ok && now.Before(r.expires) {
+		return r, true
+	}
+	if ok {
+		delete(c.entries, importPath)
+	}
+	return resolveResult{}, false
+}
+
+// put caches the source src of importPath, or the error err if the import
+// path cannot be resolved.
+func (c *resolveCache) put(importPath string, src *apiSource, err error, now time.Time) {
+	if c == nil {
+		return
+	}
+	ttl := c.ttl
+	if err != nil {
+		ttl = c.failureTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxResolveCacheEntries {
+		for p, r := range c.entries {
+			if !now.Before(r.expires) {
+				delete(c.entries, p)
+			}
+		}
+		if len(c.entries) >= maxResolveCacheEntries {
+			c.entries = make(map[string]resolveResult)
+		}
+	}
+	c.entries[importPath] = resolveResult{src: src, err: err, expires: now.Add(ttl)}
+}
+
+// serveAPIResolve serves the location of the source of the import path
+// following /resolve/, such as the repository URL and the templates of the
+// browse URLs, without fetching the files of the package or building its
+// documentation. Results are cached and lookups count against the crawl
+// budget of the host.
+func (s *server) serveAPIResolve(resp http.ResponseWriter, req *http.Request) error {
+	importPath := strings.TrimPrefix(req.URL.Path, "/resolve/")
+	if !gosrc.IsValidPath(importPath) {
+		return unresolvable(importPath, "invalid import path")
+	}
+	if blocked, err := s.db.Primary().IsBlocked(importPath); err != nil {
+		return err
+	} else if blocked {
+		return unresolvable(importPath, "blocked")
+	}
+	r, ok := s.resolved.get(importPath, time.Now())
+	if !ok {
+		if err := s.crawlBudget.acquire(importPath, time.Now()); err != nil {
+			return &httpError{status: http.StatusServiceUnavailable, err: err}
+		}
+		src, err := gosrc.GetSource(req.Context(), s.httpClient, importPath)
+		if e, ok := err.(gosrc.NotFoundError); ok {
+			r.err = unresolvable(importPath, e.Message)
+		} else if _, ok := err.(*gosrc.RemoteError); ok {
+			return &httpError{status: http.StatusBadGateway, err: err}
+		} else if err != nil {
+			return err
+		} else {
+			r.src = newAPISource(src)
+		}
+		s.resolved.put(importPath, r.src, r.err, time.Now())
+	}
+	if r.err != nil {
+		return r.err
+	}
+	return writeAPISource(resp, r.src, s.resolved)
+}
+
+// writeAPISource writes the source src as the response of the resolve API
+// endpoint. Clients may cache it as long as the cache c.
+func writeAPISource(resp http.ResponseWriter, src *apiSource, c *resolveCache) error {
+	resp.Header().Set("Content-Type", jsonMIMEType)
+	if c != nil {
+		resp.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(c.ttl/time.Second)))
+	}
+	return json.NewEncoder(resp).Encode(src)
+}
@@ -0,0 +1,130 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/gddo/gosrc"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewAPISource(t *testing.T) {
+	src := &gosrc.Source{
+		ImportPath:    "alice.org/pkg/sub",
+		ProjectRoot:   "alice.org/pkg",
+		ResolvedPath:  "github.com/alice/pkg/sub",
+		VCS:           "git",
+		Host:          "github.com",
+		RepoURL:       "https://github.com/alice/pkg",
+		RepoDir:       "sub",
+		DefaultBranch: "main",
+		BrowseURL:     "https://github.com/alice/pkg/tree/main/sub",
+		DirTemplate:   "https://github.com/alice/pkg/tree/main{/dir}",
+		FileTemplate:  "https://github.com/alice/pkg/blob/main{/dir}/{file}#L{line}",
+	}
+	p, err := json.Marshal(newAPISource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]string
+	if err := json.Unmarshal(p, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"path":          "alice.org/pkg/sub",
+		"projectRoot":   "alice.org/pkg",
+		"resolvedPath":  "github.com/alice/pkg/sub",
+		"vcs":           "git",
+		"host":          "github.com",
+		"repoURL":       "https://github.com/alice/pkg",
+		"repoDir":       "sub",
+		"defaultBranch": "main",
+		"browseURL":     "https://github.com/alice/pkg/tree/main/sub",
+		"dirTemplate":   "https://github.com/alice/pkg/tree/main{/dir}",
+		"fileTemplate":  "https://github.com/alice/pkg/blob/main{/dir}/{file}#L{line}",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("newAPISource mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestServeAPIResolveInvalid(t *testing.T) {
+	s := &server{}
+	h := errorHandler{fn: s.serveAPIResolve, errFn: handleAPIError}
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest("GET", "/resolve/example.com//pkg", nil))
+	if resp.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", resp.Code, http.StatusNotFound)
+	}
+	var data struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+	const want = "cannot resolve example.com//pkg: invalid import path"
+	if data.Error.Message != want {
+		t.Errorf("error message = %q, want %q", data.Error.Message, want)
+	}
+}
+
+func TestResolveCache(t *testing.T) {
+	now := time.Now()
+	c := newResolveCache(time.Hour, time.Minute)
+	src := &apiSource{Path: "example.com/pkg"}
+	c.put("example.com/pkg", src, nil, now)
+	notFound := unresolvable("example.com/missing", "not found")
+	c.put("example.com/missing", nil, notFound, now)
+
+	if r, ok := c.get("example.com/pkg", now.Add(30*time.Minute)); !ok || r.src != src {
+		t.Errorf("get(example.com/pkg) = %v, %v, want cached source", r, ok)
+	}
+	if r, ok := c.get("example.com/missing", now.Add(30*time.Second)); !ok || r.err != notFound {
+		t.Errorf("get(example.com/missing) = %v, %v, want cached error", r, ok)
+	}
+	if _, ok := c.get("example.com/missing", now.Add(2*time.Minute)); ok {
+		t.Errorf("get(example.com/missing) returned an error older than the failure TTL")
+	}
+	if _, ok := c.get("example.com/pkg", now.Add(2*time.Hour)); ok {
+		t.Errorf("get(example.com/pkg) returned an expired source")
+	}
+
+	var nilCache *resolveCache
+	nilCache.put("example.com/pkg", src, nil, now)
+	if _, ok := nilCache.get("example.com/pkg", now); ok {
+		t.Errorf("nil cache returned a result")
+	}
+	if c := newResolveCache(0, time.Minute); c != nil {
+		t.Errorf("newResolveCache(0) = %v, want nil", c)
+	}
+}
+
+func TestWriteAPISourceCacheControl(t *testing.T) {
+	src := &apiSource{Path: "example.com/pkg"}
+	for _, tt := range []struct {
+		c    *resolveCache
+		want string
+	}{
+		{newResolveCache(time.Hour, time.Minute), "public, max-age=3600"},
+		{nil, ""},
+	} {
+		resp := httptest.NewRecorder()
+		if err := writeAPISource(resp, src, tt.c); err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("Cache-Control = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/golang/gddo/httputil"
+)
+
+// robotList holds the clients configured to always be classified as
+// robots, in addition to the built-in detection.
+type robotList struct {
+	userAgents []string // substrings of the User-Agent header
+	nets       []*net.IPNet
+}
+
+// parseRobotList parses User-Agent substrings and CIDR blocks.
+func parseRobotList(userAgents, cidrs []string) (*robotList, error) {
+	l := &robotList{}
+	for _, ua := range userAgents {
+		if ua != "" {
+			l.userAgents = append(l.userAgents, ua)
+		}
+	}
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid robot CIDR %q: %v", cidr, err)
+		}
+		l.nets = append(l.nets, n)
+	}
+	return l, nil
+}
+
+// match reports whether the request is from a configured robot.
+func (l *robotList) match(req *http.Request) bool {
+	if l == nil {
+		return false
+	}
+	ua := req.Header.Get("User-Agent")
+	for _, s := range l.userAgents {
+		if strings.Contains(ua, s) {
+			return true
+		}
+	}
+	if len(l.nets) == 0 {
+		return false
+	}
+	ip := remoteIP(req)
+	if ip == nil {
+		return false
+	}
+	for _, n := range l.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP returns the IP address of the client of req or nil if the remote
+// address is not valid.
+func remoteIP(req *http.Request) net.IP {
+	return net.ParseIP(httputil.StripPort(req.RemoteAddr))
+}
@@ -0,0 +1,40 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRobotList(t *testing.T) {
+	l, err := parseRobotList([]string{"acme-monitor"}, []string{"10.1.0.0/16", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		userAgent, remoteAddr string
+		want                  bool
+	}{
+		{"Mozilla/5.0", "192.168.1.1:1234", false},
+		{"acme-monitor/1.2", "192.168.1.1:1234", true},
+		{"Mozilla/5.0", "10.1.2.3:1234", true},
+		{"Mozilla/5.0", "10.2.2.3:1234", false},
+		{"Mozilla/5.0", "[2001:db8::1]:80", true},
+		{"Mozilla/5.0", "10.1.2.3, 172.16.0.1", false},
+		{"Mozilla/5.0", "unknown", false},
+	} {
+		req := &http.Request{Header: http.Header{"User-Agent": {tt.userAgent}}, RemoteAddr: tt.remoteAddr}
+		if got := l.match(req); got != tt.want {
+			t.Errorf("match(%q, %q) = %v, want %v", tt.userAgent, tt.remoteAddr, got, tt.want)
+		}
+	}
+
+	if _, err := parseRobotList(nil, []string{"10.1.0.0"}); err == nil {
+		t.Error("parseRobotList with invalid CIDR returned nil error")
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/golang/gddo/database"
+)
+
+// searchBatchSize is the number of search results rendered between flushes
+// of the response.
+const searchBatchSize = 20
+
+type searchIterator interface {
+	Next() (database.Package, bool)
+	Err() error
+}
+
+// searchBatches reads the first batch of n results from it and returns it
+// with a channel receiving the remaining batches. The channel is closed when
+// there are no more results or ctx is done.
+func searchBatches(ctx context.Context, it searchIterator, n int) ([]database.Package, <-chan []database.Package, error) {
+	first, ok := nextBatch(it, n)
+	if err := it.Err(); err != nil {
+		return nil, nil, err
+	}
+	ch := make(chan []database.Package)
+	if !ok {
+		close(ch)
+		return first, ch, nil
+	}
+	go func() {
+		defer close(ch)
+		for more := true; more; {
+			var batch []database.Package
+			batch, more = nextBatch(it, n)
+			if len(batch) == 0 {
+				return
+			}
+			select {
+			case ch <- batch:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return first, ch, nil
+}
+
+// nextBatch returns the next n results of it. The returned bool is false if
+// the iteration ended before n results were read.
+func nextBatch(it searchIterator, n int) ([]database.Package, bool) {
+	var batch []database.Package
+	for len(batch) < n {
+		p, ok := it.Next()
+		if !ok {
+			return batch, false
+		}
+		batch = append(batch, p)
+	}
+	return batch, true
+}
+
+// flusher returns a function for templates flushing the response written so
+// far to the client.
+func flusher(resp http.ResponseWriter) func() string {
+	return func() string {
+		if f, ok := resp.(http.Flusher); ok {
+			f.Flush()
+		}
+		return ""
+	}
+}
+
+// recencyOption is a choice of the recency filter of search results.
+type recencyOption struct {
+	Label    string
+	Query    string // search query with the filter
+	Selected bool
+}
+
+// recencyOptions returns the choices of the recency filter for the search
+// query q. The filter is the updated:< term of the query.
+func recencyOptions(q string) []recencyOption {
+	current := database.QueryRecency(q)
+	var options []recencyOption
+	for _, o := range []struct{ label, recency string }{
+		{"any time", ""},
+		{"past month", "30d"},
+		{"past 3 months", "90d"},
+		{"past year", "1y"},
+	} {
+		options = append(options, recencyOption{
+			Label:    o.label,
+			Query:    database.WithRecency(q, o.recency),
+			Selected: o.recency == current,
+		})
+	}
+	return options
+}
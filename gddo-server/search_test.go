@@ -0,0 +1,90 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/golang/gddo/database"
+	"github.com/google/go-cmp/cmp"
+)
+
+type testSearchIterator struct {
+	n, max int
+	err    error
+}
+
+func (it *testSearchIterator) Next() (database.Package, bool) {
+	if it.err != nil || it.n >= it.max {
+		return database.Package{}, false
+	}
+	it.n++
+	return database.Package{Path: strconv.Itoa(it.n)}, true
+}
+
+func (it *testSearchIterator) Err() error { return it.err }
+
+func TestSearchBatches(t *testing.T) {
+	for _, tt := range []struct {
+		max  int
+		want [][]string
+	}{
+		{max: 0, want: [][]string{nil}},
+		{max: 2, want: [][]string{{"1", "2"}}},
+		{max: 3, want: [][]string{{"1", "2", "3"}}},
+		{max: 7, want: [][]string{{"1", "2", "3"}, {"4", "5", "6"}, {"7"}}},
+	} {
+		first, more, err := searchBatches(context.Background(), &testSearchIterator{max: tt.max}, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got [][]string
+		for batch := first; ; {
+			var paths []string
+			for _, p := range batch {
+				paths = append(paths, p.Path)
+			}
+			got = append(got, paths)
+			var ok bool
+			if batch, ok = <-more; !ok {
+				break
+			}
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("searchBatches with %d results mismatch (-want +got):\n%s", tt.max, diff)
+		}
+	}
+
+	if _, _, err := searchBatches(context.Background(), &testSearchIterator{err: errors.New("boom")}, 3); err == nil {
+		t.Error("searchBatches with failing iterator returned nil error")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, more, err := searchBatches(ctx, &testSearchIterator{max: 100}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	for range more {
+		// The channel is closed once the context is done.
+	}
+}
+
+func TestRecencyOptions(t *testing.T) {
+	want := []recencyOption{
+		{Label: "any time", Query: "router"},
+		{Label: "past month", Query: "router updated:<30d"},
+		{Label: "past 3 months", Query: "router updated:<90d", Selected: true},
+		{Label: "past year", Query: "router updated:<1y"},
+	}
+	if diff := cmp.Diff(want, recencyOptions("router updated:<90d")); diff != "" {
+		t.Errorf("recencyOptions() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/golang/gddo/database"
+)
+
+const rssMIMEType = "application/rss+xml; charset=utf-8"
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+	Description string `xml:"description,omitempty"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// searchFeedFormat returns the feed format requested by a search with the
+// format parameter, rss or atom, or "" for the page of results.
+func searchFeedFormat(req *http.Request) string {
+	switch f := req.URL.Query().Get("format"); f {
+	case "rss", "atom":
+		return f
+	}
+	return ""
+}
+
+// recentPackages returns the packages of pkgs with a known update time,
+// most recently updated first.
+func recentPackages(pkgs []database.Package) []database.Package {
+	var recent []database.Package
+	for _, pkg := range pkgs {
+		if !pkg.Updated.IsZero() {
+			recent = append(recent, pkg)
+		}
+	}
+	sort.SliceStable(recent, func(i, j int) bool { return recent[i].Updated.After(recent[j].Updated) })
+	return recent
+}
+
+// newSearchAtomFeed returns the Atom feed of the search results pkgs, most
+// recently updated first. pageURL is the absolute URL of the result page.
+func newSearchAtomFeed(siteName, pageURL, q string, pkgs []database.Package, pkgURL func(string) string) *atomFeed {
+	feed := &atomFeed{
+		Title: q + " - " + siteName,
+		ID:    pageURL,
+		Links: []atomLink{{Href: pageURL}, {Rel: "self", Href: pageURL + "&format=atom"}},
+	}
+	if len(pkgs) > 0 {
+		feed.Updated = pkgs[0].Updated.UTC()
+	}
+	for _, pkg := range pkgs {
+		u := pkgURL(pkg.Path)
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   pkg.Path,
+			ID:      u,
+			Link:    atomLink{Href: u},
+			Updated: pkg.Updated.UTC(),
+			Content: atomText{Type: "text", Body: pkg.Synopsis},
+		})
+	}
+	return feed
+}
+
+// newSearchRSSFeed returns the RSS feed of the search results pkgs, most
+// recently updated first. pageURL is the absolute URL of the result page.
+func newSearchRSSFeed(siteName, pageURL, q string, pkgs []database.Package, pkgURL func(string) string) *rssFeed {
+	feed := &rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       q + " - " + siteName,
+			Link:        pageURL,
+			Description: "Go packages matching " + q + ", most recently updated first.",
+		},
+	}
+	if len(pkgs) > 0 {
+		feed.Channel.LastBuildDate = pkgs[0].Updated.UTC().Format(time.RFC1123Z)
+	}
+	for _, pkg := range pkgs {
+		u := pkgURL(pkg.Path)
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       pkg.Path,
+			Link:        u,
+			GUID:        u,
+			PubDate:     pkg.Updated.UTC().Format(time.RFC1123Z),
+			Description: pkg.Synopsis,
+		})
+	}
+	return feed
+}
+
+// serveSearchFeed serves the results of the search query q as an RSS or
+// Atom feed, most recently updated first, so that feed readers can poll a
+// saved search. The results are capped as on the result page. Results with
+// an unknown update time are left out.
+func (s *server) serveSearchFeed(resp http.ResponseWriter, req *http.Request, q, format string) error {
+	it, err := s.db.SearchIter(req.Context(), q, s.v.GetInt(ConfigSearchLimit))
+	if err != nil {
+		return err
+	}
+	var pkgs []database.Package
+	for {
+		pkg, ok := it.Next()
+		if !ok {
+			break
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	pkgs = recentPackages(pkgs)
+
+	var latest time.Time
+	if len(pkgs) > 0 {
+		latest = pkgs[0].Updated
+	}
+	if !checkFeedModified(resp, req, "search."+format+" "+q, latest) {
+		return nil
+	}
+
+	siteName := s.v.GetString(ConfigSiteName)
+	baseURL := s.siteURL(resp, req)
+	pageURL := baseURL + "/?q=" + url.QueryEscape(q)
+	pkgURL := func(p string) string { return baseURL + "/" + p }
+	var feed interface{}
+	if format == "rss" {
+		resp.Header().Set("Content-Type", rssMIMEType)
+		feed = newSearchRSSFeed(siteName, pageURL, q, pkgs, pkgURL)
+	} else {
+		resp.Header().Set("Content-Type", atomMIMEType)
+		feed = newSearchAtomFeed(siteName, pageURL, q, pkgs, pkgURL)
+	}
+	if _, err := resp.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	return xml.NewEncoder(resp).Encode(feed)
+}
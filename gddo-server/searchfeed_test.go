@@ -0,0 +1,69 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/golang/gddo/database"
+)
+
+func TestSearchFeeds(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2021, 6, d, 12, 0, 0, 0, time.UTC) }
+	pkgs := recentPackages([]database.Package{
+		{Path: "github.com/a/old", Synopsis: "Package old.", Updated: day(1)},
+		{Path: "github.com/b/unknown", Synopsis: "Package unknown."},
+		{Path: "github.com/c/new", Synopsis: "Package new.", Updated: day(3)},
+	})
+	var paths []string
+	for _, pkg := range pkgs {
+		paths = append(paths, pkg.Path)
+	}
+	if diff := cmp.Diff([]string{"github.com/c/new", "github.com/a/old"}, paths); diff != "" {
+		t.Fatalf("recentPackages() mismatch (-want +got):\n%s", diff)
+	}
+
+	pkgURL := func(p string) string { return "https://godoc.org/" + p }
+	const pageURL = "https://godoc.org/?q=kubernetes"
+
+	rss, err := xml.Marshal(newSearchRSSFeed("GoDoc", pageURL, "kubernetes", pkgs, pkgURL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<rss version="2.0"><channel><title>kubernetes - GoDoc</title><link>https://godoc.org/?q=kubernetes</link>`,
+		`<lastBuildDate>Thu, 03 Jun 2021 12:00:00 +0000</lastBuildDate>`,
+		`<item><title>github.com/c/new</title><link>https://godoc.org/github.com/c/new</link><guid>https://godoc.org/github.com/c/new</guid><pubDate>Thu, 03 Jun 2021 12:00:00 +0000</pubDate><description>Package new.</description></item>`,
+	} {
+		if !strings.Contains(string(rss), want) {
+			t.Errorf("RSS feed does not contain %s:\n%s", want, rss)
+		}
+	}
+
+	atom := newSearchAtomFeed("GoDoc", pageURL, "kubernetes", pkgs, pkgURL)
+	if !atom.Updated.Equal(day(3)) {
+		t.Errorf("Atom feed updated %v, want %v", atom.Updated, day(3))
+	}
+	want := atomEntry{
+		Title:   "github.com/a/old",
+		ID:      "https://godoc.org/github.com/a/old",
+		Link:    atomLink{Href: "https://godoc.org/github.com/a/old"},
+		Updated: day(1),
+		Content: atomText{Type: "text", Body: "Package old."},
+	}
+	if len(atom.Entries) != 2 {
+		t.Fatalf("Atom feed has %d entries, want 2", len(atom.Entries))
+	}
+	if diff := cmp.Diff(want, atom.Entries[1]); diff != "" {
+		t.Errorf("Atom entry mismatch (-want +got):\n%s", diff)
+	}
+}
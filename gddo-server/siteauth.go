@@ -0,0 +1,122 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// siteAuthExempt are the path prefixes served without site authentication:
+// the health checks of the load balancer and the /debug/ endpoints, which
+// have their own access control.
+var siteAuthExempt = []string{"/_ah/", "/debug/"}
+
+// siteAuth is a handler requiring clients to authenticate with HTTP basic
+// authentication or a shared bearer token before serving the request with h.
+type siteAuth struct {
+	users map[string]string // password by user name
+	token string
+	realm string
+	h     http.Handler
+}
+
+// newSiteAuth returns h wrapped to require authentication with the users,
+// specified as name:password, or the token. newSiteAuth returns h unchanged
+// if there are neither users nor a token.
+func newSiteAuth(h http.Handler, users []string, token, realm string) (http.Handler, error) {
+	if len(users) == 0 && token == "" {
+		return h, nil
+	}
+	a := &siteAuth{users: make(map[string]string), token: token, realm: realm, h: h}
+	for _, spec := range users {
+		i := strings.IndexByte(spec, ':')
+		if i <= 0 || i == len(spec)-1 {
+			return nil, fmt.Errorf("invalid site auth user %q, want name:password", spec)
+		}
+		a.users[spec[:i]] = spec[i+1:]
+	}
+	return a, nil
+}
+
+// allowed reports whether the request has valid credentials.
+func (a *siteAuth) allowed(req *http.Request) bool {
+	if user, password, ok := req.BasicAuth(); ok {
+		want, ok := a.users[user]
+		return ok && subtle.ConstantTimeCompare([]byte(password), []byte(want)) == 1
+	}
+	const prefix = "Bearer "
+	if h := req.Header.Get("Authorization"); a.token != "" && strings.HasPrefix(h, prefix) {
+		return subtle.ConstantTimeCompare([]byte(h[len(prefix):]), []byte(a.token)) == 1
+	}
+	return false
+}
+
+func (a *siteAuth) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	for _, p := range siteAuthExempt {
+		if strings.HasPrefix(req.URL.Path, p) {
+			a.h.ServeHTTP(resp, req)
+			return
+		}
+	}
+	if !a.allowed(req) {
+		resp.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", a.realm))
+		http.Error(resp, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	a.h.ServeHTTP(&privateCacheWriter{ResponseWriter: resp}, req)
+}
+
+// privateCache returns the Cache-Control header value cc changed to keep
+// shared caches from storing the response: public is replaced by private.
+func privateCache(cc string) string {
+	directives := []string{"private"}
+	for _, d := range strings.Split(cc, ",") {
+		d = strings.TrimSpace(d)
+		if d == "" || strings.EqualFold(d, "public") || strings.EqualFold(d, "private") {
+			continue
+		}
+		directives = append(directives, d)
+	}
+	return strings.Join(directives, ", ")
+}
+
+// privateCacheWriter makes the Cache-Control header of authenticated
+// responses private, so that a proxy does not serve a response to clients
+// without credentials.
+type privateCacheWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *privateCacheWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if cc := w.Header().Get("Cache-Control"); cc != "" {
+			w.Header().Set("Cache-Control", privateCache(cc))
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *privateCacheWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *privateCacheWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
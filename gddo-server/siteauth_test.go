@@ -0,0 +1,84 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSiteAuth(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h, err := newSiteAuth(ok, []string{"alice:secret"}, "token", "GoDoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name   string
+		path   string
+		setup  func(r *http.Request)
+		status int
+	}{
+		{"no credentials", "/", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"basic auth", "/", func(r *http.Request) { r.SetBasicAuth("alice", "secret") }, http.StatusOK},
+		{"wrong password", "/", func(r *http.Request) { r.SetBasicAuth("alice", "wrong") }, http.StatusUnauthorized},
+		{"unknown user", "/", func(r *http.Request) { r.SetBasicAuth("bob", "secret") }, http.StatusUnauthorized},
+		{"token", "/search", func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") }, http.StatusOK},
+		{"wrong token", "/", func(r *http.Request) { r.Header.Set("Authorization", "Bearer other") }, http.StatusUnauthorized},
+		{"health check", "/_ah/health", func(r *http.Request) {}, http.StatusOK},
+		{"debug endpoint", "/debug/crawl-queue", func(r *http.Request) {}, http.StatusOK},
+	} {
+		r := httptest.NewRequest("GET", tt.path, nil)
+		tt.setup(r)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s: status=%d, want %d", tt.name, w.Code, tt.status)
+		}
+		if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: no WWW-Authenticate header", tt.name)
+		}
+	}
+
+	if h, _ := newSiteAuth(ok, nil, "", "GoDoc"); h != nil {
+		if _, wrapped := h.(*siteAuth); wrapped {
+			t.Error("newSiteAuth without credentials wrapped the handler")
+		}
+	}
+	if _, err := newSiteAuth(ok, []string{"alice"}, "", "GoDoc"); err == nil {
+		t.Error("newSiteAuth accepted user without password")
+	}
+}
+
+func TestSiteAuthPrivateCache(t *testing.T) {
+	public := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		w.Write([]byte("ok"))
+	})
+	h, err := newSiteAuth(public, nil, "token", "GoDoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("GET", "/-/site.css", nil)
+	r.Header.Set("Authorization", "Bearer token")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got, want := w.Header().Get("Cache-Control"), "private, max-age=3600"; got != want {
+		t.Errorf("Cache-Control = %q, want %q", got, want)
+	}
+
+	for cc, want := range map[string]string{
+		"no-cache":                            "private, no-cache",
+		"public, max-age=31536000, immutable": "private, max-age=31536000, immutable",
+		"private":                             "private",
+	} {
+		if got := privateCache(cc); got != want {
+			t.Errorf("privateCache(%q) = %q, want %q", cc, got, want)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	htemp "html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/gddo/doc"
+	"github.com/golang/gddo/gosrc"
+	"github.com/golang/gddo/httputil"
+)
+
+// serveSource serves a source file of a package fetched from the VCS. It is
+// used in place of links to the VCS host when ConfigProxySource is set.
+func (s *server) serveSource(resp http.ResponseWriter, req *http.Request) error {
+	importPath := req.Form.Get("path")
+	name := req.Form.Get("file")
+	pdoc, _, _, err := s.db.Get(req.Context(), importPath)
+	if err != nil {
+		return err
+	}
+	if pdoc == nil || !hasSourceFile(pdoc, name) {
+		return &httpError{status: http.StatusNotFound}
+	}
+	return s.serveSourceFile(resp, req, pdoc, name)
+}
+
+// serveExampleSource serves the test file declaring the example with the ID
+// in the example query parameter.
+func (s *server) serveExampleSource(resp http.ResponseWriter, req *http.Request, pdoc *doc.Package) error {
+	m := exampleIDPat.FindStringSubmatch(req.Form.Get("example"))
+	if m == nil {
+		return &httpError{status: http.StatusNotFound}
+	}
+	e := findExample(pdoc, m[1], m[2], m[3])
+	if e == nil || e.File == "" || !hasSourceFile(pdoc, e.File) {
+		return &httpError{status: http.StatusNotFound}
+	}
+	return s.serveSourceFile(resp, req, pdoc, e.File)
+}
+
+// errSourceBackoff is the error shown when the source of a package is not
+// fetched because the package is being crawled or its last crawl failed.
+var errSourceBackoff = errors.New("the package is being crawled or failed to be crawled recently")
+
+// serveSourceFile serves the source file name of pdoc fetched from the VCS.
+// The files of a package do not change until its etag does, so the page is
+// validated and cached by the etag of the package without fetching it.
+func (s *server) serveSourceFile(resp http.ResponseWriter, req *http.Request, pdoc *doc.Package, name string) error {
+	etag := sourceEtag(pdoc, name)
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+				resp.Header().Set("Etag", etag)
+				resp.WriteHeader(http.StatusNotModified)
+				return nil
+			}
+		}
+	}
+
+	key := renderKey{importPath: pdoc.ImportPath, etag: pdoc.Etag, template: "source.html " + name}
+	body, ok := s.renderCache.get(key)
+	if !ok {
+		var err error
+		body, err = s.renderSourceFile(req.Context(), pdoc, name)
+		if err != nil {
+			return err
+		}
+		s.renderCache.add(key, body)
+	}
+
+	// The page is served with byte range support so that clients can resume
+	// the download of large files.
+	resp.Header().Set("Content-Type", htmlMIMEType)
+	resp.Header().Set("Etag", etag)
+	httputil.ServeRange(resp, req, etag, bytes.NewReader(body), int64(len(body)))
+	return nil
+}
+
+// renderSourceFile fetches the source file name of pdoc from the VCS within
+// the limits of the packages fetched for requests and renders its page.
+func (s *server) renderSourceFile(ctx context.Context, pdoc *doc.Package, name string) ([]byte, error) {
+	if s.crawls.backingOff(pdoc.ImportPath, time.Now()) {
+		return nil, &httpError{status: http.StatusServiceUnavailable, err: errSourceBackoff}
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.v.GetDuration(ConfigGetTimeout))
+	defer cancel()
+	release, err := s.acquireFetch(ctx, pdoc.ImportPath)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	dir, err := gosrc.Get(ctx, s.httpClient, pdoc.ImportPath, "")
+	if err != nil {
+		return nil, err
+	}
+	var file *gosrc.File
+	for _, f := range dir.Files {
+		if f.Name == name {
+			file = f
+			break
+		}
+	}
+	if file == nil {
+		return nil, &httpError{status: http.StatusNotFound}
+	}
+	return s.templates.render("source.html", map[string]interface{}{
+		"pdoc":      newTDoc(s.v, pdoc),
+		"file":      file.Name,
+		"browseURL": file.BrowseURL,
+		"src":       highlightSource(file.Name, file.Data),
+	})
+}
+
+// sourceEtag returns the HTTP entity tag of the page of the source file name
+// of pdoc.
+func sourceEtag(pdoc *doc.Package, name string) string {
+	return fmt.Sprintf(`"%x"`, sha1.Sum([]byte(pdoc.ImportPath+"\x00"+pdoc.Etag+"\x00"+name)))
+}
+
+// hasSourceFile reports whether name is a viewable source file of pdoc.
+func hasSourceFile(pdoc *doc.Package, name string) bool {
+	for _, files := range [][]*doc.File{pdoc.Files, pdoc.TestFiles} {
+		for _, f := range files {
+			if f.Name == name && f.URL != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sourceWriter writes HTML for source code with an anchor at the start of
+// each line.
+type sourceWriter struct {
+	buf  bytes.Buffer
+	line int
+}
+
+func (w *sourceWriter) startLine() {
+	w.line++
+	fmt.Fprintf(&w.buf, `<a class="ln" id="L%d" href="#L%d">%5d</a>  `, w.line, w.line, w.line)
+}
+
+// write writes text wrapped in a span with the given class. Spans are closed
+// and reopened around line breaks so that each line is well formed.
+func (w *sourceWriter) write(class string, text []byte) {
+	for i, line := range bytes.Split(text, []byte("\n")) {
+		if i > 0 {
+			w.buf.WriteByte('\n')
+			w.startLine()
+		}
+		if len(line) == 0 {
+			continue
+		}
+		if class != "" {
+			fmt.Fprintf(&w.buf, `<span class="%s">`, class)
+		}
+		htemp.HTMLEscape(&w.buf, line)
+		if class != "" {
+			w.buf.WriteString("</span>")
+		}
+	}
+}
+
+// highlightSource returns src formatted as HTML with line anchors. Go files
+// have comments, keywords and literals highlighted.
+func highlightSource(name string, src []byte) htemp.HTML {
+	var w sourceWriter
+	w.buf.WriteString("<pre>")
+	w.startLine()
+	if !strings.HasSuffix(name, ".go") {
+		w.write("", src)
+	} else {
+		fset := token.NewFileSet()
+		file := fset.AddFile(name, fset.Base(), len(src))
+		var s scanner.Scanner
+		s.Init(file, src, nil, scanner.ScanComments)
+		last := 0
+		for {
+			pos, tok, lit := s.Scan()
+			if tok == token.EOF {
+				break
+			}
+			var class string
+			switch {
+			case tok == token.COMMENT:
+				class = "com"
+			case tok.IsKeyword():
+				class = "kwd"
+			case tok == token.STRING || tok == token.CHAR:
+				class = "str"
+			default:
+				continue
+			}
+			offset := file.Offset(pos)
+			end := offset + len(lit)
+			if offset < last || end > len(src) || string(src[offset:end]) != lit {
+				// The scanner removes carriage returns from some literals.
+				continue
+			}
+			w.write("", src[last:offset])
+			w.write(class, src[offset:end])
+			last = end
+		}
+		w.write("", src[last:])
+	}
+	w.buf.WriteString("</pre>")
+	return htemp.HTML(w.buf.String())
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/gddo/doc"
+)
+
+func TestHighlightSource(t *testing.T) {
+	for _, tt := range []struct {
+		name, src, want string
+	}{
+		{
+			name: "a.go",
+			src:  "package a\n\n/* x\ny */\nvar s = \"<b>\"\n",
+			want: `<pre><a class="ln" id="L1" href="#L1">    1</a>  <span class="kwd">package</span> a
+<a class="ln" id="L2" href="#L2">    2</a>  
+<a class="ln" id="L3" href="#L3">    3</a>  <span class="com">/* x</span>
+<a class="ln" id="L4" href="#L4">    4</a>  <span class="com">y */</span>
+<a class="ln" id="L5" href="#L5">    5</a>  <span class="kwd">var</span> s = <span class="str">&#34;&lt;b&gt;&#34;</span>
+<a class="ln" id="L6" href="#L6">    6</a>  </pre>`,
+		},
+		{
+			name: "a.s",
+			src:  "TEXT ·f(SB)",
+			want: `<pre><a class="ln" id="L1" href="#L1">    1</a>  TEXT ·f(SB)</pre>`,
+		},
+	} {
+		if got := string(highlightSource(tt.name, []byte(tt.src))); got != tt.want {
+			t.Errorf("highlightSource(%q) =\n%s\nwant:\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestServeSourceFileCached(t *testing.T) {
+	// The server has no HTTP client, so serving a file that is not cached
+	// would fail.
+	s := &server{renderCache: newRenderCache(1 << 20)}
+	pdoc := &doc.Package{ImportPath: "example.com/a", Etag: "e1"}
+	etag := sourceEtag(pdoc, "a.go")
+
+	req := httptest.NewRequest("GET", "/-/source?path=example.com/a&file=a.go", nil)
+	req.Header.Set("If-None-Match", `"x", `+etag)
+	resp := httptest.NewRecorder()
+	if err := s.serveSourceFile(resp, req, pdoc, "a.go"); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: status %d, want %d", resp.Code, http.StatusNotModified)
+	}
+
+	s.renderCache.add(renderKey{importPath: pdoc.ImportPath, etag: pdoc.Etag, template: "source.html a.go"}, []byte("0123456789"))
+	req = httptest.NewRequest("GET", "/-/source?path=example.com/a&file=a.go", nil)
+	req.Header.Set("Range", "bytes=2-4")
+	resp = httptest.NewRecorder()
+	if err := s.serveSourceFile(resp, req, pdoc, "a.go"); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != http.StatusPartialContent || resp.Body.String() != "234" {
+		t.Errorf("Range: status %d, body %q, want %d, %q", resp.Code, resp.Body.String(), http.StatusPartialContent, "234")
+	}
+	if got := resp.Header().Get("Etag"); got != etag {
+		t.Errorf("Etag %s, want %s", got, etag)
+	}
+
+	if sourceEtag(&doc.Package{ImportPath: "example.com/a", Etag: "e2"}, "a.go") == etag {
+		t.Error("etag unchanged by the etag of the package")
+	}
+}
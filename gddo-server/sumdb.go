@@ -0,0 +1,225 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/note"
+
+	"github.com/golang/gddo/doc"
+)
+
+// sumStatus is the result of verifying the go.mod file of a module version
+// against the checksum database.
+type sumStatus string
+
+const (
+	sumVerified   sumStatus = "verified"   // hash matches the database
+	sumUnverified sumStatus = "unverified" // version not in the database or no go.mod file
+	sumMismatch   sumStatus = "mismatch"   // hash differs from the database
+)
+
+// maxSumCacheEntries is the number of results cached by a sumChecker.
+const maxSumCacheEntries = 10000
+
+// maxSumTiles is the number of tiles of the checksum database log kept in
+// memory by a sumChecker.
+const maxSumTiles = 1000
+
+// sumChecker verifies the go.mod files of module versions against the
+// checksum database at url, such as https://sum.golang.org, whose records
+// are authenticated with the verifier key. The results are cached because
+// the hash of a version in the database does not change. A nil checker does
+// not verify anything.
+type sumChecker struct {
+	url    string
+	key    string
+	client *http.Client
+
+	mu     sync.Mutex
+	cache  map[string]sumStatus // by "<module>@<version> <hash>"
+	latest []byte               // latest signed tree head
+	tiles  map[string][]byte
+}
+
+func newSumChecker(url, key string, client *http.Client) (*sumChecker, error) {
+	if url == "" {
+		return nil, nil
+	}
+	if _, err := note.NewVerifier(key); err != nil {
+		return nil, fmt.Errorf("checksum database key: %v", err)
+	}
+	return &sumChecker{
+		url:    strings.TrimSuffix(url, "/"),
+		key:    key,
+		client: client,
+		cache:  make(map[string]sumStatus),
+		tiles:  make(map[string][]byte),
+	}, nil
+}
+
+// check returns the status of the go.mod file of pdoc, the documentation of
+// a package fetched at version. The status is "" for nil checkers.
+func (c *sumChecker) check(ctx context.Context, pdoc *doc.Package, version string) sumStatus {
+	if c == nil {
+		return ""
+	}
+	if pdoc.ModulePath == "" || pdoc.GoModHash == "" {
+		return sumUnverified
+	}
+	mod := pdoc.ModulePath + "@" + version
+	key := mod + " " + pdoc.GoModHash
+	c.mu.Lock()
+	status, ok := c.cache[key]
+	c.mu.Unlock()
+	if ok {
+		return status
+	}
+
+	want, err := c.lookup(ctx, pdoc.ModulePath, version)
+	switch {
+	case err != nil:
+		// Do not cache errors, the next request may succeed.
+		log.Printf("ERROR sumChecker.lookup(%q): %v", mod, err)
+		return sumUnverified
+	case want == "":
+		status = sumUnverified
+	case want == pdoc.GoModHash:
+		status = sumVerified
+	default:
+		log.Printf("ERROR CHECKSUM MISMATCH: go.mod of %s fetched for %s has hash %s, checksum database has %s", mod, pdoc.ImportPath, pdoc.GoModHash, want)
+		status = sumMismatch
+	}
+
+	c.mu.Lock()
+	if len(c.cache) >= maxSumCacheEntries {
+		c.cache = make(map[string]sumStatus)
+	}
+	c.cache[key] = status
+	c.mu.Unlock()
+	return status
+}
+
+// lookup returns the go.mod hash of the module version recorded in the
+// checksum database or "" if the database does not have the version. The
+// record and the tree it belongs to are verified with the key of the
+// checker.
+func (c *sumChecker) lookup(ctx context.Context, modulePath, version string) (string, error) {
+	if _, err := module.EscapePath(modulePath); err != nil {
+		return "", nil
+	}
+	if _, err := module.EscapeVersion(version); err != nil {
+		return "", nil
+	}
+	// A client per lookup does not keep errors or records in memory. The
+	// latest tree head and the tiles are kept by the checker.
+	ops := &sumOps{c: c, ctx: ctx}
+	lines, err := sumdb.NewClient(ops).Lookup(modulePath, version+"/go.mod")
+	if ops.notFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	for _, line := range lines {
+		if f := strings.Fields(line); len(f) == 3 {
+			return f[2], nil
+		}
+	}
+	return "", nil
+}
+
+// sumOps implements sumdb.ClientOps for a lookup by a sumChecker.
+type sumOps struct {
+	c        *sumChecker
+	ctx      context.Context
+	notFound bool // the database does not have the version
+}
+
+func (o *sumOps) ReadRemote(path string) ([]byte, error) {
+	req, err := http.NewRequest("GET", o.c.url+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := o.c.client.Do(req.WithContext(o.ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		if strings.HasPrefix(path, "/lookup/") {
+			o.notFound = true
+		}
+		return nil, fmt.Errorf("GET %s: status %d", path, resp.StatusCode)
+	default:
+		return nil, fmt.Errorf("GET %s: status %d", path, resp.StatusCode)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func (o *sumOps) ReadConfig(file string) ([]byte, error) {
+	if file == "key" {
+		return []byte(o.c.key), nil
+	}
+	o.c.mu.Lock()
+	defer o.c.mu.Unlock()
+	return o.c.latest, nil
+}
+
+func (o *sumOps) WriteConfig(file string, old, new []byte) error {
+	o.c.mu.Lock()
+	defer o.c.mu.Unlock()
+	if !bytes.Equal(o.c.latest, old) {
+		return sumdb.ErrWriteConflict
+	}
+	o.c.latest = new
+	return nil
+}
+
+func (o *sumOps) ReadCache(file string) ([]byte, error) {
+	o.c.mu.Lock()
+	defer o.c.mu.Unlock()
+	if data, ok := o.c.tiles[file]; ok {
+		return data, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func (o *sumOps) WriteCache(file string, data []byte) {
+	// Lookup records are cached by the checker with their result.
+	if !strings.Contains(file, "/tile/") {
+		return
+	}
+	o.c.mu.Lock()
+	defer o.c.mu.Unlock()
+	if len(o.c.tiles) >= maxSumTiles {
+		o.c.tiles = make(map[string][]byte)
+	}
+	o.c.tiles[file] = data
+}
+
+func (o *sumOps) Log(msg string) {
+	log.Print(msg)
+}
+
+func (o *sumOps) SecurityError(msg string) {
+	log.Printf("ERROR checksum database: %s", msg)
+}
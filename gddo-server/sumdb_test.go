@@ -0,0 +1,108 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/note"
+
+	"github.com/golang/gddo/doc"
+)
+
+// newTestSumDB returns a checksum database server with the go.mod hash
+// h1:mod for github.com/Alice/pkg@v1.0.0, its verifier key and a counter
+// of the lookups it served.
+func newTestSumDB(t *testing.T) (*httptest.Server, string, *int32) {
+	skey, vkey, err := note.GenerateKey(rand.Reader, "sum.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gosum := func(path, vers string) ([]byte, error) {
+		if path != "github.com/Alice/pkg" || vers != "v1.0.0" {
+			return nil, os.ErrNotExist
+		}
+		return []byte("github.com/Alice/pkg v1.0.0 h1:zip\n" +
+			"github.com/Alice/pkg v1.0.0/go.mod h1:mod\n"), nil
+	}
+	h := sumdb.NewServer(sumdb.NewTestServer(skey, gosum))
+	var lookups int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/lookup/") {
+			atomic.AddInt32(&lookups, 1)
+		}
+		h.ServeHTTP(w, r)
+	}))
+	return ts, vkey, &lookups
+}
+
+func TestSumChecker(t *testing.T) {
+	ts, vkey, lookups := newTestSumDB(t)
+	defer ts.Close()
+
+	c, err := newSumChecker(ts.URL, vkey, ts.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, tt := range []struct {
+		pdoc    *doc.Package
+		version string
+		want    sumStatus
+	}{
+		{&doc.Package{ModulePath: "github.com/Alice/pkg", GoModHash: "h1:mod"}, "v1.0.0", sumVerified},
+		{&doc.Package{ModulePath: "github.com/Alice/pkg", GoModHash: "h1:other"}, "v1.0.0", sumMismatch},
+		{&doc.Package{ModulePath: "github.com/Alice/pkg", GoModHash: "h1:mod"}, "v1.1.0", sumUnverified},
+		{&doc.Package{}, "v1.0.0", sumUnverified},
+	} {
+		if got := c.check(ctx, tt.pdoc, tt.version); got != tt.want {
+			t.Errorf("check(%q, %q, %q) = %q, want %q", tt.pdoc.ModulePath, tt.pdoc.GoModHash, tt.version, got, tt.want)
+		}
+	}
+
+	// Results are cached.
+	n := atomic.LoadInt32(lookups)
+	c.check(ctx, &doc.Package{ModulePath: "github.com/Alice/pkg", GoModHash: "h1:mod"}, "v1.0.0")
+	if atomic.LoadInt32(lookups) != n {
+		t.Errorf("cached result looked up again")
+	}
+
+	if got := (*sumChecker)(nil).check(ctx, &doc.Package{}, "v1.0.0"); got != "" {
+		t.Errorf("nil checker returned %q, want empty", got)
+	}
+}
+
+func TestSumCheckerWrongKey(t *testing.T) {
+	ts, _, _ := newTestSumDB(t)
+	defer ts.Close()
+
+	// Records signed by another key are not trusted.
+	_, vkey, err := note.GenerateKey(rand.Reader, "sum.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newSumChecker(ts.URL, vkey, ts.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdoc := &doc.Package{ModulePath: "github.com/Alice/pkg", GoModHash: "h1:mod"}
+	if got := c.check(context.Background(), pdoc, "v1.0.0"); got != sumUnverified {
+		t.Errorf("check() with wrong key = %q, want %q", got, sumUnverified)
+	}
+
+	if _, err := newSumChecker(ts.URL, "bad key", ts.Client()); err == nil {
+		t.Errorf("newSumChecker() with bad key returned nil error")
+	}
+}
@@ -0,0 +1,299 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/trace"
+	"github.com/spf13/viper"
+)
+
+// sweepItem is a package crawled by the refresh sweeper.
+type sweepItem struct {
+	importPath string
+	new        bool // first crawl of the path
+	hasSubdirs bool // the path has subdirectories, set for new paths only
+}
+
+// sweepOutcome is the result of the sweeper's crawl of a package.
+type sweepOutcome int
+
+const (
+	sweepSkipped sweepOutcome = iota
+	sweepRefreshed
+	sweepFailed
+)
+
+// sweepCycle counts the packages handled by a cycle of the sweeper.
+type sweepCycle struct {
+	Started  time.Time `json:"started"`
+	Duration string    `json:"duration,omitempty"`
+
+	// Packages taken from the crawl schedule, and of those the packages
+	// crawled, the crawls which failed and the packages skipped because
+	// they were being crawled, backing off from a failed crawl or no longer
+	// due.
+	Checked   int `json:"checked"`
+	Refreshed int `json:"refreshed"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+}
+
+// sweeperStats holds the progress of the refresh sweeper.
+type sweeperStats struct {
+	Paused  bool        `json:"paused"`
+	Cycles  int         `json:"cycles"`
+	Current *sweepCycle `json:"current,omitempty"`
+	Last    *sweepCycle `json:"last,omitempty"`
+}
+
+// sweeper re-crawls the packages due in the crawl schedule and crawls the new
+// paths. Every crawl_interval a cycle starts, crawling batches of packages
+// until no package is due. The requests of concurrent crawls to a host are
+// limited by the host limits of the server's HTTP client.
+type sweeper struct {
+	batch       int           // packages per batch
+	concurrency int           // packages crawled concurrently
+	delay       time.Duration // between batches of a cycle
+
+	mu    sync.Mutex
+	stats sweeperStats
+}
+
+func newSweeper(v *viper.Viper) *sweeper {
+	sw := &sweeper{
+		batch:       v.GetInt(ConfigCrawlBatch),
+		concurrency: v.GetInt(ConfigCrawlConcurrency),
+		delay:       v.GetDuration(ConfigCrawlBatchDelay),
+	}
+	if sw.batch < 1 {
+		sw.batch = 1
+	}
+	if sw.concurrency < 1 {
+		sw.concurrency = 1
+	}
+	if sw.delay <= 0 {
+		sw.delay = v.GetDuration(ConfigCrawlInterval)
+	}
+	return sw
+}
+
+// sweepHost returns the host of an import path.
+func sweepHost(importPath string) string {
+	if i := strings.Index(importPath, "/"); i >= 0 {
+		return importPath[:i]
+	}
+	return importPath
+}
+
+// run crawls the items with crawl, with at most concurrency crawls in
+// progress, and records the outcomes in the current cycle.
+func (sw *sweeper) run(ctx context.Context, items []sweepItem, crawl func(context.Context, sweepItem) sweepOutcome) {
+	c := make(chan sweepItem)
+	var wg sync.WaitGroup
+	for i := 0; i < sw.concurrency && i < len(items); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range c {
+				sw.record(crawl(ctx, item))
+			}
+		}()
+	}
+feed:
+	for _, item := range items {
+		select {
+		case c <- item:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(c)
+	wg.Wait()
+}
+
+func (sw *sweeper) setPaused(paused bool) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.stats.Paused = paused
+}
+
+func (sw *sweeper) begin() {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.stats.Current = &sweepCycle{Started: time.Now()}
+}
+
+func (sw *sweeper) end() {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	cycle := sw.stats.Current
+	cycle.Duration = time.Since(cycle.Started).String()
+	sw.stats.Current = nil
+	sw.stats.Last = cycle
+	sw.stats.Cycles++
+}
+
+func (sw *sweeper) record(outcome sweepOutcome) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	cycle := sw.stats.Current
+	if cycle == nil {
+		return
+	}
+	cycle.Checked++
+	switch outcome {
+	case sweepRefreshed:
+		cycle.Refreshed++
+	case sweepFailed:
+		cycle.Failed++
+	default:
+		cycle.Skipped++
+	}
+}
+
+// snapshot returns a copy of the stats of the sweeper.
+func (sw *sweeper) snapshot() sweeperStats {
+	if sw == nil {
+		return sweeperStats{}
+	}
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	stats := sw.stats
+	if c := stats.Current; c != nil {
+		cc := *c
+		stats.Current = &cc
+	}
+	if c := stats.Last; c != nil {
+		cc := *c
+		stats.Last = &cc
+	}
+	return stats
+}
+
+// sweep runs a cycle of the sweeper. The sweeper is paused on a cached only
+// server, which never crawls.
+func (s *server) sweep(ctx context.Context) error {
+	sw := s.sweeper
+	if s.v.GetBool(ConfigCachedOnly) {
+		sw.setPaused(true)
+		return nil
+	}
+	sw.setPaused(false)
+
+	sw.begin()
+	defer sw.end()
+	seen := make(map[string]bool)
+	for {
+		items, err := s.nextSweepBatch(seen)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		sw.run(ctx, items, s.sweepPackage)
+		if len(items) < sw.batch {
+			return nil
+		}
+		select {
+		case <-time.After(sw.delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// nextSweepBatch returns up to a batch of new paths and packages due to be
+// crawled, leaving out the packages already handled in the cycle. Packages
+// handled but still due, such as skipped packages, stay at the front of the
+// crawl schedule, so the batch is read past them.
+func (s *server) nextSweepBatch(seen map[string]bool) ([]sweepItem, error) {
+	n := s.sweeper.batch
+	var items []sweepItem
+	for len(items) < n {
+		importPath, hasSubdirs, err := s.db.PopNewCrawl()
+		if err != nil {
+			return nil, err
+		}
+		if importPath == "" {
+			break
+		}
+		items = append(items, sweepItem{importPath: importPath, new: true, hasSubdirs: hasSubdirs})
+	}
+	if len(items) == n {
+		return items, nil
+	}
+	now := time.Now()
+	for offset := 0; len(items) < n; {
+		m := n - len(items)
+		paths, err := s.db.DueCrawls(offset, m, now)
+		if err != nil {
+			return nil, err
+		}
+		if len(paths) == 0 {
+			break
+		}
+		offset += m
+		for _, p := range paths {
+			if !seen[p] {
+				seen[p] = true
+				items = append(items, sweepItem{importPath: p})
+			}
+		}
+	}
+	return items, nil
+}
+
+// sweepPackage crawls the package of item unless the package is being
+// crawled, is backing off from a failed crawl or is no longer due.
+func (s *server) sweepPackage(ctx context.Context, item sweepItem) sweepOutcome {
+	span := s.traceClient.NewSpan("Crawl")
+	defer span.Finish()
+	ctx = trace.NewContext(ctx, span)
+
+	if s.crawls.backingOff(item.importPath, time.Now()) {
+		return sweepSkipped
+	}
+
+	if item.new {
+		pdoc, err := s.crawlDoc(ctx, "new", item.importPath, nil, item.hasSubdirs, time.Time{})
+		if pdoc == nil && err == nil {
+			if err := s.db.AddBadCrawl(item.importPath); err != nil {
+				log.Printf("ERROR db.AddBadCrawl(%q): %v", item.importPath, err)
+			}
+		}
+		if err != nil {
+			return sweepFailed
+		}
+		return sweepRefreshed
+	}
+
+	pdoc, pkgs, nextCrawl, err := s.db.Primary().Get(ctx, item.importPath)
+	if err != nil {
+		log.Printf("ERROR db.Get(%q): %v", item.importPath, err)
+		return sweepFailed
+	}
+	if pdoc == nil || nextCrawl.After(time.Now()) {
+		return sweepSkipped
+	}
+	if _, err = s.crawlDoc(ctx, "crawl", pdoc.ImportPath, pdoc, len(pkgs) > 0, nextCrawl); err != nil {
+		// Touch package so that crawl advances to next package.
+		retry := time.Now().Add(s.v.GetDuration(ConfigMaxAge) / 3)
+		if err := s.db.SetNextCrawl(pdoc.ImportPath, retry); err != nil {
+			log.Printf("ERROR db.SetNextCrawl(%q): %v", pdoc.ImportPath, err)
+		}
+		s.crawls.retry(pdoc.ImportPath, retry)
+		return sweepFailed
+	}
+	return sweepRefreshed
+}
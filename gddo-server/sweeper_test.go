@@ -0,0 +1,82 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSweeperRun(t *testing.T) {
+	sw := &sweeper{batch: 8, concurrency: 4}
+	items := []sweepItem{
+		{importPath: "github.com/a/1"},
+		{importPath: "github.com/a/2"},
+		{importPath: "github.com/a/3"},
+		{importPath: "github.com/a/4"},
+		{importPath: "gitlab.com/b/1"},
+		{importPath: "gitlab.com/b/2"},
+		{importPath: "example.com/c"},
+		{importPath: "example.com/d"},
+	}
+
+	var (
+		mu            sync.Mutex
+		inFlight, max int
+	)
+	crawl := func(ctx context.Context, item sweepItem) sweepOutcome {
+		mu.Lock()
+		inFlight++
+		if inFlight > max {
+			max = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		switch item.importPath {
+		case "example.com/c":
+			return sweepFailed
+		case "example.com/d":
+			return sweepSkipped
+		}
+		return sweepRefreshed
+	}
+
+	sw.begin()
+	sw.run(context.Background(), items, crawl)
+	sw.end()
+
+	if max > sw.concurrency {
+		t.Errorf("%d crawls in flight, want at most %d", max, sw.concurrency)
+	}
+
+	stats := sw.snapshot()
+	if stats.Cycles != 1 || stats.Current != nil || stats.Last == nil {
+		t.Fatalf("stats = %+v, want one finished cycle", stats)
+	}
+	if c := stats.Last; c.Checked != 8 || c.Refreshed != 6 || c.Failed != 1 || c.Skipped != 1 {
+		t.Errorf("last cycle = %+v, want 8 checked, 6 refreshed, 1 failed and 1 skipped", c)
+	}
+}
+
+func TestSweepHost(t *testing.T) {
+	for path, want := range map[string]string{
+		"github.com/user/repo/sub": "github.com",
+		"example.com":              "example.com",
+		"net/http":                 "net",
+	} {
+		if got := sweepHost(path); got != want {
+			t.Errorf("sweepHost(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"net/http"
+	"strings"
+
+	"github.com/golang/gddo/doc"
+)
+
+// apiSymbol is an exported symbol of a package served by the symbols API.
+// Methods are named T.M after their receiver type.
+type apiSymbol struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Signature string `json:"signature"`
+}
+
+// apiSymbols returns the exported constants, variables, functions, types
+// and methods of pdoc in the order of the documentation.
+func apiSymbols(pdoc *doc.Package) []apiSymbol {
+	symbols := []apiSymbol{}
+	for _, v := range pdoc.Consts {
+		symbols = append(symbols, valueSymbols("const", v)...)
+	}
+	for _, v := range pdoc.Vars {
+		symbols = append(symbols, valueSymbols("var", v)...)
+	}
+	for _, f := range pdoc.Funcs {
+		symbols = append(symbols, apiSymbol{Name: f.Name, Kind: "func", Signature: f.Decl.Text})
+	}
+	for _, t := range pdoc.Types {
+		symbols = append(symbols, apiSymbol{Name: t.Name, Kind: "type", Signature: t.Decl.Text})
+		for _, v := range t.Consts {
+			symbols = append(symbols, valueSymbols("const", v)...)
+		}
+		for _, v := range t.Vars {
+			symbols = append(symbols, valueSymbols("var", v)...)
+		}
+		for _, f := range t.Funcs {
+			symbols = append(symbols, apiSymbol{Name: f.Name, Kind: "func", Signature: f.Decl.Text})
+		}
+		for _, m := range t.Methods {
+			symbols = append(symbols, apiSymbol{Name: t.Name + "." + m.Name, Kind: "method", Signature: m.Decl.Text})
+		}
+	}
+	return symbols
+}
+
+// valueSymbols returns a symbol for each exported name declared by the const
+// or var declaration v. The signature of a name is its specification in the
+// declaration, such as "const A T = 1".
+func valueSymbols(kind string, v *doc.Value) []apiSymbol {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", "package p\n"+v.Decl.Text, 0)
+	if err != nil {
+		return nil
+	}
+	var symbols []apiSymbol
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			spec, ok := spec.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+			var buf bytes.Buffer
+			if err := printer.Fprint(&buf, fset, spec); err != nil {
+				continue
+			}
+			sig := kind + " " + strings.Join(strings.Fields(buf.String()), " ")
+			for _, n := range spec.Names {
+				if ast.IsExported(n.Name) {
+					symbols = append(symbols, apiSymbol{Name: n.Name, Kind: kind, Signature: sig})
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+// serveAPISymbols serves the exported symbols of the package with the import
+// path following /symbols/ as JSON.
+func (s *server) serveAPISymbols(resp http.ResponseWriter, req *http.Request) error {
+	importPath := strings.TrimPrefix(req.URL.Path, "/symbols/")
+	pdoc, _, err := s.getDoc(req.Context(), importPath, robotRequest)
+	if err != nil {
+		return err
+	}
+	if pdoc == nil || pdoc.Name == "" {
+		return &httpError{status: http.StatusNotFound}
+	}
+	data := struct {
+		Path    string      `json:"path"`
+		Name    string      `json:"name"`
+		Symbols []apiSymbol `json:"symbols"`
+	}{
+		pdoc.ImportPath,
+		pdoc.Name,
+		apiSymbols(pdoc),
+	}
+	resp.Header().Set("Content-Type", jsonMIMEType)
+	return json.NewEncoder(resp).Encode(&data)
+}
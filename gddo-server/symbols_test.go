@@ -0,0 +1,41 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/golang/gddo/doc"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAPISymbols(t *testing.T) {
+	pdoc := &doc.Package{
+		Consts: []*doc.Value{{Decl: doc.Code{Text: "const (\n\tA T = iota\n\tB\n\tc\n)"}}},
+		Vars:   []*doc.Value{{Decl: doc.Code{Text: "var X, Y = 1, \"y\""}}},
+		Funcs:  []*doc.Func{{Name: "F", Decl: doc.Code{Text: "func F(x int) error"}}},
+		Types: []*doc.Type{{
+			Name:    "T",
+			Decl:    doc.Code{Text: "type T int"},
+			Funcs:   []*doc.Func{{Name: "NewT", Decl: doc.Code{Text: "func NewT() T"}}},
+			Methods: []*doc.Func{{Name: "String", Decl: doc.Code{Text: "func (t T) String() string"}}},
+		}},
+	}
+	want := []apiSymbol{
+		{Name: "A", Kind: "const", Signature: "const A T = iota"},
+		{Name: "B", Kind: "const", Signature: "const B"},
+		{Name: "X", Kind: "var", Signature: "var X, Y = 1, \"y\""},
+		{Name: "Y", Kind: "var", Signature: "var X, Y = 1, \"y\""},
+		{Name: "F", Kind: "func", Signature: "func F(x int) error"},
+		{Name: "T", Kind: "type", Signature: "type T int"},
+		{Name: "NewT", Kind: "func", Signature: "func NewT() T"},
+		{Name: "T.String", Kind: "method", Signature: "func (t T) String() string"},
+	}
+	if diff := cmp.Diff(want, apiSymbols(pdoc)); diff != "" {
+		t.Errorf("apiSymbols() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"encoding/json"
+	godoc "go/doc"
+	"net/http"
+	"strings"
+
+	"github.com/golang/gddo/doc"
+)
+
+const (
+	// maxTipLines is the number of lines of a declaration above which the
+	// declaration is truncated in a tooltip.
+	maxTipLines = 12
+
+	// maxTipSynopsis is the length above which the synopsis of a symbol is
+	// truncated in a tooltip.
+	maxTipSynopsis = 200
+)
+
+// symbolTip is the brief documentation of a symbol shown in a tooltip when
+// hovering over a reference to the symbol in a declaration of another
+// package.
+type symbolTip struct {
+	Path      string `json:"path"`
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Signature string `json:"signature"`
+	Synopsis  string `json:"synopsis"`
+}
+
+// findSymbolTip returns the tooltip of the exported symbol of pdoc with the
+// given name, named T.M for the methods and fields of the type T as in the
+// fragments of the links to the symbol.
+func findSymbolTip(pdoc *doc.Package, name string) (symbolTip, bool) {
+	tip := func(kind, sig, text string) (symbolTip, bool) {
+		return symbolTip{
+			Path:      pdoc.ImportPath,
+			Name:      name,
+			Kind:      kind,
+			Signature: truncateLines(sig, maxTipLines),
+			Synopsis:  doc.TruncateSynopsis(godoc.Synopsis(text), maxTipSynopsis),
+		}, true
+	}
+	findValue := func(kind string, values []*doc.Value) (symbolTip, bool) {
+		for _, v := range values {
+			for _, sym := range valueSymbols(kind, v) {
+				if sym.Name == name {
+					return tip(kind, sym.Signature, v.Doc)
+				}
+			}
+		}
+		return symbolTip{}, false
+	}
+	findFunc := func(prefix string, funcs []*doc.Func) (symbolTip, bool) {
+		for _, f := range funcs {
+			if prefix+f.Name == name {
+				kind := "func"
+				if prefix != "" {
+					kind = "method"
+				}
+				return tip(kind, f.Decl.Text, f.Doc)
+			}
+		}
+		return symbolTip{}, false
+	}
+
+	if t, ok := findValue("const", pdoc.Consts); ok {
+		return t, true
+	}
+	if t, ok := findValue("var", pdoc.Vars); ok {
+		return t, true
+	}
+	if t, ok := findFunc("", pdoc.Funcs); ok {
+		return t, true
+	}
+	for _, typ := range pdoc.Types {
+		if typ.Name == name {
+			return tip("type", typ.Decl.Text, typ.Doc)
+		}
+		if t, ok := findValue("const", typ.Consts); ok {
+			return t, true
+		}
+		if t, ok := findValue("var", typ.Vars); ok {
+			return t, true
+		}
+		if t, ok := findFunc("", typ.Funcs); ok {
+			return t, true
+		}
+		if t, ok := findFunc(typ.Name+".", typ.Methods); ok {
+			return t, true
+		}
+	}
+	return symbolTip{}, false
+}
+
+// truncateLines returns the first n lines of s followed by an ellipsis if s
+// has more than n lines.
+func truncateLines(s string, n int) string {
+	lines := strings.SplitN(s, "\n", n+1)
+	if len(lines) <= n {
+		return s
+	}
+	return strings.Join(lines[:n], "\n") + "\n\t..."
+}
+
+// serveSymbolTip serves the tooltip of the symbol name of the package path
+// as JSON. Only packages in the database are looked up so that hovering
+// over references never starts a crawl.
+func (s *server) serveSymbolTip(resp http.ResponseWriter, req *http.Request) error {
+	path, name := req.Form.Get("path"), req.Form.Get("name")
+	if path == "" || name == "" {
+		return &httpError{status: http.StatusBadRequest}
+	}
+	pdoc, _, err := s.db.GetDoc(req.Context(), path)
+	if err != nil {
+		return err
+	}
+	if pdoc == nil {
+		return &httpError{status: http.StatusNotFound}
+	}
+	tip, ok := findSymbolTip(pdoc, name)
+	if !ok {
+		return &httpError{status: http.StatusNotFound}
+	}
+	resp.Header().Set("Content-Type", jsonMIMEType)
+	return json.NewEncoder(resp).Encode(&tip)
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/golang/gddo/doc"
+)
+
+func TestFindSymbolTip(t *testing.T) {
+	pdoc := &doc.Package{
+		ImportPath: "example.com/p",
+		Consts:     []*doc.Value{{Decl: doc.Code{Text: "const (\n\tA = 1\n\tB = 2\n)"}, Doc: "A and B are constants. They are small."}},
+		Funcs:      []*doc.Func{{Name: "New", Decl: doc.Code{Text: "func New() *T"}, Doc: "New returns a new T."}},
+		Types: []*doc.Type{{
+			Name:    "T",
+			Decl:    doc.Code{Text: "type T struct {" + strings.Repeat("\n\tF int", 20) + "\n}"},
+			Doc:     "T is a thing.\n\nMore about T.",
+			Methods: []*doc.Func{{Name: "Close", Recv: "*T", Decl: doc.Code{Text: "func (t *T) Close() error"}, Doc: "Close closes t."}},
+		}},
+	}
+	for _, tt := range []struct {
+		name string
+		want *symbolTip
+	}{
+		{"B", &symbolTip{Path: "example.com/p", Name: "B", Kind: "const", Signature: "const B = 2", Synopsis: "A and B are constants."}},
+		{"New", &symbolTip{Path: "example.com/p", Name: "New", Kind: "func", Signature: "func New() *T", Synopsis: "New returns a new T."}},
+		{"T", &symbolTip{Path: "example.com/p", Name: "T", Kind: "type", Signature: "type T struct {" + strings.Repeat("\n\tF int", 11) + "\n\t...", Synopsis: "T is a thing."}},
+		{"T.Close", &symbolTip{Path: "example.com/p", Name: "T.Close", Kind: "method", Signature: "func (t *T) Close() error", Synopsis: "Close closes t."}},
+		{"Close", nil},
+		{"Missing", nil},
+	} {
+		got, ok := findSymbolTip(pdoc, tt.name)
+		if tt.want == nil {
+			if ok {
+				t.Errorf("findSymbolTip(%q) = %+v, want not found", tt.name, got)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("findSymbolTip(%q) not found", tt.name)
+			continue
+		}
+		if diff := cmp.Diff(*tt.want, got); diff != "" {
+			t.Errorf("findSymbolTip(%q) mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
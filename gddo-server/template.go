@@ -11,7 +11,8 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
-	godoc "go/doc"
+	"go/ast"
+	"go/doc/comment"
 	htemp "html/template"
 	"io"
 	"net/http"
@@ -80,32 +81,59 @@ type tdoc struct {
 	*doc.Package
 	allExamples    []*texample
 	sourcegraphURL string
+	proxySource    bool
+	playAll        bool
+
+	// Names of the declarations of the package, computed by Comment.
+	syms map[string]bool
 }
 
 type texample struct {
-	ID      string
-	Label   string
-	Example *doc.Example
-	Play    bool
-	obj     interface{}
+	ID        string
+	Label     string
+	Example   *doc.Example
+	Play      bool
+	SourceURL string // URL of the test file declaring the example, if any
+	obj       interface{}
 }
 
 func newTDoc(v *viper.Viper, pdoc *doc.Package) *tdoc {
 	return &tdoc{
 		Package:        pdoc,
 		sourcegraphURL: v.GetString(ConfigSourcegraphURL),
+		proxySource:    v.GetBool(ConfigProxySource),
+		playAll:        v.GetBool(ConfigPlayAll),
+	}
+}
+
+// FileURL returns the URL for viewing the source of file f, or "" if the
+// source cannot be viewed.
+func (pdoc *tdoc) FileURL(f *doc.File) string {
+	if f.URL == "" || !pdoc.proxySource {
+		return f.URL
 	}
+	return "/-/source?" + url.Values{"path": {pdoc.ImportPath}, "file": {f.Name}}.Encode()
 }
 
 func (pdoc *tdoc) SourceLink(pos doc.Pos, text string, textOnlyOK bool) htemp.HTML {
-	if pos.Line == 0 || pdoc.LineFmt == "" || pdoc.Files[pos.File].URL == "" {
+	var u string
+	if pos.Line != 0 {
+		switch f := pdoc.Files[pos.File]; {
+		case f.URL == "":
+		case pdoc.proxySource:
+			u = fmt.Sprintf("%s#L%d", pdoc.FileURL(f), pos.Line)
+		case pdoc.LineFmt != "":
+			u = fmt.Sprintf(pdoc.LineFmt, f.URL, pos.Line)
+		}
+	}
+	if u == "" {
 		if textOnlyOK {
 			return htemp.HTML(htemp.HTMLEscapeString(text))
 		}
 		return ""
 	}
 	return htemp.HTML(fmt.Sprintf(`<a title="View Source" href="%s">%s</a>`,
-		htemp.HTMLEscapeString(fmt.Sprintf(pdoc.LineFmt, pdoc.Files[pos.File].URL, pos.Line)),
+		htemp.HTMLEscapeString(u),
 		htemp.HTMLEscapeString(text)))
 }
 
@@ -152,6 +180,90 @@ func (pdoc *tdoc) UsesLink(title string, defParts ...string) htemp.HTML {
 	return htemp.HTML(fmt.Sprintf(`<a class="uses" title="%s" href="%s">Uses</a>`, htemp.HTMLEscapeString(title), htemp.HTMLEscapeString(u)))
 }
 
+// Comment formats the comment v of a declaration of the package as HTML. The
+// documentation links to the declarations of the package, such as [Name] and
+// [Type.Method], and to the imported packages are linked.
+func (pdoc *tdoc) Comment(v string) htemp.HTML {
+	return renderComment(pdoc.commentParser().Parse(v))
+}
+
+// Markdown formats the comment v of a declaration of the package as Markdown
+// with the documentation links resolved as by Comment. Links to other
+// packages are absolute URLs of the pages of this server at baseURL.
+func (pdoc *tdoc) Markdown(v, baseURL string) string {
+	return renderMarkdown(pdoc.commentParser().Parse(v), baseURL)
+}
+
+// commentParser returns a parser of the comments of the package that
+// resolves documentation links to its declarations and imported packages.
+func (pdoc *tdoc) commentParser() *comment.Parser {
+	if pdoc.syms == nil {
+		pdoc.syms = packageSymbols(pdoc.Package)
+	}
+	return &comment.Parser{
+		LookupPackage: func(name string) (string, bool) {
+			for _, imp := range pdoc.Imports {
+				if packageNameOfPath(imp) == name {
+					return imp, true
+				}
+			}
+			return "", false
+		},
+		LookupSym: func(recv, name string) bool {
+			if recv != "" {
+				name = recv + "." + name
+			}
+			return pdoc.syms[name]
+		},
+	}
+}
+
+// packageSymbols returns the names of the functions and types of pdoc, with
+// methods named Type.Method. These declarations have anchors on the package
+// page.
+func packageSymbols(pdoc *doc.Package) map[string]bool {
+	syms := make(map[string]bool)
+	for _, f := range pdoc.Funcs {
+		syms[f.Name] = true
+	}
+	for _, t := range pdoc.Types {
+		syms[t.Name] = true
+		for _, f := range t.Funcs {
+			syms[f.Name] = true
+		}
+		for _, m := range t.Methods {
+			syms[t.Name+"."+m.Name] = true
+		}
+	}
+	return syms
+}
+
+// packageNameOfPath returns the package name conventionally used for the
+// package with the import path p: the last element of the path without a
+// major version suffix, such as yaml for gopkg.in/yaml.v2.
+func packageNameOfPath(p string) string {
+	name := path.Base(p)
+	if isMajorVersion(name) {
+		name = path.Base(path.Dir(p))
+	}
+	if i := strings.Index(name, ".v"); i > 0 && isMajorVersion(name[i+1:]) {
+		name = name[:i]
+	}
+	return strings.TrimPrefix(name, "go-")
+}
+
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	for _, c := range s[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 func (pdoc *tdoc) PageName() string {
 	if pdoc.Name != "" && !pdoc.IsCmd {
 		return pdoc.Name
@@ -173,8 +285,9 @@ func (pdoc *tdoc) addExamples(obj interface{}, export, method string, examples [
 			ID:      id,
 			Example: e,
 			obj:     obj,
-			// Only show play links for packages within the standard library.
-			Play: e.Play != "" && gosrc.IsGoRepoPath(pdoc.ImportPath),
+			// Only show play links for packages within the standard library
+			// unless configured otherwise.
+			Play: e.Play != "" && (pdoc.playAll || gosrc.IsGoRepoPath(pdoc.ImportPath)),
 		}
 		if e.Name != "" {
 			te.Label += " (" + e.Name + ")"
@@ -183,10 +296,35 @@ func (pdoc *tdoc) addExamples(obj interface{}, export, method string, examples [
 			}
 			te.ID += "-" + e.Name
 		}
+		te.SourceURL = pdoc.exampleSourceURL(te.ID, e)
 		pdoc.allExamples = append(pdoc.allExamples, te)
 	}
 }
 
+// exampleSourceURL returns the URL for viewing the test file declaring the
+// example e with the given ID, or "" if the source cannot be viewed. The file
+// is served by this server only if ConfigProxySource is set.
+func (pdoc *tdoc) exampleSourceURL(id string, e *doc.Example) string {
+	if e.File == "" {
+		return ""
+	}
+	if pdoc.proxySource {
+		return fmt.Sprintf("?example=%s#L%d", id, e.Line)
+	}
+	for _, files := range [][]*doc.File{pdoc.TestFiles, pdoc.Files} {
+		for _, f := range files {
+			if f.Name != e.File || f.URL == "" {
+				continue
+			}
+			if pdoc.LineFmt != "" {
+				return fmt.Sprintf(pdoc.LineFmt, f.URL, e.Line)
+			}
+			return f.URL
+		}
+	}
+	return ""
+}
+
 type byExampleID []*texample
 
 func (e byExampleID) Len() int           { return len(e) }
@@ -336,7 +474,7 @@ func importPathFn(path string) htemp.HTML {
 }
 
 var (
-	h3Pat      = regexp.MustCompile(`<h3 id="([^"]+)">([^<]+)</h3>`)
+	h4Pat      = regexp.MustCompile(`<h4 id="([^"]+)">([^<]+)</h4>`)
 	rfcPat     = regexp.MustCompile(`RFC\s+(\d{3,4})(,?\s+[Ss]ection\s+(\d+(\.\d+)*))?`)
 	packagePat = regexp.MustCompile(`\s+package\s+([-a-z0-9]\S+)`)
 )
@@ -358,16 +496,28 @@ func replaceAll(src []byte, re *regexp.Regexp, replace func(out, src []byte, m [
 	return append(out, src...)
 }
 
-// commentFn formats a source code comment as HTML.
+// commentFn formats a source code comment as HTML. Only the documentation
+// links to standard packages, such as [io.Reader], are linked. Use the
+// Comment method of tdoc for the comments of a package.
 func commentFn(v string) htemp.HTML {
-	var buf bytes.Buffer
-	godoc.ToHTML(&buf, v, nil)
-	p := buf.Bytes()
-	p = replaceAll(p, h3Pat, func(out, src []byte, m []int) []byte {
-		out = append(out, `<h4 id="`...)
-		out = append(out, src[m[2]:m[3]]...)
-		out = append(out, `">`...)
-		out = append(out, src[m[4]:m[5]]...)
+	return renderComment(new(comment.Parser).Parse(v))
+}
+
+// commentPrinter prints the headings of comments below the h3 section
+// headers of the pages and links the documentation links to the pages of
+// this server.
+var commentPrinter = &comment.Printer{
+	HeadingLevel: 4,
+	DocLinkURL: func(link *comment.DocLink) string {
+		return link.DefaultURL("")
+	},
+}
+
+// renderComment formats a parsed comment as HTML.
+func renderComment(d *comment.Doc) htemp.HTML {
+	p := commentPrinter.HTML(d)
+	p = replaceAll(p, h4Pat, func(out, src []byte, m []int) []byte {
+		out = append(out, src[m[0]:m[1]-len("</h4>")]...)
 		out = append(out, ` <a class="permalink" href="#`...)
 		out = append(out, src[m[2]:m[3]]...)
 		out = append(out, `">&para</a></h4>`...)
@@ -408,10 +558,32 @@ func commentFn(v string) htemp.HTML {
 // commentTextFn formats a source code comment as text.
 func commentTextFn(v string) string {
 	const indent = "    "
-	var buf bytes.Buffer
-	godoc.ToText(&buf, v, indent, "\t", 80-2*len(indent))
-	p := buf.Bytes()
-	return string(p)
+	pr := &comment.Printer{
+		TextPrefix:     indent,
+		TextCodePrefix: "\t",
+		TextWidth:      80 - 2*len(indent),
+	}
+	return string(pr.Text(new(comment.Parser).Parse(v)))
+}
+
+// renderMarkdown formats a parsed comment as Markdown. The headings of the
+// comment are below the level 3 headings of the declarations and the
+// documentation links to other packages are absolute URLs of the pages of
+// this server at baseURL.
+func renderMarkdown(d *comment.Doc, baseURL string) string {
+	pr := &comment.Printer{
+		HeadingLevel: 4,
+		DocLinkURL: func(link *comment.DocLink) string {
+			return link.DefaultURL(baseURL)
+		},
+	}
+	return string(pr.Markdown(d))
+}
+
+// tableCellFn formats s as the text of a Markdown table cell.
+func tableCellFn(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.Replace(s, "|", `\|`, -1)
 }
 
 var period = []byte{'.'}
@@ -474,6 +646,14 @@ func isInterfaceFn(t *doc.Type) bool {
 	return isInterfacePat.MatchString(t.Decl.Text)
 }
 
+// longDeclLines is the number of lines above which type declarations are
+// initially collapsed on the package page.
+const longDeclLines = 20
+
+func isLongDeclFn(t *doc.Type) bool {
+	return strings.Count(t.Decl.Text, "\n") >= longDeclLines
+}
+
 func noteTitleFn(s string) string {
 	return strings.Title(strings.ToLower(s))
 }
@@ -485,6 +665,7 @@ func htmlCommentFn(s string) htemp.HTML {
 var mimeTypes = map[string]string{
 	".html": htmlMIMEType,
 	".txt":  textMIMEType,
+	".md":   markdownMIMEType,
 }
 
 type templateMap map[string]interface {
@@ -492,6 +673,38 @@ type templateMap map[string]interface {
 }
 
 func (m templateMap) execute(resp http.ResponseWriter, name string, status int, header http.Header, data interface{}) error {
+	t := m[name]
+	if t == nil {
+		return fmt.Errorf("template %s not found", name)
+	}
+	writeTemplateHeader(resp, name, status, header)
+	if status == http.StatusNotModified {
+		return nil
+	}
+	return t.Execute(resp, data)
+}
+
+// render returns the output of the template name executed with data.
+func (m templateMap) render(name string, data interface{}) ([]byte, error) {
+	t := m[name]
+	if t == nil {
+		return nil, fmt.Errorf("template %s not found", name)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// write writes body previously rendered from the template name.
+func (m templateMap) write(resp http.ResponseWriter, name string, status int, header http.Header, body []byte) error {
+	writeTemplateHeader(resp, name, status, header)
+	_, err := resp.Write(body)
+	return err
+}
+
+func writeTemplateHeader(resp http.ResponseWriter, name string, status int, header http.Header) {
 	for k, v := range header {
 		resp.Header()[k] = v
 	}
@@ -500,15 +713,7 @@ func (m templateMap) execute(resp http.ResponseWriter, name string, status int,
 		mimeType = textMIMEType
 	}
 	resp.Header().Set("Content-Type", mimeType)
-	t := m[name]
-	if t == nil {
-		return fmt.Errorf("template %s not found", name)
-	}
 	resp.WriteHeader(status)
-	if status == http.StatusNotModified {
-		return nil
-	}
-	return t.Execute(resp, data)
 }
 
 func joinTemplateDir(base string, files []string) []string {
@@ -525,13 +730,16 @@ const pkgGoDevLinkTmpl = `
 {{end}}
 `
 
-func parseTemplates(dir string, cb *httputil.CacheBusters, v *viper.Viper) (templateMap, error) {
+func parseTemplates(dir string, cb *httputil.CacheBusters, v *viper.Viper, notices *noticeSource) (templateMap, error) {
 	m := make(templateMap)
 	htmlSets := [][]string{
 		{"about.html", "common.html", "layout.html"},
 		{"bot.html", "common.html", "layout.html"},
+		{"changelog.html", "common.html", "layout.html"},
 		{"cmd.html", "common.html", "layout.html"},
+		{"compare.html", "common.html", "layout.html"},
 		{"dir.html", "common.html", "layout.html"},
+		{"gone.html", "common.html", "layout.html"},
 		{"home.html", "common.html", "layout.html"},
 		{"importers.html", "common.html", "layout.html"},
 		{"importers_robot.html", "common.html", "layout.html"},
@@ -540,11 +748,16 @@ func parseTemplates(dir string, cb *httputil.CacheBusters, v *viper.Viper) (temp
 		{"pkg.html", "common.html", "layout.html"},
 		{"results.html", "common.html", "layout.html"},
 		{"tools.html", "common.html", "layout.html"},
+		{"unavailable.html", "common.html", "layout.html"},
 		{"std.html", "common.html", "layout.html"},
 		{"subrepo.html", "common.html", "layout.html"},
 		{"graph.html", "common.html"},
+		{"source.html", "common.html", "layout.html"},
 	}
+	brand := newBranding(v)
 	hfuncs := htemp.FuncMap{
+		"branding":          func() *branding { return brand },
+		"cachedOnly":        func() bool { return v.GetBool(ConfigCachedOnly) },
 		"code":              codeFn,
 		"comment":           commentFn,
 		"equal":             reflect.DeepEqual,
@@ -552,13 +765,22 @@ func parseTemplates(dir string, cb *httputil.CacheBusters, v *viper.Viper) (temp
 		"host":              hostFn,
 		"htmlComment":       htmlCommentFn,
 		"importPath":        importPathFn,
+		"isExported":        ast.IsExported,
 		"isInterface":       isInterfaceFn,
+		"isLongDecl":        isLongDeclFn,
 		"isValidImportPath": gosrc.IsValidPath,
+		"jsonLD":            newSoftwareSourceCode,
 		"map":               mapFn,
 		"noteTitle":         noteTitleFn,
+		"ownerURL":          ownerURL,
+		"notice":            notices.get,
+		"recencyOptions":    recencyOptions,
 		"relativePath":      relativePathFn,
 		"sidebarEnabled":    func() bool { return v.GetBool(ConfigSidebar) },
-		"staticPath":        func(p string) string { return cb.AppendQueryParam(p, "v") },
+		"siteName":          func() string { return brand.Name },
+		"staticPath":        cb.Fingerprint,
+		"symbolTips":        func() bool { return v.GetBool(ConfigSymbolTips) },
+		"viewCount":         func() bool { return v.GetBool(ConfigViewCount) },
 		"notVendorPath":     func(p string) bool { return !strings.Contains(p, "/vendor") },
 	}
 	for _, set := range htmlSets {
@@ -584,13 +806,20 @@ func parseTemplates(dir string, cb *httputil.CacheBusters, v *viper.Viper) (temp
 	textSets := [][]string{
 		{"cmd.txt", "common.txt"},
 		{"dir.txt", "common.txt"},
+		{"gone.txt", "common.txt"},
 		{"home.txt", "common.txt"},
 		{"notfound.txt", "common.txt"},
 		{"pkg.txt", "common.txt"},
 		{"results.txt", "common.txt"},
+		{"unavailable.txt", "common.txt"},
+		{"cmd.md", "common.md"},
+		{"pkg.md", "common.md"},
 	}
 	tfuncs := ttemp.FuncMap{
-		"comment": commentTextFn,
+		"base":      path.Base,
+		"comment":   commentTextFn,
+		"map":       mapFn,
+		"tableCell": tableCellFn,
 	}
 	for _, set := range textSets {
 		t := ttemp.New("").Funcs(tfuncs)
@@ -7,12 +7,20 @@
 package main
 
 import (
+	"flag"
+	"html/template"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/viper"
+
+	"github.com/golang/gddo/database"
+	"github.com/golang/gddo/doc"
+	"github.com/golang/gddo/httputil"
 )
 
 func TestFlashMessages(t *testing.T) {
@@ -32,3 +40,154 @@ func TestFlashMessages(t *testing.T) {
 		t.Errorf("got messages %+v, want %+v", actualMessages, expectedMessages)
 	}
 }
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestComment(t *testing.T) {
+	src, err := ioutil.ReadFile("testdata/comment.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdoc := &tdoc{Package: &doc.Package{
+		ImportPath: "example.com/p",
+		Imports:    []string{"gopkg.in/yaml.v2", "io"},
+		Funcs:      []*doc.Func{{Name: "Open"}},
+		Types: []*doc.Type{{
+			Name:    "Reader",
+			Methods: []*doc.Func{{Name: "Read"}},
+		}},
+	}}
+	got := string(pdoc.Comment(string(src)))
+
+	const golden = "testdata/comment.golden"
+	if *update {
+		if err := ioutil.WriteFile(golden, []byte(got), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("comment of testdata/comment.txt:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPackageNameOfPath(t *testing.T) {
+	for p, want := range map[string]string{
+		"io":                          "io",
+		"net/http":                    "http",
+		"gopkg.in/yaml.v2":            "yaml",
+		"github.com/alice/pkg/v3":     "pkg",
+		"github.com/alice/go-sqlite3": "sqlite3",
+	} {
+		if got := packageNameOfPath(p); got != want {
+			t.Errorf("packageNameOfPath(%q) = %q, want %q", p, got, want)
+		}
+	}
+}
+
+func TestPackageMarkdown(t *testing.T) {
+	templates, err := parseTemplates("assets", &httputil.CacheBusters{}, viper.New(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdoc := &doc.Package{
+		ImportPath: "example.com/p",
+		Name:       "p",
+		Doc:        "Package p reads [io.Reader] values.\n\n# Usage\n\nCall [Open].",
+		Funcs: []*doc.Func{{
+			Name:     "Open",
+			Decl:     doc.Code{Text: "func Open(name string) (*File, error)"},
+			Doc:      "Open opens a file.",
+			Examples: []*doc.Example{{Code: doc.Code{Text: "f, _ := p.Open(\"x\")\nfmt.Println(f)"}, Output: "x\n"}},
+		}},
+		Types: []*doc.Type{{
+			Name:    "File",
+			Decl:    doc.Code{Text: "type File struct {\n\tName string `json:\"name\"`\n}"},
+			Methods: []*doc.Func{{Name: "Close", Recv: "*File", Decl: doc.Code{Text: "func (f *File) Close() error"}}},
+		}},
+	}
+	body, err := templates.render("pkg.md", map[string]interface{}{
+		"pdoc":    newTDoc(viper.New(), pdoc),
+		"pkgs":    []database.Package{{Path: "example.com/p/sub", Synopsis: "Package sub | does things."}},
+		"baseURL": "https://godoc.example",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(body)
+	for _, want := range []string{
+		"# package p\n",
+		"Package p reads [io.Reader](https://godoc.example/io#Reader) values.",
+		"#### Usage",
+		"Call [Open](#Open).",
+		"### func Open\n\n```go\nfunc Open(name string) (*File, error)\n```\n",
+		"Expected output:\n\n```\nx\n```\n",
+		"### type File\n",
+		"#### func (*File) Close\n",
+		"| [example.com/p/sub](https://godoc.example/example.com/p/sub) | Package sub \\| does things. |",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("pkg.md does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestDeprecationBanner(t *testing.T) {
+	templates, err := parseTemplates("assets", &httputil.CacheBusters{}, viper.New(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdoc := &doc.Package{
+		ImportPath: "example.com/m",
+		Name:       "m",
+		Deprecated: "use example.com/m/v2 instead.",
+	}
+	var buf strings.Builder
+	err = templates["pkg.html"].(*template.Template).ExecuteTemplate(&buf, "Deprecation", map[string]interface{}{
+		"pdoc":       newTDoc(viper.New(), pdoc),
+		"retraction": &doc.Retraction{Low: "v1.1.0", High: "v1.1.3", Rationale: "Data race in Close."},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<strong>This module is deprecated:</strong> use example.com/m/v2 instead.",
+		"<strong>This version is retracted</strong> (versions v1.1.0 to v1.1.3) by the module author: Data race in Close.",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Deprecation does not contain %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestExampleSourceURL(t *testing.T) {
+	pdoc := &doc.Package{
+		ImportPath: "example.com/a",
+		LineFmt:    "%s#L%d",
+		TestFiles:  []*doc.File{{Name: "a_test.go", URL: "https://example.com/a/a_test.go"}},
+		Examples: []*doc.Example{
+			{Name: "x", File: "a_test.go", Line: 12},
+			{Name: "y"},
+		},
+	}
+	for _, tt := range []struct {
+		proxySource bool
+		want        []string
+	}{
+		{false, []string{"https://example.com/a/a_test.go#L12", ""}},
+		{true, []string{"?example=package--x#L12", ""}},
+	} {
+		v := viper.New()
+		v.Set(ConfigProxySource, tt.proxySource)
+		var got []string
+		for _, e := range newTDoc(v, pdoc).AllExamples() {
+			got = append(got, e.SourceURL)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("proxy source %v: source URLs mismatch (-want +got):\n%s", tt.proxySource, diff)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/golang/gddo/database"
+	"github.com/golang/gddo/doc"
+)
+
+// treeNode is a directory in the directory tree of a repository shown in the
+// package page sidebar.
+type treeNode struct {
+	Name     string // last element of the path
+	Path     string // import path
+	Package  bool   // directory of a package in the database
+	Current  bool   // directory of the page
+	Open     bool   // ancestor of the directory of the page
+	Children []*treeNode
+}
+
+// packageTree returns the top level directories of the directory tree of
+// the repository of pdoc, given the directories dirs of the repository as
+// returned by database.ProjectTree. Directories are packages if they are in
+// project, the packages of the project.
+func packageTree(pdoc *doc.Package, dirs []string, project []database.Package) []*treeNode {
+	if pdoc.ProjectRoot == "" || len(dirs) == 0 {
+		return nil
+	}
+	packages := make(map[string]bool, len(project))
+	for _, pkg := range project {
+		packages[pkg.Path] = true
+	}
+
+	root := &treeNode{}
+	nodes := map[string]*treeNode{"": root}
+	// The paths are sorted, so parents precede their children.
+	for _, p := range dirs {
+		parent := ""
+		name := p
+		if i := strings.LastIndexByte(p, '/'); i >= 0 {
+			parent, name = p[:i], p[i+1:]
+		}
+		pn := nodes[parent]
+		if pn == nil {
+			continue
+		}
+		importPath := pdoc.ProjectRoot + "/" + p
+		n := &treeNode{
+			Name:    name,
+			Path:    importPath,
+			Package: packages[importPath],
+			Current: importPath == pdoc.ImportPath,
+			Open:    strings.HasPrefix(pdoc.ImportPath, importPath+"/"),
+		}
+		pn.Children = append(pn.Children, n)
+		nodes[p] = n
+	}
+	return root.Children
+}
@@ -0,0 +1,44 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/golang/gddo/database"
+	"github.com/golang/gddo/doc"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPackageTree(t *testing.T) {
+	pdoc := &doc.Package{
+		ImportPath:  "github.com/user/repo/a/b",
+		ProjectRoot: "github.com/user/repo",
+	}
+	dirs := []string{"a", "a/b", "a/b/c", "docs", "x/y"}
+	project := []database.Package{
+		{Path: "github.com/user/repo"},
+		{Path: "github.com/user/repo/a/b"},
+		{Path: "github.com/user/repo/a/b/c"},
+	}
+	want := []*treeNode{
+		{Name: "a", Path: "github.com/user/repo/a", Open: true, Children: []*treeNode{
+			{Name: "b", Path: "github.com/user/repo/a/b", Package: true, Current: true, Children: []*treeNode{
+				{Name: "c", Path: "github.com/user/repo/a/b/c", Package: true},
+			}},
+		}},
+		{Name: "docs", Path: "github.com/user/repo/docs"},
+	}
+	if diff := cmp.Diff(want, packageTree(pdoc, dirs, project)); diff != "" {
+		t.Errorf("packageTree mismatch (-want +got):\n%s", diff)
+	}
+
+	pdoc.ProjectRoot = ""
+	if got := packageTree(pdoc, dirs, project); got != nil {
+		t.Errorf("packageTree of standard package = %v, want nil", got)
+	}
+}
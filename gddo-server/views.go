@@ -0,0 +1,72 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	viewsCookie = "views"
+
+	// maxViewsPerDay is the number of packages remembered in the cookie to
+	// count a single view per package and day for a browser.
+	maxViewsPerDay = 64
+
+	viewsDayFormat = "20060102"
+)
+
+// viewKey returns the key of importPath in the views cookie. The key is a
+// short hash so that the cookie does not record the viewed packages.
+func viewKey(importPath string) string {
+	h := sha1.Sum([]byte(importPath))
+	return hex.EncodeToString(h[:4])
+}
+
+// countView reports whether the view of the page of importPath by the
+// browser making the request is counted. A view is counted once per package
+// and UTC day. The packages viewed during the day are remembered in a cookie
+// expiring at the end of the day.
+func countView(resp http.ResponseWriter, req *http.Request, importPath string, now time.Time) bool {
+	now = now.UTC()
+	day := now.Format(viewsDayFormat)
+	key := viewKey(importPath)
+
+	var keys []string
+	if c, err := req.Cookie(viewsCookie); err == nil {
+		keys = strings.Split(c.Value, ".")
+		if keys[0] != day {
+			keys = nil
+		} else {
+			keys = keys[1:]
+		}
+	}
+	for _, k := range keys {
+		if k == key {
+			return false
+		}
+	}
+	keys = append(keys, key)
+	if len(keys) > maxViewsPerDay {
+		keys = keys[len(keys)-maxViewsPerDay:]
+	}
+
+	y, m, d := now.Date()
+	http.SetCookie(resp, &http.Cookie{
+		Name:     viewsCookie,
+		Value:    day + "." + strings.Join(keys, "."),
+		Path:     "/",
+		Expires:  time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return true
+}
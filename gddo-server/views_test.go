@@ -0,0 +1,55 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCountView(t *testing.T) {
+	now := time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)
+	cookie := ""
+	view := func(importPath string, now time.Time) bool {
+		req := httptest.NewRequest("GET", "/"+importPath, nil)
+		if cookie != "" {
+			req.Header.Set("Cookie", cookie)
+		}
+		w := httptest.NewRecorder()
+		counted := countView(w, req, importPath, now)
+		if c := w.Header().Get("Set-Cookie"); c != "" {
+			cookie = c
+		}
+		return counted
+	}
+
+	if !view("github.com/user/a", now) {
+		t.Error("first view not counted")
+	}
+	if view("github.com/user/a", now.Add(time.Hour)) {
+		t.Error("second view on the same day counted")
+	}
+	if !view("github.com/user/b", now) {
+		t.Error("view of another package not counted")
+	}
+	if !view("github.com/user/a", now.Add(24*time.Hour)) {
+		t.Error("view on the next day not counted")
+	}
+
+	now = now.Add(24 * time.Hour)
+	for i := 0; i < maxViewsPerDay; i++ {
+		view("github.com/user/p"+strconv.Itoa(i), now)
+	}
+	if len(cookie) > 1024 {
+		t.Errorf("cookie length %d, want at most 1024", len(cookie))
+	}
+	if !view("github.com/user/a", now) {
+		t.Error("view of forgotten package not counted")
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// warmUserAgent is the user agent of the requests rendering the warmed
+// pages. It is matched by robotPat, so that warming does not change the
+// popularity of the packages.
+const warmUserAgent = "gddo-warm-bot/1.0"
+
+// readWarmPaths reads the import paths listed in r, one per line. Blank
+// lines and lines starting with # are ignored.
+func readWarmPaths(r io.Reader) ([]string, error) {
+	var paths []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		paths = append(paths, strings.Trim(line, "/"))
+	}
+	return paths, scanner.Err()
+}
+
+// warmPaths returns the import paths of the packages to warm: the paths
+// listed in the configured file followed by the most popular packages.
+func (s *server) warmPaths() ([]string, error) {
+	var paths []string
+	if name := s.v.GetString(ConfigWarmFile); name != "" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		paths, err = readWarmPaths(f)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if n := s.v.GetInt(ConfigWarmPopular); n > 0 {
+		pkgs, err := s.db.Popular(n)
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range pkgs {
+			paths = append(paths, pkg.Path)
+		}
+	}
+	return dedupePaths(paths), nil
+}
+
+func dedupePaths(paths []string) []string {
+	seen := make(map[string]bool)
+	result := paths[:0]
+	for _, p := range paths {
+		if !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// warmCache crawls the packages in paths that are not in the database or
+// are due to be crawled and renders their pages into the render cache, with
+// at most concurrency packages in progress at a time.
+func (s *server) warmCache(ctx context.Context, paths []string, concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	start := time.Now()
+	log.Printf("Warm: warming %d packages", len(paths))
+
+	var done, failed int64
+	c := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for importPath := range c {
+				if err := s.warmPackage(ctx, importPath); err != nil {
+					log.Printf("Warm %q: %v", importPath, err)
+					atomic.AddInt64(&failed, 1)
+				}
+				if n := atomic.AddInt64(&done, 1); n%100 == 0 {
+					log.Printf("Warm: %d of %d packages done in %v", n, len(paths), time.Since(start))
+				}
+			}
+		}()
+	}
+feed:
+	for _, importPath := range paths {
+		select {
+		case c <- importPath:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(c)
+	wg.Wait()
+	log.Printf("Warm: warmed %d packages (%d errors) in %v", done, failed, time.Since(start))
+}
+
+// warmPackage crawls the package at importPath if needed and renders its
+// page with the documentation returned by the crawl.
+func (s *server) warmPackage(ctx context.Context, importPath string) error {
+	pdoc, pkgs, err := s.getDoc(ctx, importPath, humanRequest)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", "/"+importPath, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", warmUserAgent)
+	return s.serveLoadedPackage(&discardResponseWriter{header: make(http.Header)}, req, &loadedDoc{pdoc, pkgs})
+}
+
+// discardResponseWriter is an http.ResponseWriter discarding the response.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardResponseWriter) WriteHeader(status int)      {}
@@ -0,0 +1,39 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadWarmPaths(t *testing.T) {
+	const list = `# Busiest packages.
+github.com/user/repo
+
+  /golang.org/x/net/html/
+fmt
+`
+	paths, err := readWarmPaths(strings.NewReader(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"github.com/user/repo", "golang.org/x/net/html", "fmt"}
+	if diff := cmp.Diff(want, paths); diff != "" {
+		t.Errorf("readWarmPaths mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDedupePaths(t *testing.T) {
+	got := dedupePaths([]string{"fmt", "net/http", "fmt", "io", "net/http"})
+	want := []string{"fmt", "net/http", "io"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("dedupePaths mismatch (-want +got):\n%s", diff)
+	}
+}
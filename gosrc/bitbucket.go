@@ -12,24 +12,31 @@ import (
 	"net/http"
 	"path"
 	"regexp"
+	"strings"
 	"time"
 )
 
 func init() {
 	addService(&service{
-		pattern: regexp.MustCompile(`^bitbucket\.org/(?P<owner>[a-z0-9A-Z_.\-]+)/(?P<repo>[a-z0-9A-Z_.\-]+)(?P<dir>/[a-z0-9A-Z_.\-/]*)?$`),
-		prefix:  "bitbucket.org/",
-		get:     getBitbucketDir,
+		pattern:   regexp.MustCompile(`^bitbucket\.org/(?P<owner>[a-z0-9A-Z_.\-]+)/(?P<repo>[a-z0-9A-Z_.\-]+)(?P<dir>/[a-z0-9A-Z_.\-/]*)?$`),
+		prefix:    "bitbucket.org/",
+		get:       getBitbucketDir,
+		getSource: getBitbucketSource,
 	})
 }
 
-var bitbucketEtagRe = regexp.MustCompile(`^(hg|git)-`)
-
 type bitbucketRepo struct {
-	Scm       string      `json:"scm"`
-	CreatedOn string      `json:"created_on"`
-	UpdatedOn string      `json:"updated_on"`
-	Parent    interface{} `json:"parent"`
+	Scm        string           `json:"scm"`
+	CreatedOn  string           `json:"created_on"`
+	UpdatedOn  string           `json:"updated_on"`
+	Parent     *bitbucketParent `json:"parent"`
+	MainBranch *struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+}
+
+type bitbucketParent struct {
+	FullName string `json:"full_name"`
 }
 
 type bitbucketRefs struct {
@@ -52,22 +59,23 @@ type bitbucketSrc struct {
 }
 
 type bitbucketPage struct {
-	Next string `json:"next",omitempty`
+	Next string `json:"next,omitempty"`
 }
 
 func getBitbucketDir(ctx context.Context, client *http.Client, match map[string]string, savedEtag string) (*Directory, error) {
-	var repo *bitbucketRepo
 	c := &httpClient{client: client}
 
-	if m := bitbucketEtagRe.FindStringSubmatch(savedEtag); m != nil {
-		match["vcs"] = m[1]
-	} else {
-		repo, err := getBitbucketRepo(ctx, c, match)
-		if err != nil {
-			return nil, err
-		}
+	repo, err := getBitbucketRepo(ctx, c, match)
+	if err != nil {
+		return nil, err
+	}
+	match["vcs"] = repo.Scm
 
-		match["vcs"] = repo.Scm
+	// The default branch is configured per repository. Old repositories
+	// without one use the default of the VCS.
+	defaultTag := defaultTags[match["vcs"]]
+	if repo.MainBranch != nil && repo.MainBranch.Name != "" {
+		defaultTag = repo.MainBranch.Name
 	}
 
 	tags := make(map[string]string)
@@ -94,8 +102,7 @@ func getBitbucketDir(ctx context.Context, client *http.Client, match map[string]
 		url = refs.Next
 	}
 
-	var err error
-	tag, commit, err := bestTag(tags, defaultTags[match["vcs"]])
+	tag, commit, err := bestTag(tags, defaultTag)
 	if err != nil {
 		return nil, err
 	}
@@ -106,18 +113,12 @@ func getBitbucketDir(ctx context.Context, client *http.Client, match map[string]
 		return nil, NotModifiedError{Since: timestamps[tag]}
 	}
 
-	if repo == nil {
-		repo, err = getBitbucketRepo(ctx, c, match)
-		if err != nil {
-			return nil, err
-		}
-	}
-
 	var dirs []string
 	var files []*File
 	var dataURLs []string
+	var asmFiles []string
 
-	url = expand("https://api.bitbucket.org/2.0/repositories/{owner}/{repo}/src/{tag}{dir}/?pagelen=100", match)
+	url = expand("https://api.bitbucket.org/2.0/repositories/{owner}/{repo}/src/{commit}{dir}/?pagelen=100", match)
 	for {
 		var contents bitbucketSrc
 		if _, err := c.getJSON(ctx, url, &contents); err != nil {
@@ -130,10 +131,12 @@ func getBitbucketDir(ctx context.Context, client *http.Client, match map[string]
 				_, name := path.Split(v.Path)
 				if isDocFile(name) {
 					files = append(files, &File{Name: name, BrowseURL: expand("https://bitbucket.org/{owner}/{repo}/src/{tag}/{0}", match, v.Path)})
-					dataURLs = append(dataURLs, expand("https://api.bitbucket.org/2.0/repositories/{owner}/{repo}/src/{tag}/{0}", match, v.Path))
+					dataURLs = append(dataURLs, expand("https://api.bitbucket.org/2.0/repositories/{owner}/{repo}/src/{commit}/{0}", match, v.Path))
+				} else if isAsmFile(name) {
+					asmFiles = append(asmFiles, name)
 				}
 			case "commit_directory":
-				dirs = append(dirs, v.Path)
+				dirs = append(dirs, path.Base(v.Path))
 			}
 		}
 		if contents.Next == "" {
@@ -151,18 +154,51 @@ func getBitbucketDir(ctx context.Context, client *http.Client, match map[string]
 		status = DeadEndFork
 	}
 
+	var forkOf string
+	if repo.Parent != nil && repo.Parent.FullName != "" {
+		forkOf = "bitbucket.org/" + repo.Parent.FullName
+	}
+
 	return &Directory{
 		BrowseURL:      expand("https://bitbucket.org/{owner}/{repo}/src/{tag}{dir}", match),
 		Etag:           etag,
 		Files:          files,
+		AsmFiles:       asmFiles,
 		LineFmt:        "%s#cl-%d",
 		ProjectName:    match["repo"],
 		ProjectRoot:    expand("bitbucket.org/{owner}/{repo}", match),
 		ProjectURL:     expand("https://bitbucket.org/{owner}/{repo}/", match),
+		RepoURL:        expand("https://bitbucket.org/{owner}/{repo}/", match),
+		RepoDir:        strings.Trim(match["dir"], "/"),
 		Subdirectories: dirs,
 		VCS:            match["vcs"],
 		Status:         status,
 		Fork:           repo.Parent != nil,
+		ForkOf:         forkOf,
+		Committed:      timestamps[tag],
+	}, nil
+}
+
+func getBitbucketSource(ctx context.Context, client *http.Client, match map[string]string) (*Source, error) {
+	c := &httpClient{client: client}
+
+	repo, err := getBitbucketRepo(ctx, c, match)
+	if err != nil {
+		return nil, err
+	}
+	match["vcs"] = repo.Scm
+	match["tag"] = defaultTags[match["vcs"]]
+	if repo.MainBranch != nil && repo.MainBranch.Name != "" {
+		match["tag"] = repo.MainBranch.Name
+	}
+	return &Source{
+		ProjectRoot:   expand("bitbucket.org/{owner}/{repo}", match),
+		VCS:           match["vcs"],
+		Host:          "bitbucket.org",
+		RepoURL:       expand("https://bitbucket.org/{owner}/{repo}/", match),
+		DefaultBranch: match["tag"],
+		DirTemplate:   expand("https://bitbucket.org/{owner}/{repo}/src/{tag}{0}", match, "{/dir}"),
+		FileTemplate:  expand("https://bitbucket.org/{owner}/{repo}/src/{tag}{0}/{1}#cl-{2}", match, "{/dir}", "{file}", "{line}"),
 	}, nil
 }
 
@@ -0,0 +1,85 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Responses of the Bitbucket 2.0 API, trimmed to the fields used.
+var testBitbucket = map[string]string{
+	"https://api.bitbucket.org/2.0/repositories/alice/pkg": `{
+		"scm": "git",
+		"created_on": "2019-03-01T10:12:41.123456+00:00",
+		"updated_on": "2021-05-14T08:01:12.654321+00:00",
+		"full_name": "alice/pkg",
+		"mainbranch": {"type": "branch", "name": "main"}
+	}`,
+	"https://api.bitbucket.org/2.0/repositories/alice/pkg/refs": `{
+		"pagelen": 100,
+		"values": [
+			{"name": "main", "type": "branch", "target": {"hash": "0123456789abcdef", "date": "2021-05-14T08:01:10+00:00"}},
+			{"name": "master", "type": "branch", "target": {"hash": "fedcba9876543210", "date": "2019-03-01T10:12:41+00:00"}},
+			{"name": "v1.0.0", "type": "tag", "target": {"hash": "0123456789abcdef", "date": "2021-05-14T08:01:10+00:00"}}
+		],
+		"page": 1
+	}`,
+	"https://api.bitbucket.org/2.0/repositories/alice/pkg/src/0123456789abcdef/sub/": `{
+		"pagelen": 100,
+		"values": [
+			{"path": "sub/doc.go", "type": "commit_file", "size": 22},
+			{"path": "sub/README.md", "type": "commit_file", "size": 6},
+			{"path": "sub/image.png", "type": "commit_file", "size": 3021},
+			{"path": "sub/internal", "type": "commit_directory"}
+		],
+		"page": 1
+	}`,
+	"https://api.bitbucket.org/2.0/repositories/alice/pkg/src/0123456789abcdef/sub/doc.go":    "package sub\n",
+	"https://api.bitbucket.org/2.0/repositories/alice/pkg/src/0123456789abcdef/sub/README.md": "# sub\n",
+}
+
+func TestGetBitbucketDir(t *testing.T) {
+	client := &http.Client{Transport: testTransport(testBitbucket)}
+	match := map[string]string{"owner": "alice", "repo": "pkg", "dir": "/sub"}
+	dir, err := getBitbucketDir(context.Background(), client, match, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Directory{
+		BrowseURL: "https://bitbucket.org/alice/pkg/src/main/sub",
+		Etag:      "git-0123456789abcdef",
+		Files: []*File{
+			{Name: "doc.go", BrowseURL: "https://bitbucket.org/alice/pkg/src/main/sub/doc.go", Data: []byte("package sub\n")},
+			{Name: "README.md", BrowseURL: "https://bitbucket.org/alice/pkg/src/main/sub/README.md", Data: []byte("# sub\n")},
+		},
+		LineFmt:        "%s#cl-%d",
+		ProjectName:    "pkg",
+		ProjectRoot:    "bitbucket.org/alice/pkg",
+		ProjectURL:     "https://bitbucket.org/alice/pkg/",
+		RepoURL:        "https://bitbucket.org/alice/pkg/",
+		RepoDir:        "sub",
+		Subdirectories: []string{"internal"},
+		VCS:            "git",
+		Status:         Active,
+		Committed:      time.Date(2021, 5, 14, 8, 1, 10, 0, time.UTC),
+	}
+	if diff := cmp.Diff(want, dir); diff != "" {
+		t.Errorf("getBitbucketDir mismatch (-want +got):\n%s", diff)
+	}
+
+	match = map[string]string{"owner": "alice", "repo": "pkg", "dir": "/sub"}
+	if _, err := getBitbucketDir(context.Background(), client, match, "git-0123456789abcdef"); err == nil {
+		t.Error("getBitbucketDir with current etag returned nil error")
+	} else if _, ok := err.(NotModifiedError); !ok {
+		t.Errorf("getBitbucketDir with current etag returned error %v, want NotModifiedError", err)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// excludedPaths holds the patterns set by SetExcludedPaths split into path
+// elements.
+var excludedPaths [][]string
+
+// SetExcludedPaths excludes the import paths matching one of the glob
+// patterns, and the directories and files below them, from Get and
+// GetVersion and from the subdirectories, files and trees of the
+// directories they return. A pattern is a slash separated import path whose
+// elements use the syntax of path.Match, except that the element ** matches
+// any number of elements, as in github.com/org/repo/experimental or
+// **/vendor. An empty list removes all patterns.
+func SetExcludedPaths(patterns []string) error {
+	var excluded [][]string
+	for _, p := range patterns {
+		p = strings.Trim(strings.TrimSpace(p), "/")
+		if p == "" {
+			continue
+		}
+		elems := strings.Split(p, "/")
+		for _, e := range elems {
+			if _, err := path.Match(e, ""); err != nil {
+				return fmt.Errorf("bad exclude pattern %q: %v", p, err)
+			}
+		}
+		excluded = append(excluded, elems)
+	}
+	excludedPaths = excluded
+	return nil
+}
+
+// IsExcludedPath reports whether importPath, or a directory containing it,
+// matches a pattern set by SetExcludedPaths.
+func IsExcludedPath(importPath string) bool {
+	if len(excludedPaths) == 0 {
+		return false
+	}
+	elems := strings.Split(importPath, "/")
+	for _, pattern := range excludedPaths {
+		if matchPathPrefix(pattern, elems) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPathPrefix reports whether the pattern elements match the first
+// elements of a path.
+func matchPathPrefix(pattern, elems []string) bool {
+	if len(pattern) == 0 {
+		return true
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(elems); i++ {
+			if matchPathPrefix(pattern[1:], elems[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(elems) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], elems[0]); !ok {
+		return false
+	}
+	return matchPathPrefix(pattern[1:], elems[1:])
+}
+
+// checkExcludedPath returns a NotFoundError if importPath is excluded by
+// SetExcludedPaths.
+func checkExcludedPath(importPath string) error {
+	if IsExcludedPath(importPath) {
+		return NotFoundError{Message: "excluded."}
+	}
+	return nil
+}
+
+// removeExcluded removes the excluded subdirectories, files and tree
+// directories from dir, the directory of importPath.
+func removeExcluded(dir *Directory, importPath string) {
+	if dir == nil || len(excludedPaths) == 0 {
+		return
+	}
+	subdirs := dir.Subdirectories[:0]
+	for _, d := range dir.Subdirectories {
+		if !IsExcludedPath(importPath + "/" + d) {
+			subdirs = append(subdirs, d)
+		}
+	}
+	dir.Subdirectories = subdirs
+	files := dir.Files[:0]
+	for _, f := range dir.Files {
+		if !IsExcludedPath(importPath + "/" + f.Name) {
+			files = append(files, f)
+		}
+	}
+	dir.Files = files
+	if dir.ProjectRoot != "" {
+		tree := dir.Tree[:0]
+		for _, d := range dir.Tree {
+			if !IsExcludedPath(dir.ProjectRoot + "/" + d) {
+				tree = append(tree, d)
+			}
+		}
+		dir.Tree = tree
+	}
+}
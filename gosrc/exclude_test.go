@@ -0,0 +1,119 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIsExcludedPath(t *testing.T) {
+	defer SetExcludedPaths(nil)
+	if err := SetExcludedPaths([]string{
+		"github.com/acme/repo/experimental",
+		"**/vendor",
+		"example.com/*/internal/gen*/",
+		"example.com/m/**/*_generated.go",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		path string
+		want bool
+	}{
+		{"github.com/acme/repo", false},
+		{"github.com/acme/repo/experimental", true},
+		{"github.com/acme/repo/experimental/sub/pkg", true},
+		{"github.com/acme/repo/experimentalist", false},
+		{"github.com/acme/repo/vendor", true},
+		{"github.com/acme/repo/vendor/golang.org/x/net", true},
+		{"vendor", true},
+		{"github.com/acme/vendored", false},
+		{"example.com/a/internal/gen", true},
+		{"example.com/a/internal/generated/sub", true},
+		{"example.com/a/b/internal/gen", false},
+		{"example.com/a/internal", false},
+		{"example.com/m/x_generated.go", true},
+		{"example.com/m/a/b/x_generated.go", true},
+		{"example.com/m/a/b/x.go", false},
+	} {
+		if got := IsExcludedPath(tt.path); got != tt.want {
+			t.Errorf("IsExcludedPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	if err := SetExcludedPaths([]string{"example.com/[a-"}); err == nil {
+		t.Error("SetExcludedPaths accepted a bad pattern")
+	}
+	if err := SetExcludedPaths(nil); err != nil {
+		t.Fatal(err)
+	}
+	if IsExcludedPath("github.com/acme/repo/vendor") {
+		t.Error("IsExcludedPath = true after removing the patterns")
+	}
+}
+
+func TestGetExcluded(t *testing.T) {
+	root, err := ioutil.TempDir("", "gosrc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	for name, data := range map[string]string{
+		"go.mod":                    "module example.com/m\n",
+		"m.go":                      "package m\n",
+		"m_generated.go":            "package m\n",
+		"api/api.go":                "package api\n",
+		"experimental/x/x.go":       "package x\n",
+		"vendor/example.org/v/v.go": "package v\n",
+	} {
+		name = filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := SetLocalModule(root); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { localModule.root, localModule.path = "", "" }()
+	if err := SetExcludedPaths([]string{"example.com/m/experimental", "**/vendor", "**/*_generated.go"}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetExcludedPaths(nil)
+
+	dir, err := Get(context.Background(), nil, "example.com/m", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, f := range dir.Files {
+		files = append(files, f.Name)
+	}
+	if diff := cmp.Diff([]string{"go.mod", "m.go"}, files); diff != "" {
+		t.Errorf("files mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"api"}, dir.Subdirectories); diff != "" {
+		t.Errorf("subdirectories mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"api"}, dir.Tree); diff != "" {
+		t.Errorf("tree mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, p := range []string{"example.com/m/experimental/x", "example.com/m/vendor/example.org/v"} {
+		if _, err := Get(context.Background(), nil, p, ""); !IsNotFound(err) {
+			t.Errorf("Get(%s) returned error %v, want NotFoundError", p, err)
+		}
+	}
+}
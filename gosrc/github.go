@@ -24,6 +24,9 @@ func init() {
 		get:             getGitHubDir,
 		getPresentation: getGitHubPresentation,
 		getProject:      getGitHubProject,
+		getTags:         getGitHubTags,
+		getCommit:       getGitHubCommit,
+		getSource:       getGitHubSource,
 	})
 
 	addService(&service{
@@ -68,6 +71,9 @@ func getGitHubDir(ctx context.Context, client *http.Client, match map[string]str
 		CreatedAt     time.Time `json:"created_at"`
 		PushedAt      time.Time `json:"pushed_at"`
 		DefaultBranch string    `json:"default_branch"`
+		Parent        *struct {
+			FullName string `json:"full_name"`
+		} `json:"parent"`
 	}
 
 	if _, err := c.getJSON(ctx, expand("https://api.github.com/repos/{owner}/{repo}", match), &repo); err != nil {
@@ -77,8 +83,15 @@ func getGitHubDir(ctx context.Context, client *http.Client, match map[string]str
 	status := Active
 	var commits []*githubCommit
 	u := expand("https://api.github.com/repos/{owner}/{repo}/commits", match)
+	q := url.Values{}
 	if match["dir"] != "" {
-		u += fmt.Sprintf("?path=%s", url.QueryEscape(match["dir"]))
+		q.Set("path", match["dir"])
+	}
+	if match["ref"] != "" {
+		q.Set("sha", match["ref"])
+	}
+	if len(q) > 0 {
+		u += "?" + q.Encode()
 	}
 	if _, err := c.getJSON(ctx, u, &commits); err != nil {
 		return nil, err
@@ -111,7 +124,11 @@ func getGitHubDir(ctx context.Context, client *http.Client, match map[string]str
 		HTMLURL string `json:"html_url"`
 	}
 
-	if _, err := c.getJSON(ctx, expand("https://api.github.com/repos/{owner}/{repo}/contents{dir}", match), &contents); err != nil {
+	u = expand("https://api.github.com/repos/{owner}/{repo}/contents{dir}", match)
+	if match["ref"] != "" {
+		u += "?ref=" + url.QueryEscape(match["ref"])
+	}
+	if _, err := c.getJSON(ctx, u, &contents); err != nil {
 		// The GitHub content API returns array values for directories
 		// and object values for files. If there's a type mismatch at
 		// the beginning of the response, then assume that the path is
@@ -129,6 +146,7 @@ func getGitHubDir(ctx context.Context, client *http.Client, match map[string]str
 	var files []*File
 	var dataURLs []string
 	var subdirs []string
+	var asmFiles []string
 
 	for _, item := range contents {
 		switch {
@@ -139,16 +157,30 @@ func getGitHubDir(ctx context.Context, client *http.Client, match map[string]str
 		case isDocFile(item.Name):
 			files = append(files, &File{Name: item.Name, BrowseURL: item.HTMLURL})
 			dataURLs = append(dataURLs, item.GitURL)
+		case isAsmFile(item.Name):
+			asmFiles = append(asmFiles, item.Name)
 		}
 	}
 
+	// The tree is only shown in the sidebar, so a failure to fetch it does
+	// not fail the directory.
+	tree, _ := getGitHubTree(ctx, c, match, commits[0].ID)
+
 	c.header = gitHubRawHeader
 	if err := c.getFiles(ctx, dataURLs, files); err != nil {
 		return nil, err
 	}
 
+	var forkOf string
+	if repo.Fork && repo.Parent != nil && repo.Parent.FullName != "" {
+		forkOf = "github.com/" + repo.Parent.FullName
+	}
+
 	browseURL := expand("https://github.com/{owner}/{repo}", match)
-	if match["dir"] != "" {
+	if match["ref"] != "" {
+		match["tag"] = match["ref"]
+		browseURL = expand("https://github.com/{owner}/{repo}/tree/{tag}{dir}", match)
+	} else if match["dir"] != "" {
 		match["tag"] = repo.DefaultBranch // TODO: This doesn't respect "go1" tag/branch special case.
 		browseURL = expand("https://github.com/{owner}/{repo}/tree/{tag}{dir}", match)
 	}
@@ -158,18 +190,45 @@ func getGitHubDir(ctx context.Context, client *http.Client, match map[string]str
 		BrowseURL:          browseURL,
 		Etag:               commits[0].ID,
 		Files:              files,
+		AsmFiles:           asmFiles,
 		LineFmt:            "%s#L%d",
 		ProjectName:        match["repo"],
 		ProjectRoot:        expand("github.com/{owner}/{repo}", match),
 		ProjectURL:         expand("https://github.com/{owner}/{repo}", match),
+		RepoURL:            expand("https://github.com/{owner}/{repo}", match),
+		RepoDir:            strings.Trim(match["dir"], "/"),
 		Subdirectories:     subdirs,
+		Tree:               tree,
 		VCS:                "git",
 		Status:             status,
 		Fork:               repo.Fork,
+		ForkOf:             forkOf,
 		Stars:              repo.Stars,
+		Committed:          lastCommitted,
 	}, nil
 }
 
+// getGitHubTree returns the directory tree of the repository at the commit
+// with the given SHA.
+func getGitHubTree(ctx context.Context, c *httpClient, match map[string]string, sha string) ([]string, error) {
+	var tree struct {
+		Tree []struct {
+			Path string
+			Type string
+		}
+	}
+	if _, err := c.getJSON(ctx, expand("https://api.github.com/repos/{owner}/{repo}/git/trees/", match)+sha+"?recursive=1", &tree); err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, item := range tree.Tree {
+		if item.Type == "tree" {
+			paths = append(paths, item.Path)
+		}
+	}
+	return treeDirs(paths), nil
+}
+
 // isQuickFork reports whether the repository is a "quick fork":
 // it has fewer than 3 commits, all within a week of the repo creation, createdAt.
 // Commits must be in reverse chronological order by Commit.Committer.Date.
@@ -191,6 +250,40 @@ func isQuickFork(commits []*githubCommit, createdAt time.Time) bool {
 	return n < 3
 }
 
+func getGitHubTags(ctx context.Context, client *http.Client, match map[string]string) ([]string, error) {
+	c := &httpClient{client: client, errFn: gitHubError}
+
+	var tags []struct {
+		Name string `json:"name"`
+	}
+	if _, err := c.getJSON(ctx, expand("https://api.github.com/repos/{owner}/{repo}/tags?per_page=100", match), &tags); err != nil {
+		return nil, err
+	}
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.Name
+	}
+	return names, nil
+}
+
+func getGitHubCommit(ctx context.Context, client *http.Client, match map[string]string, sha string) (string, error) {
+	c := &httpClient{client: client, errFn: gitHubError}
+
+	var commit githubCommit
+	resp, err := c.getJSON(ctx, expand("https://api.github.com/repos/{owner}/{repo}/commits/", match)+sha, &commit)
+	if resp != nil && resp.StatusCode == http.StatusUnprocessableEntity {
+		// GitHub responds with 422 to unknown and ambiguous SHAs.
+		return "", NotFoundError{Message: "commit " + sha + " not found"}
+	}
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(commit.ID, sha) {
+		return "", NotFoundError{Message: "commit " + sha + " not found"}
+	}
+	return commit.ID, nil
+}
+
 func getGitHubPresentation(ctx context.Context, client *http.Client, match map[string]string) (*Presentation, error) {
 	c := &httpClient{client: client, header: gitHubRawHeader}
 
@@ -297,6 +390,27 @@ func getGitHubProject(ctx context.Context, client *http.Client, match map[string
 	}, nil
 }
 
+func getGitHubSource(ctx context.Context, client *http.Client, match map[string]string) (*Source, error) {
+	c := &httpClient{client: client, errFn: gitHubError}
+
+	var repo struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if _, err := c.getJSON(ctx, expand("https://api.github.com/repos/{owner}/{repo}", match), &repo); err != nil {
+		return nil, err
+	}
+	match["tag"] = repo.DefaultBranch
+	return &Source{
+		ProjectRoot:   expand("github.com/{owner}/{repo}", match),
+		VCS:           "git",
+		Host:          "github.com",
+		RepoURL:       expand("https://github.com/{owner}/{repo}", match),
+		DefaultBranch: repo.DefaultBranch,
+		DirTemplate:   expand("https://github.com/{owner}/{repo}/tree/{tag}{0}", match, "{/dir}"),
+		FileTemplate:  expand("https://github.com/{owner}/{repo}/blob/{tag}{0}/{1}#L{2}", match, "{/dir}", "{file}", "{line}"),
+	}, nil
+}
+
 func getGistDir(ctx context.Context, client *http.Client, match map[string]string, savedEtag string) (*Directory, error) {
 	c := &httpClient{client: client, errFn: gitHubError}
 
@@ -343,6 +457,7 @@ func getGistDir(ctx context.Context, client *http.Client, match map[string]strin
 		ProjectName:    match["gist"],
 		ProjectRoot:    expand("gist.github.com/{gist}.git", match),
 		ProjectURL:     gist.HTMLURL,
+		RepoURL:        gist.HTMLURL,
 		Subdirectories: nil,
 		VCS:            "git",
 	}, nil
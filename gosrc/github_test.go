@@ -0,0 +1,73 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestGetCommit(t *testing.T) {
+	client := &http.Client{Transport: testTransport{
+		"https://api.github.com/repos/alice/pkg/commits/0123abc": `{"sha": "0123abc456789def0123abc456789def01234567"}`,
+	}}
+	ctx := context.Background()
+
+	sha, err := GetCommit(ctx, client, "github.com/alice/pkg/sub", "0123abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0123abc456789def0123abc456789def01234567"; sha != want {
+		t.Errorf("GetCommit() = %q, want %q", sha, want)
+	}
+
+	for _, sha := range []string{"0123", "0123ABC", "v1.0.0", "0123abc456789def0123abc456789def012345678"} {
+		if _, err := GetCommit(ctx, client, "github.com/alice/pkg", sha); !IsNotFound(err) {
+			t.Errorf("GetCommit(%q) returned error %v, want NotFoundError", sha, err)
+		}
+	}
+	if _, err := GetCommit(ctx, client, "gist.github.com/0123.git", "0123abc"); !IsNotFound(err) {
+		t.Errorf("GetCommit for gist returned error %v, want NotFoundError", err)
+	}
+}
+
+func TestGetGitHubDirFork(t *testing.T) {
+	client := &http.Client{Transport: testTransport{
+		"https://api.github.com/repos/bob/pkg": `{
+			"full_name": "bob/pkg",
+			"fork": true,
+			"default_branch": "main",
+			"parent": {"full_name": "alice/pkg"}
+		}`,
+		"https://api.github.com/repos/bob/pkg/commits":             `[{"sha": "0123abc", "commit": {"committer": {"date": "2021-05-14T08:01:10Z"}}}]`,
+		"https://api.github.com/repos/bob/pkg/contents":            `[{"type": "file", "name": "doc.go", "git_url": "https://api.github.com/repos/bob/pkg/git/blobs/1", "html_url": "https://github.com/bob/pkg/blob/main/doc.go"}]`,
+		"https://api.github.com/repos/bob/pkg/git/trees/0123abc":   `{"tree": []}`,
+		"https://api.github.com/repos/bob/pkg/git/blobs/1":         "package pkg\n",
+		"https://api.github.com/repos/carol/pkg":                   `{"full_name": "carol/pkg", "default_branch": "main"}`,
+		"https://api.github.com/repos/carol/pkg/commits":           `[{"sha": "0123abc", "commit": {"committer": {"date": "2021-05-14T08:01:10Z"}}}]`,
+		"https://api.github.com/repos/carol/pkg/contents":          `[{"type": "file", "name": "doc.go", "git_url": "https://api.github.com/repos/carol/pkg/git/blobs/1", "html_url": "https://github.com/carol/pkg/blob/main/doc.go"}]`,
+		"https://api.github.com/repos/carol/pkg/git/trees/0123abc": `{"tree": []}`,
+		"https://api.github.com/repos/carol/pkg/git/blobs/1":       "package pkg\n",
+	}}
+	for _, tt := range []struct {
+		owner  string
+		fork   bool
+		forkOf string
+	}{
+		{"bob", true, "github.com/alice/pkg"},
+		{"carol", false, ""},
+	} {
+		dir, err := getGitHubDir(context.Background(), client, map[string]string{"owner": tt.owner, "repo": "pkg", "dir": ""}, "")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.owner, err)
+		}
+		if dir.Fork != tt.fork || dir.ForkOf != tt.forkOf {
+			t.Errorf("%s: Fork, ForkOf = %v, %q, want %v, %q", tt.owner, dir.Fork, dir.ForkOf, tt.fork, tt.forkOf)
+		}
+	}
+}
@@ -40,11 +40,14 @@ func getStandardDir(ctx context.Context, client *http.Client, importPath string,
 
 	var files []*File
 	var dataURLs []string
+	var asmFiles []string
 	for _, m := range golangFileRe.FindAllSubmatch(p, -1) {
 		fname := string(m[1])
 		if isDocFile(fname) {
 			files = append(files, &File{Name: fname, BrowseURL: browseURL + fname})
 			dataURLs = append(dataURLs, browseURL+fname+"?m=text")
+		} else if isAsmFile(fname) {
+			asmFiles = append(asmFiles, fname)
 		}
 	}
 
@@ -56,11 +59,14 @@ func getStandardDir(ctx context.Context, client *http.Client, importPath string,
 		BrowseURL:    browseURL,
 		Etag:         etag,
 		Files:        files,
+		AsmFiles:     asmFiles,
 		ImportPath:   importPath,
 		LineFmt:      "%s#L%d",
 		ProjectName:  "Go",
 		ProjectRoot:  "",
 		ProjectURL:   "https://golang.org/",
+		RepoURL:      "https://go.googlesource.com/go",
+		RepoDir:      "src/" + importPath,
 		ResolvedPath: importPath,
 	}, nil
 }
@@ -115,6 +115,8 @@ func getGoogleDir(ctx context.Context, client *http.Client, match map[string]str
 		ProjectName: expand("{repo}{dot}{subrepo}", match),
 		ProjectRoot: expand("code.google.com/{pr}/{repo}{dot}{subrepo}", match),
 		ProjectURL:  projectURL,
+		RepoURL:     projectURL,
+		RepoDir:     strings.Trim(match["dir"], "/"),
 		VCS:         match["vcs"],
 	}, nil
 }
@@ -13,11 +13,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"path"
 	"regexp"
 	"strings"
 	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
 )
 
 const ExpiresAfter = 2 * 365 * 24 * time.Hour // Package with no commits and imports expires.
@@ -71,6 +75,15 @@ type Directory struct {
 	// Project home page.
 	ProjectURL string
 
+	// Home page of the repository containing the directory on the version
+	// control service website. It differs from ProjectURL for projects with
+	// a custom import path. Optional.
+	RepoURL string
+
+	// Slash separated path of the directory relative to the root of the
+	// repository, or "" for the root.
+	RepoDir string
+
 	// Version control system: git, hg, bzr, ...
 	VCS string
 
@@ -84,9 +97,18 @@ type Directory struct {
 	// Files.
 	Files []*File
 
+	// Names of the assembly (.s) files in the directory. The files are not
+	// fetched. Optional.
+	AsmFiles []string
+
 	// Subdirectories, not guaranteed to contain Go code.
 	Subdirectories []string
 
+	// Slash separated paths of the directories in the repository relative to
+	// the repository root, not guaranteed to contain Go code. The tree is
+	// bounded in depth and size. Optional.
+	Tree []string
+
 	// Location of directory on version control service website.
 	BrowseURL string
 
@@ -98,8 +120,16 @@ type Directory struct {
 	// Whether the repository of this directory is a fork of another one.
 	Fork bool
 
+	// Project root of the repository this repository is a fork of, if
+	// reported by the host. Optional.
+	ForkOf string
+
 	// How many stars (for a GitHub project) the repository of this directory has.
 	Stars int
+
+	// Time of the last commit to the directory, or to the repository if the
+	// host does not report commits by directory. Zero if unknown.
+	Committed time.Time
 }
 
 // Project represents a repository.
@@ -160,6 +190,9 @@ type service struct {
 	get             func(context.Context, *http.Client, map[string]string, string) (*Directory, error)
 	getPresentation func(context.Context, *http.Client, map[string]string) (*Presentation, error)
 	getProject      func(context.Context, *http.Client, map[string]string) (*Project, error)
+	getTags         func(context.Context, *http.Client, map[string]string) ([]string, error)
+	getCommit       func(context.Context, *http.Client, map[string]string, string) (string, error)
+	getSource       func(context.Context, *http.Client, map[string]string) (*Source, error)
 }
 
 var services []*service
@@ -231,7 +264,23 @@ func attrValue(attrs []xml.Attr, name string) string {
 	return ""
 }
 
+// fetchMeta returns the go-import and go-source meta tags of importPath,
+// from the meta cache if the tags were fetched recently.
 func fetchMeta(ctx context.Context, client *http.Client, importPath string) (scheme string, im *importMeta, sm *sourceMeta, redir bool, err error) {
+	now := time.Now()
+	if r, ok := metaCache.get(importPath, now); ok {
+		return r.scheme, r.im, r.sm, r.redir, r.err
+	}
+	scheme, im, sm, redir, cacheControl, err := fetchMetaPage(ctx, client, importPath)
+	if ctx.Err() == nil {
+		metaCache.put(importPath, &metaResult{scheme: scheme, im: im, sm: sm, redir: redir, err: err}, cacheControl, now)
+	}
+	return scheme, im, sm, redir, err
+}
+
+// fetchMetaPage fetches the page of importPath with the go-get parameter and
+// returns its meta tags and its Cache-Control header.
+func fetchMetaPage(ctx context.Context, client *http.Client, importPath string) (scheme string, im *importMeta, sm *sourceMeta, redir bool, cacheControl string, err error) {
 	uri := importPath
 	if !strings.Contains(uri, "/") {
 		// Add slash for root of domain.
@@ -242,19 +291,21 @@ func fetchMeta(ctx context.Context, client *http.Client, importPath string) (sch
 	c := httpClient{client: client}
 	scheme = "https"
 	resp, err := c.get(ctx, scheme+"://"+uri)
-	if err != nil || resp.StatusCode != 200 {
+	if (err != nil || resp.StatusCode != 200) && isInsecureHost(importPath) {
 		if err == nil {
 			resp.Body.Close()
 		}
+		log.Printf("WARNING: fetching go-import meta tag of %s over insecure HTTP", importPath)
 		scheme = "http"
 		resp, err = c.get(ctx, scheme+"://"+uri)
-		if err != nil {
-			return scheme, nil, nil, false, err
-		}
+	}
+	if err != nil {
+		return scheme, nil, nil, false, "", err
 	}
 	defer resp.Body.Close()
+	cacheControl = resp.Header.Get("Cache-Control")
 	im, sm, redir, err = parseMeta(scheme, importPath, resp.Body)
-	return scheme, im, sm, redir, err
+	return scheme, im, sm, redir, cacheControl, err
 }
 
 var refreshToGodocPat = regexp.MustCompile(`(?i)^\d+; url=https?://godoc\.org/`)
@@ -355,37 +406,60 @@ var getVCSDirFn = func(ctx context.Context, client *http.Client, m map[string]st
 	return nil, errNoMatch
 }
 
-// getDynamic gets a directory from a service that is not statically known.
-func getDynamic(ctx context.Context, client *http.Client, importPath, etag string) (*Directory, error) {
-	metaProto, im, sm, redir, err := fetchMeta(ctx, client, importPath)
+// fetchProjectMeta is like fetchMeta, but also checks that the go-import
+// meta tag of importPath matches the one of its project root. The scheme
+// and redir results are the ones of the project root.
+func fetchProjectMeta(ctx context.Context, client *http.Client, importPath string) (scheme string, im *importMeta, sm *sourceMeta, redir bool, err error) {
+	scheme, im, sm, redir, err = fetchMeta(ctx, client, importPath)
 	if err != nil {
-		return nil, err
+		return "", nil, nil, false, err
 	}
 
 	if im.projectRoot != importPath {
 		var imRoot *importMeta
-		metaProto, imRoot, _, redir, err = fetchMeta(ctx, client, im.projectRoot)
+		scheme, imRoot, _, redir, err = fetchMeta(ctx, client, im.projectRoot)
 		if err != nil {
-			return nil, err
+			return "", nil, nil, false, err
 		}
 		if *imRoot != *im {
-			return nil, NotFoundError{Message: "project root mismatch."}
+			return "", nil, nil, false, NotFoundError{Message: "project root mismatch."}
 		}
 	}
+	return scheme, im, sm, redir, nil
+}
 
-	// clonePath is the repo URL from import meta tag, with the "scheme://" prefix removed.
-	// It should be used for cloning repositories.
-	// repo is the repo URL from import meta tag, with the "scheme://" prefix removed, and
-	// a possible ".vcs" suffix trimmed.
+// metaRepo splits the repo URL of the go-import meta tag im.
+//
+// clonePath is the repo URL from import meta tag, with the "scheme://" prefix removed.
+// It should be used for cloning repositories.
+// repo is the repo URL from import meta tag, with the "scheme://" prefix removed, and
+// a possible ".vcs" suffix trimmed.
+func metaRepo(im *importMeta) (proto, clonePath, repo string, err error) {
 	i := strings.Index(im.repo, "://")
 	if i < 0 {
-		return nil, NotFoundError{Message: "bad repo URL: " + im.repo}
+		return "", "", "", NotFoundError{Message: "bad repo URL: " + im.repo}
 	}
-	proto := im.repo[:i]
-	clonePath := im.repo[i+len("://"):]
-	repo := strings.TrimSuffix(clonePath, "."+im.vcs)
+	proto = im.repo[:i]
+	clonePath = im.repo[i+len("://"):]
+	repo = strings.TrimSuffix(clonePath, "."+im.vcs)
 	if !IsValidRemotePath(repo) {
-		return nil, fmt.Errorf("bad path from meta: %s", repo)
+		return "", "", "", fmt.Errorf("bad path from meta: %s", repo)
+	}
+	if err := checkAllowedHost(repo); err != nil {
+		return "", "", "", err
+	}
+	return proto, clonePath, repo, nil
+}
+
+// getDynamic gets a directory from a service that is not statically known.
+func getDynamic(ctx context.Context, client *http.Client, importPath, etag string) (*Directory, error) {
+	metaProto, im, sm, redir, err := fetchProjectMeta(ctx, client, importPath)
+	if err != nil {
+		return nil, err
+	}
+	proto, clonePath, repo, err := metaRepo(im)
+	if err != nil {
+		return nil, err
 	}
 	dirName := importPath[len(im.projectRoot):]
 
@@ -410,6 +484,9 @@ func getDynamic(ctx context.Context, client *http.Client, importPath, etag strin
 	dir.ImportPath = importPath
 	dir.ProjectRoot = im.projectRoot
 	dir.ResolvedPath = resolvedPath
+	// The repository URL set by the service hosting the repository is kept;
+	// only the project is known by the custom import path.
+	dir.RepoDir = strings.Trim(dirName, "/")
 	dir.ProjectName = path.Base(im.projectRoot)
 	if !redir {
 		dir.ProjectURL = metaProto + "://" + im.projectRoot
@@ -471,6 +548,9 @@ func getStatic(ctx context.Context, client *http.Client, importPath, etag string
 				dir.ImportPath = importPath
 				dir.ResolvedPath = importPath
 			}
+			if err == nil {
+				removeExcluded(dir, importPath)
+			}
 			return dir, err
 		}
 	}
@@ -478,12 +558,20 @@ func getStatic(ctx context.Context, client *http.Client, importPath, etag string
 }
 
 func Get(ctx context.Context, client *http.Client, importPath string, etag string) (dir *Directory, err error) {
+	if err := checkExcludedPath(importPath); err != nil {
+		return nil, err
+	}
 	switch {
+	case localModuleDir(importPath) != "":
+		dir, err = getLocalModule(importPath)
 	case localPath != "":
 		dir, err = getLocal(importPath)
 	case IsGoRepoPath(importPath):
 		dir, err = getStandardDir(ctx, client, importPath, etag)
 	case IsValidRemotePath(importPath):
+		if err := checkAllowedHost(importPath); err != nil {
+			return nil, err
+		}
 		dir, err = getStatic(ctx, client, importPath, etag)
 		if err == errNoMatch {
 			dir, err = getDynamic(ctx, client, importPath, etag)
@@ -495,6 +583,9 @@ func Get(ctx context.Context, client *http.Client, importPath string, etag strin
 	if err == errNoMatch {
 		err = NotFoundError{Message: "Import path not valid:"}
 	}
+	if err == nil {
+		removeExcluded(dir, importPath)
+	}
 
 	return dir, err
 }
@@ -541,6 +632,112 @@ func GetProject(ctx context.Context, client *http.Client, importPath string) (*P
 	return nil, NotFoundError{Message: "path does not match registered service"}
 }
 
+// GetTags returns the names of the tags in the repository containing
+// importPath.
+func GetTags(ctx context.Context, client *http.Client, importPath string) ([]string, error) {
+	for _, s := range services {
+		if s.getTags == nil {
+			continue
+		}
+		match, err := s.match(importPath)
+		if err != nil {
+			return nil, err
+		}
+		if match != nil {
+			return s.getTags(ctx, client, match)
+		}
+	}
+	return nil, NotFoundError{Message: "path does not match registered service"}
+}
+
+// GetVersion is like Get, but gets the directory at the given tag of the
+// repository. Only services that support GetTags are supported.
+func GetVersion(ctx context.Context, client *http.Client, importPath, tag string) (*Directory, error) {
+	if err := checkAllowedHost(importPath); err != nil {
+		return nil, err
+	}
+	if err := checkExcludedPath(importPath); err != nil {
+		return nil, err
+	}
+	for _, s := range services {
+		if s.getTags == nil {
+			continue
+		}
+		match, err := s.match(importPath)
+		if err != nil {
+			return nil, err
+		}
+		if match != nil {
+			match["ref"] = tag
+			dir, err := s.get(ctx, client, match, "")
+			if dir != nil {
+				dir.ImportPath = importPath
+				dir.ResolvedPath = importPath
+			}
+			return dir, err
+		}
+	}
+	return nil, NotFoundError{Message: "path does not match registered service"}
+}
+
+var commitSHAPat = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// IsCommitSHA returns true if s is a full or abbreviated hexadecimal commit
+// SHA.
+func IsCommitSHA(s string) bool {
+	return commitSHAPat.MatchString(s)
+}
+
+// GetCommit returns the full SHA of the commit with the full or abbreviated
+// SHA sha in the repository containing importPath. A NotFoundError is
+// returned if the commit does not exist. Only services that support GetTags
+// are supported, so that the directory at the commit can be fetched with
+// GetVersion.
+func GetCommit(ctx context.Context, client *http.Client, importPath, sha string) (string, error) {
+	if !IsCommitSHA(sha) {
+		return "", NotFoundError{Message: "invalid commit " + sha}
+	}
+	if err := checkAllowedHost(importPath); err != nil {
+		return "", err
+	}
+	for _, s := range services {
+		if s.getCommit == nil || s.getTags == nil {
+			continue
+		}
+		match, err := s.match(importPath)
+		if err != nil {
+			return "", err
+		}
+		if match != nil {
+			return s.getCommit(ctx, client, match, sha)
+		}
+	}
+	return "", NotFoundError{Message: "commits are not supported for " + importPath}
+}
+
+// LatestVersion returns the highest stable semantic version in tags. If
+// there is no stable version, the highest pre-release version is returned.
+// LatestVersion returns "" if no tag is a semantic version.
+func LatestVersion(tags []string) string {
+	var stable, pre string
+	for _, tag := range tags {
+		if semver.Canonical(tag) != tag {
+			continue
+		}
+		if semver.Prerelease(tag) == "" {
+			if stable == "" || semver.Compare(tag, stable) > 0 {
+				stable = tag
+			}
+		} else if pre == "" || semver.Compare(tag, pre) > 0 {
+			pre = tag
+		}
+	}
+	if stable != "" {
+		return stable
+	}
+	return pre
+}
+
 // MaybeRedirect uses the provided import path, import comment, and resolved GitHub path
 // to make a decision of whether to redirect to another, more canonical import path.
 // It returns nil error to indicate no redirect, or a NotFoundError error to redirect.
@@ -572,3 +769,41 @@ func MaybeRedirect(importPath, importComment, resolvedGitHubPath string) error {
 	// No redirect.
 	return nil
 }
+
+// modulePat matches the module directive of a go.mod file.
+var modulePat = regexp.MustCompile(`(?m)^module[ \t]+"?([^"\s]+)"?[ \t]*(?://.*)?\r?$`)
+
+// ModulePath returns the module path declared by the module directive of the
+// go.mod file data or "" if there is no module directive.
+func ModulePath(data []byte) string {
+	m := modulePat.FindSubmatch(data)
+	if m == nil {
+		return ""
+	}
+	return string(m[1])
+}
+
+// ModuleRedirect uses the provided import path and the module path declared
+// by the go.mod file in the directory of the package to make a decision of
+// whether to redirect to the canonical import path of the module. It returns
+// nil error to indicate no redirect, or a NotFoundError error to redirect.
+//
+// The major version suffix of the module path is ignored, because the
+// packages of a major version are usually stored in the same directory as
+// the previous versions.
+func ModuleRedirect(importPath, modulePath string) error {
+	if modulePath == "" || modulePath == importPath {
+		return nil
+	}
+	target := modulePath
+	if prefix, pathMajor, ok := module.SplitPathVersion(modulePath); ok && strings.HasPrefix(pathMajor, "/") {
+		target = prefix
+	}
+	if target == importPath || !IsValidRemotePath(target) {
+		return nil
+	}
+	return NotFoundError{
+		Message:  "not at canonical import path",
+		Redirect: target,
+	}
+}
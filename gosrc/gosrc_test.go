@@ -24,6 +24,8 @@ var testWeb = map[string]string{
 	"https://alice.org/pkg": `<head> <meta name="go-import" content="alice.org/pkg git https://github.com/alice/pkg"></head>`,
 	// Package in sub-directory.
 	"https://alice.org/pkg/sub": `<head> <meta name="go-import" content="alice.org/pkg git https://github.com/alice/pkg"><body>`,
+	// Package several directories deep.
+	"https://alice.org/pkg/a/b/c": `<head> <meta name="go-import" content="alice.org/pkg git https://github.com/alice/pkg"><body>`,
 	// Fallback to http.
 	"http://alice.org/pkg/http": `<head> <meta name="go-import" content="alice.org/pkg git https://github.com/alice/pkg">`,
 	// Meta tag in sub-directory does not match meta tag at root.
@@ -97,6 +99,10 @@ var testWeb = map[string]string{
 
 	// The repo element of go-import includes "../"
 	"http://my.host/pkg": `<head> <meta name="go-import" content="my.host/pkg git http://vcs.net/myhost/../../tmp/pkg.git"></head>`,
+
+	// Meta tag served only over plain HTTP by a host not allowed to be
+	// fetched insecurely.
+	"http://carol.org/pkg": `<head> <meta name="go-import" content="carol.org/pkg git https://github.com/carol/pkg"></head>`,
 }
 
 var getDynamicTests = []struct {
@@ -110,6 +116,7 @@ var getDynamicTests = []struct {
 		ProjectName:  "pkg",
 		ProjectRoot:  "alice.org/pkg",
 		ProjectURL:   "https://alice.org/pkg",
+		RepoURL:      "https://github.com/alice/pkg",
 		ResolvedPath: "github.com/alice/pkg",
 		VCS:          "git",
 		Files:        []*File{{Name: "main.go", BrowseURL: "https://github.com/alice/pkg/blob/master/main.go"}},
@@ -121,10 +128,25 @@ var getDynamicTests = []struct {
 		ProjectName:  "pkg",
 		ProjectRoot:  "alice.org/pkg",
 		ProjectURL:   "https://alice.org/pkg",
+		RepoURL:      "https://github.com/alice/pkg",
+		RepoDir:      "sub",
 		ResolvedPath: "github.com/alice/pkg/sub",
 		VCS:          "git",
 		Files:        []*File{{Name: "main.go", BrowseURL: "https://github.com/alice/pkg/blob/master/sub/main.go"}},
 	}},
+	{"alice.org/pkg/a/b/c", &Directory{
+		BrowseURL:    "https://github.com/alice/pkg/tree/master/a/b/c",
+		ImportPath:   "alice.org/pkg/a/b/c",
+		LineFmt:      "%s#L%d",
+		ProjectName:  "pkg",
+		ProjectRoot:  "alice.org/pkg",
+		ProjectURL:   "https://alice.org/pkg",
+		RepoURL:      "https://github.com/alice/pkg",
+		RepoDir:      "a/b/c",
+		ResolvedPath: "github.com/alice/pkg/a/b/c",
+		VCS:          "git",
+		Files:        []*File{{Name: "main.go", BrowseURL: "https://github.com/alice/pkg/blob/master/a/b/c/main.go"}},
+	}},
 	{"alice.org/pkg/http", &Directory{
 		BrowseURL:    "https://github.com/alice/pkg/tree/master/http",
 		ImportPath:   "alice.org/pkg/http",
@@ -132,6 +154,8 @@ var getDynamicTests = []struct {
 		ProjectName:  "pkg",
 		ProjectRoot:  "alice.org/pkg",
 		ProjectURL:   "https://alice.org/pkg",
+		RepoURL:      "https://github.com/alice/pkg",
+		RepoDir:      "http",
 		ResolvedPath: "github.com/alice/pkg/http",
 		VCS:          "git",
 		Files:        []*File{{Name: "main.go", BrowseURL: "https://github.com/alice/pkg/blob/master/http/main.go"}},
@@ -143,6 +167,8 @@ var getDynamicTests = []struct {
 		ProjectName:  "pkg",
 		ProjectRoot:  "alice.org/pkg",
 		ProjectURL:   "http://alice.org/pkg",
+		RepoURL:      "https://github.com/alice/pkg",
+		RepoDir:      "source",
 		ResolvedPath: "github.com/alice/pkg/source",
 		VCS:          "git",
 		Files:        []*File{{Name: "main.go", BrowseURL: "http://alice.org/pkg/source?f=main.go"}},
@@ -154,6 +180,8 @@ var getDynamicTests = []struct {
 		ProjectName:  "pkg",
 		ProjectRoot:  "alice.org/pkg",
 		ProjectURL:   "http://alice.org/pkg",
+		RepoURL:      "https://github.com/alice/pkg",
+		RepoDir:      "ignore",
 		ResolvedPath: "github.com/alice/pkg/ignore",
 		VCS:          "git",
 		Files:        []*File{{Name: "main.go", BrowseURL: "http://alice.org/pkg/ignore?f=main.go"}},
@@ -176,6 +204,7 @@ var getDynamicTests = []struct {
 		ProjectName:  "pkg",
 		ProjectRoot:  "bob.com/pkg",
 		ProjectURL:   "https://bob.com/pkg",
+		RepoDir:      "sub",
 		ResolvedPath: "vcs.net/bob/pkg.git/sub",
 		VCS:          "git",
 		Files:        []*File{{Name: "main.go"}},
@@ -187,6 +216,7 @@ var getDynamicTests = []struct {
 		ProjectName:  "pkg",
 		ProjectRoot:  "bob.com/pkg",
 		ProjectURL:   "http://bob.com/pkg",
+		RepoDir:      "source",
 		ResolvedPath: "vcs.net/bob/pkg.git/source",
 		VCS:          "git",
 		Files:        []*File{{Name: "main.go", BrowseURL: "http://bob.com/pkg/source/?f=main.go"}},
@@ -198,6 +228,7 @@ var getDynamicTests = []struct {
 		ProjectName:  "benchstat",
 		ProjectRoot:  "rsc.io/benchstat",
 		ProjectURL:   "https://github.com/rsc/benchstat",
+		RepoURL:      "https://github.com/rsc/benchstat",
 		ResolvedPath: "github.com/rsc/benchstat",
 		VCS:          "git",
 		Files:        []*File{{Name: "main.go", BrowseURL: "https://github.com/rsc/benchstat/blob/master/main.go"}},
@@ -209,6 +240,8 @@ var getDynamicTests = []struct {
 		ProjectName:  "examples",
 		ProjectRoot:  "azul3d.org/examples",
 		ProjectURL:   "https://github.com/azul3d/examples",
+		RepoURL:      "https://github.com/azul3d/examples",
+		RepoDir:      "abs",
 		ResolvedPath: "github.com/azul3d/examples/abs",
 		VCS:          "git",
 		Files:        []*File{{Name: "main.go", BrowseURL: "https://gotools.org/azul3d.org/examples/abs#main.go"}},
@@ -220,11 +253,13 @@ var getDynamicTests = []struct {
 		ProjectName:  "blah2",
 		ProjectRoot:  "myitcv.io/blah2",
 		ProjectURL:   "http://myitcv.io/blah2",
+		RepoURL:      "https://github.com/myitcv/x",
 		ResolvedPath: "github.com/myitcv/x",
 		VCS:          "git",
 		Files:        []*File{{Name: "main.go", BrowseURL: "https://github.com/myitcv/x/blob/master/main.go"}},
 	}},
 	{"my.host/pkg", nil},
+	{"carol.org/pkg", nil},
 }
 
 type testTransport map[string]string
@@ -239,6 +274,7 @@ func (t testTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	resp := &http.Response{
 		StatusCode: statusCode,
 		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
 	}
 	return resp, nil
 }
@@ -260,6 +296,7 @@ func testGet(ctx context.Context, client *http.Client, match map[string]string,
 			ProjectName: m[2],
 			ProjectRoot: fmt.Sprintf("github.com/%s/%s", m[1], m[2]),
 			ProjectURL:  fmt.Sprintf("https://github.com/%s/%s", m[1], m[2]),
+			RepoURL:     fmt.Sprintf("https://github.com/%s/%s", m[1], m[2]),
 			VCS:         "git",
 			Files: []*File{{
 				Name:      "main.go",
@@ -290,6 +327,8 @@ func TestGetDynamic(t *testing.T) {
 	}()
 	services = []*service{{pattern: regexp.MustCompile(".*"), get: testGet}}
 	getVCSDirFn = testGet
+	SetInsecureHosts([]string{"alice.org", "rsc.io", "myitcv.io", "my.host"})
+	defer SetInsecureHosts(nil)
 	client := &http.Client{Transport: testTransport(testWeb)}
 
 	for _, tt := range getDynamicTests {
@@ -419,3 +458,85 @@ func TestMaybeRedirect(t *testing.T) {
 		}
 	}
 }
+
+func TestModuleRedirect(t *testing.T) {
+	tests := []struct {
+		importPath   string
+		modulePath   string
+		wantRedirect string // Empty string means no redirect.
+	}{
+		{importPath: "github.com/robpike/ivy", modulePath: "", wantRedirect: ""},
+		{importPath: "robpike.io/ivy", modulePath: "robpike.io/ivy", wantRedirect: ""},
+		{importPath: "github.com/robpike/ivy", modulePath: "robpike.io/ivy", wantRedirect: "robpike.io/ivy"},
+		{importPath: "github.com/go-yaml/yaml", modulePath: "gopkg.in/yaml.v2", wantRedirect: "gopkg.in/yaml.v2"},
+		{importPath: "github.com/alice/pkg", modulePath: "github.com/alice/pkg/v2", wantRedirect: ""},
+		{importPath: "github.com/bob/pkg", modulePath: "github.com/alice/pkg/v3", wantRedirect: "github.com/alice/pkg"},
+		{importPath: "github.com/alice/pkg", modulePath: "pkg", wantRedirect: ""},
+	}
+	for _, tt := range tests {
+		var want error
+		if tt.wantRedirect != "" {
+			want = NotFoundError{
+				Message:  "not at canonical import path",
+				Redirect: tt.wantRedirect,
+			}
+		}
+		if got := ModuleRedirect(tt.importPath, tt.modulePath); got != want {
+			t.Errorf("ModuleRedirect(%q, %q) = %v, want %v", tt.importPath, tt.modulePath, got, want)
+		}
+	}
+}
+
+type failTransport struct{ t *testing.T }
+
+func (ft failTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ft.t.Errorf("unexpected request for %s", req.URL)
+	return nil, fmt.Errorf("unexpected request for %s", req.URL)
+}
+
+func TestAllowedHosts(t *testing.T) {
+	savedServices := services
+	savedGetVCSDirFn := getVCSDirFn
+	defer func() {
+		services = savedServices
+		getVCSDirFn = savedGetVCSDirFn
+		SetAllowedHosts(nil)
+	}()
+	services = []*service{{pattern: regexp.MustCompile(".*"), get: testGet}}
+	getVCSDirFn = testGet
+	ctx := context.Background()
+
+	SetAllowedHosts([]string{"alice.org"})
+	client := &http.Client{Transport: failTransport{t}}
+	if _, err := Get(ctx, client, "bob.com/pkg", ""); !IsNotFound(err) {
+		t.Errorf("Get(bob.com/pkg) returned error %v, want NotFoundError", err)
+	}
+
+	// The go-import meta tag resolves alice.org/pkg to github.com.
+	client = &http.Client{Transport: testTransport(testWeb)}
+	if _, err := getDynamic(ctx, client, "alice.org/pkg", ""); !IsNotFound(err) {
+		t.Errorf("getDynamic(alice.org/pkg) returned error %v, want NotFoundError", err)
+	}
+
+	SetAllowedHosts([]string{"alice.org", "GitHub.com"})
+	if _, err := getDynamic(ctx, client, "alice.org/pkg", ""); err != nil {
+		t.Errorf("getDynamic(alice.org/pkg) returned unexpected error %v", err)
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	for _, tt := range []struct {
+		tags []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"release", "go1"}, ""},
+		{[]string{"v1.0.0", "v1.10.0", "v1.9.1", "v2.0.0-rc.1"}, "v1.10.0"},
+		{[]string{"v0.1.0-alpha", "v0.1.0-beta", "foo"}, "v0.1.0-beta"},
+		{[]string{"v1", "v1.2", "v1.0.0+meta", "v0.3.0"}, "v0.3.0"},
+	} {
+		if got := LatestVersion(tt.tags); got != tt.want {
+			t.Errorf("LatestVersion(%q) = %q, want %q", tt.tags, got, tt.want)
+		}
+	}
+}
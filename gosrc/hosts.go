@@ -0,0 +1,75 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"strings"
+)
+
+var (
+	allowedHosts  map[string]bool
+	insecureHosts map[string]bool
+)
+
+// SetAllowedHosts restricts Get to import paths whose host, and whose
+// repository host after resolving go-import meta tags, is in hosts. Paths in
+// the standard library are always allowed. An empty list removes the
+// restriction.
+func SetAllowedHosts(hosts []string) {
+	allowedHosts = hostSet(hosts)
+}
+
+// SetInsecureHosts allows Get to fall back to plain HTTP for the go-import
+// meta tags of import paths whose host is in hosts, and to clone their
+// repositories over unencrypted protocols. Other hosts are only fetched over
+// HTTPS or SSH. An empty list removes all hosts.
+func SetInsecureHosts(hosts []string) {
+	insecureHosts = hostSet(hosts)
+}
+
+// hostSet returns the lower case hosts as a set, or nil if there are no hosts.
+func hostSet(hosts []string) map[string]bool {
+	var set map[string]bool
+	for _, h := range hosts {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "" {
+			continue
+		}
+		if set == nil {
+			set = make(map[string]bool)
+		}
+		set[h] = true
+	}
+	return set
+}
+
+// isInsecureHost returns whether the host of importPath is in the list set by
+// SetInsecureHosts.
+func isInsecureHost(importPath string) bool {
+	return insecureHosts[strings.ToLower(pathHost(importPath))]
+}
+
+// pathHost returns the host element of importPath.
+func pathHost(importPath string) string {
+	if i := strings.Index(importPath, "/"); i >= 0 {
+		return importPath[:i]
+	}
+	return importPath
+}
+
+// checkAllowedHost returns a NotFoundError if the host of importPath is not
+// in the list set by SetAllowedHosts.
+func checkAllowedHost(importPath string) error {
+	if allowedHosts == nil {
+		return nil
+	}
+	host := pathHost(importPath)
+	if allowedHosts[strings.ToLower(host)] {
+		return nil
+	}
+	return NotFoundError{Message: "host " + host + " is not allowed."}
+}
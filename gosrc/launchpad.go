@@ -131,6 +131,8 @@ func getLaunchpadDir(ctx context.Context, client *http.Client, match map[string]
 		ProjectName: match["repo"],
 		ProjectRoot: expand("launchpad.net/{repo}", match),
 		ProjectURL:  expand("https://launchpad.net/{repo}/", match),
+		RepoURL:     expand("https://launchpad.net/{repo}/", match),
+		RepoDir:     strings.Trim(match["dir"], "/"),
 		VCS:         "bzr",
 	}, nil
 }
@@ -7,15 +7,25 @@
 package gosrc
 
 import (
+	"errors"
 	"go/build"
 	"io/ioutil"
+	"os"
+	"path"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
 var localPath string
 
+// localModule is the module read from the file system in local module mode.
+var localModule struct {
+	root string // directory of the go.mod file
+	path string // module path
+}
+
 // SetLocalDevMode sets the package to local development mode. In this mode,
 // the GOPATH specified by path is used to find directories instead of version
 // control services.
@@ -23,6 +33,109 @@ func SetLocalDevMode(path string) {
 	localPath = path
 }
 
+// SetLocalModule sets the package to local module mode. In this mode, the
+// packages of the module in directory root are read from the file system
+// instead of version control services. The module path is read from the
+// go.mod file in root. Packages outside of the module are fetched as usual.
+func SetLocalModule(root string) error {
+	data, err := ioutil.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return err
+	}
+	modulePath := ModulePath(data)
+	if modulePath == "" {
+		return errors.New("gosrc: no module directive in " + filepath.Join(root, "go.mod"))
+	}
+	localModule.root = root
+	localModule.path = modulePath
+	return nil
+}
+
+// localModuleDir returns the directory of importPath in the local module or
+// "" if importPath is not in the module. Paths with empty, "." or ".."
+// elements are rejected so that the result cannot escape the module root.
+func localModuleDir(importPath string) string {
+	switch {
+	case localModule.root == "":
+		return ""
+	case importPath == localModule.path:
+		return localModule.root
+	case strings.HasPrefix(importPath, localModule.path+"/"):
+		rel := importPath[len(localModule.path)+1:]
+		for _, elem := range strings.Split(rel, "/") {
+			if elem == "" || elem == "." || elem == ".." || strings.ContainsRune(elem, '\\') {
+				return ""
+			}
+		}
+		return filepath.Join(localModule.root, filepath.FromSlash(rel))
+	}
+	return ""
+}
+
+// getLocalModule gets the directory of importPath in the local module.
+func getLocalModule(importPath string) (*Directory, error) {
+	dir := localModuleDir(importPath)
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, NotFoundError{Message: "directory not found in local module."}
+	}
+	var modTime time.Time
+	var files []*File
+	var asmFiles []string
+	var subdirs []string
+	for _, fi := range fis {
+		name := fi.Name()
+		if fi.IsDir() {
+			if isLocalPackageDir(filepath.Join(dir, name)) {
+				subdirs = append(subdirs, name)
+			}
+			continue
+		}
+		if isAsmFile(name) {
+			asmFiles = append(asmFiles, name)
+			continue
+		}
+		if !isDocFile(name) {
+			continue
+		}
+		if fi.ModTime().After(modTime) {
+			modTime = fi.ModTime()
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, &File{Name: name, Data: b})
+	}
+	tree, err := walkTree(localModule.root, isLocalPackageDir)
+	if err != nil {
+		return nil, err
+	}
+	return &Directory{
+		ImportPath:     importPath,
+		ProjectRoot:    localModule.path,
+		ProjectName:    path.Base(localModule.path),
+		Etag:           strconv.FormatInt(modTime.UnixNano(), 16),
+		Files:          files,
+		AsmFiles:       asmFiles,
+		Subdirectories: subdirs,
+		Tree:           tree,
+		Status:         Active,
+	}, nil
+}
+
+// isLocalPackageDir reports whether dir is a directory of the local module,
+// possibly containing Go packages. Directories ignored by the go command and
+// nested modules are excluded.
+func isLocalPackageDir(dir string) bool {
+	name := filepath.Base(dir)
+	if name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(dir, "go.mod"))
+	return os.IsNotExist(err)
+}
+
 func getLocal(importPath string) (*Directory, error) {
 	ctx := build.Default
 	if localPath != "" {
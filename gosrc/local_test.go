@@ -0,0 +1,99 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestGetLocalModule(t *testing.T) {
+	root, err := ioutil.TempDir("", "gosrc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	for name, data := range map[string]string{
+		"go.mod":              "module example.com/m // local\n\ngo 1.16\n",
+		"m.go":                "package m\n",
+		"m_amd64.s":           "TEXT ·f(SB),4,$0\n",
+		"README.md":           "# m\n",
+		"notes.txt":           "notes\n",
+		"sub/sub.go":          "package sub\n",
+		"testdata/x.go":       "package x\n",
+		".git/config":         "\n",
+		"nested/go.mod":       "module example.com/nested\n",
+		"nested/nested.go":    "package nested\n",
+		"sub/internal/int.go": "package internal\n",
+	} {
+		name = filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := SetLocalModule(root); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { localModule.root, localModule.path = "", "" }()
+
+	dir, err := Get(context.Background(), nil, "example.com/m", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Directory{
+		ImportPath:  "example.com/m",
+		ProjectRoot: "example.com/m",
+		ProjectName: "m",
+		Files: []*File{
+			{Name: "README.md", Data: []byte("# m\n")},
+			{Name: "go.mod", Data: []byte("module example.com/m // local\n\ngo 1.16\n")},
+			{Name: "m.go", Data: []byte("package m\n")},
+		},
+		AsmFiles:       []string{"m_amd64.s"},
+		Subdirectories: []string{"sub"},
+		Tree:           []string{"sub", "sub/internal"},
+		Status:         Active,
+	}
+	if diff := cmp.Diff(want, dir, cmpopts.IgnoreFields(Directory{}, "Etag")); diff != "" {
+		t.Errorf("Get(example.com/m) mismatch (-want +got):\n%s", diff)
+	}
+
+	dir, err = Get(context.Background(), nil, "example.com/m/sub", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := dir.Subdirectories, []string{"internal"}; !cmp.Equal(got, want) {
+		t.Errorf("Get(example.com/m/sub) Subdirectories = %v, want %v", got, want)
+	}
+
+	if _, err := Get(context.Background(), nil, "example.com/m/missing", ""); !IsNotFound(err) {
+		t.Errorf("Get(example.com/m/missing) returned error %v, want not found", err)
+	}
+
+	for _, importPath := range []string{
+		"example.com/m/..",
+		"example.com/m/../..",
+		"example.com/m/./sub",
+		"example.com/m//sub",
+		"example.com/m/sub/",
+		`example.com/m/..\sub`,
+	} {
+		if dir := localModuleDir(importPath); dir != "" {
+			t.Errorf("localModuleDir(%q) = %q, want \"\"", importPath, dir)
+		}
+	}
+}
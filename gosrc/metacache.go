@@ -0,0 +1,163 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxMetaCacheEntries bounds the number of import paths in the meta cache.
+const maxMetaCacheEntries = 10000
+
+// metaResult is the result of a lookup of the go-import and go-source meta
+// tags of an import path.
+type metaResult struct {
+	scheme  string
+	im      *importMeta
+	sm      *sourceMeta
+	redir   bool
+	err     error
+	expires time.Time
+}
+
+// copy returns a copy of r with copies of the meta tags, so that callers
+// modifying the tags do not change the cache.
+func (r *metaResult) copy() *metaResult {
+	c := *r
+	if r.im != nil {
+		im := *r.im
+		c.im = &im
+	}
+	if r.sm != nil {
+		sm := *r.sm
+		c.sm = &sm
+	}
+	return &c
+}
+
+// MetaCacheStats are the counters of the meta cache.
+type MetaCacheStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Entries int   `json:"entries"`
+}
+
+// metaLookupCache caches the meta tags of vanity import paths so that the
+// pages of vanity hosts are not fetched on every crawl of their packages.
+type metaLookupCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	failureTTL time.Duration
+	entries    map[string]*metaResult
+	hits       int64
+	misses     int64
+}
+
+var metaCache = &metaLookupCache{}
+
+// SetMetaCacheTTL caches the go-import meta tags found for an import path
+// for ttl, or less if the Cache-Control header of the page asks for it, and
+// pages without the tags for failureTTL. Other failures are not cached. A
+// zero ttl disables the cache. The cache and its counters are cleared.
+func SetMetaCacheTTL(ttl, failureTTL time.Duration) {
+	metaCache.mu.Lock()
+	defer metaCache.mu.Unlock()
+	metaCache.ttl = ttl
+	metaCache.failureTTL = failureTTL
+	metaCache.entries = nil
+	metaCache.hits = 0
+	metaCache.misses = 0
+}
+
+// GetMetaCacheStats returns the counters of the meta cache.
+func GetMetaCacheStats() MetaCacheStats {
+	metaCache.mu.Lock()
+	defer metaCache.mu.Unlock()
+	return MetaCacheStats{Hits: metaCache.hits, Misses: metaCache.misses, Entries: len(metaCache.entries)}
+}
+
+// get returns a copy of the unexpired result of the lookup of importPath.
+func (c *metaLookupCache) get(importPath string, now time.Time) (*metaResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl <= 0 {
+		return nil, false
+	}
+	r, ok := c.entries[importPath]
+	if ok && now.Before(r.expires) {
+		c.hits++
+		return r.copy(), true
+	}
+	if ok {
+		delete(c.entries, importPath)
+	}
+	c.misses++
+	return nil, false
+}
+
+// put caches a copy of the result r of the lookup of importPath. cacheControl
+// is the Cache-Control header of the meta page. Failures other than pages
+// without meta tags, such as network errors, are not cached.
+func (c *metaLookupCache) put(importPath string, r *metaResult, cacheControl string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl <= 0 || r.err != nil && !IsNotFound(r.err) {
+		return
+	}
+	ttl := c.ttl
+	if r.err != nil {
+		ttl = c.failureTTL
+	}
+	if maxAge, ok := cacheControlMaxAge(cacheControl); !ok {
+		return
+	} else if maxAge >= 0 && maxAge < ttl {
+		ttl = maxAge
+	}
+	if ttl <= 0 {
+		return
+	}
+	if len(c.entries) >= maxMetaCacheEntries {
+		for p, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, p)
+			}
+		}
+		if len(c.entries) >= maxMetaCacheEntries {
+			c.entries = nil
+		}
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]*metaResult)
+	}
+	r = r.copy()
+	r.expires = now.Add(ttl)
+	c.entries[importPath] = r
+}
+
+// cacheControlMaxAge returns the max-age directive of the Cache-Control
+// header value h, or -1 if there is none. The result is false if the header
+// does not allow the response to be stored.
+func cacheControlMaxAge(h string) (time.Duration, bool) {
+	maxAge := time.Duration(-1)
+	for _, d := range strings.Split(h, ",") {
+		d = strings.ToLower(strings.TrimSpace(d))
+		switch {
+		case d == "no-store" || d == "no-cache":
+			return 0, false
+		case strings.HasPrefix(d, "max-age="):
+			n, err := strconv.Atoi(strings.Trim(d[len("max-age="):], `"`))
+			if err != nil || n < 0 {
+				return 0, false
+			}
+			maxAge = time.Duration(n) * time.Second
+		}
+	}
+	return maxAge, true
+}
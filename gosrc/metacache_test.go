@@ -0,0 +1,125 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingTransport serves body with the Cache-Control header cacheControl
+// and counts the requests.
+type countingTransport struct {
+	body         string
+	cacheControl string
+	n            int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.n++
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader(t.body)),
+	}
+	if t.cacheControl != "" {
+		resp.Header.Set("Cache-Control", t.cacheControl)
+	}
+	return resp, nil
+}
+
+const metaCacheTestPage = `<head><meta name="go-import" content="example.org/pkg git https://github.com/alice/pkg"></head>`
+
+func TestFetchMetaCache(t *testing.T) {
+	defer SetMetaCacheTTL(0, 0)
+
+	tests := []struct {
+		name         string
+		ttl          time.Duration
+		body         string
+		cacheControl string
+		wantFetches  int
+	}{
+		{"disabled", 0, metaCacheTestPage, "", 2},
+		{"cached", time.Hour, metaCacheTestPage, "", 1},
+		{"max-age", time.Hour, metaCacheTestPage, "public, max-age=3600", 1},
+		{"max-age zero", time.Hour, metaCacheTestPage, "max-age=0", 2},
+		{"no-store", time.Hour, metaCacheTestPage, "no-store", 2},
+		{"failure", time.Hour, "<head></head>", "", 1},
+	}
+	for _, tt := range tests {
+		SetMetaCacheTTL(tt.ttl, time.Minute)
+		transport := &countingTransport{body: tt.body, cacheControl: tt.cacheControl}
+		client := &http.Client{Transport: transport}
+		_, im1, _, _, err1 := fetchMeta(context.Background(), client, "example.org/pkg")
+		_, im2, _, _, err2 := fetchMeta(context.Background(), client, "example.org/pkg")
+		if transport.n != tt.wantFetches {
+			t.Errorf("%s: fetched %d times, want %d", tt.name, transport.n, tt.wantFetches)
+		}
+		if (err1 == nil) != (err2 == nil) || (im1 == nil) != (im2 == nil) || im1 != nil && *im1 != *im2 {
+			t.Errorf("%s: second lookup = %v, %v; want %v, %v", tt.name, im2, err2, im1, err1)
+		}
+	}
+}
+
+func TestMetaCacheExpiry(t *testing.T) {
+	defer SetMetaCacheTTL(0, 0)
+	SetMetaCacheTTL(time.Hour, time.Minute)
+
+	now := time.Now()
+	metaCache.put("example.org/ok", &metaResult{im: &importMeta{}}, "max-age=600", now)
+	metaCache.put("example.org/bad", &metaResult{err: NotFoundError{}}, "", now)
+	metaCache.put("example.org/down", &metaResult{err: errors.New("connection refused")}, "", now)
+
+	tests := []struct {
+		importPath string
+		after      time.Duration
+		want       bool
+	}{
+		{"example.org/ok", 5 * time.Minute, true},
+		{"example.org/ok", 10 * time.Minute, false},
+		{"example.org/bad", 30 * time.Second, true},
+		{"example.org/bad", 2 * time.Minute, false},
+		{"example.org/down", 0, false},
+	}
+	for _, tt := range tests {
+		if _, ok := metaCache.get(tt.importPath, now.Add(tt.after)); ok != tt.want {
+			t.Errorf("get(%q) after %v = %v, want %v", tt.importPath, tt.after, ok, tt.want)
+		}
+	}
+	stats := GetMetaCacheStats()
+	if stats.Hits != 2 || stats.Misses != 3 {
+		t.Errorf("GetMetaCacheStats() = %+v, want 2 hits and 3 misses", stats)
+	}
+}
+
+func TestMetaCacheCopies(t *testing.T) {
+	defer SetMetaCacheTTL(0, 0)
+	SetMetaCacheTTL(time.Hour, time.Minute)
+
+	now := time.Now()
+	im := &importMeta{projectRoot: "example.org/pkg", vcs: "git", repo: "https://github.com/alice/pkg"}
+	metaCache.put("example.org/pkg", &metaResult{im: im}, "", now)
+	im.repo = "changed after put"
+
+	r, ok := metaCache.get("example.org/pkg", now)
+	if !ok {
+		t.Fatal("get() after put() = false, want true")
+	}
+	if r.im.repo != "https://github.com/alice/pkg" {
+		t.Errorf("get() returned repo %q, want the repo at the time of put()", r.im.repo)
+	}
+	r.im.repo = "changed after get"
+	if r, _ := metaCache.get("example.org/pkg", now); r.im.repo != "https://github.com/alice/pkg" {
+		t.Errorf("get() returned repo %q after the caller changed a result", r.im.repo)
+	}
+}
@@ -9,6 +9,7 @@
 package gosrc
 
 import (
+	"fmt"
 	"path"
 	"regexp"
 	"strings"
@@ -23,24 +24,29 @@ func isValidPathElement(s string) bool {
 
 // IsValidRemotePath returns true if importPath is structurally valid for "go get".
 func IsValidRemotePath(importPath string) bool {
+	return checkRemotePath(importPath) == nil
+}
 
+// checkRemotePath returns an InvalidPathError if importPath is not
+// structurally valid for "go get".
+func checkRemotePath(importPath string) error {
 	parts := strings.Split(importPath, "/")
 
 	if !validTLDs[path.Ext(parts[0])] {
-		return false
+		return &InvalidPathError{Path: importPath, Reason: "host without a known top level domain"}
 	}
 
 	if !validHost.MatchString(parts[0]) {
-		return false
+		return &InvalidPathError{Path: importPath, Reason: "invalid host"}
 	}
 
 	for _, part := range parts[1:] {
 		if !isValidPathElement(part) {
-			return false
+			return &InvalidPathError{Path: importPath, Reason: fmt.Sprintf("invalid path element %q", part)}
 		}
 	}
 
-	return true
+	return nil
 }
 
 // IsGoRepoPath returns true if path is in $GOROOT/src.
@@ -54,3 +60,86 @@ func IsValidPath(importPath string) bool {
 		pathFlags["vendor/"+importPath]&packagePath != 0 ||
 		IsValidRemotePath(importPath)
 }
+
+// gitSuffixHosts are the hosts that serve a repository at the same path with
+// or without the ".git" suffix.
+var gitSuffixHosts = map[string]bool{
+	"github.com":    true,
+	"bitbucket.org": true,
+}
+
+// CanonicalPath returns the canonical spelling of importPath. Repeated and
+// trailing slashes are removed, as is the ".git" suffix of the repository
+// name on hosts where the suffix is optional. Import paths are case
+// sensitive, so the case is not changed.
+func CanonicalPath(importPath string) string {
+	p := strings.Trim(path.Clean("/"+importPath), "/")
+	parts := strings.SplitN(p, "/", 4)
+	if len(parts) >= 3 && gitSuffixHosts[parts[0]] {
+		if repo := strings.TrimSuffix(parts[2], ".git"); repo != "" {
+			parts[2] = repo
+		}
+		p = strings.Join(parts, "/")
+	}
+	return p
+}
+
+// InvalidPathError is returned by ParseImportPath for import paths that are
+// not structurally valid.
+type InvalidPathError struct {
+	Path   string
+	Reason string
+}
+
+func (e *InvalidPathError) Error() string {
+	return fmt.Sprintf("invalid import path %q: %s", e.Path, e.Reason)
+}
+
+// ImportPath is the result of parsing an import path with ParseImportPath.
+type ImportPath struct {
+	// Canonical spelling of the import path.
+	Path string
+
+	// Host of the repository, or "" for a standard package.
+	Host string
+
+	// Import path of the repository root, or "" if the root cannot be
+	// determined without fetching the go-import meta tag from the host.
+	Repo string
+
+	// Directory of the package in the repository. Dir is only set when
+	// Repo is.
+	Dir string
+}
+
+// ParseImportPath validates importPath and splits its canonical spelling, as
+// returned by CanonicalPath, into the host, repository root and package
+// directory. The repository root is known for paths on the hosting services
+// supported by this package and for paths with a VCS suffix such as ".git".
+// An *InvalidPathError is returned for invalid paths.
+func ParseImportPath(importPath string) (*ImportPath, error) {
+	p := CanonicalPath(importPath)
+	if p == "" {
+		return nil, &InvalidPathError{Path: importPath, Reason: "empty path"}
+	}
+	if IsGoRepoPath(p) {
+		return &ImportPath{Path: p}, nil
+	}
+	if err := checkRemotePath(p); err != nil {
+		return nil, err
+	}
+	ip := &ImportPath{Path: p, Host: strings.SplitN(p, "/", 2)[0]}
+	for _, s := range services {
+		match, err := s.match(p)
+		if err != nil {
+			return nil, &InvalidPathError{Path: importPath, Reason: "path does not match the repository layout of " + strings.TrimSuffix(s.prefix, "/")}
+		}
+		if match != nil {
+			dir := match["dir"]
+			ip.Repo = p[:len(p)-len(dir)]
+			ip.Dir = strings.Trim(dir, "/")
+			break
+		}
+	}
+	return ip, nil
+}
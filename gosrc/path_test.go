@@ -8,6 +8,8 @@ package gosrc
 
 import (
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 var goodImportPaths = []string{
@@ -52,3 +54,68 @@ func TestIsValidRemotePath(t *testing.T) {
 		}
 	}
 }
+
+func TestCanonicalPath(t *testing.T) {
+	for _, tt := range []struct {
+		importPath, want string
+	}{
+		{"github.com/user/repo", "github.com/user/repo"},
+		{"github.com/user/repo/", "github.com/user/repo"},
+		{"github.com//user/repo//sub/", "github.com/user/repo/sub"},
+		{"github.com/user/repo.git", "github.com/user/repo"},
+		{"github.com/user/repo.git/sub", "github.com/user/repo/sub"},
+		{"bitbucket.org/user/repo.git", "bitbucket.org/user/repo"},
+		// Only the repository element has an optional suffix.
+		{"github.com/user/repo/sub.git", "github.com/user/repo/sub.git"},
+		{"github.com/user.git/repo", "github.com/user.git/repo"},
+		{"github.com/user/.git", "github.com/user/.git"},
+		// The suffix selects the VCS on other hosts.
+		{"example.com/foo.git", "example.com/foo.git"},
+		{"example.com/foo.git/bar/", "example.com/foo.git/bar"},
+		// Import paths are case sensitive.
+		{"github.com/User/Repo", "github.com/User/Repo"},
+		{"github.com/user/repo.GIT", "github.com/user/repo.GIT"},
+		{"", ""},
+	} {
+		if got := CanonicalPath(tt.importPath); got != tt.want {
+			t.Errorf("CanonicalPath(%q) = %q, want %q", tt.importPath, got, tt.want)
+		}
+	}
+}
+
+func TestParseImportPath(t *testing.T) {
+	for _, tt := range []struct {
+		importPath string
+		want       *ImportPath
+	}{
+		{"net/http", &ImportPath{Path: "net/http"}},
+		{"github.com/user/repo", &ImportPath{Path: "github.com/user/repo", Host: "github.com", Repo: "github.com/user/repo"}},
+		{"github.com/user/repo.git/a/b/", &ImportPath{Path: "github.com/user/repo/a/b", Host: "github.com", Repo: "github.com/user/repo", Dir: "a/b"}},
+		{"bitbucket.org/user/repo/sub", &ImportPath{Path: "bitbucket.org/user/repo/sub", Host: "bitbucket.org", Repo: "bitbucket.org/user/repo", Dir: "sub"}},
+		{"launchpad.net/~user/+junk/version/sub", &ImportPath{Path: "launchpad.net/~user/+junk/version/sub", Host: "launchpad.net", Repo: "launchpad.net/~user/+junk/version", Dir: "sub"}},
+		{"example.com/foo.git/bar", &ImportPath{Path: "example.com/foo.git/bar", Host: "example.com", Repo: "example.com/foo.git", Dir: "bar"}},
+		{"example.com/foo/bar", &ImportPath{Path: "example.com/foo/bar", Host: "example.com"}},
+		{"exampleproject.com/unicode/испытание", &ImportPath{Path: "exampleproject.com/unicode/испытание", Host: "exampleproject.com"}},
+		{"", nil},
+		{"foobar", nil},
+		{"favicon.ico", nil},
+		{"Example.com/foo", nil},
+		{"github.com/user/repo/.ignore/x", nil},
+		{"github.com/user", nil},
+	} {
+		got, err := ParseImportPath(tt.importPath)
+		if tt.want == nil {
+			if _, ok := err.(*InvalidPathError); !ok {
+				t.Errorf("ParseImportPath(%q) = %+v, %v, want *InvalidPathError", tt.importPath, got, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseImportPath(%q) returned error %v", tt.importPath, err)
+			continue
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("ParseImportPath(%q) mismatch (-want +got):\n%s", tt.importPath, diff)
+		}
+	}
+}
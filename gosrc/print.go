@@ -67,6 +67,8 @@ func printDir(path string) {
 	fmt.Println("ProjectRoot   ", dir.ProjectRoot)
 	fmt.Println("ProjectName   ", dir.ProjectName)
 	fmt.Println("ProjectURL    ", dir.ProjectURL)
+	fmt.Println("RepoURL       ", dir.RepoURL)
+	fmt.Println("RepoDir       ", dir.RepoDir)
 	fmt.Println("VCS           ", dir.VCS)
 	fmt.Println("Etag          ", dir.Etag)
 	fmt.Println("BrowseURL     ", dir.BrowseURL)
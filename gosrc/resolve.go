@@ -0,0 +1,176 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Source describes where the source of a directory is hosted.
+type Source struct {
+	// The import path of the directory.
+	ImportPath string
+
+	// Import path prefix for all packages in the project.
+	ProjectRoot string
+
+	// Import path of the directory after resolving go-import meta tags, if
+	// any.
+	ResolvedPath string
+
+	// Version control system: git, hg, bzr, ...
+	VCS string
+
+	// Host of the repository.
+	Host string
+
+	// URL of the repository.
+	RepoURL string
+
+	// Slash separated path of the directory relative to the root of the
+	// repository, or "" for the root.
+	RepoDir string
+
+	// Default branch of the repository. Optional.
+	DefaultBranch string
+
+	// Location of the directory on the version control service website,
+	// expanded from DirTemplate. Optional.
+	BrowseURL string
+
+	// Templates of the locations of directories and files of the repository
+	// on the version control service website, in the format of the go-source
+	// meta tag: {dir} and {/dir} are replaced with the directory relative to
+	// the root of the repository, {file} with the file name and {line} with
+	// the line number. Optional.
+	DirTemplate  string
+	FileTemplate string
+}
+
+// errNoSource is returned by getStaticSource for services that do not
+// support source lookups.
+var errNoSource = errors.New("no source lookup")
+
+// GetSource resolves importPath to the location of its source without
+// fetching the files of the directory.
+func GetSource(ctx context.Context, client *http.Client, importPath string) (*Source, error) {
+	if err := checkExcludedPath(importPath); err != nil {
+		return nil, err
+	}
+	var src *Source
+	var err error
+	switch {
+	case localModuleDir(importPath) != "" || localPath != "":
+		return nil, NotFoundError{Message: "no remote source for local packages."}
+	case IsGoRepoPath(importPath):
+		src = getStandardSource(importPath)
+	case IsValidRemotePath(importPath):
+		if err := checkAllowedHost(importPath); err != nil {
+			return nil, err
+		}
+		src, err = getStaticSource(ctx, client, importPath)
+		if err == errNoMatch {
+			src, err = getDynamicSource(ctx, client, importPath)
+		}
+	default:
+		err = errNoMatch
+	}
+	switch err {
+	case errNoMatch:
+		err = NotFoundError{Message: "Import path not valid:"}
+	case errNoSource:
+		err = NotFoundError{Message: "source lookup is not supported for " + importPath}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if src.DirTemplate != "" {
+		src.BrowseURL = replaceDir(src.DirTemplate, src.RepoDir)
+	}
+	return src, nil
+}
+
+func getStandardSource(importPath string) *Source {
+	const repoURL = "https://go.googlesource.com/go"
+	return &Source{
+		ImportPath:    importPath,
+		ResolvedPath:  importPath,
+		VCS:           "git",
+		Host:          "go.googlesource.com",
+		RepoURL:       repoURL,
+		RepoDir:       "src/" + importPath,
+		DefaultBranch: "master",
+		DirTemplate:   repoURL + "/+/refs/heads/master{/dir}",
+		FileTemplate:  repoURL + "/+/refs/heads/master{/dir}/{file}#{line}",
+	}
+}
+
+// getStaticSource resolves an import path of a statically known service.
+// getStaticSource returns errNoMatch if the import path is not recognized and
+// errNoSource if the service does not support source lookups.
+func getStaticSource(ctx context.Context, client *http.Client, importPath string) (*Source, error) {
+	for _, s := range services {
+		match, err := s.match(importPath)
+		if err != nil {
+			return nil, err
+		}
+		if match == nil {
+			continue
+		}
+		if s.getSource == nil {
+			return nil, errNoSource
+		}
+		src, err := s.getSource(ctx, client, match)
+		if err != nil {
+			return nil, err
+		}
+		src.ImportPath = importPath
+		src.ResolvedPath = importPath
+		src.RepoDir = strings.Trim(match["dir"], "/")
+		return src, nil
+	}
+	return nil, errNoMatch
+}
+
+// getDynamicSource resolves an import path of a service that is not
+// statically known using its go-import and go-source meta tags.
+func getDynamicSource(ctx context.Context, client *http.Client, importPath string) (*Source, error) {
+	_, im, sm, _, err := fetchProjectMeta(ctx, client, importPath)
+	if err != nil {
+		return nil, err
+	}
+	_, _, repo, err := metaRepo(im)
+	if err != nil {
+		return nil, err
+	}
+	dirName := importPath[len(im.projectRoot):]
+
+	src, err := getStaticSource(ctx, client, repo+dirName)
+	if err == errNoMatch || err == errNoSource {
+		src, err = &Source{VCS: im.vcs, Host: pathHost(repo), RepoURL: im.repo}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	src.ImportPath = importPath
+	src.ProjectRoot = im.projectRoot
+	src.ResolvedPath = repo + dirName
+	src.RepoDir = strings.Trim(dirName, "/")
+
+	if sm != nil {
+		if isHTTPURL(sm.dirTemplate) {
+			src.DirTemplate = sm.dirTemplate
+		}
+		if isHTTPURL(sm.fileTemplate) {
+			src.FileTemplate = sm.fileTemplate
+		}
+	}
+	return src, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var getSourceTests = []struct {
+	importPath string
+	src        *Source
+}{
+	{"github.com/alice/pkg/sub", &Source{
+		ImportPath:    "github.com/alice/pkg/sub",
+		ProjectRoot:   "github.com/alice/pkg",
+		ResolvedPath:  "github.com/alice/pkg/sub",
+		VCS:           "git",
+		Host:          "github.com",
+		RepoURL:       "https://github.com/alice/pkg",
+		RepoDir:       "sub",
+		DefaultBranch: "main",
+		BrowseURL:     "https://github.com/alice/pkg/tree/main/sub",
+		DirTemplate:   "https://github.com/alice/pkg/tree/main{/dir}",
+		FileTemplate:  "https://github.com/alice/pkg/blob/main{/dir}/{file}#L{line}",
+	}},
+	{"alice.org/pkg/a/b/c", &Source{
+		ImportPath:    "alice.org/pkg/a/b/c",
+		ProjectRoot:   "alice.org/pkg",
+		ResolvedPath:  "github.com/alice/pkg/a/b/c",
+		VCS:           "git",
+		Host:          "github.com",
+		RepoURL:       "https://github.com/alice/pkg",
+		RepoDir:       "a/b/c",
+		DefaultBranch: "main",
+		BrowseURL:     "https://github.com/alice/pkg/tree/main/a/b/c",
+		DirTemplate:   "https://github.com/alice/pkg/tree/main{/dir}",
+		FileTemplate:  "https://github.com/alice/pkg/blob/main{/dir}/{file}#L{line}",
+	}},
+	{"alice.org/pkg/source", &Source{
+		ImportPath:    "alice.org/pkg/source",
+		ProjectRoot:   "alice.org/pkg",
+		ResolvedPath:  "github.com/alice/pkg/source",
+		VCS:           "git",
+		Host:          "github.com",
+		RepoURL:       "https://github.com/alice/pkg",
+		RepoDir:       "source",
+		DefaultBranch: "main",
+		BrowseURL:     "http://alice.org/pkg/source",
+		DirTemplate:   "http://alice.org/pkg{/dir}",
+		FileTemplate:  "http://alice.org/pkg{/dir}?f={file}#Line{line}",
+	}},
+	{"bob.com/pkg/sub", &Source{
+		ImportPath:   "bob.com/pkg/sub",
+		ProjectRoot:  "bob.com/pkg",
+		ResolvedPath: "vcs.net/bob/pkg/sub",
+		VCS:          "git",
+		Host:         "vcs.net",
+		RepoURL:      "https://vcs.net/bob/pkg.git",
+		RepoDir:      "sub",
+	}},
+	{"bob.com/pkg/source", &Source{
+		ImportPath:   "bob.com/pkg/source",
+		ProjectRoot:  "bob.com/pkg",
+		ResolvedPath: "vcs.net/bob/pkg/source",
+		VCS:          "git",
+		Host:         "vcs.net",
+		RepoURL:      "https://vcs.net/bob/pkg.git",
+		RepoDir:      "source",
+		BrowseURL:    "http://bob.com/pkg/source/",
+		DirTemplate:  "http://bob.com/pkg{/dir}/",
+		FileTemplate: "http://bob.com/pkg{/dir}/?f={file}#Line{line}",
+	}},
+	{"net/http", &Source{
+		ImportPath:    "net/http",
+		ResolvedPath:  "net/http",
+		VCS:           "git",
+		Host:          "go.googlesource.com",
+		RepoURL:       "https://go.googlesource.com/go",
+		RepoDir:       "src/net/http",
+		DefaultBranch: "master",
+		BrowseURL:     "https://go.googlesource.com/go/+/refs/heads/master/src/net/http",
+		DirTemplate:   "https://go.googlesource.com/go/+/refs/heads/master{/dir}",
+		FileTemplate:  "https://go.googlesource.com/go/+/refs/heads/master{/dir}/{file}#{line}",
+	}},
+	{"alice.org/pkg/mismatch", nil},
+	{"github.com/bob/missing", nil},
+	{"carol.org/pkg", nil},
+}
+
+func TestGetSource(t *testing.T) {
+	SetInsecureHosts([]string{"alice.org"})
+	defer SetInsecureHosts(nil)
+	web := testTransport{"https://api.github.com/repos/alice/pkg": `{"default_branch": "main"}`}
+	for k, v := range testWeb {
+		web[k] = v
+	}
+	client := &http.Client{Transport: web}
+
+	for _, tt := range getSourceTests {
+		src, err := GetSource(context.Background(), client, tt.importPath)
+		if tt.src == nil {
+			if err == nil {
+				t.Errorf("GetSource(ctx, client, %q) did not return expected error", tt.importPath)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("GetSource(ctx, client, %q) returned unexpected error: %v", tt.importPath, err)
+			continue
+		}
+		if diff := cmp.Diff(tt.src, src); diff != "" {
+			t.Errorf("GetSource(ctx, client, %q) mismatch (-want +got):\n%s", tt.importPath, diff)
+		}
+	}
+}
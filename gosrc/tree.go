@@ -0,0 +1,92 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxTreeDepth is the number of levels of the directory tree of a
+	// repository returned in Directory.Tree.
+	maxTreeDepth = 4
+
+	// maxTreeSize is the number of directories returned in Directory.Tree.
+	// The deepest directories are dropped from larger trees.
+	maxTreeSize = 500
+)
+
+// isTreeDir reports whether the directory with the given name is included
+// in the directory tree of a repository. Directories ignored by the go
+// command are excluded.
+func isTreeDir(name string) bool {
+	return isValidPathElement(name) && name != "testdata" &&
+		!strings.HasPrefix(name, ".") && !strings.HasPrefix(name, "_")
+}
+
+// treeDirs returns the slash separated paths of directories relative to the
+// root of a repository bounded to maxTreeDepth levels and maxTreeSize
+// directories, sorted.
+func treeDirs(paths []string) []string {
+	depth := func(p string) int { return strings.Count(p, "/") + 1 }
+	var dirs []string
+Paths:
+	for _, p := range paths {
+		if p == "" || depth(p) > maxTreeDepth {
+			continue
+		}
+		for _, elem := range strings.Split(p, "/") {
+			if !isTreeDir(elem) {
+				continue Paths
+			}
+		}
+		dirs = append(dirs, p)
+	}
+	if len(dirs) > maxTreeSize {
+		sort.Slice(dirs, func(i, j int) bool {
+			if di, dj := depth(dirs[i]), depth(dirs[j]); di != dj {
+				return di < dj
+			}
+			return dirs[i] < dirs[j]
+		})
+		dirs = dirs[:maxTreeSize]
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// walkTree returns the directory tree of the repository checked out at
+// root. Directories for which include returns false are skipped along with
+// their subdirectories.
+func walkTree(root string, include func(dir string) bool) ([]string, error) {
+	var paths []string
+	err := filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() || p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !isTreeDir(fi.Name()) || strings.Count(rel, "/") >= maxTreeDepth || include != nil && !include(p) {
+			return filepath.SkipDir
+		}
+		paths = append(paths, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return treeDirs(paths), nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package gosrc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTreeDirs(t *testing.T) {
+	got := treeDirs([]string{"b", "a", "a/testdata", "a/b/c/d", "a/b/c/d/e", ".git", "_x/y", "a/b", "a/b/c"})
+	want := []string{"a", "a/b", "a/b/c", "a/b/c/d", "b"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("treeDirs mismatch (-want +got):\n%s", diff)
+	}
+
+	var paths []string
+	for i := 0; i < maxTreeSize; i++ {
+		paths = append(paths, "deep/d"+strconv.Itoa(i))
+	}
+	paths = append(paths, "top")
+	got = treeDirs(paths)
+	if len(got) != maxTreeSize {
+		t.Errorf("len(treeDirs) = %d, want %d", len(got), maxTreeSize)
+	}
+	if got[len(got)-1] != "top" {
+		t.Errorf("treeDirs dropped top level directory")
+	}
+}
+
+func TestWalkTree(t *testing.T) {
+	root, err := ioutil.TempDir("", "gosrc-tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	for _, d := range []string{"a/b", "a/testdata/x", "c", ".git/objects", "m/sub"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(d)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := walkTree(root, func(dir string) bool { return !strings.HasSuffix(dir, "m") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a", "a/b", "c"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("walkTree mismatch (-want +got):\n%s", diff)
+	}
+}
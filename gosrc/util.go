@@ -48,7 +48,11 @@ func expand(template string, match map[string]string, subs ...string) string {
 	return string(p)
 }
 
-var readmePat = regexp.MustCompile(`(?i)^readme(?:$|\.)`)
+var readmePat = regexp.MustCompile(`(?i)^readme(?:$|[._-])`)
+
+// changelogPat matches the names of changelog files, such as CHANGELOG.md,
+// CHANGES, HISTORY.rst, NEWS.txt or RELEASE-NOTES.md.
+var changelogPat = regexp.MustCompile(`(?i)^(?:change[_-]?log|changes|history|news|release[_-]?notes)(?:\.(?:md|markdown|rst|txt))?$`)
 
 // isDocFile returns true if a file with name n should be included in the
 // documentation.
@@ -56,7 +60,13 @@ func isDocFile(n string) bool {
 	if strings.HasSuffix(n, ".go") && n[0] != '_' && n[0] != '.' {
 		return true
 	}
-	return readmePat.MatchString(n)
+	return n == "go.mod" || n == "CODEOWNERS" || readmePat.MatchString(n) || changelogPat.MatchString(n)
+}
+
+// isAsmFile returns true if a file with name n is an assembly file of the
+// package. Assembly files are listed but not fetched.
+func isAsmFile(n string) bool {
+	return strings.HasSuffix(n, ".s") && n[0] != '_' && n[0] != '.'
 }
 
 var linePat = regexp.MustCompile(`(?m)^//line .*$`)
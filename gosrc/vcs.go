@@ -124,7 +124,7 @@ func downloadGit(schemes []string, clonePath, repo, savedEtag string) (string, s
 	var scheme string
 	for i := range schemes {
 		cmd := exec.Command("git", "ls-remote", "--heads", "--tags", schemes[i]+"://"+clonePath)
-		log.Println(strings.Join(cmd.Args, " "))
+		log.Println("DEBUG", strings.Join(cmd.Args, " "))
 		var err error
 		p, err = outputWithTimeout(cmd, lsRemoteTimeout)
 		if err == nil {
@@ -161,7 +161,7 @@ func downloadGit(schemes []string, clonePath, repo, savedEtag string) (string, s
 			return "", "", err
 		}
 		cmd := exec.Command("git", "clone", scheme+"://"+clonePath, dir)
-		log.Println(strings.Join(cmd.Args, " "))
+		log.Println("DEBUG", strings.Join(cmd.Args, " "))
 		if err := runWithTimeout(cmd, cloneTimeout); err != nil {
 			return "", "", err
 		}
@@ -169,7 +169,7 @@ func downloadGit(schemes []string, clonePath, repo, savedEtag string) (string, s
 		return tag, etag, nil
 	default:
 		cmd := exec.Command("git", "fetch")
-		log.Println(strings.Join(cmd.Args, " "))
+		log.Println("DEBUG", strings.Join(cmd.Args, " "))
 		cmd.Dir = dir
 		if err := runWithTimeout(cmd, fetchTimeout); err != nil {
 			return "", "", err
@@ -215,13 +215,13 @@ func downloadSVN(schemes []string, clonePath, repo, savedEtag string) (string, s
 			return "", "", err
 		}
 		cmd := exec.Command("svn", "checkout", scheme+"://"+clonePath, "-r", revno, dir)
-		log.Println(strings.Join(cmd.Args, " "))
+		log.Println("DEBUG", strings.Join(cmd.Args, " "))
 		if err := runWithTimeout(cmd, cloneTimeout); err != nil {
 			return "", "", err
 		}
 	case localRevno != revno:
 		cmd := exec.Command("svn", "update", "-r", revno)
-		log.Println(strings.Join(cmd.Args, " "))
+		log.Println("DEBUG", strings.Join(cmd.Args, " "))
 		cmd.Dir = dir
 		if err := runWithTimeout(cmd, fetchTimeout); err != nil {
 			return "", "", err
@@ -235,7 +235,7 @@ var svnrevRe = regexp.MustCompile(`(?m)^Last Changed Rev: ([0-9]+)$`)
 
 func getSVNRevision(target string) (string, error) {
 	cmd := exec.Command("svn", "info", target)
-	log.Println(strings.Join(cmd.Args, " "))
+	log.Println("DEBUG", strings.Join(cmd.Args, " "))
 	out, err := outputWithTimeout(cmd, lsRemoteTimeout)
 	if err != nil {
 		return "", err
@@ -247,6 +247,17 @@ func getSVNRevision(target string) (string, error) {
 	return "", NotFoundError{Message: "Last changed revision not found"}
 }
 
+// secureSchemes returns the schemes of encrypted protocols in schemes.
+func secureSchemes(schemes []string) []string {
+	var result []string
+	for _, s := range schemes {
+		if s == "https" || s == "ssh" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
 func getVCSDir(ctx context.Context, client *http.Client, match map[string]string, etagSaved string) (*Directory, error) {
 	cmd := vcsCmds[match["vcs"]]
 	if cmd == nil {
@@ -278,6 +289,16 @@ func getVCSDir(ctx context.Context, client *http.Client, match map[string]string
 		clonePath = match["repo"]
 	}
 
+	if isInsecureHost(clonePath) {
+		log.Printf("WARNING: %s may be fetched over an insecure protocol", clonePath)
+	} else if schemes = secureSchemes(schemes); len(schemes) == 0 {
+		if match["scheme"] != "" {
+			return nil, NotFoundError{Message: "insecure scheme " + scheme + " not allowed for " + pathHost(clonePath)}
+		}
+		// Ignore the insecure scheme of the saved etag.
+		schemes = secureSchemes(cmd.schemes)
+	}
+
 	// Download and checkout.
 
 	tag, etag, err := cmd.download(schemes, clonePath, match["repo"], etagSaved)
@@ -291,7 +312,8 @@ func getVCSDir(ctx context.Context, client *http.Client, match map[string]string
 
 	// Slurp source files.
 
-	d := filepath.Join(TempDir, filepath.FromSlash(expand("{repo}.{vcs}", match)), filepath.FromSlash(match["dir"]))
+	root := filepath.Join(TempDir, filepath.FromSlash(expand("{repo}.{vcs}", match)))
+	d := filepath.Join(root, filepath.FromSlash(match["dir"]))
 	f, err := os.Open(d)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -314,12 +336,15 @@ func getVCSDir(ctx context.Context, client *http.Client, match map[string]string
 
 	var files []*File
 	var subdirs []string
+	var asmFiles []string
 	for _, fi := range fis {
 		switch {
 		case fi.IsDir():
 			if isValidPathElement(fi.Name()) {
 				subdirs = append(subdirs, fi.Name())
 			}
+		case isAsmFile(fi.Name()):
+			asmFiles = append(asmFiles, fi.Name())
 		case isDocFile(fi.Name()):
 			b, err := ioutil.ReadFile(filepath.Join(d, fi.Name()))
 			if err != nil {
@@ -333,16 +358,25 @@ func getVCSDir(ctx context.Context, client *http.Client, match map[string]string
 		}
 	}
 
+	tree, err := walkTree(root, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Directory{
 		LineFmt:        template.line,
 		ProjectRoot:    expand("{repo}.{vcs}", match),
 		ProjectName:    path.Base(match["repo"]),
 		ProjectURL:     expand(template.project, urlMatch),
+		RepoURL:        expand(template.project, urlMatch),
+		RepoDir:        strings.Trim(match["dir"], "/"),
 		BrowseURL:      "",
 		Etag:           etag,
 		VCS:            match["vcs"],
 		Subdirectories: subdirs,
+		Tree:           tree,
 		Files:          files,
+		AsmFiles:       asmFiles,
 	}, nil
 }
 
@@ -11,6 +11,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"path"
 	"strings"
 	"sync"
 )
@@ -93,3 +94,73 @@ func (cb *CacheBusters) AppendQueryParam(path string, name string) string {
 	}
 	return path + "?" + name + "=" + token
 }
+
+// Fingerprint inserts the token in the file name of path before the
+// extension, for example "/-/site.css" becomes "/-/site.<token>.css". Paths
+// with a token that is not a hexadecimal digest, such as one computed from
+// the Last-Modified header, get the token as the "v" query parameter
+// instead. Use FingerprintHandler to serve the fingerprinted paths.
+func (cb *CacheBusters) Fingerprint(p string) string {
+	token := cb.Get(p)
+	if token == "" {
+		return p
+	}
+	if !isFingerprint(token) {
+		return p + "?v=" + token
+	}
+	ext := path.Ext(p)
+	return p[:len(p)-len(ext)] + "." + token + ext
+}
+
+func isFingerprint(s string) bool {
+	if len(s) < 8 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !('0' <= s[i] && s[i] <= '9' || 'a' <= s[i] && s[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// FingerprintHandler serves paths created by CacheBusters.Fingerprint with
+// the handler of CacheBusters. The fingerprint is removed from the path and
+// passed to the handler as the "v" query parameter so that static files are
+// served as immutable. Requests for paths with an outdated fingerprint are
+// redirected to the current one, so that only the current content is served
+// as immutable. Requests for paths without a fingerprint get a not found
+// error.
+type FingerprintHandler struct {
+	CacheBusters *CacheBusters
+}
+
+func (h FingerprintHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	dir, file := path.Split(r.URL.Path)
+	ext := path.Ext(file)
+	base := file[:len(file)-len(ext)]
+	i := strings.LastIndexByte(base, '.')
+	if i < 0 || !isFingerprint(base[i+1:]) {
+		http.NotFound(w, r)
+		return
+	}
+	p := dir + base[:i] + ext
+	token := h.CacheBusters.Get(p)
+	if token == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if token != base[i+1:] {
+		http.Redirect(w, r, h.CacheBusters.Fingerprint(p), http.StatusFound)
+		return
+	}
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = p
+	r2.URL.RawPath = ""
+	r2.URL.RawQuery = url.Values{"v": {token}}.Encode()
+	r2.Form = nil
+	h.CacheBusters.Handler.ServeHTTP(w, r2)
+}
@@ -8,6 +8,8 @@ package httputil
 
 import (
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -27,3 +29,42 @@ func TestCacheBusters(t *testing.T) {
 		t.Errorf("could not extract token from StaticServer FileHandler")
 	}
 }
+
+func TestFingerprint(t *testing.T) {
+	var ss StaticServer
+	mux := http.NewServeMux()
+	cbs := &CacheBusters{Handler: mux}
+	mux.Handle("/-/site.css", ss.FileHandler("buster_test.go"))
+	mux.Handle("/-/", FingerprintHandler{CacheBusters: cbs})
+	token := cbs.Get("/-/site.css")
+
+	p := cbs.Fingerprint("/-/site.css")
+	if want := "/-/site." + token + ".css"; p != want {
+		t.Fatalf("Fingerprint returned %q, want %q", p, want)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", p, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s returned status %d", p, w.Code)
+	}
+	if cc := w.Header().Get("Cache-Control"); !strings.Contains(cc, "immutable") {
+		t.Errorf("GET %s returned Cache-Control %q, want immutable", p, cc)
+	}
+
+	for _, p := range []string{"/-/site.xyz.css", "/-/other.css", "/-/other." + token + ".css"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", p, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("GET %s returned status %d, want %d", p, w.Code, http.StatusNotFound)
+		}
+	}
+
+	// A page rendered before the file changed links to the old content.
+	old := "/-/site.0123456789abcdef.css"
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", old, nil))
+	if w.Code != http.StatusFound || w.Header().Get("Location") != p {
+		t.Errorf("GET %s returned status %d, Location %q, want %d, %q", old, w.Code, w.Header().Get("Location"), http.StatusFound, p)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+// This file implements a http.RoundTripper that limits the number of
+// concurrent requests to each host.
+
+package httputil
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// HostLimitTransport is an implementation of http.RoundTripper that limits
+// the number of requests in flight to each host. Requests beyond the limit
+// wait until a request to the same host completes or the context of the
+// request is done. A request is in flight until its response headers are
+// received, so that a caller slow to read or close the body does not hold
+// up other requests to the host.
+type HostLimitTransport struct {
+	// Maximum number of requests in flight to a host. Zero means no limit.
+	MaxPerHost int
+	Base       http.RoundTripper
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimit
+}
+
+type hostLimit struct {
+	sem     chan struct{}
+	waiting int
+	refs    int // requests in flight or waiting
+}
+
+// HostStats holds the number of requests to a host.
+type HostStats struct {
+	InFlight int `json:"in_flight"`
+	Waiting  int `json:"waiting"`
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *HostLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.MaxPerHost <= 0 {
+		return t.base().RoundTrip(req)
+	}
+	host := strings.ToLower(req.URL.Hostname())
+	l := t.acquire(host)
+	select {
+	case l.sem <- struct{}{}:
+		t.mu.Lock()
+		l.waiting--
+		t.mu.Unlock()
+	case <-req.Context().Done():
+		t.mu.Lock()
+		l.waiting--
+		t.mu.Unlock()
+		t.release(host, l, false)
+		return nil, req.Context().Err()
+	}
+	resp, err := t.base().RoundTrip(req)
+	t.release(host, l, true)
+	return resp, err
+}
+
+func (t *HostLimitTransport) acquire(host string) *hostLimit {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.hosts == nil {
+		t.hosts = make(map[string]*hostLimit)
+	}
+	l := t.hosts[host]
+	if l == nil {
+		l = &hostLimit{sem: make(chan struct{}, t.MaxPerHost)}
+		t.hosts[host] = l
+	}
+	l.refs++
+	l.waiting++
+	return l
+}
+
+func (t *HostLimitTransport) release(host string, l *hostLimit, inFlight bool) {
+	if inFlight {
+		<-l.sem
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l.refs--
+	if l.refs == 0 {
+		delete(t.hosts, host)
+	}
+}
+
+// Stats returns the number of requests in flight and waiting for each host
+// with at least one request.
+func (t *HostLimitTransport) Stats() map[string]HostStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := make(map[string]HostStats, len(t.hosts))
+	for host, l := range t.hosts {
+		stats[host] = HostStats{InFlight: l.refs - l.waiting, Waiting: l.waiting}
+	}
+	return stats
+}
+
+// CancelRequest cancels an in-flight request by closing its connection.
+func (t *HostLimitTransport) CancelRequest(req *http.Request) {
+	type canceler interface {
+		CancelRequest(req *http.Request)
+	}
+	if cr, ok := t.base().(canceler); ok {
+		cr.CancelRequest(req)
+	}
+}
+
+func (t *HostLimitTransport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package httputil
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingTransport responds to requests when unblocked.
+type blockingTransport struct {
+	started chan string
+	unblock chan struct{}
+}
+
+func (t *blockingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.started <- req.URL.Host
+	<-t.unblock
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("ok"))}, nil
+}
+
+func TestHostLimitTransport(t *testing.T) {
+	base := &blockingTransport{started: make(chan string, 10), unblock: make(chan struct{})}
+	tr := &HostLimitTransport{MaxPerHost: 2, Base: base}
+
+	var wg sync.WaitGroup
+	get := func(url string) {
+		defer wg.Done()
+		req, _ := http.NewRequest("GET", url, nil)
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp.Body.Close()
+	}
+	wg.Add(4)
+	go get("https://a.example.com/1")
+	go get("https://a.example.com/2")
+	go get("https://a.example.com/3")
+	go get("https://b.example.com/1")
+	hosts := map[string]int{}
+	for i := 0; i < 3; i++ {
+		hosts[<-base.started]++
+	}
+	if hosts["a.example.com"] != 2 || hosts["b.example.com"] != 1 {
+		t.Fatalf("started requests = %v, want 2 to a.example.com and 1 to b.example.com", hosts)
+	}
+	// Wait for the third request to a.example.com to queue.
+	want := HostStats{InFlight: 2, Waiting: 1}
+	var stats map[string]HostStats
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if stats = tr.Stats(); stats["a.example.com"] == want {
+			break
+		}
+	}
+	if stats["a.example.com"] != want {
+		t.Errorf("Stats()[a.example.com] = %+v, want %+v", stats["a.example.com"], want)
+	}
+
+	// A waiting request fails when its context is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://a.example.com/4", nil)
+	cancel()
+	if _, err := tr.RoundTrip(req); err != context.Canceled {
+		t.Errorf("RoundTrip with canceled context returned error %v, want %v", err, context.Canceled)
+	}
+
+	close(base.unblock)
+	<-base.started
+	wg.Wait()
+
+	// A response with an unread body does not hold up the host.
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "https://a.example.com/5", nil)
+		if _, err := tr.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+		<-base.started
+	}
+	if stats := tr.Stats(); len(stats) != 0 {
+		t.Errorf("Stats() after requests completed = %v, want empty", stats)
+	}
+}
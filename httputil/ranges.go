@@ -0,0 +1,125 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package httputil
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// byteRange is a range of bytes of a representation.
+type byteRange struct {
+	start, length int64
+}
+
+func (br byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", br.start, br.start+br.length-1, size)
+}
+
+// parseRange parses the value of a Range header requesting a single range of
+// bytes of a representation of size bytes. The value is ignored, as allowed
+// by RFC 7233, if it is malformed or requests several ranges. The length of
+// the returned range is zero if the range is not satisfiable.
+func parseRange(s string, size int64) (br byteRange, ignored bool) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(s, prefix) {
+		return byteRange{}, true
+	}
+	s = strings.TrimSpace(s[len(prefix):])
+	if strings.Contains(s, ",") {
+		return byteRange{}, true
+	}
+	i := strings.IndexByte(s, '-')
+	if i < 0 {
+		return byteRange{}, true
+	}
+	first, last := strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+	if first == "" {
+		// Suffix range of the last bytes.
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return byteRange{}, true
+		}
+		if n > size {
+			n = size
+		}
+		return byteRange{start: size - n, length: n}, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return byteRange{}, true
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return byteRange{}, true
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	if start >= size {
+		return byteRange{start: start}, false
+	}
+	return byteRange{start: start, length: end - start + 1}, false
+}
+
+// ServeRange writes the representation read from content, of size bytes and
+// with the entity tag etag, in response to r. If the request has a Range
+// header for a single range of bytes and the If-Range precondition, if any,
+// holds for etag, then only that range is written with status 206 or status
+// 416 is written if the range is not satisfiable. Otherwise the whole
+// representation is written with status 200.
+//
+// The caller sets the other headers of the response, such as Content-Type and
+// Etag, before calling ServeRange.
+func ServeRange(w http.ResponseWriter, r *http.Request, etag string, content io.Reader, size int64) {
+	w.Header().Set("Accept-Ranges", "bytes")
+	status := http.StatusOK
+	br := byteRange{length: size}
+	if s := r.Header.Get("Range"); s != "" && ifRange(r, etag) && (r.Method == "GET" || r.Method == "HEAD") {
+		if rbr, ignored := parseRange(s, size); !ignored {
+			if rbr.length == 0 {
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+				http.Error(w, http.StatusText(http.StatusRequestedRangeNotSatisfiable), http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+			status = http.StatusPartialContent
+			br = rbr
+			w.Header().Set("Content-Range", br.contentRange(size))
+		}
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(br.length, 10))
+	w.WriteHeader(status)
+	if r.Method == "HEAD" {
+		return
+	}
+	if br.start > 0 {
+		if _, err := io.CopyN(ioutil.Discard, content, br.start); err != nil {
+			return
+		}
+	}
+	io.CopyN(w, content, br.length)
+}
+
+// ifRange reports whether the If-Range precondition of r holds for a
+// representation with the entity tag etag. The precondition holds if the
+// request has no If-Range header. Dates are not supported because the
+// representations served do not have a modification time, and weak entity
+// tags never match as required by RFC 7233.
+func ifRange(r *http.Request, etag string) bool {
+	v := strings.TrimSpace(r.Header.Get("If-Range"))
+	if v == "" {
+		return true
+	}
+	return etag != "" && !strings.HasPrefix(etag, "W/") && v == etag
+}
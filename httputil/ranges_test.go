@@ -0,0 +1,146 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const rangeContent = "0123456789"
+
+var serveRangeTests = []struct {
+	name    string
+	header  http.Header
+	status  int
+	body    string
+	content string // expected Content-Range header
+}{
+	{
+		name:   "no range",
+		status: http.StatusOK,
+		body:   rangeContent,
+	},
+	{
+		name:    "single range",
+		header:  http.Header{"Range": {"bytes=2-4"}},
+		status:  http.StatusPartialContent,
+		body:    "234",
+		content: "bytes 2-4/10",
+	},
+	{
+		name:    "open range",
+		header:  http.Header{"Range": {"bytes=7-"}},
+		status:  http.StatusPartialContent,
+		body:    "789",
+		content: "bytes 7-9/10",
+	},
+	{
+		name:    "suffix range",
+		header:  http.Header{"Range": {"bytes=-2"}},
+		status:  http.StatusPartialContent,
+		body:    "89",
+		content: "bytes 8-9/10",
+	},
+	{
+		name:    "end past size",
+		header:  http.Header{"Range": {"bytes=8-100"}},
+		status:  http.StatusPartialContent,
+		body:    "89",
+		content: "bytes 8-9/10",
+	},
+	{
+		name:    "unsatisfiable range",
+		header:  http.Header{"Range": {"bytes=10-"}},
+		status:  http.StatusRequestedRangeNotSatisfiable,
+		content: "bytes */10",
+	},
+	{
+		name:   "multiple ranges",
+		header: http.Header{"Range": {"bytes=0-1,4-5"}},
+		status: http.StatusOK,
+		body:   rangeContent,
+	},
+	{
+		name:   "malformed range",
+		header: http.Header{"Range": {"bytes=4-2"}},
+		status: http.StatusOK,
+		body:   rangeContent,
+	},
+	{
+		name:    "if-range match",
+		header:  http.Header{"Range": {"bytes=0-0"}, "If-Range": {`"etag"`}},
+		status:  http.StatusPartialContent,
+		body:    "0",
+		content: "bytes 0-0/10",
+	},
+	{
+		name:   "if-range mismatch",
+		header: http.Header{"Range": {"bytes=0-0"}, "If-Range": {`"other"`}},
+		status: http.StatusOK,
+		body:   rangeContent,
+	},
+	{
+		name:   "if-range date",
+		header: http.Header{"Range": {"bytes=0-0"}, "If-Range": {"Mon, 02 Jan 2006 15:04:05 GMT"}},
+		status: http.StatusOK,
+		body:   rangeContent,
+	},
+}
+
+func TestServeRange(t *testing.T) {
+	for _, tt := range serveRangeTests {
+		r := httptest.NewRequest("GET", "/file", nil)
+		for k, v := range tt.header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		ServeRange(w, r, `"etag"`, strings.NewReader(rangeContent), int64(len(rangeContent)))
+
+		if w.Code != tt.status {
+			t.Errorf("%s: status=%d, want %d", tt.name, w.Code, tt.status)
+		}
+		if got := w.Header().Get("Accept-Ranges"); got != "bytes" {
+			t.Errorf("%s: Accept-Ranges=%q, want %q", tt.name, got, "bytes")
+		}
+		if got := w.Header().Get("Content-Range"); got != tt.content {
+			t.Errorf("%s: Content-Range=%q, want %q", tt.name, got, tt.content)
+		}
+		if tt.status == http.StatusRequestedRangeNotSatisfiable {
+			continue
+		}
+		if got := w.Body.String(); got != tt.body {
+			t.Errorf("%s: body=%q, want %q", tt.name, got, tt.body)
+		}
+	}
+}
+
+func TestStaticRange(t *testing.T) {
+	ss := &StaticServer{}
+	h := ss.FilesHandler("ranges_test.go")
+
+	r := httptest.NewRequest("GET", "/ranges_test.go", nil)
+	r.Header.Set("Range", "bytes=0-6")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "// Copy" {
+		t.Errorf("single range: status=%d body=%q, want %d %q", w.Code, w.Body.String(), http.StatusPartialContent, "// Copy")
+	}
+
+	r = httptest.NewRequest("GET", "/ranges_test.go", nil)
+	r.Header.Set("Range", "bytes=1000000-")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("unsatisfiable range: status=%d, want %d", w.Code, http.StatusRequestedRangeNotSatisfiable)
+	}
+	if got := w.Header().Get("Content-Range"); !strings.HasPrefix(got, "bytes */") {
+		t.Errorf("unsatisfiable range: Content-Range=%q, want bytes */size", got)
+	}
+}
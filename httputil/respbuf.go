@@ -18,10 +18,23 @@ type ResponseBuffer struct {
 	buf    bytes.Buffer
 	status int
 	header http.Header
+
+	w       http.ResponseWriter // written to by Flush, if set
+	flushed bool
+}
+
+// NewResponseBuffer returns a response buffer that writes the response to w
+// when flushed. The response is kept in the buffer until the first call to
+// Flush, so that the owner can still replace it with an error response.
+func NewResponseBuffer(w http.ResponseWriter) *ResponseBuffer {
+	return &ResponseBuffer{w: w}
 }
 
 // Write implements the http.ResponseWriter interface.
 func (rb *ResponseBuffer) Write(p []byte) (int, error) {
+	if rb.flushed {
+		return rb.w.Write(p)
+	}
 	return rb.buf.Write(p)
 }
 
@@ -38,8 +51,42 @@ func (rb *ResponseBuffer) Header() http.Header {
 	return rb.header
 }
 
-// WriteTo implements the io.WriterTo interface.
+// Flush implements the http.Flusher interface. The first call writes the
+// buffered response to the writer of the buffer. Later writes go directly to
+// the writer. Flush does nothing for buffers without a writer.
+func (rb *ResponseBuffer) Flush() {
+	if rb.w == nil {
+		return
+	}
+	if !rb.flushed {
+		rb.flushed = true
+		for k, v := range rb.header {
+			rb.w.Header()[k] = v
+		}
+		if rb.status != 0 {
+			rb.w.WriteHeader(rb.status)
+		}
+		if rb.buf.Len() > 0 {
+			rb.w.Write(rb.buf.Bytes())
+			rb.buf.Reset()
+		}
+	}
+	if f, ok := rb.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Flushed reports whether the response was written by Flush.
+func (rb *ResponseBuffer) Flushed() bool {
+	return rb.flushed
+}
+
+// WriteTo implements the io.WriterTo interface. It does nothing if the
+// response was written by Flush.
 func (rb *ResponseBuffer) WriteTo(w http.ResponseWriter) error {
+	if rb.flushed {
+		return nil
+	}
 	for k, v := range rb.header {
 		w.Header()[k] = v
 	}
@@ -0,0 +1,43 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseBufferFlush(t *testing.T) {
+	w := httptest.NewRecorder()
+	rb := NewResponseBuffer(w)
+	rb.Header().Set("Content-Type", "text/plain")
+	rb.WriteHeader(http.StatusAccepted)
+	rb.Write([]byte("hello "))
+	if w.Body.Len() != 0 || rb.Flushed() {
+		t.Fatalf("response written before Flush: %q", w.Body.String())
+	}
+	rb.Flush()
+	rb.Write([]byte("world"))
+	rb.WriteTo(w)
+	if !rb.Flushed() || !w.Flushed {
+		t.Errorf("Flushed() = %v, recorder flushed = %v, want true, true", rb.Flushed(), w.Flushed)
+	}
+	if w.Code != http.StatusAccepted || w.Header().Get("Content-Type") != "text/plain" || w.Body.String() != "hello world" {
+		t.Errorf("got status %d, content type %q, body %q, want %d, %q, %q", w.Code, w.Header().Get("Content-Type"), w.Body.String(), http.StatusAccepted, "text/plain", "hello world")
+	}
+
+	// Buffers without a writer are not flushed.
+	rb = new(ResponseBuffer)
+	rb.Write([]byte("hello"))
+	rb.Flush()
+	w = httptest.NewRecorder()
+	rb.WriteTo(w)
+	if rb.Flushed() || w.Body.String() != "hello" {
+		t.Errorf("zero buffer: Flushed() = %v, body %q, want false, %q", rb.Flushed(), w.Body.String(), "hello")
+	}
+}
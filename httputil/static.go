@@ -19,7 +19,6 @@ import (
 	"os"
 	"path"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -34,6 +33,12 @@ type StaticServer struct {
 	// headers.
 	MaxAge time.Duration
 
+	// CacheControl specifies the cache control header for requests without a
+	// cache busting token. If CacheControl is empty, then a public max age of
+	// MaxAge is used. Requests with a cache busting token are always cached
+	// for a year as immutable.
+	CacheControl string
+
 	// Error specifies the function used to generate error responses. If Error
 	// is nil, then http.Error is used to generate error responses.
 	Error Error
@@ -228,12 +233,14 @@ func (h *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if maxAge == 0 {
 		maxAge = 24 * time.Hour
 	}
+	cacheControl := h.ss.CacheControl
+	if cacheControl == "" {
+		cacheControl = fmt.Sprintf("public, max-age=%d", maxAge/time.Second)
+	}
 	if r.FormValue("v") != "" {
-		maxAge = 365 * 24 * time.Hour
+		cacheControl = fmt.Sprintf("public, max-age=%d, immutable", 365*24*time.Hour/time.Second)
 	}
 
-	cacheControl := fmt.Sprintf("public, max-age=%d", maxAge/time.Second)
-
 	for _, e := range header.ParseList(r.Header, "If-None-Match") {
 		if e == etag {
 			w.Header().Set("Cache-Control", cacheControl)
@@ -255,11 +262,12 @@ func (h *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if ct != "" {
 		w.Header().Set("Content-Type", ct)
 	}
-	if cl != 0 {
-		w.Header().Set("Content-Length", strconv.FormatInt(cl, 10))
-	}
-	w.WriteHeader(http.StatusOK)
-	if r.Method != "HEAD" {
-		io.Copy(w, rc)
+	if cl <= 0 {
+		w.WriteHeader(http.StatusOK)
+		if r.Method != "HEAD" {
+			io.Copy(w, rc)
+		}
+		return
 	}
+	ServeRange(w, r, etag, rc, cl)
 }
@@ -73,6 +73,7 @@ var fileServerTests = []*struct {
 		header: http.Header{
 			"Etag":           {testEtag},
 			"Cache-Control":  {"public, max-age=3"},
+			"Accept-Ranges":  {"bytes"},
 			"Content-Length": {testContentLength},
 			"Content-Type":   {"application/octet-stream"},
 		},
@@ -88,6 +89,7 @@ var fileServerTests = []*struct {
 		header: http.Header{
 			"Etag":           {testEtag},
 			"Cache-Control":  {"public, max-age=3"},
+			"Accept-Ranges":  {"bytes"},
 			"Content-Length": {testContentLength},
 			"Content-Type":   {"application/octet-stream"},
 		},
@@ -102,7 +104,24 @@ var fileServerTests = []*struct {
 		status: http.StatusOK,
 		header: http.Header{
 			"Etag":           {testEtag},
-			"Cache-Control":  {"public, max-age=31536000"},
+			"Cache-Control":  {"public, max-age=31536000, immutable"},
+			"Accept-Ranges":  {"bytes"},
+			"Content-Length": {testContentLength},
+			"Content-Type":   {"application/octet-stream"},
+		},
+	},
+	{
+		name: "get with cache control",
+		ss:   &httputil.StaticServer{MaxAge: 3 * time.Second, CacheControl: "no-cache"},
+		r: &http.Request{
+			URL:    mustParseURL("/dir/static_test.go"),
+			Method: "GET",
+		},
+		status: http.StatusOK,
+		header: http.Header{
+			"Etag":           {testEtag},
+			"Cache-Control":  {"no-cache"},
+			"Accept-Ranges":  {"bytes"},
 			"Content-Length": {testContentLength},
 			"Content-Type":   {"application/octet-stream"},
 		},
@@ -118,6 +137,7 @@ var fileServerTests = []*struct {
 		header: http.Header{
 			"Etag":           {testEtag},
 			"Cache-Control":  {"public, max-age=3"},
+			"Accept-Ranges":  {"bytes"},
 			"Content-Length": {testContentLength},
 			"Content-Type":   {"application/octet-stream"},
 		},
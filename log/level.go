@@ -0,0 +1,86 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	stdlog "log"
+	"strings"
+
+	"github.com/inconshreveable/log15"
+)
+
+// stdLevelPrefixes map the prefixes of the messages logged with the standard
+// library log package, followed by a space or a colon, to the levels of the
+// messages. Messages without one of the prefixes are logged at the info
+// level.
+var stdLevelPrefixes = []struct {
+	prefix string
+	lvl    log15.Lvl
+}{
+	{"DEBUG", log15.LvlDebug},
+	{"WARNING", log15.LvlWarn},
+	{"ERROR", log15.LvlError},
+}
+
+// Configure sets the root logger to write the messages at level or above to
+// w in the given format: "text" for logfmt or "json" for JSON objects with
+// the level in the lvl field. The messages of the standard library log
+// package are redirected to the root logger with the level given by their
+// DEBUG, WARNING or ERROR prefix.
+func Configure(w io.Writer, level, format string) error {
+	level = strings.ToLower(level)
+	if level == "warning" {
+		level = "warn"
+	}
+	lvl, err := log15.LvlFromString(level)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q, want debug, info, warn or error", level)
+	}
+	var f log15.Format
+	switch format {
+	case "text":
+		f = log15.LogfmtFormat()
+	case "json":
+		f = log15.JsonFormat()
+	default:
+		return fmt.Errorf("invalid log format %q, want text or json", format)
+	}
+	log15.Root().SetHandler(log15.LvlFilterHandler(lvl, log15.StreamHandler(w, f)))
+	stdlog.SetFlags(0)
+	stdlog.SetOutput(stdWriter{log15.Root()})
+	return nil
+}
+
+// stdWriter logs the messages of the standard library log package to l.
+type stdWriter struct {
+	l log15.Logger
+}
+
+func (w stdWriter) Write(p []byte) (int, error) {
+	msg := string(bytes.TrimRight(p, "\n"))
+	lvl := log15.LvlInfo
+	for _, lp := range stdLevelPrefixes {
+		if rest := strings.TrimPrefix(msg, lp.prefix); rest != msg && (strings.HasPrefix(rest, " ") || strings.HasPrefix(rest, ":")) {
+			msg, lvl = strings.TrimLeft(rest, ": "), lp.lvl
+			break
+		}
+	}
+	switch lvl {
+	case log15.LvlDebug:
+		w.l.Debug(msg)
+	case log15.LvlWarn:
+		w.l.Warn(msg)
+	case log15.LvlError:
+		w.l.Error(msg)
+	default:
+		w.l.Info(msg)
+	}
+	return len(p), nil
+}
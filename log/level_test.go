@@ -0,0 +1,69 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/inconshreveable/log15"
+)
+
+func TestStdWriter(t *testing.T) {
+	var buf bytes.Buffer
+	l := log15.New()
+	l.SetHandler(log15.LvlFilterHandler(log15.LvlInfo, log15.StreamHandler(&buf, log15.JsonFormat())))
+	w := stdWriter{l}
+	for _, msg := range []string{
+		"DEBUG git clone example.com/repo\n",
+		"crawl example.com/repo\n",
+		"WARNING: example.com may be fetched over an insecure protocol\n",
+		"ERROR db.Get(\"example.com/repo\"): timeout\n",
+	} {
+		if _, err := w.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type record struct {
+		Lvl string
+		Msg string
+	}
+	var got []record
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var r record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+		got = append(got, r)
+	}
+	want := []record{
+		{"info", "crawl example.com/repo"},
+		{"warn", "example.com may be fetched over an insecure protocol"},
+		{"eror", `db.Get("example.com/repo"): timeout`},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d records %v, want %v", len(got), got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("record %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestConfigureErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Configure(&buf, "verbose", "text"); err == nil {
+		t.Error("Configure accepted invalid level")
+	}
+	if err := Configure(&buf, "info", "xml"); err == nil {
+		t.Error("Configure accepted invalid format")
+	}
+}